@@ -8,39 +8,28 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/nhtuan0700/godis/internal/config"
 	"github.com/nhtuan0700/godis/internal/server"
 )
 
+// Usage: godis [/path/to/godis.conf] [--name value ...]
+//
+// --mode picks the server architecture: single-thread, multi-worker or reuseport (default),
+// --workers and --io-handlers size the multi-worker modes, see config.Mode
 func main() {
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-
-	s, err := server.NewServer()
-	if err != nil {
+	if err := config.Load(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	// Expose the /debug/pprof endpoints on a separate goroutine
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
 	}()
 
-	if err := s.StartMultiListeners(); err != nil {
+	if err := server.Run(signals); err != nil {
 		log.Fatal(err)
 	}
-
-	s.WaitForSignal(signals)
-
-	// wg := sync.WaitGroup{}
-	// wg.Add(2)
-
-	// go func() {
-	// 	// Single threaded listener server
-	// 	err := server.RunIOMultiplexingServer(&wg)
-	// 	if err != nil {
-	// 		log.Fatal(err)
-	// 	}
-	// }()
-
-	// go server.WaitForSignal(&wg, signals)
 }
@@ -0,0 +1,141 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// DefaultUser is the user of the connections that did not AUTH, it cannot be deleted
+const DefaultUser = "default"
+
+var (
+	errNoKeyPermission     = errors.New("NOPERM No permissions to access a key")
+	errNoChannelPermission = errors.New("NOPERM No permissions to access a channel")
+)
+
+// ACL holds the users of a server, the connections check every command against the user they authenticated as.
+// The users are replaced rather than changed, see User, so the lock is only held to look them up.
+type ACL struct {
+	mu    sync.RWMutex
+	users map[string]*User
+	log   denialLog
+}
+
+// New returns an ACL with only the default user, it needs the password of requirepass when one is set
+func New() *ACL {
+	return &ACL{users: map[string]*User{DefaultUser: newDefaultUser()}}
+}
+
+// newDefaultUser may run everything on every key and channel, like the default user of redis
+func newDefaultUser() *User {
+	u := newUser(DefaultUser)
+	u.enabled, u.allKeys, u.allChannels = true, true, true
+	_ = u.setCommandRule("+@all")
+	u.setRequirePass(config.RequirePass.Load())
+	return u
+}
+
+// setRequirePass replaces the passwords of the user with pass, an empty pass makes it nopass
+func (u *User) setRequirePass(pass string) {
+	u.passwords, u.nopass = nil, pass == ""
+	if pass != "" {
+		u.addPassword(hashPassword(pass))
+	}
+}
+
+// SetRequirePass applies CONFIG SET requirepass to the default user
+func (a *ACL) SetRequirePass(pass string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u := a.users[DefaultUser].clone()
+	u.setRequirePass(pass)
+	a.users[DefaultUser] = u
+}
+
+func (a *ACL) user(name string) *User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.users[name]
+}
+
+// Authenticate reports whether password opens the user, a disabled user cannot authenticate
+func (a *ACL) Authenticate(username, password string) bool {
+	u := a.user(username)
+	return u != nil && u.authenticate(password)
+}
+
+// AuthRequired reports whether a connection must AUTH before running commands:
+// the default user has a password or is disabled
+func (a *ACL) AuthRequired() bool {
+	u := a.user(DefaultUser)
+	return !u.enabled || !u.nopass
+}
+
+// Denial is an action the permissions of a user do not allow, it is the error replied to the client
+type Denial struct {
+	// Reason is "command", "key" or "channel", Object the command, key or channel denied
+	Reason string
+	Object string
+	err    error
+}
+
+func (d *Denial) Error() string {
+	return d.err.Error()
+}
+
+// Check returns the reason why the user may not run the command, nil if it may. The arity of args must be valid.
+// A deleted user may not run anything, its connections can only AUTH again.
+// Write commands need the write access to their keys, the other commands the read access.
+func (a *ACL) Check(username string, spec *core.CommandSpec, args []string) *Denial {
+	u := a.user(username)
+	name := strings.ToLower(spec.Name)
+	if u != nil && len(args) > 0 && u.subcommands[spec.Name] != nil {
+		name += "|" + strings.ToLower(args[0])
+	}
+	if u == nil || !u.canRun(spec, args) {
+		return &Denial{Reason: "command", Object: name,
+			err: fmt.Errorf("NOPERM User %s has no permissions to run the '%s' command", username, name)}
+	}
+
+	// the channels of the sharded Pub/Sub commands are reported as keys, they are checked as channels
+	if !spec.HasFlag(core.FlagPubSub) {
+		write := spec.HasFlag(core.FlagWrite)
+		for _, key := range spec.Keys(args) {
+			if !u.canAccessKey(key, write) {
+				return &Denial{Reason: "key", Object: key, err: errNoKeyPermission}
+			}
+		}
+		return nil
+	}
+
+	var channels []string
+	switch spec.Name {
+	case constant.CMD_PUBLISH, constant.CMD_SPUBLISH:
+		channels = args[:1]
+	case constant.CMD_SUBSCRIBE, constant.CMD_SSUBSCRIBE, constant.CMD_PSUBSCRIBE:
+		channels = args
+	}
+	for _, channel := range channels {
+		if !u.canAccessChannel(channel, spec.Name == constant.CMD_PSUBSCRIBE) {
+			return &Denial{Reason: "channel", Object: channel, err: errNoChannelPermission}
+		}
+	}
+	return nil
+}
+
+// LogDenial records d in ACL LOG, context is "toplevel" or "multi" for a command queued in a transaction
+func (a *ACL) LogDenial(d *Denial, username, context, clientInfo string) {
+	a.log.add(d.Reason, context, d.Object, username, clientInfo, time.Now())
+}
+
+// LogAuthFailure records a failed AUTH in ACL LOG
+func (a *ACL) LogAuthFailure(username, clientInfo string) {
+	a.log.add("auth", "toplevel", "AUTH", username, clientInfo, time.Now())
+}
@@ -0,0 +1,178 @@
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func execute(a *ACL, args ...string) string {
+	return string(a.Execute(DefaultUser, args))
+}
+
+func check(a *ACL, username string, args ...string) *Denial {
+	return a.Check(username, core.LookupCommand(args[0]), args[1:])
+}
+
+func TestDefaultUser(t *testing.T) {
+	a := New()
+	assert.False(t, a.AuthRequired())
+	assert.True(t, a.Authenticate(DefaultUser, "anything"))
+	assert.Equal(t, "*1\r\n$34\r\nuser default on nopass ~* &* +@all\r\n", execute(a, "LIST"))
+
+	a.SetRequirePass("secret")
+	assert.True(t, a.AuthRequired())
+	assert.False(t, a.Authenticate(DefaultUser, "anything"))
+	assert.True(t, a.Authenticate(DefaultUser, "secret"))
+	assert.Equal(t, "-ERR The 'default' user cannot be removed\r\n", execute(a, "DELUSER", "default"))
+
+	a.SetRequirePass("")
+	assert.False(t, a.AuthRequired())
+}
+
+func TestSetUser(t *testing.T) {
+	a := New()
+	assert.Equal(t, "+OK\r\n", execute(a, "SETUSER", "alice"))
+	assert.Equal(t, "user alice off resetchannels -@all", a.user("alice").describe())
+	assert.False(t, a.Authenticate("alice", ""))
+
+	assert.Equal(t, "+OK\r\n", execute(a, "SETUSER", "alice", "on", ">pw", "~app:*", "%R~shared:*", "&news.*", "+@read", "+set", "-mget", "+config|get"))
+	assert.True(t, a.Authenticate("alice", "pw"))
+	assert.False(t, a.Authenticate("alice", "nope"))
+	assert.Equal(t, "user alice on #"+hashPassword("pw")+" ~app:* %R~shared:* &news.* -@all +@read +set -mget +config|get",
+		a.user("alice").describe())
+	assert.Equal(t, "*12\r\n$5\r\nflags\r\n*1\r\n$2\r\non\r\n$9\r\npasswords\r\n*1\r\n$64\r\n"+hashPassword("pw")+"\r\n"+
+		"$8\r\ncommands\r\n$35\r\n-@all +@read +set -mget +config|get\r\n$4\r\nkeys\r\n$18\r\n~app:* %R~shared:*\r\n"+
+		"$8\r\nchannels\r\n$7\r\n&news.*\r\n$9\r\nselectors\r\n*0\r\n",
+		execute(a, "GETUSER", "alice"))
+	assert.Equal(t, "$-1\r\n", execute(a, "GETUSER", "bob"))
+
+	// applying the rules again changes nothing, a rule replaces the ones with the same target
+	assert.Equal(t, "+OK\r\n", execute(a, "SETUSER", "alice", "~app:*", "&news.*", "+@read", "+set", "+mget"))
+	assert.Equal(t, "user alice on #"+hashPassword("pw")+" ~app:* %R~shared:* &news.* -@all +config|get +@read +set +mget",
+		a.user("alice").describe())
+	execute(a, "SETUSER", "alice", "-mget")
+
+	// an invalid rule changes nothing
+	assert.Equal(t, "-ERR Error in ACL SETUSER modifier '+nope': Unknown command or category name in ACL\r\n",
+		execute(a, "SETUSER", "alice", "off", "+nope"))
+	assert.True(t, a.user("alice").enabled)
+	assert.Equal(t, "-ERR Error in ACL SETUSER modifier '<other': The password you are trying to remove from the user does not exist\r\n",
+		execute(a, "SETUSER", "alice", "<other"))
+	assert.Equal(t, "-ERR Error in ACL SETUSER modifier 'bad': Syntax error\r\n", execute(a, "SETUSER", "alice", "bad"))
+	assert.Equal(t, "-ERR Error in ACL SETUSER modifier '#abc': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters\r\n",
+		execute(a, "SETUSER", "alice", "#abc"))
+	assert.Contains(t, execute(a, "SETUSER", "bob", "allkeys", "~x"), "Adding a pattern after the * pattern")
+	assert.Equal(t, "-ERR Usernames can't contain spaces or null characters\r\n", execute(a, "SETUSER", "a b"))
+
+	assert.Equal(t, "+OK\r\n", execute(a, "SETUSER", "alice", "reset"))
+	assert.Equal(t, "user alice off resetchannels -@all", a.user("alice").describe())
+	assert.Equal(t, "*2\r\n$5\r\nalice\r\n$7\r\ndefault\r\n", execute(a, "USERS"))
+	assert.Equal(t, ":1\r\n", execute(a, "DELUSER", "alice", "bob"))
+}
+
+func TestCheck(t *testing.T) {
+	a := New()
+	execute(a, "SETUSER", "alice", "on", "nopass", "~app:*", "%R~shared:*", "&news.*", "+@read", "+set", "-mget", "+config|get", "+@pubsub")
+
+	assert.Nil(t, check(a, DefaultUser, "KEYS", "*"))
+	assert.Nil(t, check(a, "alice", "GET", "app:1"))
+	assert.Nil(t, check(a, "alice", "GET", "shared:1"))
+	assert.Nil(t, check(a, "alice", "SET", "app:1", "v"))
+	assert.Nil(t, check(a, "alice", "CONFIG", "GET", "hz"))
+
+	d := check(a, "alice", "SET", "shared:1", "v")
+	assert.Equal(t, "key", d.Reason)
+	assert.Equal(t, "shared:1", d.Object)
+	assert.EqualError(t, d, "NOPERM No permissions to access a key")
+	assert.EqualError(t, check(a, "alice", "EXISTS", "app:1", "other"), "NOPERM No permissions to access a key")
+
+	d = check(a, "alice", "MGET", "app:1")
+	assert.Equal(t, "command", d.Reason)
+	assert.EqualError(t, d, "NOPERM User alice has no permissions to run the 'mget' command")
+	assert.EqualError(t, check(a, "alice", "DEL", "app:1"), "NOPERM User alice has no permissions to run the 'del' command")
+	assert.EqualError(t, check(a, "alice", "CONFIG", "SET", "hz", "10"), "NOPERM User alice has no permissions to run the 'config|set' command")
+
+	assert.Nil(t, check(a, "alice", "PUBLISH", "news.tech", "hi"))
+	assert.Nil(t, check(a, "alice", "PSUBSCRIBE", "news.*"))
+	assert.Nil(t, check(a, "alice", "SPUBLISH", "news.tech", "hi"))
+	assert.EqualError(t, check(a, "alice", "SUBSCRIBE", "news.tech", "sport"), "NOPERM No permissions to access a channel")
+	// a pattern matching more channels than the ones of the user is denied
+	assert.EqualError(t, check(a, "alice", "PSUBSCRIBE", "news.t*"), "NOPERM No permissions to access a channel")
+	assert.Nil(t, check(a, "alice", "UNSUBSCRIBE", "sport"))
+
+	execute(a, "DELUSER", "alice")
+	assert.EqualError(t, check(a, "alice", "GET", "app:1"), "NOPERM User alice has no permissions to run the 'get' command")
+}
+
+func TestCat(t *testing.T) {
+	a := New()
+	assert.True(t, strings.HasPrefix(execute(a, "CAT"), "*23\r\n$8\r\nkeyspace\r\n"))
+	cat := execute(a, "CAT", "blocking")
+	assert.Contains(t, cat, "$5\r\nblpop\r\n")
+	assert.NotContains(t, cat, "$4\r\nlpop\r\n")
+	assert.Equal(t, "-ERR Unknown category 'nope'\r\n", execute(a, "CAT", "nope"))
+}
+
+func TestLog(t *testing.T) {
+	a := New()
+	execute(a, "SETUSER", "alice", "on", "nopass", "+get")
+	a.LogDenial(check(a, "alice", "GET", "k"), "alice", "toplevel", "fd=1")
+	a.LogDenial(check(a, "alice", "GET", "k"), "alice", "toplevel", "fd=2")
+	a.LogDenial(check(a, "alice", "SET", "k", "v"), "alice", "multi", "fd=2")
+	a.LogAuthFailure("bob", "fd=3")
+
+	entries := a.log.reply(10, time.Now())
+	assert.Len(t, entries, 3)
+	assert.Equal(t, []any{"reason", "auth", "context", "toplevel", "object", "AUTH", "username", "bob"}, entries[0].([]any)[2:10])
+	assert.Equal(t, []any{"count", 1, "reason", "command", "context", "multi", "object", "set"}, entries[1].([]any)[:8])
+	// the second denial of GET k is counted in the first entry, with the last client
+	last := entries[2].([]any)
+	assert.Equal(t, []any{"count", 2, "reason", "key", "context", "toplevel", "object", "k", "username", "alice"}, last[:10])
+	assert.Equal(t, []any{"client-info", "fd=2", "entry-id", int64(0)}, last[12:16])
+
+	assert.Len(t, a.log.reply(1, time.Now()), 1)
+	// a denial like an entry older than logGrouping starts a new one
+	a.log.add("key", "toplevel", "k", "alice", "fd=1", time.Now().Add(2*logGrouping))
+	assert.Len(t, a.log.reply(10, time.Now()), 4)
+
+	assert.Equal(t, "+OK\r\n", execute(a, "LOG", "RESET"))
+	assert.Equal(t, "*0\r\n", execute(a, "LOG"))
+	assert.Equal(t, "-ERR value is out of range, must be positive\r\n", execute(a, "LOG", "-1"))
+}
+
+func TestACLFile(t *testing.T) {
+	file := config.ACLFile
+	t.Cleanup(func() { config.ACLFile = file })
+	config.ACLFile = ""
+	a := New()
+	assert.True(t, strings.HasPrefix(execute(a, "SAVE"), "-ERR This instance is not configured to use an ACL file."))
+
+	config.ACLFile = filepath.Join(t.TempDir(), "users.acl")
+	execute(a, "SETUSER", "alice", "on", ">pw", "~app:*", "+@read")
+	assert.Equal(t, "+OK\r\n", execute(a, "SAVE"))
+	data, err := os.ReadFile(config.ACLFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "user alice on #"+hashPassword("pw")+" ~app:* resetchannels -@all +@read\nuser default on nopass ~* &* +@all\n", string(data))
+
+	loaded := New()
+	assert.Equal(t, "+OK\r\n", execute(loaded, "LOAD"))
+	assert.Equal(t, execute(a, "LIST"), execute(loaded, "LIST"))
+	assert.True(t, loaded.Authenticate("alice", "pw"))
+
+	// a file with an error changes nothing
+	assert.NoError(t, os.WriteFile(config.ACLFile, []byte("# users\nuser bob on\nuser carol +nope\n"), 0644))
+	assert.Equal(t, "-ERR "+config.ACLFile+":3: error in user declaration '+nope': Unknown command or category name in ACL\r\n", execute(loaded, "LOAD"))
+	assert.Nil(t, loaded.user("bob"))
+
+	// the default user is added when the file has none
+	assert.NoError(t, os.WriteFile(config.ACLFile, []byte("user bob on nopass +ping\n"), 0644))
+	assert.Equal(t, "+OK\r\n", execute(loaded, "LOAD"))
+	assert.Equal(t, "*2\r\n$3\r\nbob\r\n$7\r\ndefault\r\n", execute(loaded, "USERS"))
+}
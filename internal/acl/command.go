@@ -0,0 +1,194 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// Execute runs an ACL subcommand for a connection authenticated as username:
+//
+//	ACL SETUSER username [rule ...]
+//	ACL GETUSER username
+//	ACL DELUSER username [username ...]
+//	ACL LIST
+//	ACL USERS
+//	ACL WHOAMI
+//	ACL CAT [category]
+//	ACL LOG [count | RESET]
+//	ACL SAVE
+//	ACL LOAD
+func (a *ACL) Execute(username string, args []string) []byte {
+	sub := strings.ToUpper(args[0])
+	wrongArgs := core.Encode(fmt.Errorf("ERR wrong number of arguments for 'acl|%s' command", strings.ToLower(sub)), false)
+	switch sub {
+	case "SETUSER":
+		if len(args) < 2 {
+			return wrongArgs
+		}
+		if err := a.setUser(args[1], args[2:]); err != nil {
+			return core.Encode(err, false)
+		}
+		return constant.RespOk
+	case "GETUSER":
+		if len(args) != 2 {
+			return wrongArgs
+		}
+		u := a.user(args[1])
+		if u == nil {
+			return constant.RespNil
+		}
+		return core.Encode(u.info(), false)
+	case "DELUSER":
+		if len(args) < 2 {
+			return wrongArgs
+		}
+		deleted, err := a.deleteUsers(args[1:])
+		if err != nil {
+			return core.Encode(err, false)
+		}
+		return core.Encode(deleted, false)
+	case "LIST":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		return core.Encode(a.describeUsers(), false)
+	case "USERS":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		return core.Encode(a.usernames(), false)
+	case "WHOAMI":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		return core.Encode(username, false)
+	case "CAT":
+		if len(args) > 2 {
+			return wrongArgs
+		}
+		if len(args) == 1 {
+			return core.Encode(core.ACLCategories, false)
+		}
+		category := strings.ToLower(args[1])
+		if !slices.Contains(core.ACLCategories, category) {
+			return core.Encode(fmt.Errorf("ERR Unknown category '%s'", args[1]), false)
+		}
+		names := []string{}
+		for _, spec := range core.Commands() {
+			if spec.HasCategory(category) {
+				names = append(names, strings.ToLower(spec.Name))
+			}
+		}
+		return core.Encode(names, false)
+	case "LOG":
+		if len(args) > 2 {
+			return wrongArgs
+		}
+		count := 10
+		if len(args) == 2 {
+			if strings.ToUpper(args[1]) == "RESET" {
+				a.log.reset()
+				return constant.RespOk
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 {
+				return core.Encode(errors.New("ERR value is out of range, must be positive"), false)
+			}
+			count = n
+		}
+		return core.Encode(a.log.reply(count, time.Now()), false)
+	case "SAVE":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		if err := a.SaveFile(); err != nil {
+			if errors.Is(err, errNoACLFile) {
+				return core.Encode(err, false)
+			}
+			return core.Encode(errors.New("ERR There was an error trying to save the ACLs. Please check the server logs for more information"), false)
+		}
+		return constant.RespOk
+	case "LOAD":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		if err := a.LoadFile(); err != nil {
+			if errors.Is(err, errNoACLFile) {
+				return core.Encode(err, false)
+			}
+			return core.Encode(errors.New("ERR "+err.Error()), false)
+		}
+		return constant.RespOk
+	default:
+		return core.Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try ACL HELP.", args[0]), false)
+	}
+}
+
+// setUser applies rules to a copy of the user, a new one if it does not exist, and stores it when every rule is valid
+func (a *ACL) setUser(name string, rules []string) error {
+	if strings.ContainsAny(name, " \x00") {
+		return errors.New("ERR Usernames can't contain spaces or null characters")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u := newUser(name)
+	if existing, ok := a.users[name]; ok {
+		u = existing.clone()
+	}
+	for _, rule := range rules {
+		if err := u.setRule(rule); err != nil {
+			return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': %s", rule, err)
+		}
+	}
+	a.users[name] = u
+	return nil
+}
+
+// deleteUsers returns the number of users deleted, the connections authenticated as one of them can no longer run anything
+func (a *ACL) deleteUsers(names []string) (int, error) {
+	if slices.Contains(names, DefaultUser) {
+		return 0, errors.New("ERR The 'default' user cannot be removed")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	deleted := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (a *ACL) usernames() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// info is the ACL GETUSER reply, selectors are not supported so they are always empty
+func (u *User) info() []any {
+	return []any{
+		"flags", u.flags(),
+		"passwords", slices.Clone(u.passwords),
+		"commands", strings.Join(u.commandRules, " "),
+		"keys", strings.Join(u.keyRules(), " "),
+		"channels", strings.Join(u.channelRules(), " "),
+		"selectors", []any{},
+	}
+}
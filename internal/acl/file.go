@@ -0,0 +1,83 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/config"
+)
+
+var errNoACLFile = errors.New("ERR This instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and set aclfile to save them.")
+
+// LoadFile replaces the users by the ones of config.ACLFile, nothing changes when the file has an error.
+// The file has the lines of ACL LIST, a default user is added with its defaults when the file has none.
+func (a *ACL) LoadFile() error {
+	if config.ACLFile == "" {
+		return errNoACLFile
+	}
+	data, err := os.ReadFile(config.ACLFile)
+	if err != nil {
+		return err
+	}
+	users, err := parseUsers(config.ACLFile, string(data))
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users = users
+	return nil
+}
+
+// parseUsers parses the "user <name> [rule ...]" lines of an ACL file, empty lines and # comments are skipped
+func parseUsers(path, data string) (map[string]*User, error) {
+	users := make(map[string]*User)
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: should start with user keyword and a username", path, i+1)
+		}
+		name := fields[1]
+		if _, ok := users[name]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate user '%s' found", path, i+1, name)
+		}
+		u := newUser(name)
+		for _, rule := range fields[2:] {
+			if err := u.setRule(rule); err != nil {
+				return nil, fmt.Errorf("%s:%d: error in user declaration '%s': %s", path, i+1, rule, err)
+			}
+		}
+		users[name] = u
+	}
+	if _, ok := users[DefaultUser]; !ok {
+		users[DefaultUser] = newDefaultUser()
+	}
+	return users, nil
+}
+
+// SaveFile writes the users to config.ACLFile, one ACL LIST line each
+func (a *ACL) SaveFile() error {
+	if config.ACLFile == "" {
+		return errNoACLFile
+	}
+	return config.WriteFileAtomic(config.ACLFile, []byte(strings.Join(a.describeUsers(), "\n")+"\n"))
+}
+
+// describeUsers returns the ACL LIST lines, sorted by username
+func (a *ACL) describeUsers() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	lines := make([]string, 0, len(a.users))
+	for _, u := range a.users {
+		lines = append(lines, u.describe())
+	}
+	slices.Sort(lines)
+	return lines
+}
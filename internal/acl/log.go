@@ -0,0 +1,95 @@
+package acl
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+)
+
+// logGrouping is the time during which a denial like a logged one increments its count instead of adding an entry
+const logGrouping = 60 * time.Second
+
+// logEntry is an entry of ACL LOG
+type logEntry struct {
+	count      int
+	reason     string
+	context    string
+	object     string
+	username   string
+	clientInfo string
+	id         int64
+	created    time.Time
+	updated    time.Time
+}
+
+// denialLog keeps the last config.ACLLogMaxLen denials, the newest first
+type denialLog struct {
+	mu      sync.Mutex
+	entries []*logEntry
+	nextID  int64
+}
+
+func (l *denialLog) add(reason, context, object, username, clientInfo string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, e := range l.entries {
+		if e.reason == reason && e.context == context && e.object == object && e.username == username &&
+			now.Sub(e.created) < logGrouping {
+			e.count++
+			e.updated = now
+			e.clientInfo = clientInfo
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+
+	e := &logEntry{
+		count:      1,
+		reason:     reason,
+		context:    context,
+		object:     object,
+		username:   username,
+		clientInfo: clientInfo,
+		id:         l.nextID,
+		created:    now,
+		updated:    now,
+	}
+	l.nextID++
+	l.entries = append([]*logEntry{e}, l.entries...)
+	if maxLen := config.ACLLogMaxLen.Load(); len(l.entries) > maxLen {
+		l.entries = l.entries[:maxLen]
+	}
+}
+
+func (l *denialLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// reply is the ACL LOG reply with the count newest entries
+func (l *denialLog) reply(count int, now time.Time) []any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	res := make([]any, 0, min(count, len(l.entries)))
+	for _, e := range l.entries[:min(count, len(l.entries))] {
+		res = append(res, []any{
+			"count", e.count,
+			"reason", e.reason,
+			"context", e.context,
+			"object", e.object,
+			"username", e.username,
+			"age-seconds", strconv.FormatFloat(now.Sub(e.created).Seconds(), 'f', 3, 64),
+			"client-info", e.clientInfo,
+			"entry-id", e.id,
+			"timestamp-created", e.created.UnixMilli(),
+			"timestamp-last-updated", e.updated.UnixMilli(),
+		})
+	}
+	return res
+}
@@ -0,0 +1,371 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var (
+	errSyntax            = errors.New("Syntax error")
+	errUnknownCommand    = errors.New("Unknown command or category name in ACL")
+	errNoSuchPassword    = errors.New("The password you are trying to remove from the user does not exist")
+	errBadPasswordHash   = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	errKeyAfterAllKeys   = errors.New("Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns")
+	errChannelAfterAllCh = errors.New("Adding a pattern after the * pattern (or the 'allchannels' flag) is not valid and does not have any effect. Try 'resetchannels' to start with an empty list of channels")
+)
+
+// User is an ACL user. A user is not changed once it is in the ACL, SETUSER stores a changed copy instead,
+// so the checks of the connections read it without holding the lock of the ACL.
+type User struct {
+	name    string
+	enabled bool
+	// nopass users accept any password
+	nopass bool
+	// passwords are SHA-256 hex digests, in the order they were added
+	passwords []string
+
+	// commands tells whether a command is allowed by its upper-case name,
+	// subcommands overrides it for the first argument of a command, e.g. +config|get
+	commands    map[string]bool
+	subcommands map[string]map[string]bool
+	// commandRules are the rules applied since the last +@all or -@all, they describe the commands of the user
+	commandRules []string
+
+	// allKeys and allChannels are the ~* and &* patterns, no other pattern can be added after them
+	allKeys     bool
+	keys        []keyPattern
+	allChannels bool
+	channels    []string
+}
+
+// keyPattern is a ~pattern, %R~pattern or %W~pattern rule
+type keyPattern struct {
+	pattern     string
+	read, write bool
+}
+
+// newUser returns a user that is off, has no password and may run nothing, like a user created by ACL SETUSER
+func newUser(name string) *User {
+	return &User{
+		name:         name,
+		commands:     make(map[string]bool),
+		subcommands:  make(map[string]map[string]bool),
+		commandRules: []string{"-@all"},
+	}
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.commands = maps.Clone(u.commands)
+	c.subcommands = make(map[string]map[string]bool, len(u.subcommands))
+	for name, subs := range u.subcommands {
+		c.subcommands[name] = maps.Clone(subs)
+	}
+	c.commandRules = slices.Clone(u.commandRules)
+	c.keys = slices.Clone(u.keys)
+	c.channels = slices.Clone(u.channels)
+	return &c
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// setRule applies one rule of ACL SETUSER, the errors are the ones redis reports for the rule
+func (u *User) setRule(rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		u.enabled = true
+		return nil
+	case "off":
+		u.enabled = false
+		return nil
+	case "nopass":
+		u.passwords, u.nopass = nil, true
+		return nil
+	case "resetpass":
+		u.passwords, u.nopass = nil, false
+		return nil
+	case "allkeys":
+		u.allKeys, u.keys = true, nil
+		return nil
+	case "resetkeys":
+		u.allKeys, u.keys = false, nil
+		return nil
+	case "allchannels":
+		u.allChannels, u.channels = true, nil
+		return nil
+	case "resetchannels":
+		u.allChannels, u.channels = false, nil
+		return nil
+	case "allcommands":
+		return u.setCommandRule("+@all")
+	case "nocommands":
+		return u.setCommandRule("-@all")
+	case "reset":
+		*u = *newUser(u.name)
+		return nil
+	}
+	if rule == "" {
+		return errSyntax
+	}
+
+	switch rule[0] {
+	case '>':
+		u.addPassword(hashPassword(rule[1:]))
+	case '#':
+		if !isPasswordHash(rule[1:]) {
+			return errBadPasswordHash
+		}
+		u.addPassword(rule[1:])
+	case '<':
+		return u.removePassword(hashPassword(rule[1:]))
+	case '!':
+		if !isPasswordHash(rule[1:]) {
+			return errBadPasswordHash
+		}
+		return u.removePassword(rule[1:])
+	case '~':
+		return u.addKeyPattern(keyPattern{pattern: rule[1:], read: true, write: true})
+	case '%':
+		perms, pattern, ok := strings.Cut(rule[1:], "~")
+		if !ok || perms == "" {
+			return errSyntax
+		}
+		p := keyPattern{pattern: pattern}
+		for _, c := range strings.ToUpper(perms) {
+			switch c {
+			case 'R':
+				p.read = true
+			case 'W':
+				p.write = true
+			default:
+				return errSyntax
+			}
+		}
+		return u.addKeyPattern(p)
+	case '&':
+		return u.addChannel(rule[1:])
+	case '+', '-':
+		return u.setCommandRule(rule)
+	default:
+		return errSyntax
+	}
+	return nil
+}
+
+func isPasswordHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// addPassword adds a password hash, a user with a password is no longer nopass
+func (u *User) addPassword(hash string) {
+	u.nopass = false
+	if !slices.Contains(u.passwords, hash) {
+		u.passwords = append(u.passwords, hash)
+	}
+}
+
+func (u *User) removePassword(hash string) error {
+	i := slices.Index(u.passwords, hash)
+	if i < 0 {
+		return errNoSuchPassword
+	}
+	u.passwords = slices.Delete(u.passwords, i, i+1)
+	return nil
+}
+
+func (u *User) addKeyPattern(p keyPattern) error {
+	if u.allKeys {
+		return errKeyAfterAllKeys
+	}
+	if p.pattern == "*" && p.read && p.write {
+		u.allKeys, u.keys = true, nil
+		return nil
+	}
+	if !slices.Contains(u.keys, p) {
+		u.keys = append(u.keys, p)
+	}
+	return nil
+}
+
+func (u *User) addChannel(pattern string) error {
+	if u.allChannels {
+		return errChannelAfterAllCh
+	}
+	if pattern == "*" {
+		u.allChannels, u.channels = true, nil
+		return nil
+	}
+	if !slices.Contains(u.channels, pattern) {
+		u.channels = append(u.channels, pattern)
+	}
+	return nil
+}
+
+// setCommandRule applies +command, -command, +command|subcommand, +@category and their - forms
+func (u *User) setCommandRule(rule string) error {
+	allow := rule[0] == '+'
+	name := rule[1:]
+
+	if category, ok := strings.CutPrefix(name, "@"); ok {
+		category = strings.ToLower(category)
+		if category == "all" {
+			for _, spec := range core.Commands() {
+				u.setCommand(spec.Name, allow)
+			}
+			u.commandRules = []string{strings.ToLower(rule)}
+			return nil
+		}
+		if !slices.Contains(core.ACLCategories, category) {
+			return errUnknownCommand
+		}
+		for _, spec := range core.Commands() {
+			if spec.HasCategory(category) {
+				u.setCommand(spec.Name, allow)
+			}
+		}
+	} else if name, sub, ok := strings.Cut(name, "|"); ok {
+		spec := core.LookupCommand(name)
+		if spec == nil || sub == "" {
+			return errUnknownCommand
+		}
+		subs, ok := u.subcommands[spec.Name]
+		if !ok {
+			subs = make(map[string]bool)
+			u.subcommands[spec.Name] = subs
+		}
+		subs[strings.ToLower(sub)] = allow
+	} else {
+		spec := core.LookupCommand(name)
+		if spec == nil {
+			return errUnknownCommand
+		}
+		u.setCommand(spec.Name, allow)
+	}
+	// a rule overrides the earlier rules with the same target, they are no longer needed to describe the user
+	rule = strings.ToLower(rule)
+	u.commandRules = slices.DeleteFunc(u.commandRules, func(r string) bool { return r[1:] == rule[1:] })
+	u.commandRules = append(u.commandRules, rule)
+	return nil
+}
+
+// setCommand allows or denies a command with all its subcommands
+func (u *User) setCommand(name string, allow bool) {
+	u.commands[name] = allow
+	delete(u.subcommands, name)
+}
+
+// canRun reports whether the user may run the command with args, a subcommand rule wins over the command
+func (u *User) canRun(spec *core.CommandSpec, args []string) bool {
+	if subs, ok := u.subcommands[spec.Name]; ok && len(args) > 0 {
+		if allowed, ok := subs[strings.ToLower(args[0])]; ok {
+			return allowed
+		}
+	}
+	return u.commands[spec.Name]
+}
+
+// canAccessKey reports whether a pattern of the user gives the read or write access to key
+func (u *User) canAccessKey(key string, write bool) bool {
+	if u.allKeys {
+		return true
+	}
+	for _, p := range u.keys {
+		if ((write && p.write) || (!write && p.read)) && core.GlobMatch(p.pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessChannel reports whether the user may use channel. A PSUBSCRIBE pattern is not matched,
+// it must be one of the patterns of the user like in redis, otherwise it could match forbidden channels.
+func (u *User) canAccessChannel(channel string, isPattern bool) bool {
+	if u.allChannels {
+		return true
+	}
+	for _, pattern := range u.channels {
+		if (isPattern && pattern == channel) || (!isPattern && core.GlobMatch(pattern, channel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate reports whether password opens the user
+func (u *User) authenticate(password string) bool {
+	return u.enabled && (u.nopass || slices.Contains(u.passwords, hashPassword(password)))
+}
+
+func (u *User) flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+func (u *User) keyRules() []string {
+	if u.allKeys {
+		return []string{"~*"}
+	}
+	rules := make([]string, 0, len(u.keys))
+	for _, p := range u.keys {
+		switch {
+		case p.read && p.write:
+			rules = append(rules, "~"+p.pattern)
+		case p.read:
+			rules = append(rules, "%R~"+p.pattern)
+		default:
+			rules = append(rules, "%W~"+p.pattern)
+		}
+	}
+	return rules
+}
+
+func (u *User) channelRules() []string {
+	if u.allChannels {
+		return []string{"&*"}
+	}
+	rules := make([]string, 0, len(u.channels))
+	for _, pattern := range u.channels {
+		rules = append(rules, "&"+pattern)
+	}
+	return rules
+}
+
+// describe is the line of the user in ACL LIST and in the ACL file, SETUSER with its rules recreates the user
+func (u *User) describe() string {
+	rules := append([]string{"user", u.name}, u.flags()...)
+	for _, hash := range u.passwords {
+		rules = append(rules, "#"+hash)
+	}
+	rules = append(rules, u.keyRules()...)
+	if channels := u.channelRules(); len(channels) > 0 {
+		rules = append(rules, channels...)
+	} else {
+		rules = append(rules, "resetchannels")
+	}
+	rules = append(rules, u.commandRules...)
+	return strings.Join(rules, " ")
+}
@@ -1,16 +1,143 @@
 package config
 
+import (
+	"net"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// The settings below are read by Load from the config file and the command line, see params.go.
+// Plain variables are only read after startup, the Setting ones can also be changed by CONFIG SET.
+
 const Protocol = "tcp"
-const Address = ":3000"
-const MaxConnections = 20000
 
-const EvictionRatio = 0.1
-const MaxKeyNumber = 10
+var Bind = ""
+var Port = 3000
+
+// Address is the address the listeners bind to
+func Address() string {
+	return net.JoinHostPort(Bind, strconv.Itoa(Port))
+}
+
+var MaxConnections = 20000
+
+// Mode is the server architecture:
+// "single-thread" runs the commands in the event loop accepting the connections,
+// "multi-worker" has one listener handing connections to I/O handlers, the keyspace is sharded across workers,
+// "reuseport" is "multi-worker" with ListenerNumber listeners sharing the port with SO_REUSEPORT.
+var Mode = "reuseport"
+
+var Modes = []string{"single-thread", "multi-worker", "reuseport"}
+
+// Workers and IOHandlers of the multi-worker modes, each worker owns a shard of the keyspace
+var Workers = max(1, runtime.NumCPU()/2)
+var IOHandlers = max(1, runtime.NumCPU()/2)
+
+// MaxMemory in bytes, keys are evicted when the memory used by the keyspace goes above it, 0 means no limit.
+// Each worker gets an equal share of it.
+var MaxMemory = NewSetting[int64](0)
 
-// policy: "allkeys-random" | "allkeys-lru"
-const EvictPolicy = "allkeys-lru"
+// policy: "allkeys-random" | "allkeys-lru" | "allkeys-lfu" |
+// "volatile-random" | "volatile-lru" | "volatile-lfu" | "volatile-ttl" | "noeviction".
+// The volatile policies only evict keys with a TTL, with noeviction the commands that may use
+// more memory are rejected with an OOM error once maxmemory is reached.
+var EvictPolicy = NewSetting("allkeys-lru")
+
+var EvictPolicies = []string{
+	"allkeys-random", "allkeys-lru", "allkeys-lfu",
+	"volatile-random", "volatile-lru", "volatile-lfu", "volatile-ttl", "noeviction",
+}
+
+// LFU access counter, same as redis lfu-log-factor and lfu-decay-time:
+// the higher the log factor the more hits are needed to grow the counter,
+// the counter is decremented once every LfuDecayTime minutes without access, 0 never decrements it
+var LfuLogFactor = NewSetting(10)
+var LfuDecayTime = NewSetting(1)
 
 const EpoolMaxSize = 16
-const LruSampledSize = 5
 
-const ListenerNumber = 2
+// LruSampledSize is the number of keys sampled to pick the one to evict, same as redis maxmemory-samples
+var LruSampledSize = NewSetting(5)
+
+// MemorySamples is the number of elements of a collection whose size is sampled
+// to estimate the memory it uses, like the default of MEMORY USAGE
+const MemorySamples = 5
+
+var ListenerNumber = 2
+
+// Same as redis client-query-buffer-limit, a client sending more without a complete command is closed
+var MaxQueryBufferSize = NewSetting[int64](1024 * 1024 * 1024)
+
+// OutputBufferLimit is a class of the redis client-output-buffer-limit directive: a client whose replies
+// not written to the socket yet reach Hard bytes, or stay above Soft bytes for SoftSeconds, is closed.
+// 0 disables a limit.
+type OutputBufferLimit struct {
+	Hard        int64
+	Soft        int64
+	SoftSeconds int
+}
+
+// Client classes of OutputBufferLimits, a client with subscriptions is a pubsub client
+const (
+	ClientNormal = iota
+	ClientPubSub
+)
+
+var ClientClasses = []string{"normal", "pubsub"}
+
+// OutputBufferLimits per client class. Pubsub has the redis default, unlike redis normal clients are
+// limited too, a client pipelining commands without reading the replies would grow them without bound.
+var OutputBufferLimits = NewSetting([2]OutputBufferLimit{
+	ClientNormal: {Hard: 1024 * 1024 * 1024},
+	ClientPubSub: {Hard: 32 * 1024 * 1024, Soft: 8 * 1024 * 1024, SoftSeconds: 60},
+})
+
+// Hz is the number of active expiration cycles per second, same as redis hz
+var Hz = NewSetting(2)
+
+// ActiveExpireFrequency is the period of the active expiration cycle
+func ActiveExpireFrequency() time.Duration {
+	return time.Second / time.Duration(Hz.Load())
+}
+
+// Snapshot file, loaded at startup and written by SAVE, BGSAVE and the save policy
+var RDBDir = "."
+var RDBFileName = "dump.rdb"
+
+// SaveParam same as the redis "save <seconds> <changes>" directive:
+// snapshot when at least Changes writes happened in the last Seconds
+type SaveParam struct {
+	Seconds int
+	Changes int64
+}
+
+// Redis default save policy, an empty list disables automatic snapshots
+var SaveParams = NewSetting([]SaveParam{
+	{Seconds: 3600, Changes: 1},
+	{Seconds: 300, Changes: 100},
+	{Seconds: 60, Changes: 10000},
+})
+
+// Append only file, when enabled it is loaded at startup instead of the snapshot
+var AppendOnly = false
+
+var AppendFilename = "appendonly.aof"
+
+// AppendFsync: "always" | "everysec" | "no"
+var AppendFsync = "everysec"
+
+// RequirePass is the password of the default user, same as redis requirepass, empty means none.
+// CONFIG SET replaces the passwords of the default user with it.
+var RequirePass = NewSetting("")
+
+// ACLFile holds the ACL users, it is loaded at startup and by ACL LOAD and written by ACL SAVE.
+// Without it the users only live in memory.
+var ACLFile = ""
+
+// ACLLogMaxLen is the number of denied actions kept by ACL LOG
+var ACLLogMaxLen = NewSetting(128)
+
+// ProtectedMode same as redis protected-mode: while the server binds to every interface and the default user
+// has no password, only the connections from the loopback interface are accepted
+var ProtectedMode = NewSetting(true)
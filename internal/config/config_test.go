@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// restoreParams puts back every param and the config file after the test
+func restoreParams(t *testing.T) {
+	values := make(map[*param]string)
+	for _, p := range params {
+		values[p] = p.get()
+	}
+	file := configFile
+	t.Cleanup(func() {
+		for p, v := range values {
+			store, err := p.prepare(v)
+			assert.NoError(t, err)
+			store()
+		}
+		configFile = file
+	})
+}
+
+func TestParseMemory(t *testing.T) {
+	for s, n := range map[string]int64{"0": 0, "100": 100, "1k": 1000, "1kb": 1024, "2MB": 2 << 20, "1g": 1e9, "1gb": 1 << 30, "7b": 7} {
+		v, err := ParseMemory(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, n, v, s)
+	}
+	for _, s := range []string{"", "-1", "kb", "1tb", "1.5mb", "99999999999gb"} {
+		_, err := ParseMemory(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSet(t *testing.T) {
+	restoreParams(t)
+	assert.NoError(t, Set("hz", "20", "maxmemory", "1mb", "maxmemory-policy", "ALLKEYS-LFU"))
+	assert.Equal(t, 20, Hz.Load())
+	assert.Equal(t, int64(1<<20), MaxMemory.Load())
+	assert.Equal(t, "allkeys-lfu", EvictPolicy.Load())
+	assert.Equal(t, []string{"maxmemory", "1048576", "maxmemory-policy", "allkeys-lfu", "maxmemory-samples", "5"}, Get("MAXMEMORY*"))
+
+	// nothing is changed when one of the values is wrong
+	assert.EqualError(t, Set("hz", "30", "maxmemory", "lots"),
+		"ERR CONFIG SET failed (possibly related to argument 'maxmemory') - argument must be a memory value")
+	assert.Equal(t, 20, Hz.Load())
+	assert.EqualError(t, Set("hz", "30", "hz", "40"),
+		"ERR CONFIG SET failed (possibly related to argument 'hz') - duplicate parameter")
+	assert.EqualError(t, Set("port", "1"),
+		"ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config")
+	assert.EqualError(t, Set("nope", "1"), "ERR Unknown option or number of arguments for CONFIG SET - 'nope'")
+	assert.EqualError(t, Set("appendfsync", "never"),
+		"ERR CONFIG SET failed (possibly related to argument 'appendfsync') - can't set immutable config")
+	assert.EqualError(t, Set("maxmemory-policy", "lru"),
+		"ERR CONFIG SET failed (possibly related to argument 'maxmemory-policy') - argument(s) must be one of the following: "+
+			"allkeys-random, allkeys-lru, allkeys-lfu, volatile-random, volatile-lru, volatile-lfu, volatile-ttl, noeviction")
+
+	assert.NoError(t, Set("save", ""))
+	assert.Empty(t, SaveParams.Load())
+	assert.NoError(t, Set("save", "10 1 20 2"))
+	assert.Equal(t, []SaveParam{{10, 1}, {20, 2}}, SaveParams.Load())
+	assert.Error(t, Set("save", "10"))
+	assert.Equal(t, []string{"save", "10 1 20 2"}, Get("save"))
+}
+
+func TestSplitArgs(t *testing.T) {
+	for line, args := range map[string][]string{
+		"":                       nil,
+		"   ":                    nil,
+		"port 6380":              {"port", "6380"},
+		"  save  60\t100 ":       {"save", "60", "100"},
+		`dir "/tmp/my dir"`:      {"dir", "/tmp/my dir"},
+		`dir "a\"b\\c\x41\n"`:    {"dir", "a\"b\\cA\n"},
+		`dir 'it is "raw" \n'`:   {"dir", `it is "raw" \n`},
+		`save ""`:                {"save", ""},
+		"# comment with 'quote'": {"#", "comment", "with", "quote"},
+	} {
+		got, err := splitArgs(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, args, got, line)
+	}
+	for _, line := range []string{`dir "open`, `dir 'open`, `dir "a"b`} {
+		_, err := splitArgs(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestOutputBufferLimits(t *testing.T) {
+	restoreParams(t)
+	assert.Equal(t, []string{"client-output-buffer-limit", "normal 1073741824 0 0 pubsub 33554432 8388608 60"},
+		Get("client-output-buffer-limit"))
+
+	// the classes that are not listed keep their limits
+	assert.NoError(t, Set("client-output-buffer-limit", "PUBSUB 1mb 512kb 10"))
+	assert.Equal(t, [2]OutputBufferLimit{
+		ClientNormal: {Hard: 1 << 30},
+		ClientPubSub: {Hard: 1 << 20, Soft: 512 << 10, SoftSeconds: 10},
+	}, OutputBufferLimits.Load())
+	assert.NoError(t, Set("client-output-buffer-limit", "normal 0 0 0 pubsub 0 0 0"))
+	assert.Equal(t, [2]OutputBufferLimit{}, OutputBufferLimits.Load())
+
+	assert.Error(t, Set("client-output-buffer-limit", "replica 1mb 1mb 60"))
+	assert.Error(t, Set("client-output-buffer-limit", "normal 1mb 1mb"))
+	assert.Error(t, Set("client-output-buffer-limit", "normal 1mb 1mb -1"))
+	assert.Equal(t, [2]OutputBufferLimit{}, OutputBufferLimits.Load())
+}
+
+func TestLoad(t *testing.T) {
+	restoreParams(t)
+	path := filepath.Join(t.TempDir(), "godis.conf")
+	assert.NoError(t, os.WriteFile(path, []byte(`# the save lines add up
+port 6380
+save 900 1
+save 300 10
+dir "/tmp/my dir"
+maxmemory 100mb
+`), 0600))
+	assert.NoError(t, Load([]string{path, "--maxmemory", "2gb", "--save", "60", "1", "--appendonly", "yes"}))
+	assert.Equal(t, 6380, Port)
+	assert.Equal(t, ":6380", Address())
+	assert.Equal(t, "/tmp/my dir", RDBDir)
+	// the command line comes after the file
+	assert.Equal(t, int64(2<<30), MaxMemory.Load())
+	assert.Equal(t, []SaveParam{{60, 1}}, SaveParams.Load())
+	assert.True(t, AppendOnly)
+
+	assert.NoError(t, os.WriteFile(path, []byte("port 6380\nhz 0\n"), 0600))
+	assert.ErrorContains(t, Load([]string{path}), "line 2: argument must be between 1 and 500 inclusive")
+	assert.NoError(t, os.WriteFile(path, []byte("nope 1\n"), 0600))
+	assert.ErrorContains(t, Load([]string{path}), "line 1: unknown directive 'nope'")
+	assert.Error(t, Load([]string{"--port"}))
+	assert.Error(t, Load([]string{"--port", "1", "2"}))
+	assert.Error(t, Load([]string{"--", "1"}))
+	assert.Error(t, Load([]string{filepath.Join(t.TempDir(), "missing.conf")}))
+}
+
+func TestRewrite(t *testing.T) {
+	restoreParams(t)
+	configFile = ""
+	assert.ErrorIs(t, Rewrite(), ErrNoConfigFile)
+
+	path := filepath.Join(t.TempDir(), "godis.conf")
+	assert.NoError(t, os.WriteFile(path, []byte(`# my config
+port 6380
+save 900 1
+# between the save lines
+save 300 10
+
+hz 10
+`), 0600))
+	assert.NoError(t, Load([]string{path}))
+	assert.NoError(t, Set("save", "60 5", "maxmemory", "1kb", "hz", "2"))
+	assert.NoError(t, Rewrite())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	// the lines are updated in place, the params changed from their default are appended
+	assert.Equal(t, `# my config
+port 6380
+save 60 5
+# between the save lines
+
+hz 2
+# Generated by CONFIG REWRITE
+maxmemory 1024
+`, string(data))
+
+	// a second rewrite changes the lines written by the first one
+	assert.NoError(t, Set("maxmemory", "2kb", "maxmemory-policy", "noeviction"))
+	assert.NoError(t, Rewrite())
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "# Generated by CONFIG REWRITE\nmaxmemory 2048\nmaxmemory-policy noeviction\n")
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configFile is the absolute path of the config file given to Load, CONFIG REWRITE writes it back
+var configFile string
+
+const rewriteSignature = "# Generated by CONFIG REWRITE"
+
+var ErrNoConfigFile = errors.New("ERR The server is running without a config file")
+
+// Load applies the config file then the command line options, like redis-server:
+//
+//	godis [/path/to/godis.conf] [--name value ...]
+//
+// An option takes every argument up to the next one, e.g. --save 60 1000 300 10
+func Load(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		if err := loadFile(args[0]); err != nil {
+			return err
+		}
+		args = args[1:]
+	}
+	l := newLoader()
+	for i := 0; i < len(args); {
+		if !strings.HasPrefix(args[i], "--") || len(args[i]) == 2 {
+			return fmt.Errorf("invalid option '%s', options look like --name value", args[i])
+		}
+		j := i + 1
+		for j < len(args) && !strings.HasPrefix(args[j], "--") {
+			j++
+		}
+		if err := l.apply(append([]string{args[i][2:]}, args[i+1:j]...)); err != nil {
+			return fmt.Errorf("option '%s': %w", args[i], err)
+		}
+		i = j
+	}
+	return nil
+}
+
+func loadFile(name string) error {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	l := newLoader()
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		args, err := splitArgs(scanner.Text())
+		if err == nil && (len(args) == 0 || strings.HasPrefix(args[0], "#")) {
+			continue
+		}
+		if err == nil {
+			err = l.apply(args)
+		}
+		if err != nil {
+			return fmt.Errorf("config file %s, line %d: %w", path, lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	configFile = path
+	return nil
+}
+
+// loader applies the lines of a config file or the command line options
+type loader struct {
+	seen map[*param]bool
+}
+
+func newLoader() *loader {
+	return &loader{seen: make(map[*param]bool)}
+}
+
+func (l *loader) apply(args []string) error {
+	p := lookup(args[0])
+	if p == nil {
+		return fmt.Errorf("unknown directive '%s'", args[0])
+	}
+	value := strings.Join(args[1:], " ")
+	if p.multiLine {
+		// the first line replaces the default, the next ones add to it
+		if l.seen[p] {
+			value = p.get() + " " + value
+		}
+	} else if len(args) != 2 {
+		return fmt.Errorf("wrong number of arguments for '%s'", p.name)
+	}
+	l.seen[p] = true
+	store, err := p.prepare(value)
+	if err != nil {
+		return err
+	}
+	store()
+	return nil
+}
+
+// splitArgs splits a config line into its arguments like redis sdssplitargs:
+// arguments are separated by spaces and may be quoted, "" supports escapes like \n and \x41
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t' || line[i] == '\r') {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+		var arg strings.Builder
+		switch line[i] {
+		case '"':
+			i++
+			for {
+				if i == len(line) {
+					return nil, errors.New("unbalanced quotes")
+				}
+				if line[i] == '"' {
+					i++
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						arg.WriteByte('\n')
+					case 'r':
+						arg.WriteByte('\r')
+					case 't':
+						arg.WriteByte('\t')
+					case 'a':
+						arg.WriteByte('\a')
+					case 'b':
+						arg.WriteByte('\b')
+					case 'x':
+						if b, err := strconv.ParseUint(line[min(i+1, len(line)):min(i+3, len(line))], 16, 8); err == nil {
+							arg.WriteByte(byte(b))
+							i += 2
+						} else {
+							arg.WriteByte('x')
+						}
+					default:
+						arg.WriteByte(line[i])
+					}
+					i++
+					continue
+				}
+				arg.WriteByte(line[i])
+				i++
+			}
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unbalanced quotes")
+			}
+			arg.WriteString(line[i+1 : i+1+end])
+			i += end + 2
+		default:
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' {
+				arg.WriteByte(line[i])
+				i++
+			}
+			args = append(args, arg.String())
+			continue
+		}
+		// a closing quote must be followed by a space or the end of the line
+		if i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' {
+			return nil, errors.New("closing quote must be followed by a space")
+		}
+		args = append(args, arg.String())
+	}
+}
+
+// line formats the param as a config file line
+func (p *param) line() string {
+	value := p.get()
+	if p.multiLine && value != "" {
+		return p.name + " " + value
+	}
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"'\\#") || strconv.Quote(value) != `"`+value+`"` {
+		value = strconv.Quote(value)
+	}
+	return p.name + " " + value
+}
+
+// Rewrite writes the current settings to the config file like CONFIG REWRITE:
+// the lines of the params are updated in place, comments are kept,
+// and the params changed from their default that the file misses are appended
+func Rewrite() error {
+	if configFile == "" {
+		return ErrNoConfigFile
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	var out []string
+	written := make(map[*param]bool)
+	signed := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == rewriteSignature {
+			signed = true
+		}
+		args, err := splitArgs(line)
+		if err != nil || len(args) == 0 {
+			out = append(out, line)
+			continue
+		}
+		p := lookup(args[0])
+		if p == nil {
+			out = append(out, line)
+			continue
+		}
+		// the first line of a param holds its whole value, the next ones are dropped
+		if !written[p] {
+			written[p] = true
+			out = append(out, p.line())
+		}
+	}
+	for _, p := range params {
+		if written[p] || p.get() == p.def {
+			continue
+		}
+		if !signed {
+			out = append(out, rewriteSignature)
+			signed = true
+		}
+		out = append(out, p.line())
+	}
+	return WriteFileAtomic(configFile, []byte(strings.Join(out, "\n")+"\n"))
+}
+
+// WriteFileAtomic replaces the file by renaming a temporary one so a crash never leaves it half written
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf("temp-%d-*%s", os.Getpid(), filepath.Ext(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// param is a setting that can be read and written by name: in the config file,
+// on the command line and with CONFIG GET and CONFIG SET
+type param struct {
+	name string
+	// only mutable params can be changed by CONFIG SET, the others are read once at startup
+	mutable bool
+	// multiLine params accumulate their repeated lines in a config file, like save
+	multiLine bool
+	get       func() string
+	// prepare validates a value and returns the function storing it,
+	// so CONFIG SET can check every value before changing any
+	prepare func(value string) (func(), error)
+	// def is the value before Load, CONFIG REWRITE only adds the params that differ from it
+	def string
+}
+
+// value is a Setting or a variable
+type value[T any] interface {
+	Load() T
+	Store(T)
+}
+
+// variable is a plain setting, read only after startup
+type variable[T any] struct {
+	p *T
+}
+
+func (v variable[T]) Load() T {
+	return *v.p
+}
+
+func (v variable[T]) Store(x T) {
+	*v.p = x
+}
+
+func newParam[T any](name string, mutable bool, v value[T], parse func(string) (T, error), format func(T) string) *param {
+	return &param{
+		name:    name,
+		mutable: mutable,
+		get:     func() string { return format(v.Load()) },
+		prepare: func(s string) (func(), error) {
+			x, err := parse(s)
+			if err != nil {
+				return nil, err
+			}
+			return func() { v.Store(x) }, nil
+		},
+	}
+}
+
+var params = []*param{
+	newParam("bind", false, variable[string]{&Bind}, parseString, formatString),
+	newParam("port", false, variable[int]{&Port}, intRange(0, 65535), strconv.Itoa),
+	newParam("maxclients", false, variable[int]{&MaxConnections}, intRange(1, math.MaxInt32), strconv.Itoa),
+	newParam("mode", false, variable[string]{&Mode}, oneOf(Modes...), formatString),
+	newParam("workers", false, variable[int]{&Workers}, intRange(1, 1024), strconv.Itoa),
+	newParam("io-handlers", false, variable[int]{&IOHandlers}, intRange(1, 1024), strconv.Itoa),
+	newParam("listeners", false, variable[int]{&ListenerNumber}, intRange(1, 1024), strconv.Itoa),
+	newParam("client-query-buffer-limit", true, MaxQueryBufferSize, memoryRange(1024*1024, math.MaxInt64), formatInt64),
+	multiLine(newParam("client-output-buffer-limit", true, OutputBufferLimits, parseOutputBufferLimits, formatOutputBufferLimits)),
+	newParam("maxmemory", true, MaxMemory, memoryRange(0, math.MaxInt64), formatInt64),
+	newParam("maxmemory-policy", true, EvictPolicy, oneOf(EvictPolicies...), formatString),
+	newParam("maxmemory-samples", true, LruSampledSize, intRange(1, 64), strconv.Itoa),
+	newParam("lfu-log-factor", true, LfuLogFactor, intRange(0, math.MaxInt32), strconv.Itoa),
+	newParam("lfu-decay-time", true, LfuDecayTime, intRange(0, math.MaxInt32), strconv.Itoa),
+	newParam("hz", true, Hz, intRange(1, 500), strconv.Itoa),
+	newParam("dir", false, variable[string]{&RDBDir}, parseString, formatString),
+	newParam("dbfilename", false, variable[string]{&RDBFileName}, parseString, formatString),
+	multiLine(newParam("save", true, SaveParams, parseSave, formatSave)),
+	newParam("appendonly", false, variable[bool]{&AppendOnly}, parseYesNo, formatYesNo),
+	newParam("appendfilename", false, variable[string]{&AppendFilename}, parseString, formatString),
+	newParam("appendfsync", false, variable[string]{&AppendFsync}, oneOf("always", "everysec", "no"), formatString),
+	newParam("requirepass", true, RequirePass, parseString, formatString),
+	newParam("aclfile", false, variable[string]{&ACLFile}, parseString, formatString),
+	newParam("acllog-max-len", true, ACLLogMaxLen, intRange(0, math.MaxInt32), strconv.Itoa),
+	newParam("protected-mode", true, ProtectedMode, parseYesNo, formatYesNo),
+}
+
+func init() {
+	for _, p := range params {
+		p.def = p.get()
+	}
+}
+
+func multiLine(p *param) *param {
+	p.multiLine = true
+	return p
+}
+
+func lookup(name string) *param {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Get returns the names and values of the params matching any of the glob patterns, for CONFIG GET
+func Get(patterns ...string) []string {
+	var res []string
+	for _, p := range params {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), p.name); ok {
+				res = append(res, p.name, p.get())
+				break
+			}
+		}
+	}
+	return res
+}
+
+// Set changes the params of the name value pairs for CONFIG SET,
+// every value is checked first so either all of them are changed or none
+func Set(pairs ...string) error {
+	stores := make([]func(), 0, len(pairs)/2)
+	seen := make(map[*param]bool)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		name, value := pairs[i], pairs[i+1]
+		p := lookup(name)
+		if p == nil {
+			return fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", name)
+		}
+		if !p.mutable {
+			return setError(name, errors.New("can't set immutable config"))
+		}
+		if seen[p] {
+			return setError(name, errors.New("duplicate parameter"))
+		}
+		seen[p] = true
+		store, err := p.prepare(value)
+		if err != nil {
+			return setError(name, err)
+		}
+		stores = append(stores, store)
+	}
+	for _, store := range stores {
+		store()
+	}
+	return nil
+}
+
+func setError(name string, err error) error {
+	return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", name, err)
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func formatString(s string) string {
+	return s
+}
+
+func intRange(min, max int) func(string) (int, error) {
+	return func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, errors.New("argument couldn't be parsed into an integer")
+		}
+		if n < min || n > max {
+			return 0, fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+		}
+		return n, nil
+	}
+}
+
+func memoryRange(min, max int64) func(string) (int64, error) {
+	return func(s string) (int64, error) {
+		n, err := ParseMemory(s)
+		if err != nil {
+			return 0, err
+		}
+		if n < min || n > max {
+			return 0, fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+		}
+		return n, nil
+	}
+}
+
+func formatInt64(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// memoryUnits same as redis: k is 1000 bytes, kb is 1024
+var memoryUnits = []struct {
+	suffix string
+	mul    int64
+}{
+	{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+	{"g", 1000 * 1000 * 1000}, {"m", 1000 * 1000}, {"k", 1000}, {"b", 1},
+}
+
+// ParseMemory parses a number of bytes with an optional unit, e.g. 100mb
+func ParseMemory(s string) (int64, error) {
+	s = strings.ToLower(s)
+	mul := int64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, mul = strings.TrimSuffix(s, unit.suffix), unit.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/mul {
+		return 0, errors.New("argument must be a memory value")
+	}
+	return n * mul, nil
+}
+
+func oneOf(values ...string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		for _, v := range values {
+			if strings.EqualFold(s, v) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(values, ", "))
+	}
+}
+
+func parseYesNo(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, errors.New("argument must be 'yes' or 'no'")
+}
+
+func formatYesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// parseOutputBufferLimits parses "<class> <hard> <soft> <soft seconds> ...",
+// like redis the classes that are not listed keep their limits
+func parseOutputBufferLimits(s string) ([2]OutputBufferLimit, error) {
+	res := OutputBufferLimits.Load()
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields)%4 != 0 {
+		return res, errors.New("Wrong number of arguments in buffer limit configuration.")
+	}
+	for i := 0; i < len(fields); i += 4 {
+		class := slices.Index(ClientClasses, strings.ToLower(fields[i]))
+		if class < 0 {
+			return res, errors.New("Invalid client class specified in buffer limit configuration.")
+		}
+		hard, err1 := ParseMemory(fields[i+1])
+		soft, err2 := ParseMemory(fields[i+2])
+		seconds, err3 := strconv.Atoi(fields[i+3])
+		if err1 != nil || err2 != nil || err3 != nil || seconds < 0 {
+			return res, errors.New("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		res[class] = OutputBufferLimit{Hard: hard, Soft: soft, SoftSeconds: seconds}
+	}
+	return res, nil
+}
+
+func formatOutputBufferLimits(limits [2]OutputBufferLimit) string {
+	fields := make([]string, 0, 4*len(limits))
+	for class, limit := range limits {
+		fields = append(fields, ClientClasses[class], formatInt64(limit.Hard), formatInt64(limit.Soft), strconv.Itoa(limit.SoftSeconds))
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseSave parses "<seconds> <changes> ...", an empty value disables automatic snapshots
+func parseSave(s string) ([]SaveParam, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, errors.New("Invalid save parameters")
+	}
+	res := make([]SaveParam, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err1 := strconv.Atoi(fields[i])
+		changes, err2 := strconv.ParseInt(fields[i+1], 10, 64)
+		if err1 != nil || err2 != nil || seconds < 1 || changes < 0 {
+			return nil, errors.New("Invalid save parameters")
+		}
+		res = append(res, SaveParam{Seconds: seconds, Changes: changes})
+	}
+	return res, nil
+}
+
+func formatSave(save []SaveParam) string {
+	fields := make([]string, 0, 2*len(save))
+	for _, param := range save {
+		fields = append(fields, strconv.Itoa(param.Seconds), strconv.FormatInt(param.Changes, 10))
+	}
+	return strings.Join(fields, " ")
+}
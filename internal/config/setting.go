@@ -0,0 +1,23 @@
+package config
+
+import "sync/atomic"
+
+// Setting holds a value CONFIG SET can change while the server runs,
+// workers and I/O handlers read it from their own goroutines so it is stored atomically
+type Setting[T any] struct {
+	v atomic.Pointer[T]
+}
+
+func NewSetting[T any](value T) *Setting[T] {
+	s := &Setting[T]{}
+	s.Store(value)
+	return s
+}
+
+func (s *Setting[T]) Load() T {
+	return *s.v.Load()
+}
+
+func (s *Setting[T]) Store(value T) {
+	s.v.Store(&value)
+}
@@ -1,23 +1,156 @@
 package constant
 
 const (
-	CMD_PING      = "PING"
-	CMD_GET       = "GET"
-	CMD_SET       = "SET"
-	CMD_TTL       = "TTL"
-	CMD_PTTL      = "PTTL"
-	CMD_DEL       = "DEL"
-	CMD_EXIST     = "EXISTS"
-	CMD_EXPIRE    = "EXPIRE"
-	CMD_SADD      = "SADD"
-	CMD_SREM      = "SREM"
-	CMD_SISMEMBER = "SISMEMBER"
-	CMD_SMEMBERS  = "SMEMBERS"
-	CMD_ZADD      = "ZADD"
-	CMD_ZSCORE    = "ZSCORE"
-	CMD_ZRANK     = "ZRANK"
-	CMD_ZREM      = "ZREM"
-	CMD_INFO      = "INFO"
+	CMD_PING             = "PING"
+	CMD_GET              = "GET"
+	CMD_SET              = "SET"
+	CMD_SETNX            = "SETNX"
+	CMD_SETEX            = "SETEX"
+	CMD_PSETEX           = "PSETEX"
+	CMD_GETSET           = "GETSET"
+	CMD_TTL              = "TTL"
+	CMD_PTTL             = "PTTL"
+	CMD_DEL              = "DEL"
+	CMD_EXIST            = "EXISTS"
+	CMD_EXPIRE           = "EXPIRE"
+	CMD_PEXPIREAT        = "PEXPIREAT"
+	CMD_PEXPIRE          = "PEXPIRE"
+	CMD_EXPIREAT         = "EXPIREAT"
+	CMD_PERSIST          = "PERSIST"
+	CMD_EXPIRETIME       = "EXPIRETIME"
+	CMD_PEXPIRETIME      = "PEXPIRETIME"
+	CMD_RENAME           = "RENAME"
+	CMD_RENAMENX         = "RENAMENX"
+	CMD_KEYS             = "KEYS"
+	CMD_SCAN             = "SCAN"
+	CMD_TYPE             = "TYPE"
+	CMD_RANDOMKEY        = "RANDOMKEY"
+	CMD_DBSIZE           = "DBSIZE"
+	CMD_OBJECT           = "OBJECT"
+	CMD_SADD             = "SADD"
+	CMD_SREM             = "SREM"
+	CMD_SISMEMBER        = "SISMEMBER"
+	CMD_SMEMBERS         = "SMEMBERS"
+	CMD_SCARD            = "SCARD"
+	CMD_SMISMEMBER       = "SMISMEMBER"
+	CMD_SMOVE            = "SMOVE"
+	CMD_SPOP             = "SPOP"
+	CMD_SRANDMEMBER      = "SRANDMEMBER"
+	CMD_SINTER           = "SINTER"
+	CMD_SINTERSTORE      = "SINTERSTORE"
+	CMD_SINTERCARD       = "SINTERCARD"
+	CMD_SUNION           = "SUNION"
+	CMD_SUNIONSTORE      = "SUNIONSTORE"
+	CMD_SDIFF            = "SDIFF"
+	CMD_SDIFFSTORE       = "SDIFFSTORE"
+	CMD_SSCAN            = "SSCAN"
+	CMD_ZADD             = "ZADD"
+	CMD_ZSCORE           = "ZSCORE"
+	CMD_ZMSCORE          = "ZMSCORE"
+	CMD_ZINCRBY          = "ZINCRBY"
+	CMD_ZRANK            = "ZRANK"
+	CMD_ZREM             = "ZREM"
+	CMD_ZREVRANK         = "ZREVRANK"
+	CMD_ZCARD            = "ZCARD"
+	CMD_ZCOUNT           = "ZCOUNT"
+	CMD_ZLEXCOUNT        = "ZLEXCOUNT"
+	CMD_ZRANGE           = "ZRANGE"
+	CMD_ZRANGESTORE      = "ZRANGESTORE"
+	CMD_ZREVRANGE        = "ZREVRANGE"
+	CMD_ZRANGEBYSCORE    = "ZRANGEBYSCORE"
+	CMD_ZPOPMIN          = "ZPOPMIN"
+	CMD_ZPOPMAX          = "ZPOPMAX"
+	CMD_ZMPOP            = "ZMPOP"
+	CMD_BZPOPMIN         = "BZPOPMIN"
+	CMD_BZPOPMAX         = "BZPOPMAX"
+	CMD_BZMPOP           = "BZMPOP"
+	CMD_ZREMRANGEBYRANK  = "ZREMRANGEBYRANK"
+	CMD_ZREMRANGEBYSCORE = "ZREMRANGEBYSCORE"
+	CMD_ZREMRANGEBYLEX   = "ZREMRANGEBYLEX"
+	CMD_ZUNION           = "ZUNION"
+	CMD_ZUNIONSTORE      = "ZUNIONSTORE"
+	CMD_ZINTER           = "ZINTER"
+	CMD_ZINTERSTORE      = "ZINTERSTORE"
+	CMD_ZINTERCARD       = "ZINTERCARD"
+	CMD_ZDIFF            = "ZDIFF"
+	CMD_ZDIFFSTORE       = "ZDIFFSTORE"
+	CMD_INFO             = "INFO"
+	CMD_COMMAND          = "COMMAND"
+	CMD_MEMORY           = "MEMORY"
+	CMD_CONFIG           = "CONFIG"
+	CMD_AUTH             = "AUTH"
+	CMD_ACL              = "ACL"
+	// Persistence
+	CMD_SAVE         = "SAVE"
+	CMD_BGSAVE       = "BGSAVE"
+	CMD_LASTSAVE     = "LASTSAVE"
+	CMD_BGREWRITEAOF = "BGREWRITEAOF"
+	// String
+	CMD_INCR        = "INCR"
+	CMD_DECR        = "DECR"
+	CMD_INCRBY      = "INCRBY"
+	CMD_DECRBY      = "DECRBY"
+	CMD_INCRBYFLOAT = "INCRBYFLOAT"
+	CMD_APPEND      = "APPEND"
+	CMD_STRLEN      = "STRLEN"
+	CMD_GETRANGE    = "GETRANGE"
+	CMD_SETRANGE    = "SETRANGE"
+	CMD_GETDEL      = "GETDEL"
+	CMD_GETEX       = "GETEX"
+	CMD_MGET        = "MGET"
+	CMD_MSET        = "MSET"
+	CMD_MSETNX      = "MSETNX"
+	// Hash
+	CMD_HSET         = "HSET"
+	CMD_HSETNX       = "HSETNX"
+	CMD_HGET         = "HGET"
+	CMD_HMGET        = "HMGET"
+	CMD_HDEL         = "HDEL"
+	CMD_HEXISTS      = "HEXISTS"
+	CMD_HLEN         = "HLEN"
+	CMD_HSTRLEN      = "HSTRLEN"
+	CMD_HKEYS        = "HKEYS"
+	CMD_HVALS        = "HVALS"
+	CMD_HGETALL      = "HGETALL"
+	CMD_HINCRBY      = "HINCRBY"
+	CMD_HINCRBYFLOAT = "HINCRBYFLOAT"
+	CMD_HRANDFIELD   = "HRANDFIELD"
+	CMD_HSCAN        = "HSCAN"
+	// List
+	CMD_LPUSH   = "LPUSH"
+	CMD_RPUSH   = "RPUSH"
+	CMD_LPUSHX  = "LPUSHX"
+	CMD_RPUSHX  = "RPUSHX"
+	CMD_LPOP    = "LPOP"
+	CMD_RPOP    = "RPOP"
+	CMD_LLEN    = "LLEN"
+	CMD_LRANGE  = "LRANGE"
+	CMD_LINDEX  = "LINDEX"
+	CMD_LSET    = "LSET"
+	CMD_LINSERT = "LINSERT"
+	CMD_LREM    = "LREM"
+	CMD_LTRIM   = "LTRIM"
+	CMD_LPOS    = "LPOS"
+	CMD_LMOVE   = "LMOVE"
+	CMD_BLPOP   = "BLPOP"
+	CMD_BRPOP   = "BRPOP"
+	CMD_BLMOVE  = "BLMOVE"
+	// Pub/Sub
+	CMD_SUBSCRIBE    = "SUBSCRIBE"
+	CMD_UNSUBSCRIBE  = "UNSUBSCRIBE"
+	CMD_PSUBSCRIBE   = "PSUBSCRIBE"
+	CMD_PUNSUBSCRIBE = "PUNSUBSCRIBE"
+	CMD_SSUBSCRIBE   = "SSUBSCRIBE"
+	CMD_SUNSUBSCRIBE = "SUNSUBSCRIBE"
+	CMD_PUBLISH      = "PUBLISH"
+	CMD_SPUBLISH     = "SPUBLISH"
+	CMD_PUBSUB       = "PUBSUB"
+	// Transactions
+	CMD_MULTI   = "MULTI"
+	CMD_EXEC    = "EXEC"
+	CMD_DISCARD = "DISCARD"
+	CMD_WATCH   = "WATCH"
+	CMD_UNWATCH = "UNWATCH"
 	// Count-Min Sketch
 	CMD_CMS_INITBYDIM  = "CMS.INITBYDIM"
 	CMD_CMS_INITBYPROB = "CMS.INITBYPROB"
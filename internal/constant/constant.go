@@ -1,17 +1,17 @@
 package constant
 
-import "time"
-
 const CRLF = "\r\n"
 
 var (
 	RespNil                 = []byte("$-1\r\n")
+	RespNilArray            = []byte("*-1\r\n")
 	RespOk                  = []byte("+OK\r\n")
 	RespKeyNotExist         = []byte(":-2\r\n")
 	RespZero                = []byte(":0\r\n")
 	RespOne                 = []byte(":1\r\n")
 	TTLKeyExistNoExpire     = []byte(":-1\r\n")
 	ErrorWrongTypeKey       = []byte("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+	ErrorOOM                = []byte("-OOM command not allowed when used memory > 'maxmemory'\r\n")
 	ActiveExpireSampleSized = 20
 	ActiveExpireThreshold   = 0.1
 	BPlusTreeDegree         = 4
@@ -32,5 +32,3 @@ const (
 	ServerStatusBusy
 	ServerStatusShuttingDown
 )
-
-const ActiveExpireFrequency = 500 * time.Millisecond
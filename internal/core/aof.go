@@ -0,0 +1,328 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+)
+
+// appendfsync policies, same as redis
+const (
+	FsyncAlways   = "always"
+	FsyncEverySec = "everysec"
+	FsyncNo       = "no"
+)
+
+// AOF is the append only file, every write command executed by a worker is appended in RESP.
+// Workers of all shards append to the same file, the order between shards does not matter
+// since a command only touches the keys of its own shard.
+//
+// After a rewrite the file starts with a snapshot of the keyspace, followed by the commands
+// executed since the snapshot was taken.
+type AOF struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	fsync string
+	buf   []byte
+	// unsynced is set when data was written since the last fsync, for the everysec policy
+	unsynced bool
+
+	// While a rewrite is in progress, appended commands are also kept in rewriteBuf
+	// and written after the snapshot in the new file
+	rewriting  bool
+	rewriteBuf []byte
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// OpenAOF opens the file at path for appending, creating it if needed
+func OpenAOF(path string, fsync string) (*AOF, error) {
+	switch fsync {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	default:
+		return nil, fmt.Errorf("invalid appendfsync policy %q", fsync)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	aof := &AOF{
+		path:  path,
+		file:  file,
+		fsync: fsync,
+		stop:  make(chan struct{}),
+	}
+	if fsync == FsyncEverySec {
+		aof.wg.Add(1)
+		go aof.syncEverySecond()
+	}
+	return aof, nil
+}
+
+// Append writes the commands to the file, with the always policy they are on disk when it returns
+func (a *AOF) Append(argvs [][]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.buf = a.buf[:0]
+	for _, argv := range argvs {
+		a.buf = appendCommand(a.buf, argv)
+	}
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, a.buf...)
+	}
+
+	if _, err := a.file.Write(a.buf); err != nil {
+		log.Printf("Error writing to the AOF: %v", err)
+		return
+	}
+	switch a.fsync {
+	case FsyncAlways:
+		if err := a.file.Sync(); err != nil {
+			log.Printf("Can't fsync the AOF: %v", err)
+		}
+	case FsyncEverySec:
+		a.unsynced = true
+	}
+}
+
+func appendCommand(buf []byte, argv []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(argv)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range argv {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+func (a *AOF) syncEverySecond() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			file, unsynced := a.file, a.unsynced
+			a.unsynced = false
+			a.mu.Unlock()
+
+			// fsync outside the lock so workers keep appending, the file may be swapped by a rewrite meanwhile
+			if unsynced {
+				if err := file.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+					log.Printf("Can't fsync the AOF: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// StartRewrite is called at the point in time of the snapshot the new file starts with,
+// commands appended from now on are written after the snapshot
+func (a *AOF) StartRewrite() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rewriting = true
+	a.rewriteBuf = nil
+}
+
+// FinishRewrite writes the snapshot and the commands appended since StartRewrite to a new file,
+// then atomically replaces the current file with it
+func (a *AOF) FinishRewrite(shards ...[]byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), fmt.Sprintf("temp-rewriteaof-%d-*.aof", os.Getpid()))
+	if err != nil {
+		a.AbortRewrite()
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// the snapshot is the bulk of the file, it is written while workers keep appending
+	if err := WriteSnapshot(tmp, shards...); err != nil {
+		tmp.Close()
+		a.AbortRewrite()
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rewriting = false
+	rewriteBuf := a.rewriteBuf
+	a.rewriteBuf = nil
+
+	if _, err := tmp.Write(rewriteBuf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// tmp is now the AOF, it was opened without O_APPEND but nobody else writes to it
+	a.file.Close()
+	a.file = tmp
+	a.unsynced = false
+	return nil
+}
+
+// AbortRewrite stops keeping the appended commands for a rewrite that failed
+func (a *AOF) AbortRewrite() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rewriting = false
+	a.rewriteBuf = nil
+}
+
+// Close flushes the file to disk and closes it
+func (a *AOF) Close() error {
+	close(a.stop)
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.file.Sync(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
+
+// LoadAOF reads the AOF at path: onEntry is called for the keys of the snapshot it starts with,
+// then onCommand for every command in order. The commands of a transaction, between MULTI and EXEC,
+// are only passed once its EXEC is read.
+// A command or a transaction cut by a crash at the end of the file is dropped and the file is truncated,
+// the AOF must be loaded before OpenAOF appends to it.
+func LoadAOF(path string, onEntry func(key string, obj *RedisObj, expireAtMs uint64), onCommand func(cmd *Command)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pos := 0
+	if bytes.HasPrefix(data, []byte(rdbMagic)) {
+		entries, n, err := readSnapshotPrefix(data)
+		if err != nil {
+			return err
+		}
+		loadSnapshotEntries(entries, onEntry)
+		pos = n
+	}
+
+	// multiPos is the offset of the MULTI of the transaction being read, -1 outside of one
+	multiPos := -1
+	var queued []*Command
+	for pos < len(data) {
+		// unlike clients, the AOF never holds inline commands
+		if data[pos] != '*' {
+			return fmt.Errorf("bad AOF format at offset %d: %w", pos, ErrProtocol)
+		}
+		cmd, n, err := parseCommand(data[pos:])
+		if err == ErrIncompleteRESP {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("bad AOF format at offset %d: %w", pos, err)
+		}
+		switch {
+		case cmd == nil:
+		case strings.EqualFold(cmd.Cmd, constant.CMD_MULTI):
+			multiPos = pos
+		case strings.EqualFold(cmd.Cmd, constant.CMD_EXEC):
+			for _, cmd := range queued {
+				onCommand(cmd)
+			}
+			multiPos, queued = -1, nil
+		case multiPos >= 0:
+			queued = append(queued, cmd)
+		default:
+			onCommand(cmd)
+		}
+		pos += n
+	}
+
+	if multiPos >= 0 {
+		pos = multiPos
+	}
+	if pos < len(data) {
+		log.Printf("!!! Warning: short read while loading the AOF %s, truncating it from %d to %d bytes", path, len(data), pos)
+		return os.Truncate(path, int64(pos))
+	}
+	return nil
+}
+
+// SetAOF makes the db append its write commands to aof, nil stops appending
+func (db *RedisDB) SetAOF(aof *AOF) {
+	db.aof = aof
+}
+
+// alsoPropagate logs argv before the command being executed, e.g. the DEL of an evicted key
+func (db *RedisDB) alsoPropagate(argv ...string) {
+	if db.aof != nil {
+		db.propagated = append(db.propagated, argv)
+	}
+}
+
+// rewriteCommand logs argvs instead of the command being executed,
+// e.g. relative TTLs are logged as absolute deadlines so a replay does not extend them
+func (db *RedisDB) rewriteCommand(argvs ...[]string) {
+	if db.aof != nil {
+		db.rewritten = argvs
+	}
+}
+
+// preventPropagation tells the write command being executed changed nothing, e.g. an EXPIRE prevented by GT,
+// so it is neither logged nor counted as a change. A relative EXPIRE replayed later could apply.
+func (db *RedisDB) preventPropagation() {
+	db.unchanged = true
+}
+
+// feedAOF appends what the command changed to the AOF, ok is false when the command
+// did not change the keyspace itself
+func (db *RedisDB) feedAOF(spec *CommandSpec, args []string, ok bool) {
+	if db.aof == nil {
+		return
+	}
+
+	argvs := db.propagated
+	if ok {
+		if db.rewritten != nil {
+			argvs = append(argvs, db.rewritten...)
+		} else {
+			argvs = append(argvs, append([]string{spec.Name}, args...))
+		}
+	}
+	switch {
+	case db.transactionAOF != nil:
+		db.transactionAOF = append(db.transactionAOF, argvs...)
+	case len(argvs) > 0:
+		db.aof.Append(argvs)
+	}
+	db.propagated, db.rewritten = nil, nil
+}
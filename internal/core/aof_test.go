@@ -0,0 +1,152 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// replay loads the AOF at path into a new db
+func replay(t *testing.T, path string) *core.RedisDB {
+	db := core.NewRedisDB()
+	assert.NoError(t, core.LoadAOF(path, db.Restore, func(cmd *core.Command) {
+		core.ExecuteCommand(db, cmd)
+	}))
+	return db
+}
+
+func TestAOFAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncAlways)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	execute(db, "SET", "k", "v")
+	execute(db, "SADD", "s", "a", "b")
+	execute(db, "GET", "k")
+	execute(db, "SET", "gone", "v")
+	execute(db, "DEL", "gone")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n", string(data[:27]))
+	assert.NotContains(t, string(data), "GET")
+
+	loaded := replay(t, path)
+	assert.Equal(t, "$1\r\nv\r\n", execute(loaded, "GET", "k"))
+	assert.Equal(t, ":1\r\n", execute(loaded, "SISMEMBER", "s", "b"))
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "gone"))
+}
+
+func TestAOFLogsAbsoluteDeadlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	execute(db, "SET", "k", "v", "EX", "100")
+	execute(db, "SET", "other", "v")
+	execute(db, "EXPIRE", "other", "50")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "EXPIRE\r\n")
+	assert.NotContains(t, string(data), "EX\r\n")
+
+	// a replay later does not extend the lifetimes
+	time.Sleep(20 * time.Millisecond)
+	loaded := replay(t, path)
+	for _, key := range []string{"k", "other"} {
+		expected, _ := db.GetExpiry(key)
+		actual, ok := loaded.GetExpiry(key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestAOFTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	complete := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+	assert.NoError(t, os.WriteFile(path, []byte(complete+"*3\r\n$3\r\nSET\r\n$1\r\nb"), 0644))
+
+	loaded := replay(t, path)
+	assert.Equal(t, "$1\r\n1\r\n", execute(loaded, "GET", "a"))
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "b"))
+
+	// the partial command is dropped so new commands are appended after a valid one
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, complete, string(data))
+
+	assert.NoError(t, os.WriteFile(path, []byte(complete+"$3\r\nbad\r\n"), 0644))
+	assert.ErrorIs(t, core.LoadAOF(path, nil, func(*core.Command) {}), core.ErrProtocol)
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncEverySec)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	for i := 0; i < 5; i++ {
+		execute(db, "SET", "counter", strconv.Itoa(i))
+	}
+	execute(db, "CMS.INITBYDIM", "cms", "10", "2")
+	execute(db, "CMS.INCRBY", "cms", "a", "3")
+
+	aof.StartRewrite()
+	entries, err := db.DumpSnapshot(nil)
+	assert.NoError(t, err)
+	// executed while the snapshot is written
+	execute(db, "SET", "after", "rewrite")
+	assert.NoError(t, aof.FinishRewrite(entries))
+	execute(db, "SET", "after", "swap")
+	assert.NoError(t, aof.Close())
+
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "GODIS", string(data[:5]))
+
+	loaded := replay(t, path)
+	assert.Equal(t, "$1\r\n4\r\n", execute(loaded, "GET", "counter"))
+	assert.Equal(t, "*1\r\n:3\r\n", execute(loaded, "CMS.QUERY", "cms", "a"))
+	assert.Equal(t, "$4\r\nswap\r\n", execute(loaded, "GET", "after"))
+}
+
+func TestAOFLogsPoppedMembers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	for i := 0; i < 20; i++ {
+		execute(db, "SADD", "s", strconv.Itoa(i))
+	}
+	execute(db, "SPOP", "s")
+	execute(db, "SPOP", "s", "5")
+	execute(db, "SADD", "all", "a")
+	execute(db, "SPOP", "all", "2")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "SPOP")
+
+	// the random members popped are the ones removed by the replay
+	loaded := replay(t, path)
+	assert.Equal(t, execute(db, "SCARD", "s"), execute(loaded, "SCARD", "s"))
+	assert.Equal(t, execute(db, "SINTERCARD", "1", "s"), execute(loaded, "SINTERCARD", "1", "s"))
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, execute(db, "SISMEMBER", "s", strconv.Itoa(i)), execute(loaded, "SISMEMBER", "s", strconv.Itoa(i)))
+	}
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "all"))
+}
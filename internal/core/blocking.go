@@ -0,0 +1,151 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	errTimeoutNotFloat = errors.New("ERR timeout is not a float or out of range")
+	errTimeoutNegative = errors.New("ERR timeout is negative")
+)
+
+// blockRequest is left by a blocking command that found no data, see blockForKeys
+type blockRequest struct {
+	keys    []string
+	timeout time.Duration
+	// reply sent when the timeout is reached
+	timeoutReply []byte
+}
+
+// blockedTask is a task parked until one of its keys is created or its deadline is reached
+type blockedTask struct {
+	task         *Task
+	keys         []string
+	deadline     time.Time
+	timeoutReply []byte
+}
+
+// parseTimeout parses the timeout of a blocking command in seconds, 0 blocks forever
+func parseTimeout(arg string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds > math.MaxInt64/float64(time.Second) {
+		return 0, errTimeoutNotFloat
+	}
+	if seconds < 0 {
+		return 0, errTimeoutNegative
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// blockForKeys is called by a blocking command that found none of its keys ready.
+// It returns the timeout reply, which is the reply when the command cannot block (ExecuteCommand);
+// ExecuteTask parks the task instead and retries the command once one of the keys is created.
+func (db *RedisDB) blockForKeys(keys []string, timeout time.Duration, timeoutReply []byte) []byte {
+	db.blockRequest = &blockRequest{
+		keys:         keys,
+		timeout:      timeout,
+		timeoutReply: timeoutReply,
+	}
+	return timeoutReply
+}
+
+func (db *RedisDB) takeBlockRequest() *blockRequest {
+	req := db.blockRequest
+	db.blockRequest = nil
+	return req
+}
+
+// block parks the task on the keys of the request
+func (db *RedisDB) block(task *Task, req *blockRequest) {
+	bt := &blockedTask{
+		task:         task,
+		keys:         req.keys,
+		timeoutReply: req.timeoutReply,
+	}
+	if req.timeout > 0 {
+		bt.deadline = time.Now().Add(req.timeout)
+	}
+
+	db.blockedTasks[task] = bt
+	db.blockedVersion++
+	for _, key := range req.keys {
+		db.blocked[key] = append(db.blocked[key], bt)
+	}
+}
+
+// UnblockTask forgets a parked task without replying, when its client is gone.
+// It returns false when the task is not parked.
+func (db *RedisDB) UnblockTask(task *Task) bool {
+	bt, ok := db.blockedTasks[task]
+	if !ok {
+		return false
+	}
+
+	delete(db.blockedTasks, task)
+	db.blockedVersion++
+	for _, key := range bt.keys {
+		tasks := db.blocked[key]
+		for i, t := range tasks {
+			if t == bt {
+				tasks = append(tasks[:i], tasks[i+1:]...)
+				break
+			}
+		}
+		if len(tasks) == 0 {
+			delete(db.blocked, key)
+		} else {
+			db.blocked[key] = tasks
+		}
+	}
+	return true
+}
+
+// signalKeyAsReady is called when a key is created, a task blocked on it may be served now
+func (db *RedisDB) signalKeyAsReady(key string) {
+	if len(db.blocked[key]) > 0 {
+		db.readyKeys = append(db.readyKeys, key)
+	}
+}
+
+// serveBlockedTasks retries the tasks parked on the keys created by the last command,
+// in the order they blocked. Serving a task may create other keys (BLMOVE), they are served as well.
+func (db *RedisDB) serveBlockedTasks() {
+	for len(db.readyKeys) > 0 {
+		key := db.readyKeys[0]
+		db.readyKeys = db.readyKeys[1:]
+
+		// UnblockTask modifies db.blocked[key]
+		for _, bt := range append([]*blockedTask(nil), db.blocked[key]...) {
+			res := executeCommand(db, bt.task.Command)
+			if db.takeBlockRequest() != nil {
+				// nothing left for the tasks behind this one
+				break
+			}
+			db.UnblockTask(bt.task)
+			bt.task.Reply(res)
+		}
+	}
+}
+
+// NextBlockDeadline returns the earliest deadline of the parked tasks, ok is false when none has a timeout
+func (db *RedisDB) NextBlockDeadline() (deadline time.Time, ok bool) {
+	for _, bt := range db.blockedTasks {
+		if !bt.deadline.IsZero() && (!ok || bt.deadline.Before(deadline)) {
+			deadline, ok = bt.deadline, true
+		}
+	}
+	return deadline, ok
+}
+
+// TimeoutBlockedTasks replies to the parked tasks whose deadline is reached
+func (db *RedisDB) TimeoutBlockedTasks(now time.Time) {
+	for task, bt := range db.blockedTasks {
+		if !bt.deadline.IsZero() && !now.Before(bt.deadline) {
+			db.UnblockTask(task)
+			task.Reply(bt.timeoutReply)
+		}
+	}
+}
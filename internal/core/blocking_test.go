@@ -0,0 +1,170 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// submit executes the command like a worker does and returns its reply channel
+func submit(db *core.RedisDB, cmd string, args ...string) (*core.Task, chan []byte) {
+	task := &core.Task{
+		Command:   &core.Command{Cmd: cmd, Args: args},
+		ReplyChan: make(chan []byte, 1),
+	}
+	core.ExecuteTask(db, task)
+	return task, task.ReplyChan
+}
+
+// replied returns the reply of the task, or "" if it did not reply yet
+func replied(replyChan chan []byte) string {
+	select {
+	case res := <-replyChan:
+		return string(res)
+	default:
+		return ""
+	}
+}
+
+func TestBlockingPopWithoutBlocking(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "RPUSH", "b", "1", "2")
+
+	_, reply := submit(db, "BLPOP", "a", "b", "0")
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\n1\r\n", replied(reply))
+	_, reply = submit(db, "BRPOP", "b", "0")
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\n2\r\n", replied(reply))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "b"))
+
+	// outside of a worker, a blocking command replies as if it timed out
+	assert.Equal(t, "*-1\r\n", execute(db, "BLPOP", "a", "0"))
+	assert.Equal(t, "$-1\r\n", execute(db, "BLMOVE", "a", "b", "LEFT", "LEFT", "0"))
+	assert.Equal(t, "-ERR timeout is negative\r\n", execute(db, "BLPOP", "a", "-1"))
+	assert.Equal(t, "-ERR timeout is not a float or out of range\r\n", execute(db, "BLPOP", "a", "x"))
+}
+
+func TestBlockedTaskServedInOrder(t *testing.T) {
+	db := core.NewRedisDB()
+
+	_, first := submit(db, "BLPOP", "a", "q", "0")
+	_, second := submit(db, "BRPOP", "q", "0")
+	assert.Equal(t, "", replied(first))
+	assert.Equal(t, "", replied(second))
+
+	_, reply := submit(db, "RPUSH", "q", "x")
+	assert.Equal(t, ":1\r\n", replied(reply))
+	assert.Equal(t, "*2\r\n$1\r\nq\r\n$1\r\nx\r\n", replied(first))
+	assert.Equal(t, "", replied(second))
+
+	submit(db, "RPUSH", "q", "y", "z")
+	assert.Equal(t, "*2\r\n$1\r\nq\r\n$1\r\nz\r\n", replied(second))
+	assert.Equal(t, "*1\r\n$1\r\ny\r\n", execute(db, "LRANGE", "q", "0", "-1"))
+}
+
+func TestBlockedMoveServesNextKey(t *testing.T) {
+	db := core.NewRedisDB()
+
+	_, move := submit(db, "BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	_, pop := submit(db, "BLPOP", "dst", "0")
+	submit(db, "LPUSH", "src", "v")
+
+	assert.Equal(t, "$1\r\nv\r\n", replied(move))
+	assert.Equal(t, "*2\r\n$3\r\ndst\r\n$1\r\nv\r\n", replied(pop))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "src"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "dst"))
+}
+
+func TestBlockedTaskTimeoutAndUnblock(t *testing.T) {
+	db := core.NewRedisDB()
+
+	_, timed := submit(db, "BLPOP", "q", "0.05")
+	gone, _ := submit(db, "BLPOP", "q", "0")
+	deadline, ok := db.NextBlockDeadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 50*time.Millisecond)
+
+	db.TimeoutBlockedTasks(time.Now())
+	assert.Equal(t, "", replied(timed))
+	db.TimeoutBlockedTasks(deadline)
+	assert.Equal(t, "*-1\r\n", replied(timed))
+	_, ok = db.NextBlockDeadline()
+	assert.False(t, ok)
+
+	assert.True(t, db.UnblockTask(gone))
+	assert.False(t, db.UnblockTask(gone))
+	submit(db, "RPUSH", "q", "x")
+	assert.Equal(t, ":1\r\n", execute(db, "LLEN", "q"))
+}
+
+func TestServedBlockedTaskIsLoggedAsPop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	submit(db, "BLPOP", "q", "0")
+	submit(db, "RPUSH", "q", "x", "y")
+	submit(db, "BLMOVE", "q", "dst", "LEFT", "LEFT", "0")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "*4\r\n$5\r\nRPUSH\r\n$1\r\nq\r\n$1\r\nx\r\n$1\r\ny\r\n"+
+		"*2\r\n$4\r\nLPOP\r\n$1\r\nq\r\n"+
+		"*5\r\n$5\r\nLMOVE\r\n$1\r\nq\r\n$3\r\ndst\r\n$4\r\nLEFT\r\n$4\r\nLEFT\r\n", string(data))
+
+	loaded := replay(t, path)
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "q"))
+	assert.Equal(t, "*1\r\n$1\r\ny\r\n", execute(loaded, "LRANGE", "dst", "0", "-1"))
+}
+
+func TestBlockedZPopServedByZAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	_, popMin := submit(db, "BZPOPMIN", "a", "z", "0")
+	_, mpop := submit(db, "BZMPOP", "0", "2", "a", "z", "MAX", "COUNT", "2")
+	assert.Equal(t, "", replied(popMin))
+
+	submit(db, "ZADD", "z", "1", "x", "2", "y", "3", "w", "4", "v")
+	assert.Equal(t, "*3\r\n$1\r\nz\r\n$1\r\nx\r\n$1\r\n1\r\n", replied(popMin))
+	assert.Equal(t, "*2\r\n$1\r\nz\r\n*2\r\n*2\r\n$1\r\nv\r\n$1\r\n4\r\n*2\r\n$1\r\nw\r\n$1\r\n3\r\n", replied(mpop))
+	assert.Equal(t, "*1\r\n$1\r\ny\r\n", execute(db, "ZRANGE", "z", "0", "-1"))
+
+	// outside of a worker, they reply as if they timed out
+	assert.Equal(t, "*-1\r\n", execute(db, "BZPOPMAX", "a", "0"))
+	assert.Equal(t, "*-1\r\n", execute(db, "BZMPOP", "0", "1", "a", "MIN"))
+	assert.NoError(t, aof.Close())
+
+	loaded := replay(t, path)
+	assert.Equal(t, "*1\r\n$1\r\ny\r\n", execute(loaded, "ZRANGE", "z", "0", "-1"))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "*2\r\n$7\r\nZPOPMIN\r\n$1\r\nz\r\n*3\r\n$7\r\nZPOPMAX\r\n$1\r\nz\r\n$1\r\n2\r\n")
+}
+
+func TestBlockedZPopOnKeyEmptiedByZRem(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a")
+	assert.Equal(t, ":1\r\n", execute(db, "ZREM", "z", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "z"))
+
+	_, popMin := submit(db, "BZPOPMIN", "z", "0")
+	_, mpop := submit(db, "BZMPOP", "0", "1", "z", "MIN")
+	assert.Equal(t, "", replied(popMin))
+	assert.Equal(t, "", replied(mpop))
+	assert.Equal(t, "*-1\r\n", execute(db, "ZMPOP", "1", "z", "MIN"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZPOPMIN", "z"))
+
+	submit(db, "ZADD", "z", "1", "x", "2", "y")
+	assert.Equal(t, "*3\r\n$1\r\nz\r\n$1\r\nx\r\n$1\r\n1\r\n", replied(popMin))
+	assert.Equal(t, "*2\r\n$1\r\nz\r\n*1\r\n*2\r\n$1\r\ny\r\n$1\r\n2\r\n", replied(mpop))
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/nhtuan0700/godis/internal/config"
 	"github.com/nhtuan0700/godis/internal/constant"
 )
 
@@ -16,7 +19,7 @@ type Command struct {
 }
 
 // PING [message]
-func cmdPING(args []string) []byte {
+func cmdPING(redisDB *RedisDB, args []string) []byte {
 	switch {
 	case len(args) == 0:
 		return Encode("PONG", true)
@@ -27,99 +30,156 @@ func cmdPING(args []string) []byte {
 	}
 }
 
-// SET key value [EX seconds]
-func cmdSet(redisDB *RedisDB, args []string) []byte {
-	if len(args) == 1 || len(args) == 3 || len(args) > 4 {
-		return Encode(errors.New("ERR wrong number of arguments for 'set' command"), false)
-	}
+// setOptions are the options of SET, see parseSetArgs
+type setOptions struct {
+	nx, xx  bool
+	get     bool
+	keepTTL bool
+	// ttlMs is given by EX or PX, expireAtMs by EXAT or PXAT
+	ttlMs      uint64
+	expireAtMs uint64
+}
 
-	var ttlMs uint64 = 0
-	key, value := args[0], args[1]
-	if len(args) > 2 {
-		ttlSec, err := strconv.ParseInt(args[3], 10, 64)
-		if err != nil {
-			return Encode(errors.New("ERR value is not an integer or out of range"), false)
+// parseSetArgs parses [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL],
+// conflicting options are a syntax error like in redis
+func parseSetArgs(args []string) (setOptions, error) {
+	var opts setOptions
+	hasExpire := false
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			if opts.xx {
+				return opts, errSyntax
+			}
+			opts.nx = true
+		case "XX":
+			if opts.nx {
+				return opts, errSyntax
+			}
+			opts.xx = true
+		case "GET":
+			opts.get = true
+		case "KEEPTTL":
+			if hasExpire {
+				return opts, errSyntax
+			}
+			opts.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if hasExpire || opts.keepTTL || i+1 == len(args) {
+				return opts, errSyntax
+			}
+			hasExpire = true
+			i++
+			ms, err := parseExpireTime(args[i], option, "set")
+			if err != nil {
+				return opts, err
+			}
+			if option == "EX" || option == "PX" {
+				opts.ttlMs = ms
+			} else {
+				opts.expireAtMs = ms
+			}
+		default:
+			return opts, errSyntax
 		}
-		ttlMs = uint64(ttlSec) * 1000
 	}
-
-	redisDB.Set(key, NewRedisObj(value), ttlMs)
-	return constant.RespOk
+	return opts, nil
 }
 
-// GET key
-func cmdGet(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'get' command"), false)
+// parseExpireTime converts the value of an EX, PX, EXAT or PXAT option to milliseconds,
+// a TTL for EX and PX, a unix time for EXAT and PXAT
+func parseExpireTime(arg string, unit string, cmdName string) (uint64, error) {
+	value, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, errNotInteger
 	}
-
-	key := args[0]
-	obj := redisDB.Get(key)
-	if obj == nil {
-		return constant.RespNil
+	errInvalid := fmt.Errorf("ERR invalid expire time in '%s' command", cmdName)
+	if value <= 0 {
+		return 0, errInvalid
 	}
 
-	if redisDB.HasExpired(key) {
-		return constant.RespNil
+	if unit == "EX" || unit == "EXAT" {
+		if value > math.MaxInt64/1000 {
+			return 0, errInvalid
+		}
+		value *= 1000
 	}
-
-	return Encode(obj.value, false)
+	// the deadline must not overflow
+	if (unit == "EX" || unit == "PX") && value > math.MaxInt64-time.Now().UnixMilli() {
+		return 0, errInvalid
+	}
+	return uint64(value), nil
 }
 
-// TTL key
-func cmdTTL(redisDB *RedisDB, args []string) []byte {
-	if len(args) > 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'ttl' command"), false)
+// setString stores a string value with a TTL (ttlMs) or a deadline (expireAtMs), or keeping the current TTL,
+// and logs the deadline to the AOF so a replay does not extend it
+func setString(redisDB *RedisDB, key string, value string, ttlMs uint64, expireAtMs uint64, keepTTL bool) {
+	if keepTTL {
+		expireAtMs, _ = redisDB.GetExpiry(key)
 	}
 
-	key := args[0]
-	obj := redisDB.Get(key)
-	if obj == nil {
-		return constant.RespKeyNotExist
+	redisDB.Set(key, newStringObj(value), ttlMs)
+	if expireAtMs > 0 {
+		redisDB.SetExpireAt(key, expireAtMs)
 	}
 
-	exp, ok := redisDB.GetExpiry(key)
-	if !ok {
-		return constant.TTLKeyExistNoExpire
-	}
-	remains := int64(exp - uint64(time.Now().UnixMilli()))
-	if remains < 0 {
-		return constant.RespKeyNotExist
+	if exp, ok := redisDB.GetExpiry(key); ok {
+		redisDB.rewriteCommand(
+			[]string{constant.CMD_SET, key, value},
+			[]string{constant.CMD_PEXPIREAT, key, strconv.FormatUint(exp, 10)},
+		)
+	} else {
+		redisDB.rewriteCommand([]string{constant.CMD_SET, key, value})
 	}
-
-	return Encode(remains/1000, false)
 }
 
-// PTTL key
-func cmdPTTL(redisDB *RedisDB, args []string) []byte {
-	if len(args) > 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'pttl' command"), false)
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func cmdSet(redisDB *RedisDB, args []string) []byte {
+	opts, err := parseSetArgs(args[2:])
+	if err != nil {
+		return Encode(err, false)
 	}
 
-	key := args[0]
-	obj := redisDB.Get(key)
-	if obj == nil {
-		return constant.RespKeyNotExist
+	key, value := args[0], args[1]
+	old, exist, errReply := getString(redisDB, key)
+	// GET fails on other types, without GET a value of any type is overwritten
+	if opts.get && errReply != nil {
+		return errReply
 	}
 
-	exp, ok := redisDB.GetExpiry(key)
-	if !ok {
-		return constant.TTLKeyExistNoExpire
+	// with GET the reply is the old value, even when NX or XX prevent the set
+	res := constant.RespOk
+	if opts.get {
+		res = constant.RespNil
+		if exist {
+			res = Encode(old, false)
+		}
 	}
-	remains := int64(exp - uint64(time.Now().UnixMilli()))
-	if remains < 0 {
-		return constant.RespKeyNotExist
+	if (opts.nx && exist) || (opts.xx && !exist) {
+		if opts.get {
+			return res
+		}
+		return constant.RespNil
 	}
 
-	return Encode(remains, false)
+	setString(redisDB, key, value, opts.ttlMs, opts.expireAtMs, opts.keepTTL)
+	return res
 }
 
-// DEL key [key ...]
-func cmdDel(redisDB *RedisDB, args []string) []byte {
-	if len(args) == 0 {
-		return Encode(errors.New("ERR wrong number of arguments for 'del' command"), false)
+// GET key
+func cmdGet(redisDB *RedisDB, args []string) []byte {
+	value, exist, errReply := getString(redisDB, args[0])
+	if errReply != nil {
+		return errReply
 	}
+	if !exist {
+		return constant.RespNil
+	}
+	return Encode(value, false)
+}
 
+// DEL key [key ...]
+func cmdDel(redisDB *RedisDB, args []string) []byte {
 	delCount := 0
 	for _, key := range args {
 		ok := redisDB.Delete(key)
@@ -133,10 +193,6 @@ func cmdDel(redisDB *RedisDB, args []string) []byte {
 
 // EXISTS key [key ...]
 func cmdExists(redisDB *RedisDB, args []string) []byte {
-	if len(args) == 0 {
-		return Encode(errors.New("ERR wrong number of arguments for 'exists' command"), false)
-	}
-
 	existingCount := 0
 	for _, key := range args {
 		obj := redisDB.Get(key)
@@ -149,36 +205,63 @@ func cmdExists(redisDB *RedisDB, args []string) []byte {
 	return Encode(existingCount, false)
 }
 
-// EXPIRE key seconds
-func cmdExpire(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'expire' command"), false)
-	}
-
-	key, value := args[0], args[1]
-	obj := redisDB.Get(key)
-	if obj == nil {
-		return Encode(0, false)
+// RENAME key newkey
+func cmdRename(redisDB *RedisDB, args []string) []byte {
+	if !redisDB.Rename(args[0], args[1]) {
+		return Encode(errors.New("ERR no such key"), false)
 	}
+	return constant.RespOk
+}
 
-	expiredSec, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return Encode(errors.New("ERR value is not an integer or out of range"), false)
+// RENAMENX key newkey
+func cmdRenameNX(redisDB *RedisDB, args []string) []byte {
+	key, newKey := args[0], args[1]
+	if redisDB.Get(key) == nil {
+		return Encode(errors.New("ERR no such key"), false)
 	}
-	if expiredSec <= 0 {
-		redisDB.Delete(key)
-	} else {
-		redisDB.SetExpiry(key, uint64(expiredSec*1000))
+	if redisDB.Get(newKey) != nil {
+		return constant.RespZero
 	}
 
-	return Encode(1, false)
+	redisDB.Rename(key, newKey)
+	return constant.RespOne
 }
 
 // INFO [section [section...]]
 func cmdINFO(redisDB *RedisDB, args []string) []byte {
-	var info []byte
-	buf := bytes.NewBuffer(info)
-	buf.WriteString("# Keyspace\r\n")
-	buf.WriteString(fmt.Sprintf("db:key=%d,epxires=%d,avg_ttl=0\r\n", len(redisDB.dict), len(redisDB.expireDict)))
+	sections := make(map[string]bool)
+	for _, arg := range args {
+		sections[strings.ToLower(arg)] = true
+	}
+	all := len(args) == 0 || sections["all"] || sections["default"] || sections["everything"]
+
+	var buf bytes.Buffer
+	// section writes the header of a section, sections are separated by an empty line
+	section := func(name string) bool {
+		if !all && !sections[strings.ToLower(name)] {
+			return false
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\r\n")
+		}
+		fmt.Fprintf(&buf, "# %s\r\n", name)
+		return true
+	}
+	if section("Memory") {
+		used := redisDB.UsedMemory()
+		fmt.Fprintf(&buf, "used_memory:%d\r\n", used)
+		fmt.Fprintf(&buf, "used_memory_human:%s\r\n", BytesToHuman(used))
+		fmt.Fprintf(&buf, "maxmemory:%d\r\n", config.MaxMemory.Load())
+		fmt.Fprintf(&buf, "maxmemory_human:%s\r\n", BytesToHuman(config.MaxMemory.Load()))
+		fmt.Fprintf(&buf, "maxmemory_policy:%s\r\n", config.EvictPolicy.Load())
+	}
+	if section("Stats") {
+		fmt.Fprintf(&buf, "total_commands_processed:%d\r\n", redisDB.stats.numCommands)
+		fmt.Fprintf(&buf, "expired_keys:%d\r\n", redisDB.stats.expiredKeys)
+		fmt.Fprintf(&buf, "evicted_keys:%d\r\n", redisDB.stats.evictedKeys)
+	}
+	if section("Keyspace") {
+		fmt.Fprintf(&buf, "db0:keys=%d,expires=%d,avg_ttl=0\r\n", redisDB.dict.Len(), redisDB.expireDict.Len())
+	}
 	return Encode(buf.String(), false)
 }
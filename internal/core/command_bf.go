@@ -10,12 +10,8 @@ import (
 
 // BF.RESERVE key error_rate entries
 func cmdBFRESERVE(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 3 {
-		return Encode(errors.New("ERR wrong number of arguments for 'bf.reserve' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if exist {
 		_, ok := obj.value.(*data_structure.BloomFilter)
 		if !ok {
@@ -41,24 +37,20 @@ func cmdBFRESERVE(redisDB *RedisDB, args []string) []byte {
 	if capacity < 1 || capacity > 1<<30 {
 		return Encode(errors.New("ERR capacity must be in the range [1, 1073741824]"), false)
 	}
-	redisDB.dict[key] = NewRedisObj(data_structure.CreateBloomFilter(capacity, errorRate))
+	redisDB.Set(key, NewRedisObj(data_structure.CreateBloomFilter(capacity, errorRate)), 0)
 
 	return constant.RespOk
 }
 
 // BF.ADD key entry
 func cmdBFADD(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'bf.add' command"), false)
-	}
-
 	key, entry := args[0], args[1]
 	var bloom *data_structure.BloomFilter
 
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		bloom = data_structure.CreateBloomFilter(constant.BfDefaultInitCapacity, constant.BfDefaultErrRate)
-		redisDB.dict[key] = NewRedisObj(bloom)
+		redisDB.Set(key, NewRedisObj(bloom), 0)
 	} else {
 		var ok bool
 		bloom, ok = obj.value.(*data_structure.BloomFilter)
@@ -76,16 +68,12 @@ func cmdBFADD(redisDB *RedisDB, args []string) []byte {
 
 // BF.MADD key entry [entry ...]
 func cmdBFMADD(redisDB *RedisDB, args []string) []byte {
-	if len(args) < 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'bf.madd' command"), false)
-	}
-
 	key := args[0]
 	var bloom *data_structure.BloomFilter
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		bloom = data_structure.CreateBloomFilter(constant.BfDefaultInitCapacity, constant.BfDefaultErrRate)
-		redisDB.dict[key] = NewRedisObj(bloom)
+		redisDB.Set(key, NewRedisObj(bloom), 0)
 	} else {
 		var ok bool
 		bloom, ok = obj.value.(*data_structure.BloomFilter)
@@ -108,12 +96,9 @@ func cmdBFMADD(redisDB *RedisDB, args []string) []byte {
 
 // BF.EXISTS key entry
 func cmdBFEXISTS(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'bf.exists' command"), false)
-	}
 	key, entry := args[0], args[1]
 	var bloom *data_structure.BloomFilter
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(constant.RespZero, false)
 	}
@@ -130,14 +115,10 @@ func cmdBFEXISTS(redisDB *RedisDB, args []string) []byte {
 
 // BF.MEXISTS key entry [entry ...]
 func cmdBFMEXISTS(redisDB *RedisDB, args []string) []byte {
-	if len(args) < 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'bf.exists' command"), false)
-	}
-
 	res := make([]any, 0)
 	key := args[0]
 	var bloom *data_structure.BloomFilter
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		for i := 1; i < len(args); i++ {
 			res = append(res, 0)
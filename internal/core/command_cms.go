@@ -10,12 +10,8 @@ import (
 
 // CMS.INITBYDIM key width depth
 func cmdCMSINITBYDIM(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 3 {
-		return Encode(errors.New("ERR wrong number of arguments for 'cms.initbydim' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if exist {
 		_, ok := obj.value.(*data_structure.CMS)
 		if !ok {
@@ -34,18 +30,15 @@ func cmdCMSINITBYDIM(redisDB *RedisDB, args []string) []byte {
 	}
 
 	cms := data_structure.CreateCMS(uint32(width), uint32(depth))
-	redisDB.dict[key] = NewRedisObj(cms)
+	redisDB.Set(key, NewRedisObj(cms), 0)
 
 	return constant.RespOk
 }
 
 // CMS.INITBYPROB key error probability
 func cmdCMSINITBYPROB(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 3 {
-		return Encode(errors.New("ERR wrong number of arguments for 'cms.initbyprob' command"), false)
-	}
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if exist {
 		_, ok := obj.value.(*data_structure.CMS)
 		if !ok {
@@ -65,7 +58,7 @@ func cmdCMSINITBYPROB(redisDB *RedisDB, args []string) []byte {
 
 	width, depth := data_structure.CalcCMSDim(errRate, probability)
 	cms := data_structure.CreateCMS(width, depth)
-	redisDB.dict[key] = NewRedisObj(cms)
+	redisDB.Set(key, NewRedisObj(cms), 0)
 
 	return constant.RespOk
 }
@@ -77,7 +70,7 @@ func cmdCMSINCRBY(redisDB *RedisDB, args []string) []byte {
 	}
 
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(errors.New("CMS: key does not exist"), false)
 	}
@@ -102,12 +95,8 @@ func cmdCMSINCRBY(redisDB *RedisDB, args []string) []byte {
 
 // CMS.QUERY key item [item ...]
 func cmdCMSQUERY(redisDB *RedisDB, args []string) []byte {
-	if len(args) < 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'cms.query' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(errors.New("CMS: key does not exist"), false)
 	}
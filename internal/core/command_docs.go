@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// COMMAND [COUNT | INFO [command-name ...] | DOCS [command-name ...]]
+func cmdCOMMAND(redisDB *RedisDB, args []string) []byte {
+	if len(args) == 0 {
+		return Encode(commandInfos(sortedCommandNames()), false)
+	}
+
+	subCmd := strings.ToUpper(args[0])
+	switch subCmd {
+	case "COUNT":
+		if len(args) != 1 {
+			return Encode(errors.New("ERR wrong number of arguments for 'command|count' command"), false)
+		}
+		return Encode(len(commandTable), false)
+	case "INFO":
+		names := args[1:]
+		if len(names) == 0 {
+			names = sortedCommandNames()
+		}
+		return Encode(commandInfos(names), false)
+	case "DOCS":
+		names := args[1:]
+		if len(names) == 0 {
+			names = sortedCommandNames()
+		}
+		return Encode(commandDocs(names), false)
+	default:
+		return Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try COMMAND HELP.", args[0]), false)
+	}
+}
+
+func sortedCommandNames() []string {
+	names := make([]string, 0, len(commandTable))
+	for name := range commandTable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commandInfos builds the COMMAND INFO reply, unknown commands are reported as nil
+func commandInfos(names []string) []any {
+	res := make([]any, len(names))
+	for i, name := range names {
+		spec := LookupCommand(name)
+		if spec == nil {
+			continue
+		}
+		res[i] = spec.info()
+	}
+	return res
+}
+
+// commandDocs builds the COMMAND DOCS reply, a flat list of name and docs pairs, unknown commands are skipped
+func commandDocs(names []string) []any {
+	res := make([]any, 0, 2*len(names))
+	for _, name := range names {
+		spec := LookupCommand(name)
+		if spec == nil {
+			continue
+		}
+		res = append(res, strings.ToLower(spec.Name), []any{
+			"summary", spec.Summary,
+			"since", spec.Since,
+			"group", spec.Group,
+			"complexity", spec.Complexity,
+		})
+	}
+	return res
+}
+
+// info is the entry of the spec in COMMAND INFO:
+// name, arity, flags, first key, last key, step, acl categories, tips, key specs, subcommands
+func (spec *CommandSpec) info() []any {
+	flags := make([]string, 0)
+	for _, f := range flagNames {
+		if spec.HasFlag(f.flag) {
+			flags = append(flags, f.name)
+		}
+	}
+	if spec.NumKeys != 0 {
+		flags = append(flags, "movablekeys")
+	}
+
+	categories := spec.Categories()
+	for i, category := range categories {
+		categories[i] = "@" + category
+	}
+
+	tips := make([]string, len(spec.Tips))
+	copy(tips, spec.Tips)
+
+	return []any{
+		strings.ToLower(spec.Name),
+		spec.Arity,
+		flags,
+		spec.FirstKey,
+		spec.LastKey,
+		spec.Step,
+		categories,
+		tips,
+		[]any{},
+		[]any{},
+	}
+}
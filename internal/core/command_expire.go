@@ -0,0 +1,187 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+)
+
+// expireCondition is the NX, XX, GT or LT option of the EXPIRE family
+type expireCondition struct {
+	nx, xx, gt, lt bool
+}
+
+func parseExpireCondition(args []string) (expireCondition, error) {
+	var cond expireCondition
+	for _, arg := range args {
+		switch strings.ToUpper(arg) {
+		case "NX":
+			cond.nx = true
+		case "XX":
+			cond.xx = true
+		case "GT":
+			cond.gt = true
+		case "LT":
+			cond.lt = true
+		default:
+			return cond, fmt.Errorf("ERR Unsupported option %s", arg)
+		}
+	}
+
+	if cond.nx && (cond.xx || cond.gt || cond.lt) {
+		return cond, errors.New("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if cond.gt && cond.lt {
+		return cond, errors.New("ERR GT and LT options at the same time are not compatible")
+	}
+	return cond, nil
+}
+
+// allows reports whether the condition lets the deadline of a key change to expireAtMs,
+// a key without TTL counts as an infinite TTL for GT and LT like in redis
+func (cond expireCondition) allows(redisDB *RedisDB, key string, expireAtMs int64) bool {
+	current, hasExpiry := redisDB.GetExpiry(key)
+	switch {
+	case cond.nx:
+		return !hasExpiry
+	case cond.xx && !hasExpiry:
+		return false
+	case cond.gt:
+		return hasExpiry && expireAtMs > int64(current)
+	case cond.lt:
+		return !hasExpiry || expireAtMs < int64(current)
+	}
+	return true
+}
+
+// expireGeneric implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT: the time is in seconds when unit is
+// time.Second, and relative to now when relative is set. The AOF gets the absolute deadline in ms.
+func expireGeneric(redisDB *RedisDB, args []string, unit time.Duration, relative bool, cmdName string) []byte {
+	key := args[0]
+	when, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	cond, err := parseExpireCondition(args[2:])
+	if err != nil {
+		return Encode(err, false)
+	}
+
+	errInvalid := fmt.Errorf("ERR invalid expire time in '%s' command", cmdName)
+	if unit == time.Second {
+		if when > math.MaxInt64/1000 || when < math.MinInt64/1000 {
+			return Encode(errInvalid, false)
+		}
+		when *= 1000
+	}
+	if relative {
+		now := time.Now().UnixMilli()
+		if when > math.MaxInt64-now {
+			return Encode(errInvalid, false)
+		}
+		when += now
+	}
+
+	if redisDB.Get(key) == nil || !cond.allows(redisDB, key, when) {
+		redisDB.preventPropagation()
+		return constant.RespZero
+	}
+
+	if when <= time.Now().UnixMilli() {
+		redisDB.Delete(key)
+		redisDB.rewriteCommand([]string{constant.CMD_DEL, key})
+	} else {
+		redisDB.SetExpireAt(key, uint64(when))
+		redisDB.rewriteCommand([]string{constant.CMD_PEXPIREAT, key, strconv.FormatInt(when, 10)})
+	}
+	return constant.RespOne
+}
+
+// EXPIRE key seconds [NX | XX | GT | LT]
+func cmdExpire(redisDB *RedisDB, args []string) []byte {
+	return expireGeneric(redisDB, args, time.Second, true, "expire")
+}
+
+// PEXPIRE key milliseconds [NX | XX | GT | LT]
+func cmdPExpire(redisDB *RedisDB, args []string) []byte {
+	return expireGeneric(redisDB, args, time.Millisecond, true, "pexpire")
+}
+
+// EXPIREAT key unix-time-seconds [NX | XX | GT | LT]
+func cmdExpireAt(redisDB *RedisDB, args []string) []byte {
+	return expireGeneric(redisDB, args, time.Second, false, "expireat")
+}
+
+// PEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT]
+func cmdPExpireAt(redisDB *RedisDB, args []string) []byte {
+	return expireGeneric(redisDB, args, time.Millisecond, false, "pexpireat")
+}
+
+// ttlGeneric implements TTL and PTTL, -2 when the key does not exist and -1 when it has no TTL
+func ttlGeneric(redisDB *RedisDB, key string, unit time.Duration) []byte {
+	if redisDB.Get(key) == nil {
+		return constant.RespKeyNotExist
+	}
+
+	ttlMs, ok := redisDB.TTL(key)
+	if !ok {
+		return constant.TTLKeyExistNoExpire
+	}
+	ttlMs = max(ttlMs, 0)
+	if unit == time.Second {
+		// rounded to the nearest second like redis, a fresh EXPIRE 100 reports 100
+		return Encode((ttlMs+500)/1000, false)
+	}
+	return Encode(ttlMs, false)
+}
+
+// TTL key
+func cmdTTL(redisDB *RedisDB, args []string) []byte {
+	return ttlGeneric(redisDB, args[0], time.Second)
+}
+
+// PTTL key
+func cmdPTTL(redisDB *RedisDB, args []string) []byte {
+	return ttlGeneric(redisDB, args[0], time.Millisecond)
+}
+
+// expireTimeGeneric implements EXPIRETIME and PEXPIRETIME, -2 when the key does not exist and -1 when it has no TTL
+func expireTimeGeneric(redisDB *RedisDB, key string, unit time.Duration) []byte {
+	if redisDB.Get(key) == nil {
+		return constant.RespKeyNotExist
+	}
+
+	expireAtMs, ok := redisDB.GetExpiry(key)
+	if !ok {
+		return constant.TTLKeyExistNoExpire
+	}
+	if unit == time.Second {
+		return Encode(expireAtMs/1000, false)
+	}
+	return Encode(expireAtMs, false)
+}
+
+// EXPIRETIME key
+func cmdExpireTime(redisDB *RedisDB, args []string) []byte {
+	return expireTimeGeneric(redisDB, args[0], time.Second)
+}
+
+// PEXPIRETIME key
+func cmdPExpireTime(redisDB *RedisDB, args []string) []byte {
+	return expireTimeGeneric(redisDB, args[0], time.Millisecond)
+}
+
+// PERSIST key
+func cmdPersist(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	if redisDB.Get(key) == nil || !redisDB.RemoveExpiry(key) {
+		redisDB.preventPropagation()
+		return constant.RespZero
+	}
+	return constant.RespOne
+}
@@ -0,0 +1,145 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireFamily(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, ":0\r\n", execute(db, "EXPIRE", "missing", "100"))
+
+	execute(db, "SET", "k", "v")
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "k", "100"))
+	// a fresh TTL is reported as is, not rounded down
+	assert.Equal(t, ":100\r\n", execute(db, "TTL", "k"))
+
+	assert.Equal(t, ":1\r\n", execute(db, "PEXPIRE", "k", "50000"))
+	assert.Equal(t, ":50\r\n", execute(db, "TTL", "k"))
+	assert.Regexp(t, `^:(4999\d|50000)\r\n$`, execute(db, "PTTL", "k"))
+
+	now := time.Now()
+	at := now.Unix() + 200
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIREAT", "k", strconv.FormatInt(at, 10)))
+	assert.Equal(t, ":"+strconv.FormatInt(at, 10)+"\r\n", execute(db, "EXPIRETIME", "k"))
+	assert.Equal(t, ":"+strconv.FormatInt(at*1000, 10)+"\r\n", execute(db, "PEXPIRETIME", "k"))
+
+	atMs := now.UnixMilli() + 300000
+	assert.Equal(t, ":1\r\n", execute(db, "PEXPIREAT", "k", strconv.FormatInt(atMs, 10)))
+	assert.Equal(t, ":"+strconv.FormatInt(atMs, 10)+"\r\n", execute(db, "PEXPIRETIME", "k"))
+	assert.Equal(t, ":300\r\n", execute(db, "TTL", "k"))
+
+	assert.Equal(t, ":1\r\n", execute(db, "PERSIST", "k"))
+	assert.Equal(t, ":0\r\n", execute(db, "PERSIST", "k"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, ":-1\r\n", execute(db, "PTTL", "k"))
+	assert.Equal(t, ":-1\r\n", execute(db, "EXPIRETIME", "k"))
+	assert.Equal(t, ":-2\r\n", execute(db, "TTL", "missing"))
+	assert.Equal(t, ":-2\r\n", execute(db, "PEXPIRETIME", "missing"))
+	assert.Equal(t, ":0\r\n", execute(db, "PERSIST", "missing"))
+
+	// a deadline in the past deletes the key
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "k", "-1"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "k"))
+	execute(db, "SET", "k", "v")
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIREAT", "k", "1"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "k"))
+
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "EXPIRE", "k", "x"))
+	assert.Equal(t, "-ERR invalid expire time in 'expire' command\r\n", execute(db, "EXPIRE", "k", "9223372036854775807"))
+	assert.Equal(t, "-ERR invalid expire time in 'pexpire' command\r\n", execute(db, "PEXPIRE", "k", "9223372036854775807"))
+}
+
+func TestTTLNearDeadline(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v")
+	execute(db, "PEXPIRE", "k", "1400")
+	assert.Equal(t, ":1\r\n", execute(db, "TTL", "k"))
+	execute(db, "PEXPIRE", "k", "1600")
+	assert.Equal(t, ":2\r\n", execute(db, "TTL", "k"))
+
+	// past the deadline the key is still stored until a lookup deletes it, the TTL is negative, not a huge unsigned value
+	execute(db, "PEXPIRE", "k", "5")
+	time.Sleep(10 * time.Millisecond)
+	ttl, ok := db.TTL("k")
+	assert.True(t, ok)
+	assert.Negative(t, ttl)
+	assert.Equal(t, ":-2\r\n", execute(db, "PTTL", "k"))
+	_, ok = db.TTL("k")
+	assert.False(t, ok)
+}
+
+func TestExpireConditions(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v")
+
+	// without TTL: NX sets it, XX and GT don't, LT does since no TTL counts as infinite
+	assert.Equal(t, ":0\r\n", execute(db, "EXPIRE", "k", "100", "XX"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXPIRE", "k", "100", "GT"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "k", "100", "NX"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXPIRE", "k", "200", "NX"))
+	execute(db, "PERSIST", "k")
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "k", "100", "LT"))
+
+	// with a TTL of 100s
+	assert.Equal(t, ":0\r\n", execute(db, "EXPIRE", "k", "50", "GT"))
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "k", "200", "gt"))
+	assert.Equal(t, ":200\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, ":0\r\n", execute(db, "PEXPIRE", "k", "300000", "LT"))
+	assert.Equal(t, ":1\r\n", execute(db, "PEXPIRE", "k", "150000", "XX", "LT"))
+	assert.Equal(t, ":150\r\n", execute(db, "TTL", "k"))
+
+	for args, expected := range map[[2]string]string{
+		{"NX", "XX"}: "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n",
+		{"NX", "GT"}: "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n",
+		{"GT", "LT"}: "-ERR GT and LT options at the same time are not compatible\r\n",
+		{"XX", "AB"}: "-ERR Unsupported option AB\r\n",
+	} {
+		assert.Equal(t, expected, execute(db, "EXPIRE", "k", "10", args[0], args[1]), args)
+	}
+	assert.Equal(t, ":150\r\n", execute(db, "TTL", "k"))
+}
+
+func TestExpireAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		execute(db, "SET", key, "v")
+	}
+	execute(db, "PEXPIRE", "a", "100000")
+	execute(db, "EXPIREAT", "b", strconv.FormatInt(time.Now().Unix()+100, 10))
+	execute(db, "EXPIRE", "c", "100")
+	execute(db, "PERSIST", "c")
+	execute(db, "EXPIRE", "d", "0")
+	// not applied, not logged
+	execute(db, "EXPIRE", "a", "10", "GT")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "$7\r\nPEXPIRE\r\n")
+	assert.NotContains(t, string(data), "$8\r\nEXPIREAT\r\n")
+	assert.NotContains(t, string(data), "$6\r\nEXPIRE\r\n")
+
+	time.Sleep(20 * time.Millisecond)
+	loaded := replay(t, path)
+	for _, key := range []string{"a", "b"} {
+		expected, _ := db.GetExpiry(key)
+		actual, ok := loaded.GetExpiry(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, actual, key)
+	}
+	assert.Equal(t, ":-1\r\n", execute(loaded, "TTL", "c"))
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "d"))
+}
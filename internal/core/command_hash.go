@@ -0,0 +1,390 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+// getHash returns the hash at key, nil when the key does not exist,
+// or the WRONGTYPE reply when the key holds another type
+func getHash(redisDB *RedisDB, key string) (*data_structure.Hash, []byte) {
+	obj := redisDB.Get(key)
+	if obj == nil {
+		return nil, nil
+	}
+	hash, ok := obj.value.(*data_structure.Hash)
+	if !ok {
+		return nil, constant.ErrorWrongTypeKey
+	}
+	return hash, nil
+}
+
+// getOrCreateHash is getHash creating an empty hash when the key does not exist
+func getOrCreateHash(redisDB *RedisDB, key string) (*data_structure.Hash, []byte) {
+	hash, errReply := getHash(redisDB, key)
+	if errReply != nil || hash != nil {
+		return hash, errReply
+	}
+	hash = data_structure.NewHash()
+	redisDB.Set(key, NewRedisObj(hash), 0)
+	return hash, nil
+}
+
+// HSET key field value [field value ...]
+func cmdHSET(redisDB *RedisDB, args []string) []byte {
+	if len(args)%2 == 0 {
+		return Encode(errors.New("ERR wrong number of arguments for 'hset' command"), false)
+	}
+	hash, errReply := getOrCreateHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	added := 0
+	for i := 1; i < len(args); i += 2 {
+		if hash.Set(args[i], args[i+1]) {
+			added++
+		}
+	}
+	return Encode(added, false)
+}
+
+// HSETNX key field value
+func cmdHSETNX(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getOrCreateHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	if _, exist := hash.Get(args[1]); exist {
+		return constant.RespZero
+	}
+	hash.Set(args[1], args[2])
+	return constant.RespOne
+}
+
+// HGET key field
+func cmdHGET(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return constant.RespNil
+	}
+
+	value, exist := hash.Get(args[1])
+	if !exist {
+		return constant.RespNil
+	}
+	return Encode(value, false)
+}
+
+// HMGET key field [field ...]
+func cmdHMGET(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	values := make([]any, len(args)-1)
+	for i, field := range args[1:] {
+		if hash == nil {
+			continue
+		}
+		if value, exist := hash.Get(field); exist {
+			values[i] = value
+		}
+	}
+	return Encode(values, false)
+}
+
+// HDEL key field [field ...]
+func cmdHDEL(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	hash, errReply := getHash(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return constant.RespZero
+	}
+
+	deleted := hash.Delete(args[1:]...)
+	if hash.Len() == 0 {
+		redisDB.Delete(key)
+	}
+	return Encode(deleted, false)
+}
+
+// HEXISTS key field
+func cmdHEXISTS(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return constant.RespZero
+	}
+
+	if _, exist := hash.Get(args[1]); exist {
+		return constant.RespOne
+	}
+	return constant.RespZero
+}
+
+// HLEN key
+func cmdHLEN(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return constant.RespZero
+	}
+
+	return Encode(hash.Len(), false)
+}
+
+// HSTRLEN key field
+func cmdHSTRLEN(redisDB *RedisDB, args []string) []byte {
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return constant.RespZero
+	}
+
+	value, _ := hash.Get(args[1])
+	return Encode(len(value), false)
+}
+
+// hashEntries returns field, value pairs, only the fields or only the values
+func hashEntries(redisDB *RedisDB, key string, withFields, withValues bool) []byte {
+	hash, errReply := getHash(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return Encode(make([]string, 0), false)
+	}
+
+	entries := hash.Entries()
+	if withFields && withValues {
+		return Encode(entries, false)
+	}
+
+	res := make([]string, 0, hash.Len())
+	start := 0
+	if withValues {
+		start = 1
+	}
+	for i := start; i < len(entries); i += 2 {
+		res = append(res, entries[i])
+	}
+	return Encode(res, false)
+}
+
+// HKEYS key
+func cmdHKEYS(redisDB *RedisDB, args []string) []byte {
+	return hashEntries(redisDB, args[0], true, false)
+}
+
+// HVALS key
+func cmdHVALS(redisDB *RedisDB, args []string) []byte {
+	return hashEntries(redisDB, args[0], false, true)
+}
+
+// HGETALL key
+func cmdHGETALL(redisDB *RedisDB, args []string) []byte {
+	return hashEntries(redisDB, args[0], true, true)
+}
+
+// HINCRBY key field increment
+func cmdHINCRBY(redisDB *RedisDB, args []string) []byte {
+	key, field := args[0], args[1]
+	incr, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	hash, errReply := getHash(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var current int64
+	if hash != nil {
+		if value, exist := hash.Get(field); exist {
+			if current, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return Encode(errors.New("ERR hash value is not an integer"), false)
+			}
+		}
+	}
+	if (incr < 0 && current < math.MinInt64-incr) || (incr > 0 && current > math.MaxInt64-incr) {
+		return Encode(errOverflow, false)
+	}
+
+	current += incr
+	if hash == nil {
+		hash, _ = getOrCreateHash(redisDB, key)
+	}
+	hash.Set(field, strconv.FormatInt(current, 10))
+	return Encode(current, false)
+}
+
+// HINCRBYFLOAT key field increment
+func cmdHINCRBYFLOAT(redisDB *RedisDB, args []string) []byte {
+	key, field := args[0], args[1]
+	incr, err := parseFloat(args[2])
+	if err != nil {
+		return Encode(errNotFloat, false)
+	}
+	hash, errReply := getHash(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var current float64
+	if hash != nil {
+		if value, exist := hash.Get(field); exist {
+			if current, err = parseFloat(value); err != nil {
+				return Encode(errors.New("ERR hash value is not a float"), false)
+			}
+		}
+	}
+
+	current += incr
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Encode(errors.New("ERR increment would produce NaN or Infinity"), false)
+	}
+	if hash == nil {
+		hash, _ = getOrCreateHash(redisDB, key)
+	}
+	value := formatFloat(current)
+	hash.Set(field, value)
+	// replicas of the float arithmetic could differ, the AOF gets the result
+	redisDB.rewriteCommand([]string{constant.CMD_HSET, key, field, value})
+	return Encode(value, false)
+}
+
+// parseFloat parses a float argument, NaN is rejected like in redis
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || strings.TrimSpace(s) != s {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+// formatFloat gives the shortest representation that reads back to f, without exponent
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// HRANDFIELD key [count [WITHVALUES]]
+func cmdHRANDFIELD(redisDB *RedisDB, args []string) []byte {
+	if len(args) > 3 || (len(args) == 3 && strings.ToUpper(args[2]) != "WITHVALUES") {
+		return Encode(errSyntax, false)
+	}
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	if len(args) == 1 {
+		if hash == nil {
+			return constant.RespNil
+		}
+		field, _ := hash.Random()
+		return Encode(field, false)
+	}
+
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	withValues := len(args) == 3
+	if hash == nil || count == 0 {
+		return Encode(make([]string, 0), false)
+	}
+
+	var res []string
+	add := func(field, value string) {
+		res = append(res, field)
+		if withValues {
+			res = append(res, value)
+		}
+	}
+
+	switch {
+	case count < 0:
+		// fields may repeat
+		if count < -maxRandomCount {
+			return Encode(errors.New("ERR value is out of range"), false)
+		}
+		for i := int64(0); i < -count; i++ {
+			add(hash.Random())
+		}
+	case count >= int64(hash.Len()):
+		return hashEntries(redisDB, args[0], true, withValues)
+	default:
+		// distinct fields: pick random ones, or drop random ones when most of the hash is returned
+		picked := make(map[string]string, count)
+		if count*3 > int64(hash.Len()) {
+			entries := hash.Entries()
+			for i := 0; i < len(entries); i += 2 {
+				picked[entries[i]] = entries[i+1]
+			}
+			for int64(len(picked)) > count {
+				field, _ := hash.Random()
+				delete(picked, field)
+			}
+		} else {
+			for int64(len(picked)) < count {
+				field, value := hash.Random()
+				picked[field] = value
+			}
+		}
+		for field, value := range picked {
+			add(field, value)
+		}
+	}
+	return Encode(res, false)
+}
+
+// HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func cmdHSCAN(redisDB *RedisDB, args []string) []byte {
+	cursor, opts, err := parseScanArgs(args[1:], "NOVALUES")
+	if err != nil {
+		return Encode(err, false)
+	}
+	hash, errReply := getHash(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		return encodeScanReply(0, make([]string, 0))
+	}
+
+	res := make([]string, 0)
+	numFields := 0
+	cursor = scanLoop(cursor, opts.count, func() int { return numFields }, func(cursor uint64) uint64 {
+		return hash.Scan(cursor, func(field string, value string) {
+			if !opts.matches(field) {
+				return
+			}
+			numFields++
+			res = append(res, field)
+			if !opts.noValues {
+				res = append(res, value)
+			}
+		})
+	})
+	return encodeScanReply(cursor, res)
+}
@@ -0,0 +1,196 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCommands(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":2\r\n", execute(db, "HSET", "h", "a", "1", "b", "2"))
+	assert.Equal(t, ":0\r\n", execute(db, "HSET", "h", "a", "10"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'hset' command\r\n", execute(db, "HSET", "h", "a", "1", "b"))
+	assert.Equal(t, ":0\r\n", execute(db, "HSETNX", "h", "a", "100"))
+	assert.Equal(t, ":1\r\n", execute(db, "HSETNX", "h", "c", "hello"))
+
+	assert.Equal(t, "$2\r\n10\r\n", execute(db, "HGET", "h", "a"))
+	assert.Equal(t, "$-1\r\n", execute(db, "HGET", "h", "missing"))
+	assert.Equal(t, "$-1\r\n", execute(db, "HGET", "nokey", "a"))
+	assert.Equal(t, "*3\r\n$2\r\n10\r\n$-1\r\n$1\r\n2\r\n", execute(db, "HMGET", "h", "a", "x", "b"))
+	assert.Equal(t, "*1\r\n$-1\r\n", execute(db, "HMGET", "nokey", "a"))
+
+	assert.Equal(t, ":3\r\n", execute(db, "HLEN", "h"))
+	assert.Equal(t, ":5\r\n", execute(db, "HSTRLEN", "h", "c"))
+	assert.Equal(t, ":0\r\n", execute(db, "HSTRLEN", "h", "missing"))
+	assert.Equal(t, ":1\r\n", execute(db, "HEXISTS", "h", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "HEXISTS", "h", "x"))
+
+	assert.Equal(t, "*0\r\n", execute(db, "HGETALL", "nokey"))
+	execute(db, "HDEL", "h", "c")
+	all := execute(db, "HGETALL", "h")
+	assert.Contains(t, []string{
+		"*4\r\n$1\r\na\r\n$2\r\n10\r\n$1\r\nb\r\n$1\r\n2\r\n",
+		"*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\na\r\n$2\r\n10\r\n",
+	}, all)
+	assert.Contains(t, []string{"*2\r\n$1\r\na\r\n$1\r\nb\r\n", "*2\r\n$1\r\nb\r\n$1\r\na\r\n"}, execute(db, "HKEYS", "h"))
+	assert.Contains(t, []string{"*2\r\n$2\r\n10\r\n$1\r\n2\r\n", "*2\r\n$1\r\n2\r\n$2\r\n10\r\n"}, execute(db, "HVALS", "h"))
+
+	// the key is deleted with its last field
+	assert.Equal(t, ":2\r\n", execute(db, "HDEL", "h", "a", "b", "x"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "h"))
+	assert.Equal(t, ":0\r\n", execute(db, "HDEL", "h", "a"))
+}
+
+func TestHashIncr(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":5\r\n", execute(db, "HINCRBY", "h", "n", "5"))
+	assert.Equal(t, ":-2\r\n", execute(db, "HINCRBY", "h", "n", "-7"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "HINCRBY", "h", "n", "x"))
+	execute(db, "HSET", "h", "s", "abc", "max", "9223372036854775807")
+	assert.Equal(t, "-ERR hash value is not an integer\r\n", execute(db, "HINCRBY", "h", "s", "1"))
+	assert.Equal(t, "-ERR increment or decrement would overflow\r\n", execute(db, "HINCRBY", "h", "max", "1"))
+
+	assert.Equal(t, "$4\r\n10.5\r\n", execute(db, "HINCRBYFLOAT", "f", "x", "10.5"))
+	assert.Equal(t, "$3\r\n5.5\r\n", execute(db, "HINCRBYFLOAT", "f", "x", "-5"))
+	assert.Equal(t, "$2\r\n-4\r\n", execute(db, "HINCRBYFLOAT", "h", "n", "-2"))
+	assert.Equal(t, "-ERR hash value is not a float\r\n", execute(db, "HINCRBYFLOAT", "h", "s", "1"))
+	assert.Equal(t, "-ERR value is not a valid float\r\n", execute(db, "HINCRBYFLOAT", "h", "n", "nan"))
+	assert.Equal(t, "-ERR increment would produce NaN or Infinity\r\n", execute(db, "HINCRBYFLOAT", "h", "n", "inf"))
+}
+
+func TestHashWrongType(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "str", "v")
+
+	for _, args := range [][]string{
+		{"HSET", "str", "a", "1"},
+		{"HSETNX", "str", "a", "1"},
+		{"HGET", "str", "a"},
+		{"HMGET", "str", "a"},
+		{"HDEL", "str", "a"},
+		{"HEXISTS", "str", "a"},
+		{"HLEN", "str"},
+		{"HSTRLEN", "str", "a"},
+		{"HKEYS", "str"},
+		{"HVALS", "str"},
+		{"HGETALL", "str"},
+		{"HINCRBY", "str", "a", "1"},
+		{"HINCRBYFLOAT", "str", "a", "1"},
+		{"HRANDFIELD", "str"},
+		{"HSCAN", "str", "0"},
+	} {
+		assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, args[0], args[1:]...), args[0])
+	}
+}
+
+func TestHRandField(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, "$-1\r\n", execute(db, "HRANDFIELD", "h"))
+	assert.Equal(t, "*0\r\n", execute(db, "HRANDFIELD", "h", "3"))
+
+	execute(db, "HSET", "h", "a", "1", "b", "2", "c", "3")
+	assert.Contains(t, []string{"$1\r\na\r\n", "$1\r\nb\r\n", "$1\r\nc\r\n"}, execute(db, "HRANDFIELD", "h"))
+
+	// a positive count returns distinct fields, at most the whole hash
+	for count, expected := range map[string]int{"1": 1, "2": 2, "3": 3, "10": 3} {
+		reply, err := core.Decode([]byte(execute(db, "HRANDFIELD", "h", count)))
+		assert.NoError(t, err)
+		fields := reply.([]any)
+		assert.Len(t, fields, expected)
+		seen := map[any]bool{}
+		for _, f := range fields {
+			assert.False(t, seen[f])
+			seen[f] = true
+		}
+	}
+
+	// a negative count may repeat fields
+	reply, err := core.Decode([]byte(execute(db, "HRANDFIELD", "h", "-10", "WITHVALUES")))
+	assert.NoError(t, err)
+	pairs := reply.([]any)
+	assert.Len(t, pairs, 20)
+	values := map[any]any{"a": "1", "b": "2", "c": "3"}
+	for i := 0; i < len(pairs); i += 2 {
+		assert.Equal(t, values[pairs[i]], pairs[i+1])
+	}
+
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "HRANDFIELD", "h", "1", "WITHSCORES"))
+	assert.Equal(t, "-ERR value is out of range\r\n", execute(db, "HRANDFIELD", "h", "-9000000000000000000"))
+	assert.Equal(t, "-ERR value is out of range\r\n", execute(db, "HRANDFIELD", "h", "-9223372036854775808", "WITHVALUES"))
+}
+
+func TestHScan(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, "*2\r\n$1\r\n0\r\n*0\r\n", execute(db, "HSCAN", "h", "0"))
+
+	fields := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		field := "f" + string(rune('a'+i%26)) + string(rune('0'+i/26))
+		fields[field] = true
+		execute(db, "HSET", "h", field, "v")
+	}
+
+	seen := map[string]bool{}
+	cursor := "0"
+	for {
+		reply, err := core.Decode([]byte(execute(db, "HSCAN", "h", cursor, "COUNT", "7", "NOVALUES")))
+		assert.NoError(t, err)
+		res := reply.([]any)
+		cursor = res[0].(string)
+		for _, f := range res[1].([]any) {
+			seen[f.(string)] = true
+		}
+		if cursor == "0" {
+			break
+		}
+	}
+	assert.Len(t, seen, len(fields))
+
+	// a COUNT close to the integer limit scans the whole hash at once
+	reply, err := core.Decode([]byte(execute(db, "HSCAN", "h", "0", "COUNT", "9223372036854775807", "NOVALUES")))
+	assert.NoError(t, err)
+	assert.Equal(t, "0", reply.([]any)[0])
+	assert.Len(t, reply.([]any)[1], len(fields))
+
+	assert.Equal(t, "-ERR invalid cursor\r\n", execute(db, "HSCAN", "h", "x"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "HSCAN", "h", "0", "COUNT", "0"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "HSCAN", "h", "0", "FOO"))
+}
+
+func TestHScanMatch(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "HSET", "h", "hello", "1", "hallo", "2", "hxllo", "3", "hllo", "4", "heeeello", "5", "h*llo", "6")
+
+	testCases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"h?llo", []string{"hello", "hallo", "hxllo", "h*llo"}},
+		{"h*llo", []string{"hello", "hallo", "hxllo", "hllo", "heeeello", "h*llo"}},
+		{"h[ae]llo", []string{"hello", "hallo"}},
+		{"h[^e]llo", []string{"hallo", "hxllo", "h*llo"}},
+		{"h[a-b]llo", []string{"hallo"}},
+		{`h\*llo`, []string{"h*llo"}},
+		{"*", []string{"hello", "hallo", "hxllo", "hllo", "heeeello", "h*llo"}},
+		{"x*", []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			reply, err := core.Decode([]byte(execute(db, "HSCAN", "h", "0", "MATCH", tc.pattern, "COUNT", "100")))
+			assert.NoError(t, err)
+			res := reply.([]any)
+			assert.Equal(t, "0", res[0])
+			matched := []string{}
+			elements := res[1].([]any)
+			for i := 0; i < len(elements); i += 2 {
+				matched = append(matched, elements[i].(string))
+			}
+			assert.ElementsMatch(t, tc.expected, matched)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+// typeName is the type reported by TYPE and matched by SCAN TYPE,
+// the probabilistic types have the names of the RedisBloom module
+func typeName(obj *RedisObj) string {
+	switch obj.value.(type) {
+	case string, int64:
+		return "string"
+	case *data_structure.QuickList:
+		return "list"
+	case *data_structure.SimpleSet:
+		return "set"
+	case *data_structure.ZSet:
+		return "zset"
+	case *data_structure.Hash:
+		return "hash"
+	case *data_structure.CMS:
+		return "CMSk-TYPE"
+	case *data_structure.BloomFilter:
+		return "MBbloom--"
+	}
+	return "none"
+}
+
+// KEYS pattern
+func cmdKEYS(redisDB *RedisDB, args []string) []byte {
+	pattern := args[0]
+	keys := make([]string, 0)
+	redisDB.dict.Range(func(key string, _ *RedisObj) bool {
+		if !redisDB.HasExpired(key) && GlobMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return Encode(keys, false)
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func cmdSCAN(redisDB *RedisDB, args []string) []byte {
+	cursor, opts, err := parseScanArgs(args, "TYPE")
+	if err != nil {
+		return Encode(err, false)
+	}
+
+	keys := make([]string, 0)
+	var expired []string
+	cursor = scanLoop(cursor, opts.count, func() int { return len(keys) }, func(cursor uint64) uint64 {
+		return redisDB.dict.Scan(cursor, func(key string, obj *RedisObj) {
+			if redisDB.HasExpired(key) {
+				expired = append(expired, key)
+				return
+			}
+			if opts.matches(key) && (opts.typ == "" || typeName(obj) == opts.typ) {
+				keys = append(keys, key)
+			}
+		})
+	})
+	// expired keys are deleted like a lookup does, once the table is not iterated anymore
+	for _, key := range expired {
+		redisDB.deleteExpired(key)
+	}
+	return encodeScanReply(cursor, keys)
+}
+
+// TYPE key
+func cmdTYPE(redisDB *RedisDB, args []string) []byte {
+	obj := redisDB.Get(args[0])
+	if obj == nil {
+		return Encode("none", true)
+	}
+	return Encode(typeName(obj), true)
+}
+
+// RANDOMKEY
+func cmdRANDOMKEY(redisDB *RedisDB, args []string) []byte {
+	for {
+		key, _, ok := redisDB.dict.Random()
+		if !ok {
+			return constant.RespNil
+		}
+		if !redisDB.HasExpired(key) {
+			return Encode(key, false)
+		}
+		// every try deletes an expired key, so this ends even when all keys are expired
+		redisDB.deleteExpired(key)
+	}
+}
+
+// DBSIZE
+func cmdDBSIZE(redisDB *RedisDB, args []string) []byte {
+	return Encode(redisDB.dict.Len(), false)
+}
+
+// OBJECT FREQ key
+func cmdOBJECT(redisDB *RedisDB, args []string) []byte {
+	switch strings.ToUpper(args[0]) {
+	case "FREQ":
+		if len(args) != 2 {
+			return Encode(errors.New("ERR wrong number of arguments for 'object|freq' command"), false)
+		}
+		// looking at the counter is not an access
+		obj := redisDB.getNoTouch(args[1])
+		if obj == nil {
+			return constant.RespNil
+		}
+		if !isLFUPolicy() {
+			return Encode(errors.New("ERR An LFU maxmemory policy is not selected, access frequency not tracked. "+
+				"Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust."), false)
+		}
+		return Encode(int64(obj.lfuDecr()), false)
+	default:
+		return Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try OBJECT HELP.", args[0]), false)
+	}
+}
@@ -0,0 +1,149 @@
+package core_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// sortedKeys decodes an array reply of keys, sorted
+func sortedKeys(t *testing.T, reply string) []string {
+	decoded, err := core.Decode([]byte(reply))
+	assert.NoError(t, err)
+	keys := make([]string, 0)
+	for _, key := range decoded.([]any) {
+		keys = append(keys, key.(string))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestKeys(t *testing.T) {
+	db := core.NewRedisDB()
+	for _, key := range []string{"hello", "hallo", "hxllo", "hllo", "heeeello", "hbllo", "h*llo", "other"} {
+		execute(db, "SET", key, "v")
+	}
+	execute(db, "SET", "expired", "v", "PXAT", "1")
+
+	for pattern, expected := range map[string][]string{
+		"h?llo":       {"h*llo", "hallo", "hbllo", "hello", "hxllo"},
+		"h*llo":       {"h*llo", "hallo", "hbllo", "heeeello", "hello", "hllo", "hxllo"},
+		"h[ae]llo":    {"hallo", "hello"},
+		"h[^e]llo":    {"h*llo", "hallo", "hbllo", "hxllo"},
+		"h[a-b]llo":   {"hallo", "hbllo"},
+		`h\*llo`:      {"h*llo"},
+		"*":           {"h*llo", "hallo", "hbllo", "heeeello", "hello", "hllo", "hxllo", "other"},
+		"nothing*":    {},
+		"[o]the[q-s]": {"other"},
+	} {
+		assert.Equal(t, expected, sortedKeys(t, execute(db, "KEYS", pattern)), pattern)
+	}
+}
+
+func TestScan(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, "*2\r\n$1\r\n0\r\n*0\r\n", execute(db, "SCAN", "0"))
+
+	for i := 0; i < 10; i++ {
+		execute(db, "SET", fmt.Sprintf("key:%d", i), "v")
+	}
+
+	// keys present during the whole walk are returned, even while the table changes
+	seen := map[string]bool{}
+	cursor := "0"
+	for round := 0; ; round++ {
+		reply, err := core.Decode([]byte(execute(db, "SCAN", cursor, "COUNT", "2")))
+		assert.NoError(t, err)
+		res := reply.([]any)
+		for _, key := range res[1].([]any) {
+			seen[key.(string)] = true
+		}
+		execute(db, "SET", fmt.Sprintf("new:%d", round), "v")
+		execute(db, "DEL", fmt.Sprintf("key:%d", 9-round))
+		cursor = res[0].(string)
+		if cursor == "0" {
+			break
+		}
+	}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		// deleted keys may or may not be returned
+		if execute(db, "EXISTS", key) == ":1\r\n" {
+			assert.True(t, seen[key], key)
+		}
+	}
+	assert.Equal(t, ":1\r\n", execute(db, "EXISTS", "key:0"))
+
+	// a COUNT close to the integer limit returns every key at once
+	reply, err := core.Decode([]byte(execute(db, "SCAN", "0", "COUNT", "9223372036854775807")))
+	assert.NoError(t, err)
+	assert.Equal(t, "0", reply.([]any)[0])
+	assert.Len(t, reply.([]any)[1], 10)
+
+	assert.Equal(t, "-ERR invalid cursor\r\n", execute(db, "SCAN", "x"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "SCAN", "0", "NOVALUES"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "SCAN", "0", "TYPE"))
+}
+
+func TestScanOptions(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "s1", "v")
+	execute(db, "SET", "s2", "v")
+	execute(db, "LPUSH", "l1", "a")
+	execute(db, "HSET", "h1", "f", "v")
+	execute(db, "SET", "s3", "v", "PXAT", "1")
+
+	scanAll := func(args ...string) []string {
+		reply, err := core.Decode([]byte(execute(db, "SCAN", append([]string{"0", "COUNT", "1000"}, args...)...)))
+		assert.NoError(t, err)
+		res := reply.([]any)
+		assert.Equal(t, "0", res[0])
+		keys := make([]string, 0)
+		for _, key := range res[1].([]any) {
+			keys = append(keys, key.(string))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	assert.Equal(t, []string{"h1", "l1", "s1", "s2"}, scanAll())
+	assert.Equal(t, []string{"s1", "s2"}, scanAll("TYPE", "STRING"))
+	assert.Equal(t, []string{"l1"}, scanAll("TYPE", "list"))
+	assert.Equal(t, []string{"s2"}, scanAll("MATCH", "*2", "TYPE", "string"))
+	assert.Equal(t, []string{}, scanAll("TYPE", "zset"))
+	// the expired key was deleted by the scan
+	assert.Equal(t, ":4\r\n", execute(db, "DBSIZE"))
+}
+
+func TestTypeRandomKeyDBSize(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, "$-1\r\n", execute(db, "RANDOMKEY"))
+	assert.Equal(t, ":0\r\n", execute(db, "DBSIZE"))
+
+	execute(db, "SET", "str", "v")
+	execute(db, "INCR", "int")
+	execute(db, "LPUSH", "list", "a")
+	execute(db, "SADD", "set", "a")
+	execute(db, "ZADD", "zset", "1", "a")
+	execute(db, "HSET", "hash", "f", "v")
+	execute(db, "CMS.INITBYDIM", "cms", "10", "2")
+	execute(db, "BF.ADD", "bf", "a")
+	for key, expected := range map[string]string{
+		"str": "string", "int": "string", "list": "list", "set": "set", "zset": "zset", "hash": "hash",
+		"cms": "CMSk-TYPE", "bf": "MBbloom--", "missing": "none",
+	} {
+		assert.Equal(t, "+"+expected+"\r\n", execute(db, "TYPE", key), key)
+	}
+	assert.Equal(t, ":8\r\n", execute(db, "DBSIZE"))
+
+	// expired keys are never returned
+	db = core.NewRedisDB()
+	for i := 0; i < 9; i++ {
+		execute(db, "SET", fmt.Sprintf("expired:%d", i), "v", "PXAT", "1")
+	}
+	execute(db, "SET", "alive", "v")
+	assert.Equal(t, "$5\r\nalive\r\n", execute(db, "RANDOMKEY"))
+}
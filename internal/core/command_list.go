@@ -0,0 +1,506 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+var (
+	errNoSuchKey       = errors.New("ERR no such key")
+	errIndexOutOfRange = errors.New("ERR index out of range")
+	errNotPositive     = errors.New("ERR value is out of range, must be positive")
+)
+
+// getList returns the list at key, nil when the key does not exist,
+// or the WRONGTYPE reply when the key holds another type
+func getList(redisDB *RedisDB, key string) (*data_structure.QuickList, []byte) {
+	obj := redisDB.Get(key)
+	if obj == nil {
+		return nil, nil
+	}
+	list, ok := obj.value.(*data_structure.QuickList)
+	if !ok {
+		return nil, constant.ErrorWrongTypeKey
+	}
+	return list, nil
+}
+
+// deleteIfEmpty deletes the key of a list that has no element left, like redis never keeps empty lists
+func deleteIfEmpty(redisDB *RedisDB, key string, list *data_structure.QuickList) {
+	if list.Len() == 0 {
+		redisDB.Delete(key)
+	}
+}
+
+// listIndex converts a possibly negative index to an index from the head
+func listIndex(index int64, length int) int64 {
+	if index < 0 {
+		index += int64(length)
+	}
+	return index
+}
+
+// listRange converts LRANGE and LTRIM indexes to an inclusive range from the head, empty when start > stop
+func listRange(start int64, stop int64, length int) (int, int) {
+	start, stop = listIndex(start, length), listIndex(stop, length)
+	start = max(start, 0)
+	stop = min(stop, int64(length)-1)
+	if start > stop {
+		return 1, 0
+	}
+	return int(start), int(stop)
+}
+
+// parseWhere parses LEFT or RIGHT, it returns true for LEFT
+func parseWhere(arg string) (bool, error) {
+	switch strings.ToUpper(arg) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, errSyntax
+}
+
+func whereName(left bool) string {
+	if left {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+func listPop(list *data_structure.QuickList, left bool) (string, bool) {
+	if left {
+		return list.PopFront()
+	}
+	return list.PopBack()
+}
+
+func listPush(list *data_structure.QuickList, left bool, values ...string) {
+	if left {
+		list.PushFront(values...)
+	} else {
+		list.PushBack(values...)
+	}
+}
+
+// push implements LPUSH, RPUSH and their X variants which only push to an existing list
+func push(redisDB *RedisDB, args []string, left bool, onlyIfExists bool) []byte {
+	key := args[0]
+	list, errReply := getList(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		if onlyIfExists {
+			return constant.RespZero
+		}
+		list = data_structure.NewQuickList()
+		listPush(list, left, args[1:]...)
+		redisDB.Set(key, NewRedisObj(list), 0)
+		return Encode(list.Len(), false)
+	}
+
+	listPush(list, left, args[1:]...)
+	return Encode(list.Len(), false)
+}
+
+// LPUSH key element [element ...]
+func cmdLPUSH(redisDB *RedisDB, args []string) []byte {
+	return push(redisDB, args, true, false)
+}
+
+// RPUSH key element [element ...]
+func cmdRPUSH(redisDB *RedisDB, args []string) []byte {
+	return push(redisDB, args, false, false)
+}
+
+// LPUSHX key element [element ...]
+func cmdLPUSHX(redisDB *RedisDB, args []string) []byte {
+	return push(redisDB, args, true, true)
+}
+
+// RPUSHX key element [element ...]
+func cmdRPUSHX(redisDB *RedisDB, args []string) []byte {
+	return push(redisDB, args, false, true)
+}
+
+// pop implements LPOP and RPOP
+func pop(redisDB *RedisDB, args []string, left bool) []byte {
+	if len(args) > 2 {
+		return Encode(errSyntax, false)
+	}
+	count := int64(-1)
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.ParseInt(args[1], 10, 64); err != nil || count < 0 {
+			return Encode(errNotPositive, false)
+		}
+	}
+
+	key := args[0]
+	list, errReply := getList(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		if count >= 0 {
+			return constant.RespNilArray
+		}
+		return constant.RespNil
+	}
+
+	if count < 0 {
+		value, _ := listPop(list, left)
+		deleteIfEmpty(redisDB, key, list)
+		return Encode(value, false)
+	}
+
+	values := make([]string, 0, min(count, int64(list.Len())))
+	for int64(len(values)) < count {
+		value, ok := listPop(list, left)
+		if !ok {
+			break
+		}
+		values = append(values, value)
+	}
+	deleteIfEmpty(redisDB, key, list)
+	return Encode(values, false)
+}
+
+// LPOP key [count]
+func cmdLPOP(redisDB *RedisDB, args []string) []byte {
+	return pop(redisDB, args, true)
+}
+
+// RPOP key [count]
+func cmdRPOP(redisDB *RedisDB, args []string) []byte {
+	return pop(redisDB, args, false)
+}
+
+// LLEN key
+func cmdLLEN(redisDB *RedisDB, args []string) []byte {
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return constant.RespZero
+	}
+	return Encode(list.Len(), false)
+}
+
+// LRANGE key start stop
+func cmdLRANGE(redisDB *RedisDB, args []string) []byte {
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	stop, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errNotInteger, false)
+	}
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return Encode(make([]string, 0), false)
+	}
+
+	from, to := listRange(start, stop, list.Len())
+	return Encode(list.Range(from, to), false)
+}
+
+// LINDEX key index
+func cmdLINDEX(redisDB *RedisDB, args []string) []byte {
+	index, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return constant.RespNil
+	}
+
+	index = listIndex(index, list.Len())
+	if index < 0 || index >= int64(list.Len()) {
+		return constant.RespNil
+	}
+	value, _ := list.Index(int(index))
+	return Encode(value, false)
+}
+
+// LSET key index element
+func cmdLSET(redisDB *RedisDB, args []string) []byte {
+	index, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return Encode(errNoSuchKey, false)
+	}
+
+	index = listIndex(index, list.Len())
+	if index < 0 || index >= int64(list.Len()) {
+		return Encode(errIndexOutOfRange, false)
+	}
+	list.Set(int(index), args[2])
+	return constant.RespOk
+}
+
+// LINSERT key BEFORE|AFTER pivot element
+func cmdLINSERT(redisDB *RedisDB, args []string) []byte {
+	var after bool
+	switch strings.ToUpper(args[1]) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return Encode(errSyntax, false)
+	}
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return constant.RespZero
+	}
+
+	if !list.Insert(args[2], args[3], after) {
+		return Encode(-1, false)
+	}
+	return Encode(list.Len(), false)
+}
+
+// LREM key count element
+func cmdLREM(redisDB *RedisDB, args []string) []byte {
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	key := args[0]
+	list, errReply := getList(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return constant.RespZero
+	}
+
+	// a count larger than the list removes every occurrence
+	count = max(min(count, int64(list.Len())), -int64(list.Len()))
+	removed := list.Remove(args[2], int(count))
+	deleteIfEmpty(redisDB, key, list)
+	return Encode(removed, false)
+}
+
+// LTRIM key start stop
+func cmdLTRIM(redisDB *RedisDB, args []string) []byte {
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	stop, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errNotInteger, false)
+	}
+	key := args[0]
+	list, errReply := getList(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return constant.RespOk
+	}
+
+	from, to := listRange(start, stop, list.Len())
+	list.Trim(from, to)
+	deleteIfEmpty(redisDB, key, list)
+	return constant.RespOk
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func cmdLPOS(redisDB *RedisDB, args []string) []byte {
+	rank, count, maxLen := int64(1), int64(-1), int64(0)
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return Encode(errSyntax, false)
+		}
+		value, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil {
+			return Encode(errNotInteger, false)
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if value == 0 {
+				return Encode(errors.New("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"), false)
+			}
+			rank = value
+		case "COUNT":
+			if value < 0 {
+				return Encode(errors.New("ERR COUNT can't be negative"), false)
+			}
+			count = value
+		case "MAXLEN":
+			if value < 0 {
+				return Encode(errors.New("ERR MAXLEN can't be negative"), false)
+			}
+			maxLen = value
+		default:
+			return Encode(errSyntax, false)
+		}
+	}
+
+	list, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	// a negative rank searches from the tail, the returned indexes still count from the head
+	var matches []int
+	if list != nil {
+		skip := max(rank, -rank) - 1
+		compared := int64(0)
+		list.Iterate(rank < 0, func(index int, value string) bool {
+			if maxLen > 0 && compared == maxLen {
+				return false
+			}
+			compared++
+			if value != args[1] {
+				return true
+			}
+			if skip > 0 {
+				skip--
+				return true
+			}
+			matches = append(matches, index)
+			// COUNT 0 returns every match
+			return count == 0 || int64(len(matches)) < max(count, 1)
+		})
+	}
+
+	if count < 0 {
+		if len(matches) == 0 {
+			return constant.RespNil
+		}
+		return Encode(matches[0], false)
+	}
+	res := make([]any, len(matches))
+	for i, index := range matches {
+		res[i] = index
+	}
+	return Encode(res, false)
+}
+
+// move pops an element from the source list and pushes it to the destination, for LMOVE and BLMOVE.
+// The destination type is checked before anything is popped.
+func move(redisDB *RedisDB, src *data_structure.QuickList, args []string, from bool, to bool) []byte {
+	srcKey, dstKey := args[0], args[1]
+	dst, errReply := getList(redisDB, dstKey)
+	if errReply != nil {
+		return errReply
+	}
+
+	// push before deleting an empty source, the source may be the destination
+	value, _ := listPop(src, from)
+	if dst == nil {
+		dst = data_structure.NewQuickList()
+		listPush(dst, to, value)
+		redisDB.Set(dstKey, NewRedisObj(dst), 0)
+	} else {
+		listPush(dst, to, value)
+	}
+	deleteIfEmpty(redisDB, srcKey, src)
+	return Encode(value, false)
+}
+
+// LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+func cmdLMOVE(redisDB *RedisDB, args []string) []byte {
+	from, err1 := parseWhere(args[2])
+	to, err2 := parseWhere(args[3])
+	if err1 != nil || err2 != nil {
+		return Encode(errSyntax, false)
+	}
+	src, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if src == nil {
+		return constant.RespNil
+	}
+
+	return move(redisDB, src, args, from, to)
+}
+
+// blockingPop implements BLPOP and BRPOP: it pops from the first non-empty list,
+// or blocks until one of the keys is pushed to
+func blockingPop(redisDB *RedisDB, args []string, left bool) []byte {
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		return Encode(err, false)
+	}
+	keys := args[:len(args)-1]
+
+	for _, key := range keys {
+		list, errReply := getList(redisDB, key)
+		if errReply != nil {
+			return errReply
+		}
+		if list == nil {
+			continue
+		}
+
+		value, _ := listPop(list, left)
+		deleteIfEmpty(redisDB, key, list)
+		cmd := constant.CMD_LPOP
+		if !left {
+			cmd = constant.CMD_RPOP
+		}
+		redisDB.rewriteCommand([]string{cmd, key})
+		return Encode([]string{key, value}, false)
+	}
+
+	return redisDB.blockForKeys(keys, timeout, constant.RespNilArray)
+}
+
+// BLPOP key [key ...] timeout
+func cmdBLPOP(redisDB *RedisDB, args []string) []byte {
+	return blockingPop(redisDB, args, true)
+}
+
+// BRPOP key [key ...] timeout
+func cmdBRPOP(redisDB *RedisDB, args []string) []byte {
+	return blockingPop(redisDB, args, false)
+}
+
+// BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+func cmdBLMOVE(redisDB *RedisDB, args []string) []byte {
+	from, err1 := parseWhere(args[2])
+	to, err2 := parseWhere(args[3])
+	if err1 != nil || err2 != nil {
+		return Encode(errSyntax, false)
+	}
+	timeout, err := parseTimeout(args[4])
+	if err != nil {
+		return Encode(err, false)
+	}
+	src, errReply := getList(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if src == nil {
+		return redisDB.blockForKeys(args[:1], timeout, constant.RespNil)
+	}
+
+	res := move(redisDB, src, args, from, to)
+	if res[0] != '-' {
+		redisDB.rewriteCommand([]string{constant.CMD_LMOVE, args[0], args[1], whereName(from), whereName(to)})
+	}
+	return res
+}
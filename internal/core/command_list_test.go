@@ -0,0 +1,138 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPushPop(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":0\r\n", execute(db, "LPUSHX", "l", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "l"))
+	assert.Equal(t, ":2\r\n", execute(db, "RPUSH", "l", "c", "d"))
+	assert.Equal(t, ":4\r\n", execute(db, "LPUSH", "l", "b", "a"))
+	assert.Equal(t, ":5\r\n", execute(db, "RPUSHX", "l", "e"))
+	assert.Equal(t, "*5\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n$1\r\ne\r\n", execute(db, "LRANGE", "l", "0", "-1"))
+
+	assert.Equal(t, "$1\r\na\r\n", execute(db, "LPOP", "l"))
+	assert.Equal(t, "*2\r\n$1\r\ne\r\n$1\r\nd\r\n", execute(db, "RPOP", "l", "2"))
+	assert.Equal(t, "*0\r\n", execute(db, "LPOP", "l", "0"))
+	assert.Equal(t, "-ERR value is out of range, must be positive\r\n", execute(db, "LPOP", "l", "-1"))
+	// the key is deleted with its last element
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n", execute(db, "LPOP", "l", "10"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "l"))
+	assert.Equal(t, "$-1\r\n", execute(db, "LPOP", "l"))
+	assert.Equal(t, "*-1\r\n", execute(db, "RPOP", "l", "1"))
+	assert.Equal(t, ":0\r\n", execute(db, "LLEN", "l"))
+}
+
+func TestListIndexes(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "RPUSH", "l", "a", "b", "c", "d")
+
+	assert.Equal(t, ":4\r\n", execute(db, "LLEN", "l"))
+	assert.Equal(t, "*2\r\n$1\r\nc\r\n$1\r\nd\r\n", execute(db, "LRANGE", "l", "-2", "100"))
+	assert.Equal(t, "*0\r\n", execute(db, "LRANGE", "l", "3", "1"))
+	assert.Equal(t, "*0\r\n", execute(db, "LRANGE", "nokey", "0", "-1"))
+	assert.Equal(t, "$1\r\nd\r\n", execute(db, "LINDEX", "l", "-1"))
+	assert.Equal(t, "$-1\r\n", execute(db, "LINDEX", "l", "4"))
+
+	assert.Equal(t, "+OK\r\n", execute(db, "LSET", "l", "-4", "A"))
+	assert.Equal(t, "-ERR index out of range\r\n", execute(db, "LSET", "l", "4", "x"))
+	assert.Equal(t, "-ERR no such key\r\n", execute(db, "LSET", "nokey", "0", "x"))
+
+	assert.Equal(t, ":5\r\n", execute(db, "LINSERT", "l", "BEFORE", "c", "x"))
+	assert.Equal(t, ":6\r\n", execute(db, "LINSERT", "l", "after", "d", "y"))
+	assert.Equal(t, ":-1\r\n", execute(db, "LINSERT", "l", "AFTER", "missing", "z"))
+	assert.Equal(t, ":0\r\n", execute(db, "LINSERT", "nokey", "AFTER", "a", "z"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "LINSERT", "l", "UNDER", "a", "z"))
+	assert.Equal(t, "*6\r\n$1\r\nA\r\n$1\r\nb\r\n$1\r\nx\r\n$1\r\nc\r\n$1\r\nd\r\n$1\r\ny\r\n", execute(db, "LRANGE", "l", "0", "-1"))
+
+	assert.Equal(t, "+OK\r\n", execute(db, "LTRIM", "l", "1", "-2"))
+	assert.Equal(t, "*4\r\n$1\r\nb\r\n$1\r\nx\r\n$1\r\nc\r\n$1\r\nd\r\n", execute(db, "LRANGE", "l", "0", "-1"))
+	assert.Equal(t, "+OK\r\n", execute(db, "LTRIM", "l", "5", "10"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "l"))
+}
+
+func TestLREM(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "RPUSH", "l", "a", "b", "a", "c", "a")
+
+	assert.Equal(t, ":1\r\n", execute(db, "LREM", "l", "-1", "a"))
+	assert.Equal(t, "*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\na\r\n$1\r\nc\r\n", execute(db, "LRANGE", "l", "0", "-1"))
+	assert.Equal(t, ":1\r\n", execute(db, "LREM", "l", "1", "a"))
+	assert.Equal(t, "*3\r\n$1\r\nb\r\n$1\r\na\r\n$1\r\nc\r\n", execute(db, "LRANGE", "l", "0", "-1"))
+	assert.Equal(t, ":0\r\n", execute(db, "LREM", "l", "0", "x"))
+	execute(db, "LREM", "l", "0", "b")
+	execute(db, "LREM", "l", "0", "c")
+	assert.Equal(t, ":1\r\n", execute(db, "LREM", "l", "0", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "l"))
+}
+
+func TestLPOS(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "RPUSH", "l", "a", "b", "c", "1", "2", "3", "c", "c")
+
+	assert.Equal(t, ":2\r\n", execute(db, "LPOS", "l", "c"))
+	assert.Equal(t, ":6\r\n", execute(db, "LPOS", "l", "c", "RANK", "2"))
+	assert.Equal(t, ":7\r\n", execute(db, "LPOS", "l", "c", "RANK", "-1"))
+	assert.Equal(t, "*2\r\n:2\r\n:6\r\n", execute(db, "LPOS", "l", "c", "COUNT", "2"))
+	assert.Equal(t, "*3\r\n:2\r\n:6\r\n:7\r\n", execute(db, "LPOS", "l", "c", "COUNT", "0"))
+	assert.Equal(t, "*2\r\n:7\r\n:6\r\n", execute(db, "LPOS", "l", "c", "RANK", "-1", "COUNT", "2"))
+	assert.Equal(t, "*1\r\n:2\r\n", execute(db, "LPOS", "l", "c", "COUNT", "0", "MAXLEN", "6"))
+	assert.Equal(t, "$-1\r\n", execute(db, "LPOS", "l", "c", "MAXLEN", "2"))
+	assert.Equal(t, "$-1\r\n", execute(db, "LPOS", "l", "x"))
+	assert.Equal(t, "*0\r\n", execute(db, "LPOS", "nokey", "x", "COUNT", "0"))
+
+	assert.Contains(t, execute(db, "LPOS", "l", "c", "RANK", "0"), "-ERR RANK can't be zero")
+	assert.Equal(t, "-ERR COUNT can't be negative\r\n", execute(db, "LPOS", "l", "c", "COUNT", "-1"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "LPOS", "l", "c", "COUNT"))
+}
+
+func TestLMOVE(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "RPUSH", "src", "a", "b", "c")
+
+	assert.Equal(t, "$1\r\nc\r\n", execute(db, "LMOVE", "src", "dst", "RIGHT", "LEFT"))
+	assert.Equal(t, "$1\r\na\r\n", execute(db, "LMOVE", "src", "dst", "LEFT", "RIGHT"))
+	assert.Equal(t, "*2\r\n$1\r\nc\r\n$1\r\na\r\n", execute(db, "LRANGE", "dst", "0", "-1"))
+	// rotation of a single element list keeps the key
+	assert.Equal(t, "$1\r\nb\r\n", execute(db, "LMOVE", "src", "src", "LEFT", "RIGHT"))
+	assert.Equal(t, "*1\r\n$1\r\nb\r\n", execute(db, "LRANGE", "src", "0", "-1"))
+	assert.Equal(t, "$1\r\nb\r\n", execute(db, "LMOVE", "src", "dst", "LEFT", "LEFT"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "src"))
+	assert.Equal(t, "$-1\r\n", execute(db, "LMOVE", "src", "dst", "LEFT", "LEFT"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "LMOVE", "dst", "src", "UP", "LEFT"))
+
+	// nothing is popped when the destination has the wrong type
+	execute(db, "SET", "str", "v")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "LMOVE", "dst", "str", "LEFT", "LEFT"))
+	assert.Equal(t, ":3\r\n", execute(db, "LLEN", "dst"))
+}
+
+func TestListWrongType(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "str", "v")
+
+	for _, args := range [][]string{
+		{"LPUSH", "str", "a"},
+		{"RPUSHX", "str", "a"},
+		{"LPOP", "str"},
+		{"LLEN", "str"},
+		{"LRANGE", "str", "0", "1"},
+		{"LINDEX", "str", "0"},
+		{"LSET", "str", "0", "a"},
+		{"LINSERT", "str", "BEFORE", "a", "b"},
+		{"LREM", "str", "0", "a"},
+		{"LTRIM", "str", "0", "1"},
+		{"LPOS", "str", "a"},
+		{"LMOVE", "str", "l", "LEFT", "LEFT"},
+		{"BLPOP", "str", "0"},
+		{"BLMOVE", "str", "l", "LEFT", "LEFT", "0"},
+	} {
+		assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, args[0], args[1:]...), args[0])
+	}
+}
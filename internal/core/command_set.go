@@ -2,23 +2,34 @@ package core
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/nhtuan0700/godis/internal/constant"
 	"github.com/nhtuan0700/godis/internal/core/data_structure"
 )
 
-// SADD key member [member ...]
-func cmdSADD(redisDB *RedisDB, args []string) []byte {
-	if len(args) <= 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'sadd' command"), false)
+// getSet returns the set stored at key, nil when the key does not exist,
+// or the WRONGTYPE reply when it holds another type
+func getSet(redisDB *RedisDB, key string) (*data_structure.SimpleSet, []byte) {
+	obj := redisDB.Get(key)
+	if obj == nil {
+		return nil, nil
+	}
+	set, ok := obj.value.(*data_structure.SimpleSet)
+	if !ok {
+		return nil, constant.ErrorWrongTypeKey
 	}
+	return set, nil
+}
 
+// SADD key member [member ...]
+func cmdSADD(redisDB *RedisDB, args []string) []byte {
 	key := args[0]
 	var simpleSet *data_structure.SimpleSet
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		simpleSet = data_structure.NewSimpleSet()
-		redisDB.dict[key] = NewRedisObj(simpleSet)
+		redisDB.Set(key, NewRedisObj(simpleSet), 0)
 		return Encode(simpleSet.Add(args[1:]...), false)
 	}
 	simpleSet, ok := obj.value.(*data_structure.SimpleSet)
@@ -30,12 +41,8 @@ func cmdSADD(redisDB *RedisDB, args []string) []byte {
 
 // SREM key member [member ...]
 func cmdSREM(redisDB *RedisDB, args []string) []byte {
-	if len(args) <= 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'srem' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(0, false)
 	}
@@ -44,16 +51,15 @@ func cmdSREM(redisDB *RedisDB, args []string) []byte {
 		return constant.ErrorWrongTypeKey
 	}
 
-	return Encode(simpleSet.Remove(args[1:]...), false)
+	removed := simpleSet.Remove(args[1:]...)
+	deleteSetIfEmpty(redisDB, key, simpleSet)
+	return Encode(removed, false)
 }
 
 // SISMEMBER key member
 func cmdSISMEMBER(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'sismember' command"), false)
-	}
 	key, member := args[0], args[1]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(0, false)
 	}
@@ -67,12 +73,8 @@ func cmdSISMEMBER(redisDB *RedisDB, args []string) []byte {
 
 // SMEMBERS key
 func cmdSMEMEBERS(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 1 {
-		return Encode(errors.New("ERR wrong number of arguments for 'smembers' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return Encode(make([]string, 0), false)
 	}
@@ -83,3 +85,327 @@ func cmdSMEMEBERS(redisDB *RedisDB, args []string) []byte {
 
 	return Encode(simpleSet.Members(), false)
 }
+
+// SCARD key
+func cmdSCARD(redisDB *RedisDB, args []string) []byte {
+	set, errReply := getSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		return Encode(0, false)
+	}
+	return Encode(set.Len(), false)
+}
+
+// SMISMEMBER key member [member ...]
+func cmdSMISMEMBER(redisDB *RedisDB, args []string) []byte {
+	set, errReply := getSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	res := make([]any, len(args)-1)
+	for i, member := range args[1:] {
+		res[i] = 0
+		if set != nil {
+			res[i] = set.IsMember(member)
+		}
+	}
+	return Encode(res, false)
+}
+
+// SMOVE source destination member
+func cmdSMOVE(redisDB *RedisDB, args []string) []byte {
+	src, dst, member := args[0], args[1], args[2]
+	srcSet, errReply := getSet(redisDB, src)
+	if errReply != nil {
+		return errReply
+	}
+	if srcSet == nil {
+		redisDB.preventPropagation()
+		return Encode(0, false)
+	}
+	dstSet, errReply := getSet(redisDB, dst)
+	if errReply != nil {
+		return errReply
+	}
+
+	if src == dst || srcSet.Remove(member) == 0 {
+		redisDB.preventPropagation()
+		return Encode(srcSet.IsMember(member), false)
+	}
+	deleteSetIfEmpty(redisDB, src, srcSet)
+	if dstSet == nil {
+		dstSet = data_structure.NewSimpleSet()
+		redisDB.Set(dst, NewRedisObj(dstSet), 0)
+	}
+	dstSet.Add(member)
+	return Encode(1, false)
+}
+
+// deleteSetIfEmpty deletes the key of a set that has no member left
+func deleteSetIfEmpty(redisDB *RedisDB, key string, set *data_structure.SimpleSet) {
+	if set.Len() == 0 {
+		redisDB.Delete(key)
+	}
+}
+
+// SPOP key [count]
+//
+// The members are random, the command is logged as the SREM of the popped members.
+func cmdSPOP(redisDB *RedisDB, args []string) []byte {
+	if len(args) > 2 {
+		return Encode(errSyntax, false)
+	}
+	count := 1
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 0 {
+			return Encode(errors.New("ERR value is out of range, must be positive"), false)
+		}
+	}
+
+	key := args[0]
+	set, errReply := getSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil || count == 0 {
+		redisDB.preventPropagation()
+		if len(args) == 1 {
+			return constant.RespNil
+		}
+		return Encode([]string{}, false)
+	}
+
+	var popped []string
+	if count >= set.Len() {
+		popped = set.Members()
+		redisDB.Delete(key)
+		redisDB.rewriteCommand([]string{constant.CMD_DEL, key})
+	} else {
+		popped = make([]string, count)
+		for i := range popped {
+			popped[i] = set.Random()
+			set.Remove(popped[i])
+		}
+		redisDB.rewriteCommand(append([]string{constant.CMD_SREM, key}, popped...))
+	}
+	if len(args) == 1 {
+		return Encode(popped[0], false)
+	}
+	return Encode(popped, false)
+}
+
+// maxRandomCount bounds a negative count of SRANDMEMBER and HRANDFIELD, their reply repeats elements
+// and is built in memory whatever the size of the key
+const maxRandomCount = 1 << 24
+
+// SRANDMEMBER key [count]
+//
+// A negative count may return the same member several times.
+func cmdSRANDMEMBER(redisDB *RedisDB, args []string) []byte {
+	if len(args) > 2 {
+		return Encode(errSyntax, false)
+	}
+	set, errReply := getSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	if len(args) == 1 {
+		if set == nil {
+			return constant.RespNil
+		}
+		return Encode(set.Random(), false)
+	}
+
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	if set == nil || count == 0 {
+		return Encode([]string{}, false)
+	}
+
+	switch {
+	case count < 0:
+		if count < -maxRandomCount {
+			return Encode(errors.New("ERR value is out of range"), false)
+		}
+		res := make([]string, -count)
+		for i := range res {
+			res[i] = set.Random()
+		}
+		return Encode(res, false)
+	case count >= int64(set.Len()):
+		return Encode(set.Members(), false)
+	}
+
+	// distinct members: pick random ones, or drop random ones when most of the set is returned
+	picked := make(map[string]struct{}, count)
+	if count*3 > int64(set.Len()) {
+		set.Range(func(member string) bool {
+			picked[member] = struct{}{}
+			return true
+		})
+		for int64(len(picked)) > count {
+			delete(picked, set.Random())
+		}
+	} else {
+		for int64(len(picked)) < count {
+			picked[set.Random()] = struct{}{}
+		}
+	}
+	res := make([]string, 0, count)
+	for member := range picked {
+		res = append(res, member)
+	}
+	return Encode(res, false)
+}
+
+// setOp is the operation of SUNION, SINTER and SDIFF
+type setOp int
+
+const (
+	setUnion setOp = iota
+	setInter
+	setDiff
+)
+
+// combineSets computes the members of the operation on the source keys
+func combineSets(op setOp, inputs []operand) *data_structure.SimpleSet {
+	res := data_structure.NewSimpleSet()
+	add := func(member string, _ float64) bool {
+		res.Add(member)
+		return true
+	}
+	switch op {
+	case setUnion:
+		for _, input := range inputs {
+			input.Range(add)
+		}
+	case setInter:
+		smallest(inputs).Range(func(member string, _ float64) bool {
+			for _, input := range inputs {
+				if _, ok := input.Score(member); !ok {
+					return true
+				}
+			}
+			return add(member, 0)
+		})
+	case setDiff:
+		inputs[0].Range(func(member string, _ float64) bool {
+			for _, input := range inputs[1:] {
+				if _, ok := input.Score(member); ok {
+					return true
+				}
+			}
+			return add(member, 0)
+		})
+	}
+	return res
+}
+
+// setOperation implements SUNION, SINTER and SDIFF
+func setOperation(redisDB *RedisDB, op setOp, keys []string) []byte {
+	inputs, errReply := redisDB.readOperands(keys, false)
+	if errReply != nil {
+		return errReply
+	}
+	return Encode(combineSets(op, inputs).Members(), false)
+}
+
+// setOperationStore implements SUNIONSTORE, SINTERSTORE and SDIFFSTORE, dst is deleted when the result is empty
+func setOperationStore(redisDB *RedisDB, op setOp, dst string, keys []string) []byte {
+	inputs, errReply := redisDB.readOperands(keys, false)
+	if errReply != nil {
+		return errReply
+	}
+
+	set := combineSets(op, inputs)
+	if set.Len() == 0 {
+		redisDB.Delete(dst)
+	} else {
+		redisDB.Set(dst, NewRedisObj(set), 0)
+	}
+	// the sources owned by other workers were read before the command ran, see zsetOperationStore
+	if redisDB.operands != nil {
+		argvs := [][]string{{constant.CMD_DEL, dst}}
+		if set.Len() > 0 {
+			argvs = append(argvs, append([]string{constant.CMD_SADD, dst}, set.Members()...))
+		}
+		redisDB.rewriteCommand(argvs...)
+	}
+	return Encode(set.Len(), false)
+}
+
+// SUNION key [key ...]
+func cmdSUNION(redisDB *RedisDB, args []string) []byte {
+	return setOperation(redisDB, setUnion, args)
+}
+
+// SUNIONSTORE destination key [key ...]
+func cmdSUNIONSTORE(redisDB *RedisDB, args []string) []byte {
+	return setOperationStore(redisDB, setUnion, args[0], args[1:])
+}
+
+// SINTER key [key ...]
+func cmdSINTER(redisDB *RedisDB, args []string) []byte {
+	return setOperation(redisDB, setInter, args)
+}
+
+// SINTERSTORE destination key [key ...]
+func cmdSINTERSTORE(redisDB *RedisDB, args []string) []byte {
+	return setOperationStore(redisDB, setInter, args[0], args[1:])
+}
+
+// SDIFF key [key ...]
+func cmdSDIFF(redisDB *RedisDB, args []string) []byte {
+	return setOperation(redisDB, setDiff, args)
+}
+
+// SDIFFSTORE destination key [key ...]
+func cmdSDIFFSTORE(redisDB *RedisDB, args []string) []byte {
+	return setOperationStore(redisDB, setDiff, args[0], args[1:])
+}
+
+// SINTERCARD numkeys key [key ...] [LIMIT limit]
+//
+// The count stops at limit, 0 means no limit.
+func cmdSINTERCARD(redisDB *RedisDB, args []string) []byte {
+	keys, limit, err := parseInterCard(args)
+	if err != nil {
+		return Encode(err, false)
+	}
+	inputs, errReply := redisDB.readOperands(keys, false)
+	if errReply != nil {
+		return errReply
+	}
+	return Encode(interCard(inputs, limit), false)
+}
+
+// SSCAN key cursor [MATCH pattern] [COUNT count]
+func cmdSSCAN(redisDB *RedisDB, args []string) []byte {
+	cursor, opts, err := parseScanArgs(args[1:])
+	if err != nil {
+		return Encode(err, false)
+	}
+	set, errReply := getSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		return encodeScanReply(0, make([]string, 0))
+	}
+
+	res := make([]string, 0)
+	cursor = scanLoop(cursor, opts.count, func() int { return len(res) }, func(cursor uint64) uint64 {
+		return set.Scan(cursor, func(member string) {
+			if opts.matches(member) {
+				res = append(res, member)
+			}
+		})
+	})
+	return encodeScanReply(cursor, res)
+}
@@ -0,0 +1,118 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMembers(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SADD", "s", "a", "b", "c")
+
+	assert.Equal(t, ":3\r\n", execute(db, "SCARD", "s"))
+	assert.Equal(t, ":0\r\n", execute(db, "SCARD", "nokey"))
+	assert.Equal(t, "*3\r\n:1\r\n:0\r\n:1\r\n", execute(db, "SMISMEMBER", "s", "a", "x", "c"))
+	assert.Equal(t, "*1\r\n:0\r\n", execute(db, "SMISMEMBER", "nokey", "a"))
+
+	assert.Equal(t, ":1\r\n", execute(db, "SMOVE", "s", "dst", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "SMOVE", "s", "dst", "a"))
+	assert.Equal(t, ":1\r\n", execute(db, "SMOVE", "s", "s", "b"))
+	assert.Equal(t, ":1\r\n", execute(db, "SISMEMBER", "dst", "a"))
+	execute(db, "SMOVE", "s", "dst", "b")
+	execute(db, "SMOVE", "s", "dst", "c")
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "s"))
+
+	// the last member removed deletes the set
+	assert.Equal(t, ":3\r\n", execute(db, "SREM", "dst", "a", "b", "c"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "dst"))
+
+	execute(db, "SET", "str", "v")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "SCARD", "str"))
+	execute(db, "SADD", "s", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "SMOVE", "s", "str", "a"))
+}
+
+func TestSetRandom(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SADD", "s", "a", "b", "c", "d")
+
+	assert.Regexp(t, `^\$1\r\n[abcd]\r\n$`, execute(db, "SRANDMEMBER", "s"))
+	assert.Regexp(t, `^\*2\r\n(\$1\r\n[abcd]\r\n){2}$`, execute(db, "SRANDMEMBER", "s", "2"))
+	assert.Regexp(t, `^\*4\r\n(\$1\r\n[abcd]\r\n){4}$`, execute(db, "SRANDMEMBER", "s", "10"))
+	assert.Regexp(t, `^\*6\r\n(\$1\r\n[abcd]\r\n){6}$`, execute(db, "SRANDMEMBER", "s", "-6"))
+	assert.Equal(t, "*0\r\n", execute(db, "SRANDMEMBER", "s", "0"))
+	// a repeated member takes no room in the set but does in the reply
+	assert.Equal(t, "-ERR value is out of range\r\n", execute(db, "SRANDMEMBER", "s", "-9000000000000000000"))
+	assert.Equal(t, "-ERR value is out of range\r\n", execute(db, "SRANDMEMBER", "s", "-9223372036854775808"))
+	assert.Equal(t, "$-1\r\n", execute(db, "SRANDMEMBER", "nokey"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "SRANDMEMBER", "s", "x"))
+
+	assert.Regexp(t, `^\$1\r\n[abcd]\r\n$`, execute(db, "SPOP", "s"))
+	assert.Regexp(t, `^\*2\r\n(\$1\r\n[abcd]\r\n){2}$`, execute(db, "SPOP", "s", "2"))
+	assert.Equal(t, ":1\r\n", execute(db, "SCARD", "s"))
+	assert.Regexp(t, `^\*1\r\n\$1\r\n[abcd]\r\n$`, execute(db, "SPOP", "s", "5"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "s"))
+	assert.Equal(t, "$-1\r\n", execute(db, "SPOP", "s"))
+	assert.Equal(t, "*0\r\n", execute(db, "SPOP", "s", "1"))
+	assert.Equal(t, "-ERR value is out of range, must be positive\r\n", execute(db, "SPOP", "s", "-1"))
+}
+
+func TestSetOperations(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SADD", "s1", "a", "b", "c")
+	execute(db, "SADD", "s2", "b", "c", "d")
+	execute(db, "SADD", "s3", "c")
+
+	assert.Equal(t, "*1\r\n$1\r\nc\r\n", execute(db, "SINTER", "s1", "s2", "s3"))
+	assert.Equal(t, "*0\r\n", execute(db, "SINTER", "s1", "nokey"))
+	assert.Equal(t, "*1\r\n$1\r\na\r\n", execute(db, "SDIFF", "s1", "s2"))
+	assert.Equal(t, ":4\r\n", execute(db, "SUNIONSTORE", "dst", "s1", "s2"))
+	assert.Equal(t, ":4\r\n", execute(db, "SCARD", "dst"))
+	assert.Equal(t, ":2\r\n", execute(db, "SINTERSTORE", "s1", "s1", "s2"))
+	assert.Equal(t, "*2\r\n:1\r\n:1\r\n", execute(db, "SMISMEMBER", "s1", "b", "c"))
+	assert.Equal(t, ":0\r\n", execute(db, "SDIFFSTORE", "dst", "s1", "s2"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "dst"))
+
+	assert.Equal(t, ":2\r\n", execute(db, "SINTERCARD", "2", "s1", "s2"))
+	assert.Equal(t, ":1\r\n", execute(db, "SINTERCARD", "2", "s1", "s2", "LIMIT", "1"))
+	assert.Equal(t, "-ERR numkeys should be greater than 0\r\n", execute(db, "SINTERCARD", "0", "s1"))
+
+	// sorted sets are not sets
+	execute(db, "ZADD", "z", "1", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "SUNION", "s1", "z"))
+}
+
+func TestSetScan(t *testing.T) {
+	db := core.NewRedisDB()
+	for i := 0; i < 100; i++ {
+		execute(db, "SADD", "s", fmt.Sprintf("m%d", i))
+	}
+
+	seen := make(map[string]bool)
+	cursor := "0"
+	for {
+		reply, err := core.Decode([]byte(execute(db, "SSCAN", "s", cursor, "COUNT", "7")))
+		assert.NoError(t, err)
+		res := reply.([]any)
+		for _, member := range res[1].([]any) {
+			seen[member.(string)] = true
+		}
+		if cursor = res[0].(string); cursor == "0" {
+			break
+		}
+	}
+	assert.Len(t, seen, 100)
+
+	reply, err := core.Decode([]byte(execute(db, "SSCAN", "s", "0", "COUNT", "1000000000000000000")))
+	assert.NoError(t, err)
+	assert.Equal(t, "0", reply.([]any)[0])
+	assert.Len(t, reply.([]any)[1], 100)
+
+	assert.Equal(t, "*2\r\n$1\r\n0\r\n*1\r\n$2\r\nm7\r\n",
+		execute(db, "SSCAN", "s", "0", "MATCH", "m7", "COUNT", "1000"))
+	assert.Equal(t, "*2\r\n$1\r\n0\r\n*0\r\n", execute(db, "SSCAN", "nokey", "0"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "SSCAN", "s", "0", "NOVALUES"))
+}
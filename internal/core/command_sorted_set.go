@@ -3,70 +3,263 @@ package core
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/nhtuan0700/godis/internal/constant"
 	"github.com/nhtuan0700/godis/internal/core/data_structure"
 )
 
-// ZADD key score member [score member ...]
-func cmdZADD(redisDB *RedisDB, args []string) []byte {
-	if len(args) < 3 {
-		return Encode(errors.New("ERR wrong number of arguments for 'zadd' command"), false)
+var (
+	errScoreRange = errors.New("ERR min or max is not a float")
+	errLexRange   = errors.New("ERR min or max not valid string range item")
+	errLimitRange = errors.New("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	errLexScores  = errors.New("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+)
+
+// getZSet returns the sorted set stored at key, nil when the key does not exist,
+// or the WRONGTYPE reply when it holds another type
+func getZSet(redisDB *RedisDB, key string) (*data_structure.ZSet, []byte) {
+	obj := redisDB.Get(key)
+	if obj == nil {
+		return nil, nil
+	}
+	zset, ok := obj.value.(*data_structure.ZSet)
+	if !ok {
+		return nil, constant.ErrorWrongTypeKey
 	}
+	return zset, nil
+}
 
-	// 0: key of sorted set
-	startScoreIdx := 1
-	numElems := len(args) - startScoreIdx
-	if numElems%2 == 1 {
-		return Encode(errors.New("ERR syntax error"), false)
+// formatScore formats a score like redis: inf and -inf for the infinities,
+// and an exponent only for the very small and very large scores
+func formatScore(score float64) string {
+	switch abs := math.Abs(score); {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	case abs != 0 && (abs < 1e-4 || abs >= 1e17):
+		return strconv.FormatFloat(score, 'g', -1, 64)
 	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
 
-	key := args[0]
-	var zset *data_structure.ZSet
-	obj, exist := redisDB.dict[key]
+// getOrCreateZSet is getZSet creating an empty sorted set when the key does not exist
+func getOrCreateZSet(redisDB *RedisDB, key string) (*data_structure.ZSet, []byte) {
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil || zset != nil {
+		return zset, errReply
+	}
+	zset = data_structure.NewZSet()
+	redisDB.Set(key, NewRedisObj(zset), 0)
+	return zset, nil
+}
+
+// parseScore parses a score, NaN is not a valid score
+func parseScore(arg string) (float64, error) {
+	score, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, errNotFloat
+	}
+	return score, nil
+}
+
+// zaddFlags are the conditions of ZADD, they apply to every member
+type zaddFlags struct {
+	nx, xx, gt, lt bool
+	ch, incr       bool
+}
+
+// parseZAddFlags parses the flags at the start of args, it returns the number of arguments parsed
+func parseZAddFlags(args []string) (zaddFlags, int, error) {
+	var flags zaddFlags
+	n := 0
+loop:
+	for ; n < len(args); n++ {
+		switch strings.ToUpper(args[n]) {
+		case "NX":
+			flags.nx = true
+		case "XX":
+			flags.xx = true
+		case "GT":
+			flags.gt = true
+		case "LT":
+			flags.lt = true
+		case "CH":
+			flags.ch = true
+		case "INCR":
+			flags.incr = true
+		default:
+			break loop
+		}
+	}
+
+	switch {
+	case flags.nx && flags.xx:
+		return flags, n, errors.New("ERR XX and NX options at the same time are not compatible")
+	case (flags.gt && flags.lt) || (flags.nx && (flags.gt || flags.lt)):
+		return flags, n, errors.New("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	return flags, n, nil
+}
+
+// zaddResult is what ZADD did to a member
+type zaddResult int
+
+const (
+	// zaddNop is a member left unchanged by NX, XX, GT or LT
+	zaddNop zaddResult = iota
+	zaddAdded
+	zaddUpdated
+	// zaddSame is an existing member set to its own score
+	zaddSame
+)
+
+// zadd adds or updates member under flags, score is the increment with INCR. It returns the score of the member.
+func zadd(zset *data_structure.ZSet, member string, score float64, flags zaddFlags) (zaddResult, float64, error) {
+	curScore, exist := zset.GetScore(member)
 	if !exist {
-		zset = data_structure.NewZSet()
-		redisDB.dict[key] = NewRedisObj(zset)
-	} else {
-		var ok bool
-		zset, ok = obj.value.(*data_structure.ZSet)
-		if !ok {
-			return constant.ErrorWrongTypeKey
+		if flags.xx {
+			return zaddNop, 0, nil
+		}
+		zset.Add(score, member)
+		return zaddAdded, score, nil
+	}
+
+	if flags.nx {
+		return zaddNop, curScore, nil
+	}
+	if flags.incr {
+		score += curScore
+		if math.IsNaN(score) {
+			return zaddNop, curScore, errors.New("ERR resulting score is not a number (NaN)")
+		}
+	}
+	if (flags.gt && score <= curScore) || (flags.lt && score >= curScore) {
+		return zaddNop, curScore, nil
+	}
+	if score == curScore {
+		return zaddSame, score, nil
+	}
+	zset.UpdateScore(member, score)
+	return zaddUpdated, score, nil
+}
+
+// ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
+//
+// The reply is the number of members added, or of members added or updated with CH.
+// With INCR it is the new score, nil when a condition prevented the increment.
+func cmdZADD(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	flags, n, err := parseZAddFlags(args[1:])
+	elements := args[1+n:]
+	if err == nil && (len(elements) == 0 || len(elements)%2 == 1) {
+		err = errSyntax
+	}
+	if err == nil && flags.incr && len(elements) > 2 {
+		err = errors.New("ERR INCR option supports a single increment-element pair")
+	}
+	if err != nil {
+		return Encode(err, false)
+	}
+
+	// the scores are parsed first so that an invalid one changes nothing
+	scores := make([]float64, len(elements)/2)
+	for i := range scores {
+		if scores[i], err = parseScore(elements[2*i]); err != nil {
+			return Encode(err, false)
+		}
+	}
+
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		// XX never adds a member, it does not create the key
+		if flags.xx {
+			if flags.incr {
+				return constant.RespNil
+			}
+			return Encode(0, false)
 		}
+		zset, _ = getOrCreateZSet(redisDB, key)
 	}
 
-	added := 0
-	for i := startScoreIdx; i < len(args); i += 2 {
-		scoreStr, member := args[i], args[i+1]
-		scoreNum, err := strconv.ParseFloat(scoreStr, 64)
+	added, updated := 0, 0
+	var result zaddResult
+	var newScore float64
+	for i, score := range scores {
+		result, newScore, err = zadd(zset, elements[2*i+1], score, flags)
 		if err != nil {
-			return Encode(errors.New("score must be floating point number"), false)
+			return Encode(err, false)
 		}
-		ret := zset.Add(scoreNum, member)
-		if ret {
+		switch result {
+		case zaddAdded:
 			added++
+		case zaddUpdated:
+			updated++
 		}
 	}
 
+	switch {
+	case flags.incr && result == zaddNop:
+		return constant.RespNil
+	case flags.incr:
+		return Encode(formatScore(newScore), false)
+	case flags.ch:
+		return Encode(added+updated, false)
+	}
 	return Encode(added, false)
 }
 
-// ZSCORE key member
-func cmdZSCORE(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'zscore' command"), false)
+// ZINCRBY key increment member
+func cmdZINCRBY(redisDB *RedisDB, args []string) []byte {
+	increment, err := parseScore(args[1])
+	if err != nil {
+		return Encode(err, false)
+	}
+	zset, errReply := getOrCreateZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
 	}
+	_, score, err := zadd(zset, args[2], increment, zaddFlags{incr: true})
+	if err != nil {
+		return Encode(err, false)
+	}
+	return Encode(formatScore(score), false)
+}
 
-	key, member := args[0], args[1]
-	obj, exist := redisDB.dict[key]
-	if !exist {
-		return constant.RespNil
+// ZMSCORE key member [member ...]
+func cmdZMSCORE(redisDB *RedisDB, args []string) []byte {
+	zset, errReply := getZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
 	}
 
-	zset, ok := obj.value.(*data_structure.ZSet)
-	if !ok {
-		return constant.ErrorWrongTypeKey
+	scores := make([]any, len(args)-1)
+	for i, member := range args[1:] {
+		if zset == nil {
+			continue
+		}
+		if score, exist := zset.GetScore(member); exist {
+			scores[i] = formatScore(score)
+		}
+	}
+	return Encode(scores, false)
+}
+
+// ZSCORE key member
+func cmdZSCORE(redisDB *RedisDB, args []string) []byte {
+	key, member := args[0], args[1]
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return constant.RespNil
 	}
 
 	score, exist := zset.GetScore(member)
@@ -74,42 +267,57 @@ func cmdZSCORE(redisDB *RedisDB, args []string) []byte {
 		return constant.RespNil
 	}
 
-	return Encode(fmt.Sprintf("%g", score), false)
+	return Encode(formatScore(score), false)
 }
 
-// ZRANK key member
+// ZRANK key member [WITHSCORE]
 func cmdZRANK(redisDB *RedisDB, args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'zrank' command"), false)
-	}
+	return zrank(redisDB, args, false)
+}
+
+// ZREVRANK key member [WITHSCORE]
+func cmdZREVRANK(redisDB *RedisDB, args []string) []byte {
+	return zrank(redisDB, args, true)
+}
 
+// zrank replies the rank of a member, from the highest score with reverse. With WITHSCORE the reply is
+// the rank and the score, or a nil array when the member does not exist.
+func zrank(redisDB *RedisDB, args []string, reverse bool) []byte {
 	key, member := args[0], args[1]
-	obj, exist := redisDB.dict[key]
-	if !exist {
-		return constant.RespNil
+	withScore := false
+	if len(args) == 3 && strings.ToUpper(args[2]) == "WITHSCORE" {
+		withScore = true
+	} else if len(args) > 2 {
+		return Encode(errSyntax, false)
 	}
 
-	zset, ok := obj.value.(*data_structure.ZSet)
-	if !ok {
-		return constant.ErrorWrongTypeKey
+	null := constant.RespNil
+	if withScore {
+		null = constant.RespNilArray
+	}
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return null
 	}
 
-	rank, exist := zset.GetRank(member, false)
+	rank, exist := zset.GetRank(member, reverse)
 	if !exist {
-		return constant.RespNil
+		return null
+	}
+	if withScore {
+		score, _ := zset.GetScore(member)
+		return Encode([]any{rank, formatScore(score)}, false)
 	}
-
 	return Encode(rank, false)
 }
 
 // ZREM key member [member ...]
 func cmdZREM(redisDB *RedisDB, args []string) []byte {
-	if len(args) < 2 {
-		return Encode(errors.New("ERR wrong number of arguments for 'zrem' command"), false)
-	}
-
 	key := args[0]
-	obj, exist := redisDB.dict[key]
+	obj, exist := redisDB.dict.Get(key)
 	if !exist {
 		return constant.RespNil
 	}
@@ -126,6 +334,801 @@ func cmdZREM(redisDB *RedisDB, args []string) []byte {
 			removeCount++
 		}
 	}
+	deleteZSetIfEmpty(redisDB, key, zset)
 
 	return Encode(removeCount, false)
 }
+
+// ZCARD key
+func cmdZCARD(redisDB *RedisDB, args []string) []byte {
+	zset, errReply := getZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode(0, false)
+	}
+	return Encode(zset.Len(), false)
+}
+
+// ZCOUNT key min max
+func cmdZCOUNT(redisDB *RedisDB, args []string) []byte {
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return Encode(err, false)
+	}
+	zset, errReply := getZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode(0, false)
+	}
+	return Encode(zset.CountInRange(r), false)
+}
+
+// ZLEXCOUNT key min max
+func cmdZLEXCOUNT(redisDB *RedisDB, args []string) []byte {
+	r, err := parseLexRange(args[1], args[2])
+	if err != nil {
+		return Encode(err, false)
+	}
+	zset, errReply := getZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode(0, false)
+	}
+	return Encode(zset.CountInLexRange(r), false)
+}
+
+// ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func cmdZRANGE(redisDB *RedisDB, args []string) []byte {
+	return zrange(redisDB, args, zrangeRequest{}, true)
+}
+
+// ZREVRANGE key start stop [WITHSCORES]
+func cmdZREVRANGE(redisDB *RedisDB, args []string) []byte {
+	return zrange(redisDB, args, zrangeRequest{rev: true}, false)
+}
+
+// ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func cmdZRANGEBYSCORE(redisDB *RedisDB, args []string) []byte {
+	return zrange(redisDB, args, zrangeRequest{by: zrangeByScore}, false)
+}
+
+// ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+//
+// dst is deleted when the range is empty.
+func cmdZRANGESTORE(redisDB *RedisDB, args []string) []byte {
+	dst := args[0]
+	req, err := parseZRange(args[2], args[3], args[4:], zrangeRequest{store: true}, true)
+	if err != nil {
+		return Encode(err, false)
+	}
+	src, errReply := getZSet(redisDB, args[1])
+	if errReply != nil {
+		return errReply
+	}
+
+	var elements []data_structure.ZElement
+	if src != nil {
+		elements = req.elements(src)
+	}
+	if len(elements) == 0 {
+		redisDB.Delete(dst)
+		return Encode(0, false)
+	}
+	zset := data_structure.NewZSet()
+	for _, e := range elements {
+		zset.Add(e.Score, e.Member)
+	}
+	redisDB.Set(dst, NewRedisObj(zset), 0)
+	return Encode(len(elements), false)
+}
+
+// zrange replies the range of args described by the options and by the ones set in req by the command
+func zrange(redisDB *RedisDB, args []string, req zrangeRequest, auto bool) []byte {
+	parsed, err := parseZRange(args[1], args[2], args[3:], req, auto)
+	if err != nil {
+		return Encode(err, false)
+	}
+	zset, errReply := getZSet(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode([]string{}, false)
+	}
+
+	elements := parsed.elements(zset)
+	size := len(elements)
+	if parsed.withScores {
+		size *= 2
+	}
+	reply := make([]string, 0, size)
+	for _, e := range elements {
+		reply = append(reply, e.Member)
+		if parsed.withScores {
+			reply = append(reply, formatScore(e.Score))
+		}
+	}
+	return Encode(reply, false)
+}
+
+type zrangeBy int
+
+const (
+	zrangeByRank zrangeBy = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeRequest is a parsed range command. The other range commands are ZRANGE with some of its options set.
+type zrangeRequest struct {
+	by         zrangeBy
+	rev        bool
+	withScores bool
+	// store commands do not accept WITHSCORES
+	store bool
+
+	limit         bool
+	offset, count int
+
+	start, stop int64
+	scores      data_structure.ScoreRange
+	lex         data_structure.LexRange
+}
+
+// parseZRange parses the min and max of a range and its options, starting from the options set in req.
+// Only ZRANGE and ZRANGESTORE, the auto commands, accept BYSCORE, BYLEX and REV.
+func parseZRange(min, max string, options []string, req zrangeRequest, auto bool) (*zrangeRequest, error) {
+	for i := 0; i < len(options); i++ {
+		switch opt := strings.ToUpper(options[i]); {
+		case opt == "WITHSCORES" && !req.store:
+			req.withScores = true
+		case opt == "LIMIT" && i+2 < len(options):
+			offset, err := strconv.Atoi(options[i+1])
+			if err != nil {
+				return nil, errNotInteger
+			}
+			count, err := strconv.Atoi(options[i+2])
+			if err != nil {
+				return nil, errNotInteger
+			}
+			req.limit, req.offset, req.count = true, offset, count
+			i += 2
+		case opt == "BYSCORE" && auto && req.by == zrangeByRank:
+			req.by = zrangeByScore
+		case opt == "BYLEX" && auto && req.by == zrangeByRank:
+			req.by = zrangeByLex
+		case opt == "REV" && auto && !req.rev:
+			req.rev = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if req.limit && req.by == zrangeByRank {
+		return nil, errLimitRange
+	}
+	if req.withScores && req.by == zrangeByLex {
+		return nil, errLexScores
+	}
+	if !req.limit {
+		req.count = -1
+	}
+
+	var err error
+	switch req.by {
+	case zrangeByRank:
+		if req.start, err = strconv.ParseInt(min, 10, 64); err != nil {
+			return nil, errNotInteger
+		}
+		if req.stop, err = strconv.ParseInt(max, 10, 64); err != nil {
+			return nil, errNotInteger
+		}
+		return &req, nil
+	}
+
+	// the score and lex ranges of a reversed range are given from max to min
+	if req.rev {
+		min, max = max, min
+	}
+	if req.by == zrangeByScore {
+		r, err := parseScoreRange(min, max)
+		if err != nil {
+			return nil, err
+		}
+		req.scores = *r
+	} else {
+		r, err := parseLexRange(min, max)
+		if err != nil {
+			return nil, err
+		}
+		req.lex = *r
+	}
+	return &req, nil
+}
+
+// elements returns the members of zset in the range
+func (req *zrangeRequest) elements(zset *data_structure.ZSet) []data_structure.ZElement {
+	switch req.by {
+	case zrangeByScore:
+		if req.offset < 0 {
+			return nil
+		}
+		return zset.RangeByScore(&req.scores, req.offset, req.count, req.rev)
+	case zrangeByLex:
+		if req.offset < 0 {
+			return nil
+		}
+		return zset.RangeByLex(&req.lex, req.offset, req.count, req.rev)
+	}
+	start, stop := listRange(req.start, req.stop, zset.Len())
+	if start > stop {
+		return nil
+	}
+	return zset.RangeByRank(start, stop, req.rev)
+}
+
+// parseScoreRange parses the min and max of a score range, a bound starting with ( is exclusive
+func parseScoreRange(min, max string) (*data_structure.ScoreRange, error) {
+	r := &data_structure.ScoreRange{}
+	var err error
+	if r.Min, r.MinEx, err = parseScoreBound(min); err != nil {
+		return nil, err
+	}
+	if r.Max, r.MaxEx, err = parseScoreBound(max); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseScoreBound parses a score, (score or an infinity like -inf and +inf
+func parseScoreBound(arg string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(arg, "(")
+	if exclusive {
+		arg = arg[1:]
+	}
+	score, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, errScoreRange
+	}
+	return score, exclusive, nil
+}
+
+// parseLexRange parses the min and max of a lexicographic range
+func parseLexRange(min, max string) (*data_structure.LexRange, error) {
+	r := &data_structure.LexRange{}
+	var err error
+	if r.Min, err = parseLexBound(min); err != nil {
+		return nil, err
+	}
+	if r.Max, err = parseLexBound(max); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseLexBound parses [member, (member, - and +
+func parseLexBound(arg string) (data_structure.LexBound, error) {
+	switch {
+	case arg == "-":
+		return data_structure.LexBound{Inf: -1}, nil
+	case arg == "+":
+		return data_structure.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(arg, "["):
+		return data_structure.LexBound{Elm: arg[1:]}, nil
+	case strings.HasPrefix(arg, "("):
+		return data_structure.LexBound{Elm: arg[1:], Exclusive: true}, nil
+	}
+	return data_structure.LexBound{}, errLexRange
+}
+
+// deleteZSetIfEmpty deletes the key of a sorted set that has no member left
+func deleteZSetIfEmpty(redisDB *RedisDB, key string, zset *data_structure.ZSet) {
+	if zset.Len() == 0 {
+		redisDB.Delete(key)
+	}
+}
+
+// zelementsReply flattens the elements to member, score, member, score...
+func zelementsReply(elements []data_structure.ZElement) []string {
+	reply := make([]string, 0, 2*len(elements))
+	for _, e := range elements {
+		reply = append(reply, e.Member, formatScore(e.Score))
+	}
+	return reply
+}
+
+// zpop implements ZPOPMIN and ZPOPMAX
+func zpop(redisDB *RedisDB, args []string, highest bool) []byte {
+	if len(args) > 2 {
+		return Encode(errSyntax, false)
+	}
+	count := 1
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 0 {
+			return Encode(errNotPositive, false)
+		}
+	}
+
+	key := args[0]
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode([]string{}, false)
+	}
+	elements := zset.Pop(count, highest)
+	deleteZSetIfEmpty(redisDB, key, zset)
+	return Encode(zelementsReply(elements), false)
+}
+
+// ZPOPMIN key [count]
+func cmdZPOPMIN(redisDB *RedisDB, args []string) []byte {
+	return zpop(redisDB, args, false)
+}
+
+// ZPOPMAX key [count]
+func cmdZPOPMAX(redisDB *RedisDB, args []string) []byte {
+	return zpop(redisDB, args, true)
+}
+
+// zmpopRequest is a parsed ZMPOP or BZMPOP
+type zmpopRequest struct {
+	keys    []string
+	highest bool
+	count   int
+}
+
+// parseZMPop parses numkeys key [key ...] MIN|MAX [COUNT count], name is the command for the arity error
+func parseZMPop(name string, args []string) (*zmpopRequest, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 {
+		return nil, errors.New("ERR numkeys should be greater than 0")
+	}
+	if numKeys >= len(args)-1 {
+		return nil, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+	}
+
+	req := &zmpopRequest{keys: args[1 : 1+numKeys], count: 1}
+	switch strings.ToUpper(args[1+numKeys]) {
+	case "MIN":
+	case "MAX":
+		req.highest = true
+	default:
+		return nil, errSyntax
+	}
+
+	options := args[2+numKeys:]
+	switch {
+	case len(options) == 0:
+	case len(options) == 2 && strings.ToUpper(options[0]) == "COUNT":
+		if req.count, err = strconv.Atoi(options[1]); err != nil || req.count <= 0 {
+			return nil, errors.New("ERR count should be greater than 0")
+		}
+	default:
+		return nil, errSyntax
+	}
+	return req, nil
+}
+
+// pop pops from the first sorted set of the keys that exists, it returns the key popped from
+// and no element when none exists
+func (req *zmpopRequest) pop(redisDB *RedisDB) (string, []data_structure.ZElement, []byte) {
+	for _, key := range req.keys {
+		zset, errReply := getZSet(redisDB, key)
+		if errReply != nil {
+			return "", nil, errReply
+		}
+		if zset == nil || zset.Len() == 0 {
+			continue
+		}
+		elements := zset.Pop(req.count, req.highest)
+		deleteZSetIfEmpty(redisDB, key, zset)
+		return key, elements, nil
+	}
+	return "", nil, nil
+}
+
+// zmpopReply is the key followed by the pairs of member and score
+func zmpopReply(key string, elements []data_structure.ZElement) []byte {
+	pairs := make([][]string, len(elements))
+	for i, e := range elements {
+		pairs[i] = []string{e.Member, formatScore(e.Score)}
+	}
+	return Encode([]any{key, pairs}, false)
+}
+
+// ZMPOP numkeys key [key ...] MIN|MAX [COUNT count]
+func cmdZMPOP(redisDB *RedisDB, args []string) []byte {
+	req, err := parseZMPop("zmpop", args)
+	if err != nil {
+		return Encode(err, false)
+	}
+	key, elements, errReply := req.pop(redisDB)
+	if errReply != nil {
+		return errReply
+	}
+	if len(elements) == 0 {
+		return constant.RespNilArray
+	}
+	return zmpopReply(key, elements)
+}
+
+// BZMPOP timeout numkeys key [key ...] MIN|MAX [COUNT count]
+func cmdBZMPOP(redisDB *RedisDB, args []string) []byte {
+	timeout, err := parseTimeout(args[0])
+	if err != nil {
+		return Encode(err, false)
+	}
+	req, err := parseZMPop("bzmpop", args[1:])
+	if err != nil {
+		return Encode(err, false)
+	}
+	key, elements, errReply := req.pop(redisDB)
+	if errReply != nil {
+		return errReply
+	}
+	if len(elements) == 0 {
+		return redisDB.blockForKeys(req.keys, timeout, constant.RespNilArray)
+	}
+
+	cmd := constant.CMD_ZPOPMIN
+	if req.highest {
+		cmd = constant.CMD_ZPOPMAX
+	}
+	redisDB.rewriteCommand([]string{cmd, key, strconv.Itoa(len(elements))})
+	return zmpopReply(key, elements)
+}
+
+// blockingZPop implements BZPOPMIN and BZPOPMAX: it pops from the first sorted set of the keys that exists,
+// or blocks until one of the keys is added to
+func blockingZPop(redisDB *RedisDB, args []string, highest bool) []byte {
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		return Encode(err, false)
+	}
+	keys := args[:len(args)-1]
+
+	for _, key := range keys {
+		zset, errReply := getZSet(redisDB, key)
+		if errReply != nil {
+			return errReply
+		}
+		// an empty sorted set, e.g. from a snapshot taken before ZREM deleted them, counts as a missing key
+		if zset == nil || zset.Len() == 0 {
+			continue
+		}
+
+		e := zset.Pop(1, highest)[0]
+		deleteZSetIfEmpty(redisDB, key, zset)
+		cmd := constant.CMD_ZPOPMIN
+		if highest {
+			cmd = constant.CMD_ZPOPMAX
+		}
+		redisDB.rewriteCommand([]string{cmd, key})
+		return Encode([]string{key, e.Member, formatScore(e.Score)}, false)
+	}
+
+	return redisDB.blockForKeys(keys, timeout, constant.RespNilArray)
+}
+
+// BZPOPMIN key [key ...] timeout
+func cmdBZPOPMIN(redisDB *RedisDB, args []string) []byte {
+	return blockingZPop(redisDB, args, false)
+}
+
+// BZPOPMAX key [key ...] timeout
+func cmdBZPOPMAX(redisDB *RedisDB, args []string) []byte {
+	return blockingZPop(redisDB, args, true)
+}
+
+// zremRange removes the members in a range from the sorted set at key, remove returns the number of members removed
+func zremRange(redisDB *RedisDB, key string, remove func(zset *data_structure.ZSet) int) []byte {
+	zset, errReply := getZSet(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if zset == nil {
+		return Encode(0, false)
+	}
+	removed := remove(zset)
+	deleteZSetIfEmpty(redisDB, key, zset)
+	return Encode(removed, false)
+}
+
+// ZREMRANGEBYRANK key start stop
+func cmdZREMRANGEBYRANK(redisDB *RedisDB, args []string) []byte {
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	stop, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errNotInteger, false)
+	}
+	return zremRange(redisDB, args[0], func(zset *data_structure.ZSet) int {
+		from, to := listRange(start, stop, zset.Len())
+		if from > to {
+			return 0
+		}
+		return zset.RemoveRangeByRank(from, to)
+	})
+}
+
+// ZREMRANGEBYSCORE key min max
+func cmdZREMRANGEBYSCORE(redisDB *RedisDB, args []string) []byte {
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return Encode(err, false)
+	}
+	return zremRange(redisDB, args[0], func(zset *data_structure.ZSet) int {
+		return zset.RemoveRangeByScore(r)
+	})
+}
+
+// ZREMRANGEBYLEX key min max
+func cmdZREMRANGEBYLEX(redisDB *RedisDB, args []string) []byte {
+	r, err := parseLexRange(args[1], args[2])
+	if err != nil {
+		return Encode(err, false)
+	}
+	return zremRange(redisDB, args[0], func(zset *data_structure.ZSet) int {
+		return zset.RemoveRangeByLex(r)
+	})
+}
+
+// zsetOp is the operation of ZUNION, ZINTER and ZDIFF
+type zsetOp int
+
+const (
+	zsetUnion zsetOp = iota
+	zsetInter
+	zsetDiff
+)
+
+// zsetAggregate tells how the scores of a member found in several inputs are combined
+type zsetAggregate int
+
+const (
+	aggregateSum zsetAggregate = iota
+	aggregateMin
+	aggregateMax
+)
+
+// zsetOpRequest is a parsed ZUNION, ZINTER or ZDIFF, or one of their STORE variants
+type zsetOpRequest struct {
+	op         zsetOp
+	keys       []string
+	weights    []float64
+	aggregate  zsetAggregate
+	withScores bool
+}
+
+// parseZSetOp parses numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES],
+// name is the command for the errors. ZDIFF takes no WEIGHTS nor AGGREGATE and the STORE variants no WITHSCORES.
+func parseZSetOp(name string, op zsetOp, args []string, store bool) (*zsetOpRequest, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, errNotInteger
+	}
+	if numKeys < 1 {
+		return nil, fmt.Errorf("ERR at least 1 input key is needed for '%s' command", name)
+	}
+	if numKeys > len(args)-1 {
+		return nil, errSyntax
+	}
+
+	req := &zsetOpRequest{op: op, keys: args[1 : 1+numKeys]}
+	options := args[1+numKeys:]
+	for i := 0; i < len(options); i++ {
+		switch option := strings.ToUpper(options[i]); {
+		case option == "WEIGHTS" && op != zsetDiff && i+numKeys < len(options):
+			req.weights = make([]float64, numKeys)
+			for n := range req.weights {
+				i++
+				if req.weights[n], err = parseScore(options[i]); err != nil {
+					return nil, errors.New("ERR weight value is not a float")
+				}
+			}
+		case option == "AGGREGATE" && op != zsetDiff && i+1 < len(options):
+			i++
+			switch strings.ToUpper(options[i]) {
+			case "SUM":
+				req.aggregate = aggregateSum
+			case "MIN":
+				req.aggregate = aggregateMin
+			case "MAX":
+				req.aggregate = aggregateMax
+			default:
+				return nil, errSyntax
+			}
+		case option == "WITHSCORES" && !store:
+			req.withScores = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	return req, nil
+}
+
+// weighted is the score of a member of the n-th input, a weight of 0 times an infinite score counts as 0
+func (req *zsetOpRequest) weighted(n int, score float64) float64 {
+	if req.weights == nil {
+		return score
+	}
+	if score = score * req.weights[n]; math.IsNaN(score) {
+		return 0
+	}
+	return score
+}
+
+// aggregateScores combines the scores of a member found in two inputs, inf plus -inf sums to 0
+func (req *zsetOpRequest) aggregateScores(a, b float64) float64 {
+	switch req.aggregate {
+	case aggregateMin:
+		return math.Min(a, b)
+	case aggregateMax:
+		return math.Max(a, b)
+	}
+	if sum := a + b; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// combine computes the result from the source keys
+func (req *zsetOpRequest) combine(inputs []operand) *data_structure.ZSet {
+	res := data_structure.NewZSet()
+	switch req.op {
+	case zsetUnion:
+		scores := make(map[string]float64)
+		for n, input := range inputs {
+			input.Range(func(member string, score float64) bool {
+				score = req.weighted(n, score)
+				if cur, ok := scores[member]; ok {
+					score = req.aggregateScores(cur, score)
+				}
+				scores[member] = score
+				return true
+			})
+		}
+		for member, score := range scores {
+			res.Add(score, member)
+		}
+	case zsetInter:
+		smallest(inputs).Range(func(member string, _ float64) bool {
+			var score float64
+			for n, input := range inputs {
+				other, ok := input.Score(member)
+				if !ok {
+					return true
+				}
+				if other = req.weighted(n, other); n > 0 {
+					other = req.aggregateScores(score, other)
+				}
+				score = other
+			}
+			res.Add(score, member)
+			return true
+		})
+	case zsetDiff:
+		inputs[0].Range(func(member string, score float64) bool {
+			for _, input := range inputs[1:] {
+				if _, ok := input.Score(member); ok {
+					return true
+				}
+			}
+			res.Add(score, member)
+			return true
+		})
+	}
+	return res
+}
+
+// zsetOperation implements ZUNION, ZINTER and ZDIFF
+func zsetOperation(redisDB *RedisDB, name string, op zsetOp, args []string) []byte {
+	req, err := parseZSetOp(name, op, args, false)
+	if err != nil {
+		return Encode(err, false)
+	}
+	inputs, errReply := redisDB.readOperands(req.keys, true)
+	if errReply != nil {
+		return errReply
+	}
+
+	zset := req.combine(inputs)
+	if zset.Len() == 0 {
+		return Encode([]string{}, false)
+	}
+	elements := zset.RangeByRank(0, zset.Len()-1, false)
+	if req.withScores {
+		return Encode(zelementsReply(elements), false)
+	}
+	members := make([]string, len(elements))
+	for i, e := range elements {
+		members[i] = e.Member
+	}
+	return Encode(members, false)
+}
+
+// zsetOperationStore implements ZUNIONSTORE, ZINTERSTORE and ZDIFFSTORE, dst is deleted when the result is empty
+func zsetOperationStore(redisDB *RedisDB, name string, op zsetOp, args []string) []byte {
+	dst := args[0]
+	req, err := parseZSetOp(name, op, args[1:], true)
+	if err != nil {
+		return Encode(err, false)
+	}
+	inputs, errReply := redisDB.readOperands(req.keys, true)
+	if errReply != nil {
+		return errReply
+	}
+
+	zset := req.combine(inputs)
+	if zset.Len() == 0 {
+		redisDB.Delete(dst)
+	} else {
+		redisDB.Set(dst, NewRedisObj(zset), 0)
+	}
+	// the sources owned by other workers were read before the command ran and their writes are logged
+	// by their workers in any order with it, a replay could see them in another state: the result is logged instead
+	if redisDB.operands != nil {
+		argvs := [][]string{{constant.CMD_DEL, dst}}
+		if zset.Len() > 0 {
+			zadd := []string{constant.CMD_ZADD, dst}
+			for _, e := range zset.RangeByRank(0, zset.Len()-1, false) {
+				zadd = append(zadd, formatScore(e.Score), e.Member)
+			}
+			argvs = append(argvs, zadd)
+		}
+		redisDB.rewriteCommand(argvs...)
+	}
+	return Encode(zset.Len(), false)
+}
+
+// ZUNION numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+func cmdZUNION(redisDB *RedisDB, args []string) []byte {
+	return zsetOperation(redisDB, "zunion", zsetUnion, args)
+}
+
+// ZUNIONSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]
+func cmdZUNIONSTORE(redisDB *RedisDB, args []string) []byte {
+	return zsetOperationStore(redisDB, "zunionstore", zsetUnion, args)
+}
+
+// ZINTER numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+func cmdZINTER(redisDB *RedisDB, args []string) []byte {
+	return zsetOperation(redisDB, "zinter", zsetInter, args)
+}
+
+// ZINTERSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]
+func cmdZINTERSTORE(redisDB *RedisDB, args []string) []byte {
+	return zsetOperationStore(redisDB, "zinterstore", zsetInter, args)
+}
+
+// ZDIFF numkeys key [key ...] [WITHSCORES]
+func cmdZDIFF(redisDB *RedisDB, args []string) []byte {
+	return zsetOperation(redisDB, "zdiff", zsetDiff, args)
+}
+
+// ZDIFFSTORE destination numkeys key [key ...]
+func cmdZDIFFSTORE(redisDB *RedisDB, args []string) []byte {
+	return zsetOperationStore(redisDB, "zdiffstore", zsetDiff, args)
+}
+
+// ZINTERCARD numkeys key [key ...] [LIMIT limit]
+//
+// The count stops at limit, 0 means no limit.
+func cmdZINTERCARD(redisDB *RedisDB, args []string) []byte {
+	keys, limit, err := parseInterCard(args)
+	if err != nil {
+		return Encode(err, false)
+	}
+	inputs, errReply := redisDB.readOperands(keys, true)
+	if errReply != nil {
+		return errReply
+	}
+	return Encode(interCard(inputs, limit), false)
+}
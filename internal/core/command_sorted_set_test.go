@@ -0,0 +1,218 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedSetRank(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a", "2.5", "b", "3", "c")
+
+	assert.Equal(t, ":3\r\n", execute(db, "ZCARD", "z"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZCARD", "nokey"))
+	assert.Equal(t, "$3\r\n2.5\r\n", execute(db, "ZSCORE", "z", "b"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZRANK", "z", "a"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZREVRANK", "z", "a"))
+	assert.Equal(t, "*2\r\n:0\r\n$1\r\n3\r\n", execute(db, "ZREVRANK", "z", "c", "WITHSCORE"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZREVRANK", "z", "x"))
+	assert.Equal(t, "*-1\r\n", execute(db, "ZRANK", "z", "x", "withscore"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZRANK", "z", "a", "nope"))
+
+	execute(db, "SET", "s", "v")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "ZCARD", "s"))
+}
+
+func TestSortedSetRange(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a", "2", "b", "3", "c", "4", "d")
+
+	assert.Equal(t, "*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n", execute(db, "ZRANGE", "z", "0", "-1"))
+	assert.Equal(t, "*4\r\n$1\r\nc\r\n$1\r\n3\r\n$1\r\nd\r\n$1\r\n4\r\n", execute(db, "ZRANGE", "z", "-2", "10", "WITHSCORES"))
+	assert.Equal(t, "*2\r\n$1\r\nd\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "z", "0", "1", "REV"))
+	assert.Equal(t, "*2\r\n$1\r\nd\r\n$1\r\nc\r\n", execute(db, "ZREVRANGE", "z", "0", "1"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZRANGE", "z", "3", "1"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZRANGE", "nokey", "0", "-1"))
+
+	// score ranges, with REV they are given from max to min
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "z", "(1", "3", "BYSCORE"))
+	assert.Equal(t, "*2\r\n$1\r\nc\r\n$1\r\nb\r\n", execute(db, "ZRANGE", "z", "(4", "2", "BYSCORE", "REV"))
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "1", "2"))
+	assert.Equal(t, "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n", execute(db, "ZRANGEBYSCORE", "z", "-inf", "2", "WITHSCORES"))
+	assert.Equal(t, "*1\r\n$1\r\nd\r\n", execute(db, "ZRANGEBYSCORE", "z", "1", "inf", "LIMIT", "3", "-1"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZRANGEBYSCORE", "z", "1", "inf", "LIMIT", "-1", "2"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZCOUNT", "z", "(1", "(4"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZCOUNT", "z", "5", "+inf"))
+
+	// lex ranges are meant for members with the same score
+	execute(db, "ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d")
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "lex", "(a", "[c", "BYLEX"))
+	assert.Equal(t, "*2\r\n$1\r\nd\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "lex", "+", "[b", "BYLEX", "REV", "LIMIT", "0", "2"))
+	assert.Equal(t, ":4\r\n", execute(db, "ZLEXCOUNT", "lex", "-", "+"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZLEXCOUNT", "lex", "(a", "(c"))
+
+	assert.Equal(t, "-ERR min or max is not a float\r\n", execute(db, "ZCOUNT", "z", "x", "1"))
+	assert.Equal(t, "-ERR min or max not valid string range item\r\n", execute(db, "ZLEXCOUNT", "lex", "a", "+"))
+	assert.Equal(t, "-ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX\r\n",
+		execute(db, "ZRANGE", "z", "0", "1", "LIMIT", "0", "1"))
+	assert.Equal(t, "-ERR syntax error, WITHSCORES not supported in combination with BYLEX\r\n",
+		execute(db, "ZRANGE", "lex", "-", "+", "BYLEX", "WITHSCORES"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZREVRANGE", "z", "0", "1", "BYSCORE"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZRANGE", "z", "0", "1", "BYSCORE", "BYLEX"))
+}
+
+func TestZRangeStore(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a", "2", "b", "3", "c")
+
+	assert.Equal(t, ":2\r\n", execute(db, "ZRANGESTORE", "dst", "z", "2", "+inf", "BYSCORE"))
+	assert.Equal(t, "*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\nc\r\n$1\r\n3\r\n", execute(db, "ZRANGE", "dst", "0", "-1", "WITHSCORES"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZRANGESTORE", "dst", "z", "0", "-1", "WITHSCORES"))
+
+	// an empty range deletes the destination
+	assert.Equal(t, ":0\r\n", execute(db, "ZRANGESTORE", "dst", "z", "5", "10"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "dst"))
+}
+
+func TestZAdd(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":2\r\n", execute(db, "ZADD", "z", "1", "a", "2", "b"))
+	// an existing member gets the new score
+	assert.Equal(t, ":0\r\n", execute(db, "ZADD", "z", "5", "a"))
+	assert.Equal(t, "$1\r\n5\r\n", execute(db, "ZSCORE", "z", "a"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZADD", "z", "CH", "6", "a", "2", "b"))
+
+	assert.Equal(t, ":1\r\n", execute(db, "ZADD", "z", "NX", "CH", "1", "a", "3", "c"))
+	assert.Equal(t, "$1\r\n6\r\n", execute(db, "ZSCORE", "z", "a"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZADD", "z", "XX", "CH", "7", "a", "4", "d"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZSCORE", "z", "d"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZADD", "z", "GT", "CH", "1", "a", "3", "b"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZADD", "z", "LT", "CH", "1", "a", "9", "b", "1", "e"))
+	assert.Equal(t, "*8\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\ne\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n3\r\n$1\r\nc\r\n$1\r\n3\r\n",
+		execute(db, "ZRANGE", "z", "0", "-1", "WITHSCORES"))
+
+	assert.Equal(t, "$3\r\n2.5\r\n", execute(db, "ZADD", "z", "INCR", "1.5", "a"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZADD", "z", "NX", "INCR", "1", "a"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZADD", "z", "GT", "INCR", "-1", "a"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZADD", "nokey", "XX", "INCR", "1", "a"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "nokey"))
+
+	assert.Equal(t, "-ERR XX and NX options at the same time are not compatible\r\n", execute(db, "ZADD", "z", "NX", "XX", "1", "a"))
+	assert.Equal(t, "-ERR GT, LT, and/or NX options at the same time are not compatible\r\n", execute(db, "ZADD", "z", "NX", "GT", "1", "a"))
+	assert.Equal(t, "-ERR INCR option supports a single increment-element pair\r\n", execute(db, "ZADD", "z", "INCR", "1", "a", "2", "b"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZADD", "z", "CH", "1"))
+	// an invalid score changes nothing
+	assert.Equal(t, "-ERR value is not a valid float\r\n", execute(db, "ZADD", "z", "1", "x", "nan", "y"))
+	assert.Equal(t, "$-1\r\n", execute(db, "ZSCORE", "z", "x"))
+}
+
+func TestZIncrBy(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, "$1\r\n2\r\n", execute(db, "ZINCRBY", "z", "2", "a"))
+	assert.Equal(t, "$3\r\n0.5\r\n", execute(db, "ZINCRBY", "z", "-1.5", "a"))
+	assert.Equal(t, "$3\r\ninf\r\n", execute(db, "ZINCRBY", "z", "+inf", "a"))
+	assert.Equal(t, "-ERR resulting score is not a number (NaN)\r\n", execute(db, "ZINCRBY", "z", "-inf", "a"))
+	assert.Equal(t, "-ERR value is not a valid float\r\n", execute(db, "ZINCRBY", "z", "x", "a"))
+
+	execute(db, "ZADD", "z", "1", "b")
+	assert.Equal(t, "*3\r\n$3\r\ninf\r\n$-1\r\n$1\r\n1\r\n", execute(db, "ZMSCORE", "z", "a", "x", "b"))
+	assert.Equal(t, "*2\r\n$-1\r\n$-1\r\n", execute(db, "ZMSCORE", "nokey", "a", "b"))
+}
+
+func TestZPop(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a", "2", "b", "3", "c", "4", "d")
+
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$1\r\n1\r\n", execute(db, "ZPOPMIN", "z"))
+	assert.Equal(t, "*4\r\n$1\r\nd\r\n$1\r\n4\r\n$1\r\nc\r\n$1\r\n3\r\n", execute(db, "ZPOPMAX", "z", "2"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZPOPMIN", "z", "0"))
+	assert.Equal(t, "-ERR value is out of range, must be positive\r\n", execute(db, "ZPOPMIN", "z", "-1"))
+	// the key is deleted with its last member
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$1\r\n2\r\n", execute(db, "ZPOPMIN", "z", "10"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "z"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZPOPMAX", "z"))
+
+	execute(db, "ZADD", "z2", "1", "a", "2", "b", "3", "c")
+	assert.Equal(t, "*2\r\n$2\r\nz2\r\n*2\r\n*2\r\n$1\r\nc\r\n$1\r\n3\r\n*2\r\n$1\r\nb\r\n$1\r\n2\r\n",
+		execute(db, "ZMPOP", "2", "z", "z2", "MAX", "COUNT", "2"))
+	assert.Equal(t, "*2\r\n$2\r\nz2\r\n*1\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n", execute(db, "ZMPOP", "1", "z2", "min"))
+	assert.Equal(t, "*-1\r\n", execute(db, "ZMPOP", "2", "z", "z2", "MIN"))
+
+	assert.Equal(t, "-ERR numkeys should be greater than 0\r\n", execute(db, "ZMPOP", "0", "z", "MIN"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'zmpop' command\r\n", execute(db, "ZMPOP", "3", "z", "z2", "MIN"))
+	assert.Equal(t, "-ERR count should be greater than 0\r\n", execute(db, "ZMPOP", "1", "z", "MIN", "COUNT", "0"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZMPOP", "1", "z", "LEFT"))
+	assert.Equal(t, []string{"a", "b"}, core.LookupCommand("ZMPOP").Keys([]string{"2", "a", "b", "MIN"}))
+	assert.Equal(t, []string{"a"}, core.LookupCommand("BZMPOP").Keys([]string{"0", "1", "a", "MIN"}))
+}
+
+func TestZRemRange(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e")
+
+	assert.Equal(t, ":2\r\n", execute(db, "ZREMRANGEBYRANK", "z", "-2", "-1"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZREMRANGEBYRANK", "z", "5", "10"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZREMRANGEBYSCORE", "z", "(1", "2"))
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$1\r\nc\r\n", execute(db, "ZRANGE", "z", "0", "-1"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZREMRANGEBYSCORE", "z", "-inf", "+inf"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "z"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZREMRANGEBYSCORE", "z", "-inf", "+inf"))
+
+	execute(db, "ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d")
+	assert.Equal(t, ":2\r\n", execute(db, "ZREMRANGEBYLEX", "lex", "[b", "(d"))
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$1\r\nd\r\n", execute(db, "ZRANGE", "lex", "0", "-1"))
+
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "ZREMRANGEBYRANK", "lex", "a", "1"))
+	assert.Equal(t, "-ERR min or max is not a float\r\n", execute(db, "ZREMRANGEBYSCORE", "lex", "a", "1"))
+	assert.Equal(t, "-ERR min or max not valid string range item\r\n", execute(db, "ZREMRANGEBYLEX", "lex", "a", "+"))
+}
+
+func TestZSetOperations(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "ZADD", "z1", "1", "a", "2", "b", "3", "c")
+	execute(db, "ZADD", "z2", "10", "b", "20", "c", "30", "d")
+	execute(db, "SADD", "s", "c", "d")
+
+	assert.Equal(t, "*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n", execute(db, "ZUNION", "2", "z1", "z2"))
+	assert.Equal(t, "*8\r\n$1\r\na\r\n$1\r\n2\r\n$1\r\nb\r\n$2\r\n14\r\n$1\r\nc\r\n$2\r\n26\r\n$1\r\nd\r\n$2\r\n30\r\n",
+		execute(db, "ZUNION", "2", "z1", "z2", "WEIGHTS", "2", "1", "WITHSCORES"))
+	assert.Equal(t, "*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\nc\r\n$1\r\n3\r\n",
+		execute(db, "ZINTER", "2", "z1", "z2", "AGGREGATE", "MIN", "WITHSCORES"))
+	// the members of a set score 1
+	assert.Equal(t, "*2\r\n$1\r\nc\r\n$1\r\n4\r\n", execute(db, "ZINTER", "2", "z1", "s", "WITHSCORES"))
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$1\r\n1\r\n", execute(db, "ZDIFF", "3", "z1", "z2", "s", "WITHSCORES"))
+	assert.Equal(t, "*0\r\n", execute(db, "ZINTER", "2", "z1", "nokey"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZINTERCARD", "2", "z1", "z2"))
+	assert.Equal(t, ":1\r\n", execute(db, "ZINTERCARD", "2", "z1", "z2", "LIMIT", "1"))
+
+	assert.Equal(t, ":4\r\n", execute(db, "ZUNIONSTORE", "dst", "2", "z1", "z2", "AGGREGATE", "MAX"))
+	assert.Equal(t, "*8\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$2\r\n10\r\n$1\r\nc\r\n$2\r\n20\r\n$1\r\nd\r\n$2\r\n30\r\n",
+		execute(db, "ZRANGE", "dst", "0", "-1", "WITHSCORES"))
+	assert.Equal(t, ":2\r\n", execute(db, "ZINTERSTORE", "z1", "2", "z1", "z2"))
+	assert.Equal(t, "*4\r\n$1\r\nb\r\n$2\r\n12\r\n$1\r\nc\r\n$2\r\n23\r\n", execute(db, "ZRANGE", "z1", "0", "-1", "WITHSCORES"))
+	assert.Equal(t, ":0\r\n", execute(db, "ZDIFFSTORE", "dst", "2", "z1", "z2"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "dst"))
+
+	// a weight of 0 times an infinite score is 0, like inf plus -inf
+	execute(db, "ZADD", "inf", "inf", "x")
+	execute(db, "ZADD", "-inf", "-inf", "x")
+	assert.Equal(t, "*2\r\n$1\r\nx\r\n$1\r\n0\r\n", execute(db, "ZUNION", "1", "inf", "WEIGHTS", "0", "WITHSCORES"))
+	assert.Equal(t, "*2\r\n$1\r\nx\r\n$1\r\n0\r\n", execute(db, "ZUNION", "2", "inf", "-inf", "WITHSCORES"))
+
+	assert.Equal(t, "-ERR at least 1 input key is needed for 'zunionstore' command\r\n", execute(db, "ZUNIONSTORE", "dst", "0", "z1"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZUNION", "3", "z1", "z2"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZUNION", "2", "z1", "z2", "WEIGHTS", "1"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZDIFF", "2", "z1", "z2", "AGGREGATE", "MIN"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "ZINTERSTORE", "dst", "1", "z1", "WITHSCORES"))
+	assert.Equal(t, "-ERR weight value is not a float\r\n", execute(db, "ZINTER", "1", "z1", "WEIGHTS", "x"))
+	assert.Equal(t, "-ERR numkeys should be greater than 0\r\n", execute(db, "ZINTERCARD", "0", "z1"))
+	assert.Equal(t, "-ERR Number of keys can't be greater than number of args\r\n", execute(db, "ZINTERCARD", "3", "z1", "z2"))
+	assert.Equal(t, "-ERR LIMIT can't be negative\r\n", execute(db, "ZINTERCARD", "1", "z1", "LIMIT", "-1"))
+
+	execute(db, "SET", "str", "v")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "ZUNION", "2", "z1", "str"))
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+)
+
+var (
+	errStringTooLong = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	errOffsetRange   = errors.New("ERR offset is out of range")
+)
+
+// compactString is the value stored for the string s: an int64 when s is the canonical form of one,
+// like the redis int encoding, so INCR does not parse the string again and small counters take less memory
+func compactString(s string) any {
+	// longer strings can't be an int64, and parsing them is wasted work
+	if len(s) == 0 || len(s) > 20 {
+		return s
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != s {
+		return s
+	}
+	return n
+}
+
+func newStringObj(s string) *RedisObj {
+	return NewRedisObj(compactString(s))
+}
+
+// stringValue returns the value of a string object whatever its encoding, ok is false for other types
+func (obj *RedisObj) stringValue() (string, bool) {
+	switch v := obj.value.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	}
+	return "", false
+}
+
+// getString returns the string at key, exist is false when the key does not exist,
+// errReply is the WRONGTYPE reply when the key holds another type
+func getString(redisDB *RedisDB, key string) (value string, exist bool, errReply []byte) {
+	obj := redisDB.Get(key)
+	if obj == nil {
+		return "", false, nil
+	}
+	value, ok := obj.stringValue()
+	if !ok {
+		return "", true, constant.ErrorWrongTypeKey
+	}
+	return value, true, nil
+}
+
+// setStringKeepTTL replaces the value of a string key, or creates the key without TTL
+func setStringKeepTTL(redisDB *RedisDB, key string, value any) {
+	if obj := redisDB.Get(key); obj != nil {
+		obj.value = value
+		return
+	}
+	redisDB.Set(key, NewRedisObj(value), 0)
+}
+
+// SETNX key value
+func cmdSETNX(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	if redisDB.Get(key) != nil {
+		return constant.RespZero
+	}
+	redisDB.Set(key, newStringObj(args[1]), 0)
+	return constant.RespOne
+}
+
+// setWithTTL implements SETEX and PSETEX
+func setWithTTL(redisDB *RedisDB, args []string, unit string, cmdName string) []byte {
+	ttlMs, err := parseExpireTime(args[1], unit, cmdName)
+	if err != nil {
+		return Encode(err, false)
+	}
+
+	setString(redisDB, args[0], args[2], ttlMs, 0, false)
+	return constant.RespOk
+}
+
+// SETEX key seconds value
+func cmdSETEX(redisDB *RedisDB, args []string) []byte {
+	return setWithTTL(redisDB, args, "EX", "setex")
+}
+
+// PSETEX key milliseconds value
+func cmdPSETEX(redisDB *RedisDB, args []string) []byte {
+	return setWithTTL(redisDB, args, "PX", "psetex")
+}
+
+// GETSET key value
+func cmdGETSET(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	old, exist, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+
+	redisDB.Set(key, newStringObj(args[1]), 0)
+	if !exist {
+		return constant.RespNil
+	}
+	return Encode(old, false)
+}
+
+// incrBy adds incr to the integer at key, a missing key counts as 0 and the TTL is kept
+func incrBy(redisDB *RedisDB, key string, incr int64) []byte {
+	obj := redisDB.Get(key)
+	var current int64
+	if obj != nil {
+		switch v := obj.value.(type) {
+		case int64:
+			current = v
+		case string:
+			// only the canonical form of an integer is stored as a string, e.g. " 1" or "01"
+			return Encode(errNotInteger, false)
+		default:
+			return constant.ErrorWrongTypeKey
+		}
+	}
+	if (incr < 0 && current < math.MinInt64-incr) || (incr > 0 && current > math.MaxInt64-incr) {
+		return Encode(errOverflow, false)
+	}
+
+	current += incr
+	setStringKeepTTL(redisDB, key, current)
+	return Encode(current, false)
+}
+
+// INCR key
+func cmdINCR(redisDB *RedisDB, args []string) []byte {
+	return incrBy(redisDB, args[0], 1)
+}
+
+// DECR key
+func cmdDECR(redisDB *RedisDB, args []string) []byte {
+	return incrBy(redisDB, args[0], -1)
+}
+
+// INCRBY key increment
+func cmdINCRBY(redisDB *RedisDB, args []string) []byte {
+	incr, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	return incrBy(redisDB, args[0], incr)
+}
+
+// DECRBY key decrement
+func cmdDECRBY(redisDB *RedisDB, args []string) []byte {
+	decr, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	if decr == math.MinInt64 {
+		return Encode(errors.New("ERR decrement would overflow"), false)
+	}
+	return incrBy(redisDB, args[0], -decr)
+}
+
+// INCRBYFLOAT key increment
+func cmdINCRBYFLOAT(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	incr, err := parseFloat(args[1])
+	if err != nil {
+		return Encode(errNotFloat, false)
+	}
+	value, exist, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var current float64
+	if exist {
+		if current, err = parseFloat(value); err != nil {
+			return Encode(errNotFloat, false)
+		}
+	}
+
+	current += incr
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Encode(errors.New("ERR increment would produce NaN or Infinity"), false)
+	}
+	value = formatFloat(current)
+	setStringKeepTTL(redisDB, key, compactString(value))
+	// replicas of the float arithmetic could differ, the AOF gets the result
+	redisDB.rewriteCommand([]string{constant.CMD_SET, key, value, "KEEPTTL"})
+	return Encode(value, false)
+}
+
+// checkStringLength rejects strings longer than a bulk string may be
+func checkStringLength(length int64) error {
+	if length > maxBulkLen {
+		return errStringTooLong
+	}
+	return nil
+}
+
+// APPEND key value
+func cmdAPPEND(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	value, _, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if err := checkStringLength(int64(len(value) + len(args[1]))); err != nil {
+		return Encode(err, false)
+	}
+
+	value += args[1]
+	setStringKeepTTL(redisDB, key, compactString(value))
+	return Encode(len(value), false)
+}
+
+// STRLEN key
+func cmdSTRLEN(redisDB *RedisDB, args []string) []byte {
+	value, _, errReply := getString(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+	return Encode(len(value), false)
+}
+
+// GETRANGE key start end
+func cmdGETRANGE(redisDB *RedisDB, args []string) []byte {
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	end, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errNotInteger, false)
+	}
+	value, _, errReply := getString(redisDB, args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	// negative offsets count from the end, then the range is clamped to the string
+	length := int64(len(value))
+	if start < 0 && end < 0 && start > end {
+		return Encode("", false)
+	}
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if end < 0 {
+		end = max(length+end, 0)
+	}
+	end = min(end, length-1)
+	if start > end || length == 0 {
+		return Encode("", false)
+	}
+	return Encode(value[start:end+1], false)
+}
+
+// SETRANGE key offset value
+func cmdSETRANGE(redisDB *RedisDB, args []string) []byte {
+	key, patch := args[0], args[2]
+	offset, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errNotInteger, false)
+	}
+	if offset < 0 {
+		return Encode(errOffsetRange, false)
+	}
+	value, exist, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+
+	// an empty patch changes nothing, it does not even create the key
+	if len(patch) == 0 {
+		return Encode(len(value), false)
+	}
+	if offset > maxBulkLen {
+		return Encode(errStringTooLong, false)
+	}
+	if err := checkStringLength(offset + int64(len(patch))); err != nil {
+		return Encode(err, false)
+	}
+
+	buf := []byte(value)
+	if need := int(offset) + len(patch); need > len(buf) {
+		// the gap is padded with zero bytes
+		buf = append(buf, make([]byte, need-len(buf))...)
+	}
+	copy(buf[offset:], patch)
+	if exist {
+		setStringKeepTTL(redisDB, key, compactString(string(buf)))
+	} else {
+		redisDB.Set(key, newStringObj(string(buf)), 0)
+	}
+	return Encode(len(buf), false)
+}
+
+// GETDEL key
+func cmdGETDEL(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	value, exist, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exist {
+		return constant.RespNil
+	}
+
+	redisDB.Delete(key)
+	return Encode(value, false)
+}
+
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+func cmdGETEX(redisDB *RedisDB, args []string) []byte {
+	key := args[0]
+	var option string
+	var ms uint64
+	switch {
+	case len(args) == 1:
+	case len(args) == 2 && strings.ToUpper(args[1]) == "PERSIST":
+		option = "PERSIST"
+	case len(args) == 3:
+		option = strings.ToUpper(args[1])
+		if option != "EX" && option != "PX" && option != "EXAT" && option != "PXAT" {
+			return Encode(errSyntax, false)
+		}
+		var err error
+		if ms, err = parseExpireTime(args[2], option, "getex"); err != nil {
+			return Encode(err, false)
+		}
+	default:
+		return Encode(errSyntax, false)
+	}
+
+	value, exist, errReply := getString(redisDB, key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exist {
+		return constant.RespNil
+	}
+
+	switch option {
+	case "":
+		// only reading the value, the AOF gets a no-op
+	case "PERSIST":
+		redisDB.RemoveExpiry(key)
+		redisDB.rewriteCommand([]string{constant.CMD_SET, key, value})
+	default:
+		expireAtMs := ms
+		if option == "EX" || option == "PX" {
+			expireAtMs += uint64(time.Now().UnixMilli())
+		}
+		if expireAtMs <= uint64(time.Now().UnixMilli()) {
+			redisDB.Delete(key)
+			redisDB.rewriteCommand([]string{constant.CMD_DEL, key})
+		} else {
+			redisDB.SetExpireAt(key, expireAtMs)
+			redisDB.rewriteCommand([]string{constant.CMD_PEXPIREAT, key, strconv.FormatUint(expireAtMs, 10)})
+		}
+	}
+	return Encode(value, false)
+}
+
+// MGET key [key ...]
+func cmdMGET(redisDB *RedisDB, args []string) []byte {
+	values := make([]any, len(args))
+	for i, key := range args {
+		// keys holding other types are reported as missing
+		if value, exist, errReply := getString(redisDB, key); exist && errReply == nil {
+			values[i] = value
+		}
+	}
+	return Encode(values, false)
+}
+
+// checkKeyValuePairs rejects an odd number of arguments for MSET and MSETNX
+func checkKeyValuePairs(args []string, cmdName string) error {
+	if len(args)%2 != 0 {
+		return fmt.Errorf("ERR wrong number of arguments for '%s' command", cmdName)
+	}
+	return nil
+}
+
+// MSET key value [key value ...]
+func cmdMSET(redisDB *RedisDB, args []string) []byte {
+	if err := checkKeyValuePairs(args, "mset"); err != nil {
+		return Encode(err, false)
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		redisDB.Set(args[i], newStringObj(args[i+1]), 0)
+	}
+	return constant.RespOk
+}
+
+// MSETNX key value [key value ...]
+func cmdMSETNX(redisDB *RedisDB, args []string) []byte {
+	if err := checkKeyValuePairs(args, "msetnx"); err != nil {
+		return Encode(err, false)
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		if redisDB.Get(args[i]) != nil {
+			return constant.RespZero
+		}
+	}
+	for i := 0; i < len(args); i += 2 {
+		redisDB.Set(args[i], newStringObj(args[i+1]), 0)
+	}
+	return constant.RespOne
+}
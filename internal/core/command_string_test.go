@@ -0,0 +1,242 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOptions(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, "$-1\r\n", execute(db, "SET", "k", "v", "XX"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "k"))
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "k", "v", "NX"))
+	assert.Equal(t, "$-1\r\n", execute(db, "SET", "k", "other", "NX"))
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "k", "v2", "xx"))
+
+	// GET replies with the old value, even when NX prevents the set
+	assert.Equal(t, "$2\r\nv2\r\n", execute(db, "SET", "k", "v3", "GET"))
+	assert.Equal(t, "$2\r\nv3\r\n", execute(db, "SET", "k", "v4", "NX", "GET"))
+	assert.Equal(t, "$2\r\nv3\r\n", execute(db, "GET", "k"))
+	assert.Equal(t, "$-1\r\n", execute(db, "SET", "new", "v", "GET"))
+	execute(db, "LPUSH", "list", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "SET", "list", "v", "GET"))
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "list", "v"))
+
+	for _, args := range [][]string{
+		{"NX", "XX"},
+		{"EX", "10", "PX", "100"},
+		{"EX", "10", "KEEPTTL"},
+		{"KEEPTTL", "PXAT", "100"},
+		{"EX"},
+		{"WHAT"},
+	} {
+		assert.Equal(t, "-ERR syntax error\r\n", execute(db, "SET", append([]string{"k", "v"}, args...)...), args)
+	}
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "SET", "k", "v", "EX", "ten"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", execute(db, "SET", "k", "v", "EX", "0"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", execute(db, "SET", "k", "v", "PX", "-5"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", execute(db, "SET", "k", "v", "EX", "9223372036854775807"))
+}
+
+func TestSetExpiry(t *testing.T) {
+	db := core.NewRedisDB()
+	now := time.Now().UnixMilli()
+
+	execute(db, "SET", "ex", "v", "EX", "100")
+	execute(db, "SET", "px", "v", "PX", "100000")
+	execute(db, "SET", "exat", "v", "EXAT", strconv.FormatInt(now/1000+100, 10))
+	execute(db, "SET", "pxat", "v", "PXAT", strconv.FormatInt(now+100000, 10))
+	for _, key := range []string{"ex", "px", "exat", "pxat"} {
+		exp, ok := db.GetExpiry(key)
+		assert.True(t, ok, key)
+		assert.InDelta(t, now+100000, int64(exp), 2000, key)
+	}
+
+	// KEEPTTL keeps the deadline, a plain SET clears it
+	before, _ := db.GetExpiry("ex")
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "ex", "v2", "KEEPTTL"))
+	after, ok := db.GetExpiry("ex")
+	assert.True(t, ok)
+	assert.Equal(t, before, after)
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "ex", "v3"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "ex"))
+
+	// a deadline in the past deletes the key
+	execute(db, "SET", "past", "v", "PXAT", "1")
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "past"))
+}
+
+func TestSetVariants(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":1\r\n", execute(db, "SETNX", "k", "v"))
+	assert.Equal(t, ":0\r\n", execute(db, "SETNX", "k", "other"))
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GET", "k"))
+
+	assert.Equal(t, "+OK\r\n", execute(db, "SETEX", "k", "100", "v2"))
+	assert.Regexp(t, `^:(99|100)\r\n$`, execute(db, "TTL", "k"))
+	assert.Equal(t, "+OK\r\n", execute(db, "PSETEX", "p", "100000", "v"))
+	assert.Regexp(t, `^:(99|100)\r\n$`, execute(db, "TTL", "p"))
+	assert.Equal(t, "-ERR invalid expire time in 'setex' command\r\n", execute(db, "SETEX", "k", "0", "v"))
+	assert.Equal(t, "-ERR invalid expire time in 'psetex' command\r\n", execute(db, "PSETEX", "k", "-1", "v"))
+
+	// GETSET replaces the value and clears the TTL
+	assert.Equal(t, "$2\r\nv2\r\n", execute(db, "GETSET", "k", "v3"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, "$-1\r\n", execute(db, "GETSET", "new", "v"))
+	execute(db, "LPUSH", "list", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "GETSET", "list", "v"))
+}
+
+func TestStringCounters(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":1\r\n", execute(db, "INCR", "n"))
+	assert.Equal(t, ":11\r\n", execute(db, "INCRBY", "n", "10"))
+	assert.Equal(t, ":10\r\n", execute(db, "DECR", "n"))
+	assert.Equal(t, ":-5\r\n", execute(db, "DECRBY", "n", "15"))
+	assert.Equal(t, "$2\r\n-5\r\n", execute(db, "GET", "n"))
+	assert.Equal(t, ":2\r\n", execute(db, "STRLEN", "n"))
+
+	// only the canonical form of an integer is accepted
+	for _, value := range []string{"abc", " 1", "01", "+1", "1.5", "99999999999999999999"} {
+		execute(db, "SET", "s", value)
+		assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "INCR", "s"), value)
+		assert.Equal(t, "$"+strconv.Itoa(len(value))+"\r\n"+value+"\r\n", execute(db, "GET", "s"), value)
+	}
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "INCRBY", "n", "x"))
+
+	execute(db, "SET", "max", "9223372036854775807")
+	assert.Equal(t, "-ERR increment or decrement would overflow\r\n", execute(db, "INCR", "max"))
+	execute(db, "SET", "min", "-9223372036854775808")
+	assert.Equal(t, "-ERR increment or decrement would overflow\r\n", execute(db, "DECR", "min"))
+	assert.Equal(t, "-ERR decrement would overflow\r\n", execute(db, "DECRBY", "n", "-9223372036854775808"))
+
+	execute(db, "LPUSH", "list", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "INCR", "list"))
+
+	// counters keep their TTL
+	execute(db, "SET", "ttl", "1", "EX", "100")
+	execute(db, "INCR", "ttl")
+	assert.Regexp(t, `^:(99|100)\r\n$`, execute(db, "TTL", "ttl"))
+
+	assert.Equal(t, "$4\r\n10.5\r\n", execute(db, "INCRBYFLOAT", "f", "10.5"))
+	assert.Equal(t, "$3\r\n5.5\r\n", execute(db, "INCRBYFLOAT", "f", "-5"))
+	assert.Equal(t, "$1\r\n6\r\n", execute(db, "INCRBYFLOAT", "f", "0.5"))
+	assert.Equal(t, ":7\r\n", execute(db, "INCR", "f"))
+	assert.Equal(t, "$3\r\n8.5\r\n", execute(db, "INCRBYFLOAT", "f", "1.5e0"))
+	assert.Equal(t, "-ERR value is not a valid float\r\n", execute(db, "INCRBYFLOAT", "f", "x"))
+	execute(db, "SET", "s", "abc")
+	assert.Equal(t, "-ERR value is not a valid float\r\n", execute(db, "INCRBYFLOAT", "s", "1"))
+	execute(db, "SET", "big", "1.7976931348623157e308")
+	assert.Equal(t, "-ERR increment would produce NaN or Infinity\r\n", execute(db, "INCRBYFLOAT", "big", "1.7976931348623157e308"))
+}
+
+func TestStringRanges(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, ":5\r\n", execute(db, "APPEND", "s", "Hello"))
+	assert.Equal(t, ":11\r\n", execute(db, "APPEND", "s", " World"))
+	assert.Equal(t, ":11\r\n", execute(db, "STRLEN", "s"))
+	assert.Equal(t, ":0\r\n", execute(db, "STRLEN", "missing"))
+
+	assert.Equal(t, "$5\r\nHello\r\n", execute(db, "GETRANGE", "s", "0", "4"))
+	assert.Equal(t, "$5\r\nWorld\r\n", execute(db, "GETRANGE", "s", "-5", "-1"))
+	assert.Equal(t, "$11\r\nHello World\r\n", execute(db, "GETRANGE", "s", "-100", "100"))
+	assert.Equal(t, "$0\r\n\r\n", execute(db, "GETRANGE", "s", "5", "2"))
+	assert.Equal(t, "$0\r\n\r\n", execute(db, "GETRANGE", "s", "-1", "-5"))
+	assert.Equal(t, "$0\r\n\r\n", execute(db, "GETRANGE", "missing", "0", "-1"))
+
+	assert.Equal(t, ":11\r\n", execute(db, "SETRANGE", "s", "6", "Redis"))
+	assert.Equal(t, "$11\r\nHello Redis\r\n", execute(db, "GET", "s"))
+	assert.Equal(t, ":5\r\n", execute(db, "SETRANGE", "pad", "2", "abc"))
+	assert.Equal(t, "$5\r\n\x00\x00abc\r\n", execute(db, "GET", "pad"))
+	assert.Equal(t, ":0\r\n", execute(db, "SETRANGE", "empty", "10", ""))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "empty"))
+	assert.Equal(t, "-ERR offset is out of range\r\n", execute(db, "SETRANGE", "s", "-1", "x"))
+	assert.Equal(t, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n", execute(db, "SETRANGE", "s", "536870912", "x"))
+
+	// an integer is edited as its decimal form
+	execute(db, "SET", "n", "123")
+	assert.Equal(t, ":4\r\n", execute(db, "APPEND", "n", "4"))
+	assert.Equal(t, ":1235\r\n", execute(db, "INCR", "n"))
+	assert.Equal(t, ":4\r\n", execute(db, "SETRANGE", "n", "0", "9"))
+	assert.Equal(t, ":9236\r\n", execute(db, "INCR", "n"))
+}
+
+func TestGetDelGetEx(t *testing.T) {
+	db := core.NewRedisDB()
+
+	execute(db, "SET", "k", "v")
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GETDEL", "k"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "k"))
+	assert.Equal(t, "$-1\r\n", execute(db, "GETDEL", "k"))
+
+	execute(db, "SET", "k", "v")
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GETEX", "k"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GETEX", "k", "EX", "100"))
+	assert.Regexp(t, `^:(99|100)\r\n$`, execute(db, "TTL", "k"))
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GETEX", "k", "persist"))
+	assert.Equal(t, ":-1\r\n", execute(db, "TTL", "k"))
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GETEX", "k", "PXAT", "1"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "k"))
+	assert.Equal(t, "$-1\r\n", execute(db, "GETEX", "k", "EX", "100"))
+
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "GETEX", "k", "EX"))
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "GETEX", "k", "PERSIST", "EX", "10"))
+	assert.Equal(t, "-ERR invalid expire time in 'getex' command\r\n", execute(db, "GETEX", "k", "EX", "0"))
+
+	execute(db, "LPUSH", "list", "a")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "GETDEL", "list"))
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(db, "GETEX", "list"))
+}
+
+func TestMultiGetSet(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, "+OK\r\n", execute(db, "MSET", "a", "1", "b", "2"))
+	execute(db, "LPUSH", "list", "x")
+	assert.Equal(t, "*4\r\n$1\r\n1\r\n$-1\r\n$1\r\n2\r\n$-1\r\n", execute(db, "MGET", "a", "missing", "b", "list"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'mset' command\r\n", execute(db, "MSET", "a", "1", "b"))
+
+	assert.Equal(t, ":0\r\n", execute(db, "MSETNX", "a", "10", "c", "3"))
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "c"))
+	assert.Equal(t, ":1\r\n", execute(db, "MSETNX", "c", "3", "d", "4"))
+	assert.Equal(t, "*2\r\n$1\r\n3\r\n$1\r\n4\r\n", execute(db, "MGET", "c", "d"))
+}
+
+func TestStringAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncNo)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	execute(db, "SET", "f", "1", "EX", "100")
+	execute(db, "INCRBYFLOAT", "f", "0.1")
+	execute(db, "SET", "k", "v")
+	execute(db, "GETEX", "k", "EX", "50")
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "INCRBYFLOAT")
+	assert.NotContains(t, string(data), "GETEX")
+
+	loaded := replay(t, path)
+	assert.Equal(t, "$3\r\n1.1\r\n", execute(loaded, "GET", "f"))
+	for _, key := range []string{"f", "k"} {
+		expected, _ := db.GetExpiry(key)
+		actual, ok := loaded.GetExpiry(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, actual, key)
+	}
+}
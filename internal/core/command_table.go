@@ -0,0 +1,547 @@
+package core
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+)
+
+type CommandFlag uint32
+
+// Flags reported by COMMAND, same meaning as in redis
+const (
+	// FlagWrite the command may modify the keyspace
+	FlagWrite CommandFlag = 1 << iota
+	// FlagReadonly the command never modifies the keyspace
+	FlagReadonly
+	// FlagFast the command runs in O(1) or O(log N)
+	FlagFast
+	// FlagAdmin the command is meant for operators, not applications
+	FlagAdmin
+	// FlagBlocking the command may block the client until a key is written or a timeout
+	FlagBlocking
+	// FlagDenyOOM the command may use more memory, it is rejected when maxmemory is reached and nothing can be evicted
+	FlagDenyOOM
+	// FlagPubSub the command is about Pub/Sub, it is executed by the connection rather than a RedisDB
+	FlagPubSub
+)
+
+var flagNames = []struct {
+	flag CommandFlag
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagDenyOOM, "denyoom"},
+	{FlagReadonly, "readonly"},
+	{FlagFast, "fast"},
+	{FlagAdmin, "admin"},
+	{FlagPubSub, "pubsub"},
+	{FlagBlocking, "blocking"},
+}
+
+type CommandHandler func(redisDB *RedisDB, args []string) []byte
+
+// CommandSpec describes a command, the table drives dispatch, arity checks,
+// key extraction for sharding and the COMMAND replies.
+type CommandSpec struct {
+	Name string
+	// Handler is nil for commands executed by the server itself because they need every shard, like SAVE,
+	// and for the Pub/Sub, transaction, AUTH and ACL commands executed by the connection
+	Handler CommandHandler
+	// Arity counts the command name like redis does,
+	// a negative value -N means at least N
+	Arity int
+	Flags CommandFlag
+	// FirstKey, LastKey and Step give the key positions, counting the command name as position 0.
+	// FirstKey 0 means the command has no key, a negative LastKey counts from the end (-1 is the last argument).
+	FirstKey int
+	LastKey  int
+	Step     int
+	// NumKeys is the position of the numkeys argument of the commands whose keys are counted by it, like ZMPOP.
+	// The keys follow it and are added to the ones of FirstKey, COMMAND reports them with the movablekeys flag.
+	NumKeys int
+	// Tips are hints for clients about multi-shard execution, e.g. "request_policy:multi_shard"
+	Tips []string
+
+	// Docs reported by COMMAND DOCS
+	Summary    string
+	Since      string
+	Group      string
+	Complexity string
+}
+
+// commandTable maps the upper-case command name to its spec, it is filled in init
+// because COMMAND handlers read the table themselves.
+var commandTable = make(map[string]*CommandSpec)
+
+func registerCommands(specs ...*CommandSpec) {
+	for _, spec := range specs {
+		commandTable[spec.Name] = spec
+	}
+}
+
+// LookupCommand returns the spec of the command, or nil for unknown commands
+func LookupCommand(name string) *CommandSpec {
+	return commandTable[strings.ToUpper(name)]
+}
+
+// HasFlag reports whether every flag in flags is set
+func (spec *CommandSpec) HasFlag(flags CommandFlag) bool {
+	return spec.Flags&flags == flags
+}
+
+// CheckArity validates the number of arguments, not counting the command name
+func (spec *CommandSpec) CheckArity(numArgs int) bool {
+	if spec.Arity >= 0 {
+		return numArgs+1 == spec.Arity
+	}
+	return numArgs+1 >= -spec.Arity
+}
+
+// KeyIndexes returns the positions of the keys in args (args does not include the command name)
+func (spec *CommandSpec) KeyIndexes(args []string) []int {
+	var idx []int
+	if spec.FirstKey != 0 {
+		last := spec.LastKey
+		if last < 0 {
+			last = len(args) + 1 + last
+		}
+		for i := spec.FirstKey; i <= last && i <= len(args); i += spec.Step {
+			idx = append(idx, i-1)
+		}
+	}
+
+	// an invalid numkeys is left to the command for its error
+	if spec.NumKeys == 0 || spec.NumKeys > len(args) {
+		return idx
+	}
+	numKeys, err := strconv.Atoi(args[spec.NumKeys-1])
+	if err != nil || numKeys <= 0 {
+		return idx
+	}
+	for i := spec.NumKeys + 1; i <= spec.NumKeys+numKeys && i <= len(args); i++ {
+		idx = append(idx, i-1)
+	}
+	return idx
+}
+
+// Keys returns the keys the command operates on
+func (spec *CommandSpec) Keys(args []string) []string {
+	idx := spec.KeyIndexes(args)
+	keys := make([]string, len(idx))
+	for i, pos := range idx {
+		keys[i] = args[pos]
+	}
+	return keys
+}
+
+// ACLCategories are the command categories of the ACL rules, the ones of redis plus bloom and cms
+var ACLCategories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string", "bitmap", "hyperloglog",
+	"geo", "stream", "pubsub", "admin", "fast", "slow", "blocking", "dangerous", "connection",
+	"transaction", "scripting", "bloom", "cms",
+}
+
+// groupCategories maps the group of a command to its ACL category, the server group has none
+var groupCategories = map[string]string{
+	"generic": "keyspace", "string": "string", "hash": "hash", "list": "list", "set": "set",
+	"sorted-set": "sortedset", "pubsub": "pubsub", "transactions": "transaction", "connection": "connection",
+	"bf": "bloom", "cms": "cms",
+}
+
+// Categories returns the ACL categories of the command, derived from its flags and group like redis does:
+// admin commands are dangerous and the commands that are not fast are slow
+func (spec *CommandSpec) Categories() []string {
+	var categories []string
+	if spec.HasFlag(FlagWrite) {
+		categories = append(categories, "write")
+	}
+	if spec.HasFlag(FlagReadonly) {
+		categories = append(categories, "read")
+	}
+	if spec.HasFlag(FlagAdmin) {
+		categories = append(categories, "admin", "dangerous")
+	}
+	if category, ok := groupCategories[spec.Group]; ok {
+		categories = append(categories, category)
+	}
+	if spec.HasFlag(FlagFast) {
+		categories = append(categories, "fast")
+	} else {
+		categories = append(categories, "slow")
+	}
+	if spec.HasFlag(FlagBlocking) {
+		categories = append(categories, "blocking")
+	}
+	return categories
+}
+
+// HasCategory reports whether category is one of the ACL categories of the command
+func (spec *CommandSpec) HasCategory(category string) bool {
+	return slices.Contains(spec.Categories(), category)
+}
+
+// Commands returns the specs of every command, sorted by name
+func Commands() []*CommandSpec {
+	specs := make([]*CommandSpec, 0, len(commandTable))
+	for _, name := range sortedCommandNames() {
+		specs = append(specs, commandTable[name])
+	}
+	return specs
+}
+
+// HasTip reports whether the command has the given tip, e.g. "response_policy:agg_sum"
+func (spec *CommandSpec) HasTip(tip string) bool {
+	for _, t := range spec.Tips {
+		if t == tip {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	registerCommands(
+		// Connection & server
+		&CommandSpec{Name: constant.CMD_PING, Handler: cmdPING, Arity: -1, Flags: FlagFast,
+			Summary: "Returns the server's liveliness response.", Since: "1.0.0", Group: "connection", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_INFO, Handler: cmdINFO, Arity: -1,
+			Tips:    []string{"nondeterministic_output", "request_policy:all_shards", "response_policy:special"},
+			Summary: "Returns information and statistics about the server.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_COMMAND, Handler: cmdCOMMAND, Arity: -1,
+			Summary: "Returns detailed information about all commands.", Since: "2.8.13", Group: "server", Complexity: "O(N) where N is the total number of commands"},
+		// MEMORY USAGE key: the key is the second argument
+		&CommandSpec{Name: constant.CMD_MEMORY, Handler: cmdMEMORY, Arity: -2, Flags: FlagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+			Summary: "Estimates the memory usage of a key.", Since: "4.0.0", Group: "server", Complexity: "O(N) where N is the number of samples."},
+		&CommandSpec{Name: constant.CMD_SAVE, Arity: 1, Flags: FlagAdmin,
+			Summary: "Synchronously saves the database(s) to disk.", Since: "1.0.0", Group: "server", Complexity: "O(N) where N is the total number of keys in all databases"},
+		&CommandSpec{Name: constant.CMD_BGSAVE, Arity: -1, Flags: FlagAdmin,
+			Summary: "Asynchronously saves the database(s) to disk.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_LASTSAVE, Arity: 1, Flags: FlagFast,
+			Summary: "Returns the Unix timestamp of the last successful save to disk.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_BGREWRITEAOF, Arity: 1, Flags: FlagAdmin,
+			Summary: "Asynchronously rewrites the append-only file to disk.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_CONFIG, Arity: -2, Flags: FlagAdmin,
+			Summary: "A container for server configuration commands.", Since: "2.0.0", Group: "server", Complexity: "Depends on subcommand."},
+		// AUTH and ACL are executed by the connection, see server/acl.go
+		&CommandSpec{Name: constant.CMD_AUTH, Arity: -2, Flags: FlagFast,
+			Summary: "Authenticates the connection.", Since: "1.0.0", Group: "connection", Complexity: "O(N) where N is the number of passwords defined for the user"},
+		&CommandSpec{Name: constant.CMD_ACL, Arity: -2, Flags: FlagAdmin,
+			Summary: "A container for Access List Control commands.", Since: "6.0.0", Group: "server", Complexity: "Depends on subcommand."},
+
+		// Generic
+		&CommandSpec{Name: constant.CMD_DEL, Handler: cmdDel, Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, Step: 1,
+			Tips:    []string{"request_policy:multi_shard", "response_policy:agg_sum"},
+			Summary: "Deletes one or more keys.", Since: "1.0.0", Group: "generic", Complexity: "O(N) where N is the number of keys that will be removed."},
+		&CommandSpec{Name: constant.CMD_EXIST, Handler: cmdExists, Arity: -2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Tips:    []string{"request_policy:multi_shard", "response_policy:agg_sum"},
+			Summary: "Determines whether one or more keys exist.", Since: "1.0.0", Group: "generic", Complexity: "O(N) where N is the number of keys to check."},
+		&CommandSpec{Name: constant.CMD_EXPIRE, Handler: cmdExpire, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the expiration time of a key in seconds.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PEXPIRE, Handler: cmdPExpire, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the expiration time of a key in milliseconds.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_EXPIREAT, Handler: cmdExpireAt, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the expiration time of a key to a Unix timestamp.", Since: "1.2.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PEXPIREAT, Handler: cmdPExpireAt, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PERSIST, Handler: cmdPersist, Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes the expiration time of a key.", Since: "2.2.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_EXPIRETIME, Handler: cmdExpireTime, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the expiration time of a key as a Unix timestamp.", Since: "7.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PEXPIRETIME, Handler: cmdPExpireTime, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.", Since: "7.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_TTL, Handler: cmdTTL, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the expiration time in seconds of a key.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PTTL, Handler: cmdPTTL, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the expiration time in milliseconds of a key.", Since: "2.6.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_RENAME, Handler: cmdRename, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Renames a key and overwrites the destination.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_RENAMENX, Handler: cmdRenameNX, Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Renames a key only when the target key name doesn't exist.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_KEYS, Handler: cmdKEYS, Arity: 2, Flags: FlagReadonly,
+			Tips:    []string{"request_policy:all_shards", "nondeterministic_output_order"},
+			Summary: "Returns all key names that match a pattern.", Since: "1.0.0", Group: "generic", Complexity: "O(N) with N being the number of keys in the database, under the assumption that the key names in the database and the given pattern have limited length."},
+		&CommandSpec{Name: constant.CMD_SCAN, Handler: cmdSCAN, Arity: -2, Flags: FlagReadonly,
+			Tips:    []string{"nondeterministic_output", "request_policy:special", "response_policy:special"},
+			Summary: "Iterates over the key names in the database.", Since: "2.8.0", Group: "generic", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection."},
+		&CommandSpec{Name: constant.CMD_TYPE, Handler: cmdTYPE, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Determines the type of value stored at a key.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_RANDOMKEY, Handler: cmdRANDOMKEY, Arity: 1, Flags: FlagReadonly,
+			Tips:    []string{"request_policy:all_shards", "response_policy:special", "nondeterministic_output"},
+			Summary: "Returns a random key name from the database.", Since: "1.0.0", Group: "generic", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_DBSIZE, Handler: cmdDBSIZE, Arity: 1, Flags: FlagReadonly | FlagFast,
+			Tips:    []string{"request_policy:all_shards", "response_policy:agg_sum"},
+			Summary: "Returns the number of keys in the database.", Since: "1.0.0", Group: "server", Complexity: "O(1)"},
+		// OBJECT FREQ key: the key is the second argument
+		&CommandSpec{Name: constant.CMD_OBJECT, Handler: cmdOBJECT, Arity: -2, Flags: FlagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+			Tips:    []string{"nondeterministic_output"},
+			Summary: "Returns the logarithmic access frequency counter of a Redis object.", Since: "4.0.0", Group: "generic", Complexity: "O(1)"},
+
+		// String
+		&CommandSpec{Name: constant.CMD_SET, Handler: cmdSet, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_GET, Handler: cmdGet, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the string value of a key.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_SETNX, Handler: cmdSETNX, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Set the string value of a key only when the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_SETEX, Handler: cmdSETEX, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the string value and expiration time of a key. Creates the key if it doesn't exist.", Since: "2.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_PSETEX, Handler: cmdPSETEX, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets both string value and expiration time in milliseconds of a key. The key is created if it doesn't exist.", Since: "2.6.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_GETSET, Handler: cmdGETSET, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the previous string value of a key after setting it to a new value.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_INCR, Handler: cmdINCR, Arity: 2, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_DECR, Handler: cmdDECR, Arity: 2, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_INCRBY, Handler: cmdINCRBY, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_DECRBY, Handler: cmdDECRBY, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.", Since: "1.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_INCRBYFLOAT, Handler: cmdINCRBYFLOAT, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.", Since: "2.6.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_APPEND, Handler: cmdAPPEND, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.", Since: "2.0.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_STRLEN, Handler: cmdSTRLEN, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the length of a string value.", Since: "2.2.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_GETRANGE, Handler: cmdGETRANGE, Arity: 4, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns a substring of the string stored at a key.", Since: "2.4.0", Group: "string", Complexity: "O(N) where N is the length of the returned string"},
+		&CommandSpec{Name: constant.CMD_SETRANGE, Handler: cmdSETRANGE, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.", Since: "2.2.0", Group: "string", Complexity: "O(1), not counting the time taken to copy the new string in place"},
+		&CommandSpec{Name: constant.CMD_GETDEL, Handler: cmdGETDEL, Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the string value of a key after deleting the key.", Since: "6.2.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_GETEX, Handler: cmdGETEX, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the string value of a key after setting its expiration time.", Since: "6.2.0", Group: "string", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_MGET, Handler: cmdMGET, Arity: -2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Tips:    []string{"request_policy:multi_shard"},
+			Summary: "Atomically returns the string values of one or more keys.", Since: "1.0.0", Group: "string", Complexity: "O(N) where N is the number of keys to retrieve"},
+		&CommandSpec{Name: constant.CMD_MSET, Handler: cmdMSET, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 2,
+			Summary: "Atomically creates or modifies the string values of one or more keys.", Since: "1.0.1", Group: "string", Complexity: "O(N) where N is the number of keys to set"},
+		&CommandSpec{Name: constant.CMD_MSETNX, Handler: cmdMSETNX, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 2,
+			Summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.", Since: "1.0.1", Group: "string", Complexity: "O(N) where N is the number of keys to set"},
+
+		// List
+		&CommandSpec{Name: constant.CMD_LPUSH, Handler: cmdLPUSH, Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+		&CommandSpec{Name: constant.CMD_RPUSH, Handler: cmdRPUSH, Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+		&CommandSpec{Name: constant.CMD_LPUSHX, Handler: cmdLPUSHX, Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Prepends one or more elements to a list only when the list exists.", Since: "2.2.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+		&CommandSpec{Name: constant.CMD_RPUSHX, Handler: cmdRPUSHX, Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Appends an element to a list only when the list exists.", Since: "2.2.0", Group: "list", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+		&CommandSpec{Name: constant.CMD_LPOP, Handler: cmdLPOP, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements returned"},
+		&CommandSpec{Name: constant.CMD_RPOP, Handler: cmdRPOP, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns and removes the last elements of a list. Deletes the list if the last element was popped.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements returned"},
+		&CommandSpec{Name: constant.CMD_LLEN, Handler: cmdLLEN, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the length of a list.", Since: "1.0.0", Group: "list", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_LRANGE, Handler: cmdLRANGE, Arity: 4, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns a range of elements from a list.", Since: "1.0.0", Group: "list", Complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range."},
+		&CommandSpec{Name: constant.CMD_LINDEX, Handler: cmdLINDEX, Arity: 3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns an element from a list by its index.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements to traverse to get to the element at index. This makes asking for the first or the last element of the list O(1)."},
+		&CommandSpec{Name: constant.CMD_LSET, Handler: cmdLSET, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the value of an element in a list by its index.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the length of the list. Setting either the first or the last element of the list is O(1)."},
+		&CommandSpec{Name: constant.CMD_LINSERT, Handler: cmdLINSERT, Arity: 5, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Inserts an element before or after another element in a list.", Since: "2.2.0", Group: "list", Complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot."},
+		&CommandSpec{Name: constant.CMD_LREM, Handler: cmdLREM, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes elements from a list. Deletes the list if the last element was removed.", Since: "1.0.0", Group: "list", Complexity: "O(N+M) where N is the length of the list and M is the number of elements removed."},
+		&CommandSpec{Name: constant.CMD_LTRIM, Handler: cmdLTRIM, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.", Since: "1.0.0", Group: "list", Complexity: "O(N) where N is the number of elements to be removed by the operation."},
+		&CommandSpec{Name: constant.CMD_LPOS, Handler: cmdLPOS, Arity: -3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the index of matching elements in a list.", Since: "6.0.6", Group: "list", Complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time."},
+		&CommandSpec{Name: constant.CMD_LMOVE, Handler: cmdLMOVE, Arity: 5, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.", Since: "6.2.0", Group: "list", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_BLPOP, Handler: cmdBLPOP, Arity: -3, Flags: FlagWrite | FlagBlocking, FirstKey: 1, LastKey: -2, Step: 1,
+			Summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.", Since: "2.0.0", Group: "list", Complexity: "O(N) where N is the number of provided keys."},
+		&CommandSpec{Name: constant.CMD_BRPOP, Handler: cmdBRPOP, Arity: -3, Flags: FlagWrite | FlagBlocking, FirstKey: 1, LastKey: -2, Step: 1,
+			Summary: "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.", Since: "2.0.0", Group: "list", Complexity: "O(N) where N is the number of provided keys."},
+		&CommandSpec{Name: constant.CMD_BLMOVE, Handler: cmdBLMOVE, Arity: 6, Flags: FlagWrite | FlagDenyOOM | FlagBlocking, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.", Since: "6.2.0", Group: "list", Complexity: "O(1)"},
+
+		// Pub/Sub, the channels of the sharded commands are reported as keys like redis does
+		&CommandSpec{Name: constant.CMD_SUBSCRIBE, Arity: -2, Flags: FlagPubSub,
+			Summary: "Listens for messages published to channels.", Since: "2.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of channels to subscribe to."},
+		&CommandSpec{Name: constant.CMD_UNSUBSCRIBE, Arity: -1, Flags: FlagPubSub,
+			Summary: "Stops listening to messages posted to channels.", Since: "2.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of channels to unsubscribe."},
+		&CommandSpec{Name: constant.CMD_PSUBSCRIBE, Arity: -2, Flags: FlagPubSub,
+			Summary: "Listens for messages published to channels that match one or more patterns.", Since: "2.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of patterns to subscribe to."},
+		&CommandSpec{Name: constant.CMD_PUNSUBSCRIBE, Arity: -1, Flags: FlagPubSub,
+			Summary: "Stops listening to messages published to channels that match one or more patterns.", Since: "2.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of patterns to unsubscribe."},
+		&CommandSpec{Name: constant.CMD_SSUBSCRIBE, Arity: -2, Flags: FlagPubSub, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Listens for messages published to shard channels.", Since: "7.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of shard channels to subscribe to."},
+		&CommandSpec{Name: constant.CMD_SUNSUBSCRIBE, Arity: -1, Flags: FlagPubSub, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Stops listening to messages posted to shard channels.", Since: "7.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of shard channels to unsubscribe."},
+		&CommandSpec{Name: constant.CMD_PUBLISH, Arity: 3, Flags: FlagPubSub | FlagFast,
+			Summary: "Posts a message to a channel.", Since: "2.0.0", Group: "pubsub", Complexity: "O(N+M) where N is the number of clients subscribed to the receiving channel and M is the total number of subscribed patterns (by any client)."},
+		&CommandSpec{Name: constant.CMD_SPUBLISH, Arity: 3, Flags: FlagPubSub | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Post a message to a shard channel", Since: "7.0.0", Group: "pubsub", Complexity: "O(N) where N is the number of clients subscribed to the receiving shard channel."},
+		&CommandSpec{Name: constant.CMD_PUBSUB, Arity: -2, Flags: FlagPubSub,
+			Summary: "A container for Pub/Sub commands.", Since: "2.8.0", Group: "pubsub", Complexity: "Depends on subcommand."},
+
+		// Transactions, see transaction.go
+		&CommandSpec{Name: constant.CMD_MULTI, Arity: 1, Flags: FlagFast,
+			Summary: "Starts a transaction.", Since: "1.2.0", Group: "transactions", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_EXEC, Arity: 1,
+			Summary: "Executes all commands in a transaction.", Since: "1.2.0", Group: "transactions", Complexity: "Depends on commands in the transaction"},
+		&CommandSpec{Name: constant.CMD_DISCARD, Arity: 1, Flags: FlagFast,
+			Summary: "Discards a transaction.", Since: "2.0.0", Group: "transactions", Complexity: "O(N), when N is the number of queued commands"},
+		&CommandSpec{Name: constant.CMD_WATCH, Arity: -2, Flags: FlagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Monitors changes to keys to determine the execution of a transaction.", Since: "2.2.0", Group: "transactions", Complexity: "O(1) for every key."},
+		&CommandSpec{Name: constant.CMD_UNWATCH, Handler: cmdUNWATCH, Arity: 1, Flags: FlagFast,
+			Summary: "Forgets about watched keys of a transaction.", Since: "2.2.0", Group: "transactions", Complexity: "O(1)"},
+
+		// Set
+		&CommandSpec{Name: constant.CMD_SADD, Handler: cmdSADD, Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Adds one or more members to a set. Creates the key if it doesn't exist.", Since: "1.0.0", Group: "set", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments."},
+		&CommandSpec{Name: constant.CMD_SREM, Handler: cmdSREM, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes one or more members from a set. Deletes the set if the last member was removed.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the number of members to be removed."},
+		&CommandSpec{Name: constant.CMD_SISMEMBER, Handler: cmdSISMEMBER, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Determines whether a member belongs to a set.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_SMEMBERS, Handler: cmdSMEMEBERS, Arity: 2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns all members of a set.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the set cardinality."},
+		&CommandSpec{Name: constant.CMD_SCARD, Handler: cmdSCARD, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the number of members in a set.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_SMISMEMBER, Handler: cmdSMISMEMBER, Arity: -3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Determines whether multiple members belong to a set.", Since: "6.2.0", Group: "set", Complexity: "O(N) where N is the number of elements being checked for membership"},
+		&CommandSpec{Name: constant.CMD_SMOVE, Handler: cmdSMOVE, Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Moves a member from one set to another.", Since: "1.0.0", Group: "set", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_SPOP, Handler: cmdSPOP, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.", Since: "1.0.0", Group: "set", Complexity: "Without the count argument O(1), otherwise O(N) where N is the value of the passed count."},
+		&CommandSpec{Name: constant.CMD_SRANDMEMBER, Handler: cmdSRANDMEMBER, Arity: -2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Get one or multiple random members from a set", Since: "1.0.0", Group: "set", Complexity: "Without the count argument O(1), otherwise O(N) where N is the absolute value of the passed count."},
+		&CommandSpec{Name: constant.CMD_SINTER, Handler: cmdSINTER, Arity: -2, Flags: FlagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Returns the intersect of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets."},
+		&CommandSpec{Name: constant.CMD_SINTERSTORE, Handler: cmdSINTERSTORE, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Stores the intersect of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets."},
+		&CommandSpec{Name: constant.CMD_SINTERCARD, Handler: cmdSINTERCARD, Arity: -3, Flags: FlagReadonly, NumKeys: 1,
+			Summary: "Returns the number of members of the intersect of multiple sets.", Since: "7.0.0", Group: "set", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets."},
+		&CommandSpec{Name: constant.CMD_SUNION, Handler: cmdSUNION, Arity: -2, Flags: FlagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Returns the union of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+		&CommandSpec{Name: constant.CMD_SUNIONSTORE, Handler: cmdSUNIONSTORE, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Stores the union of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+		&CommandSpec{Name: constant.CMD_SDIFF, Handler: cmdSDIFF, Arity: -2, Flags: FlagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Returns the difference of multiple sets.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+		&CommandSpec{Name: constant.CMD_SDIFFSTORE, Handler: cmdSDIFFSTORE, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Summary: "Stores the difference of multiple sets in a key.", Since: "1.0.0", Group: "set", Complexity: "O(N) where N is the total number of elements in all given sets."},
+		&CommandSpec{Name: constant.CMD_SSCAN, Handler: cmdSSCAN, Arity: -3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Iterates over members of a set.", Since: "2.8.0", Group: "set", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection."},
+
+		// Sorted set
+		&CommandSpec{Name: constant.CMD_ZADD, Handler: cmdZADD, Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_ZSCORE, Handler: cmdZSCORE, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the score of a member in a sorted set.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_ZMSCORE, Handler: cmdZMSCORE, Arity: -3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the score of one or more members in a sorted set.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(N) where N is the number of members being requested."},
+		&CommandSpec{Name: constant.CMD_ZINCRBY, Handler: cmdZINCRBY, Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increments the score of a member in a sorted set.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(log(N)) where N is the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_ZRANK, Handler: cmdZRANK, Arity: -3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the index of a member in a sorted set ordered by ascending scores.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(log(N))"},
+		&CommandSpec{Name: constant.CMD_ZREM, Handler: cmdZREM, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(M*log(N)) with N being the number of elements in the sorted set and M the number of elements to be removed."},
+		&CommandSpec{Name: constant.CMD_ZREVRANK, Handler: cmdZREVRANK, Arity: -3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the index of a member in a sorted set ordered by descending scores.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(log(N))"},
+		&CommandSpec{Name: constant.CMD_ZCARD, Handler: cmdZCARD, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the number of members in a sorted set.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_ZCOUNT, Handler: cmdZCOUNT, Arity: 4, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the count of members in a sorted set that have scores within a range.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(log(N)) with N being the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_ZLEXCOUNT, Handler: cmdZLEXCOUNT, Arity: 4, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the number of members in a sorted set within a lexicographical range.", Since: "2.8.9", Group: "sorted-set", Complexity: "O(log(N)) with N being the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_ZRANGE, Handler: cmdZRANGE, Arity: -4, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns members in a sorted set within a range of indexes.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned."},
+		&CommandSpec{Name: constant.CMD_ZRANGESTORE, Handler: cmdZRANGESTORE, Arity: -5, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1,
+			Summary: "Stores a range of members from sorted set in a key.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements stored into the destination key."},
+		&CommandSpec{Name: constant.CMD_ZREVRANGE, Handler: cmdZREVRANGE, Arity: -4, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns members in a sorted set within a range of indexes in reverse order.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned."},
+		&CommandSpec{Name: constant.CMD_ZRANGEBYSCORE, Handler: cmdZRANGEBYSCORE, Arity: -4, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns members in a sorted set within a range of scores.", Since: "1.0.5", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned."},
+		&CommandSpec{Name: constant.CMD_ZPOPMIN, Handler: cmdZPOPMIN, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.", Since: "5.0.0", Group: "sorted-set", Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped."},
+		&CommandSpec{Name: constant.CMD_ZPOPMAX, Handler: cmdZPOPMAX, Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.", Since: "5.0.0", Group: "sorted-set", Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped."},
+		&CommandSpec{Name: constant.CMD_ZMPOP, Handler: cmdZMPOP, Arity: -4, Flags: FlagWrite, NumKeys: 1,
+			Summary: "Returns the highest- or lowest-scoring members from one or more sorted sets after removing them. Deletes the sorted set if the last member was popped.", Since: "7.0.0", Group: "sorted-set", Complexity: "O(K) + O(M*log(N)) where K is the number of provided keys, N being the number of elements in the sorted set, and M being the number of elements popped."},
+		&CommandSpec{Name: constant.CMD_BZPOPMIN, Handler: cmdBZPOPMIN, Arity: -3, Flags: FlagWrite | FlagFast | FlagBlocking, FirstKey: 1, LastKey: -2, Step: 1,
+			Summary: "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Since: "5.0.0", Group: "sorted-set", Complexity: "O(log(N)) with N being the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_BZPOPMAX, Handler: cmdBZPOPMAX, Arity: -3, Flags: FlagWrite | FlagFast | FlagBlocking, FirstKey: 1, LastKey: -2, Step: 1,
+			Summary: "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Since: "5.0.0", Group: "sorted-set", Complexity: "O(log(N)) with N being the number of elements in the sorted set."},
+		&CommandSpec{Name: constant.CMD_BZMPOP, Handler: cmdBZMPOP, Arity: -5, Flags: FlagWrite | FlagBlocking, NumKeys: 2,
+			Summary: "Removes and returns a member by score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Since: "7.0.0", Group: "sorted-set", Complexity: "O(K) + O(M*log(N)) where K is the number of provided keys, N being the number of elements in the sorted set, and M being the number of elements popped."},
+		&CommandSpec{Name: constant.CMD_ZREMRANGEBYRANK, Handler: cmdZREMRANGEBYRANK, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes members in a sorted set within a range of indexes. Deletes the sorted set if all members were removed.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation."},
+		&CommandSpec{Name: constant.CMD_ZREMRANGEBYSCORE, Handler: cmdZREMRANGEBYSCORE, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes members in a sorted set within a range of scores. Deletes the sorted set if all members were removed.", Since: "1.2.0", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation."},
+		&CommandSpec{Name: constant.CMD_ZREMRANGEBYLEX, Handler: cmdZREMRANGEBYLEX, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Removes members in a sorted set within a lexicographical range. Deletes the sorted set if all members were removed.", Since: "2.8.9", Group: "sorted-set", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation."},
+		&CommandSpec{Name: constant.CMD_ZUNION, Handler: cmdZUNION, Arity: -3, Flags: FlagReadonly, NumKeys: 1,
+			Summary: "Returns the union of multiple sorted sets.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(N)+O(M*log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set."},
+		&CommandSpec{Name: constant.CMD_ZUNIONSTORE, Handler: cmdZUNIONSTORE, Arity: -4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, NumKeys: 2,
+			Summary: "Stores the union of multiple sorted sets in a key.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(N)+O(M log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set."},
+		&CommandSpec{Name: constant.CMD_ZINTER, Handler: cmdZINTER, Arity: -3, Flags: FlagReadonly, NumKeys: 1,
+			Summary: "Returns the intersect of multiple sorted sets.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set."},
+		&CommandSpec{Name: constant.CMD_ZINTERSTORE, Handler: cmdZINTERSTORE, Arity: -4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, NumKeys: 2,
+			Summary: "Stores the intersect of multiple sorted sets in a key.", Since: "2.0.0", Group: "sorted-set", Complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set."},
+		&CommandSpec{Name: constant.CMD_ZINTERCARD, Handler: cmdZINTERCARD, Arity: -3, Flags: FlagReadonly, NumKeys: 1,
+			Summary: "Returns the number of members of the intersect of multiple sorted sets.", Since: "7.0.0", Group: "sorted-set", Complexity: "O(N*K) worst case with N being the smallest input sorted set, K being the number of input sorted sets."},
+		&CommandSpec{Name: constant.CMD_ZDIFF, Handler: cmdZDIFF, Arity: -3, Flags: FlagReadonly, NumKeys: 1,
+			Summary: "Returns the difference between multiple sorted sets.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(L + (N-K)log(N)) worst case where L is the total number of elements in all the sets, N is the size of the first set, and K is the size of the result set."},
+		&CommandSpec{Name: constant.CMD_ZDIFFSTORE, Handler: cmdZDIFFSTORE, Arity: -4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, NumKeys: 2,
+			Summary: "Stores the difference of multiple sorted sets in a key.", Since: "6.2.0", Group: "sorted-set", Complexity: "O(L + (N-K)log(N)) worst case where L is the total number of elements in all the sets, N is the size of the first set, and K is the size of the result set."},
+
+		// Hash
+		&CommandSpec{Name: constant.CMD_HSET, Handler: cmdHSET, Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Creates or modifies the value of a field in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs."},
+		&CommandSpec{Name: constant.CMD_HSETNX, Handler: cmdHSETNX, Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Sets the value of a field in a hash only when the field doesn't exist.", Since: "2.0.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HGET, Handler: cmdHGET, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the value of a field in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HMGET, Handler: cmdHMGET, Arity: -3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the values of all fields in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(N) where N is the number of fields being requested."},
+		&CommandSpec{Name: constant.CMD_HDEL, Handler: cmdHDEL, Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.", Since: "2.0.0", Group: "hash", Complexity: "O(N) where N is the number of fields to be removed."},
+		&CommandSpec{Name: constant.CMD_HEXISTS, Handler: cmdHEXISTS, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Determines whether a field exists in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HLEN, Handler: cmdHLEN, Arity: 2, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the number of fields in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HSTRLEN, Handler: cmdHSTRLEN, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the length of the value of a field.", Since: "3.2.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HKEYS, Handler: cmdHKEYS, Arity: 2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns all fields in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(N) where N is the size of the hash."},
+		&CommandSpec{Name: constant.CMD_HVALS, Handler: cmdHVALS, Arity: 2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns all values in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(N) where N is the size of the hash."},
+		&CommandSpec{Name: constant.CMD_HGETALL, Handler: cmdHGETALL, Arity: 2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns all fields and values in a hash.", Since: "2.0.0", Group: "hash", Complexity: "O(N) where N is the size of the hash."},
+		&CommandSpec{Name: constant.CMD_HINCRBY, Handler: cmdHINCRBY, Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.", Since: "2.0.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HINCRBYFLOAT, Handler: cmdHINCRBYFLOAT, Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.", Since: "2.6.0", Group: "hash", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_HRANDFIELD, Handler: cmdHRANDFIELD, Arity: -2, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns one or more random fields from a hash.", Since: "6.2.0", Group: "hash", Complexity: "O(N) where N is the number of fields returned"},
+		&CommandSpec{Name: constant.CMD_HSCAN, Handler: cmdHSCAN, Arity: -3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Iterates over fields and values of a hash.", Since: "2.8.0", Group: "hash", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection."},
+
+		// Count-Min Sketch
+		&CommandSpec{Name: constant.CMD_CMS_INITBYDIM, Handler: cmdCMSINITBYDIM, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Initializes a Count-Min Sketch to dimensions specified by user", Since: "2.0.0", Group: "cms", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_CMS_INITBYPROB, Handler: cmdCMSINITBYPROB, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Initializes a Count-Min Sketch to accommodate requested tolerances.", Since: "2.0.0", Group: "cms", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_CMS_INCRBY, Handler: cmdCMSINCRBY, Arity: -4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Increases the count of one or more items by increment", Since: "2.0.0", Group: "cms", Complexity: "O(n) where n is the number of items"},
+		&CommandSpec{Name: constant.CMD_CMS_QUERY, Handler: cmdCMSQUERY, Arity: -3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Returns the count for one or more items in a sketch", Since: "2.0.0", Group: "cms", Complexity: "O(n) where n is the number of items"},
+
+		// Bloom filter
+		&CommandSpec{Name: constant.CMD_BF_RESERVE, Handler: cmdBFRESERVE, Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Creates a new Bloom Filter", Since: "1.0.0", Group: "bf", Complexity: "O(1)"},
+		&CommandSpec{Name: constant.CMD_BF_ADD, Handler: cmdBFADD, Arity: 3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Adds an item to a Bloom Filter", Since: "1.0.0", Group: "bf", Complexity: "O(k), where k is the number of hash functions used by the last sub-filter"},
+		&CommandSpec{Name: constant.CMD_BF_MADD, Handler: cmdBFMADD, Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Adds one or more items to a Bloom Filter. A filter will be created if it does not exist", Since: "1.0.0", Group: "bf", Complexity: "O(k * n), where k is the number of hash functions and n is the number of items"},
+		&CommandSpec{Name: constant.CMD_BF_EXISTS, Handler: cmdBFEXISTS, Arity: 3, Flags: FlagReadonly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Checks whether an item exists in a Bloom Filter", Since: "1.0.0", Group: "bf", Complexity: "O(k), where k is the number of hash functions used by the last sub-filter"},
+		&CommandSpec{Name: constant.CMD_BF_MEXISTS, Handler: cmdBFMEXISTS, Arity: -3, Flags: FlagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Summary: "Checks whether one or more items exist in a Bloom Filter", Since: "1.0.0", Group: "bf", Complexity: "O(k * n), where k is the number of hash functions and n is the number of items"},
+	)
+}
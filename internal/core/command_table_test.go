@@ -0,0 +1,90 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func execute(db *core.RedisDB, cmd string, args ...string) string {
+	return string(core.ExecuteCommand(db, &core.Command{Cmd: cmd, Args: args}))
+}
+
+func TestArityValidation(t *testing.T) {
+	db := core.NewRedisDB()
+
+	testCases := []struct {
+		name     string
+		cmd      string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "ttl without key",
+			cmd:      "TTL",
+			expected: "-ERR wrong number of arguments for 'ttl' command\r\n",
+		},
+		{
+			name:     "get with extra argument",
+			cmd:      "GET",
+			args:     []string{"k", "extra"},
+			expected: "-ERR wrong number of arguments for 'get' command\r\n",
+		},
+		{
+			name:     "del needs at least one key",
+			cmd:      "DEL",
+			expected: "-ERR wrong number of arguments for 'del' command\r\n",
+		},
+		{
+			name:     "unknown command",
+			cmd:      "NOPE",
+			args:     []string{"a"},
+			expected: "-ERR unknown command 'NOPE', with args beginning with: 'a' \r\n",
+		},
+		{
+			name:     "variadic command",
+			cmd:      "EXISTS",
+			args:     []string{"a", "b", "c"},
+			expected: ":0\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, execute(db, tc.cmd, tc.args...))
+		})
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	assert.Equal(t, []string{"k"}, core.LookupCommand("set").Keys([]string{"k", "v", "EX", "10"}))
+	assert.Equal(t, []string{"a", "b", "c"}, core.LookupCommand("DEL").Keys([]string{"a", "b", "c"}))
+	assert.Equal(t, []string{"src", "dst"}, core.LookupCommand("RENAME").Keys([]string{"src", "dst"}))
+	assert.Empty(t, core.LookupCommand("PING").Keys([]string{"hello"}))
+	assert.Nil(t, core.LookupCommand("NOPE"))
+}
+
+func TestCommandInfo(t *testing.T) {
+	db := core.NewRedisDB()
+
+	assert.Equal(t, "*1\r\n*10\r\n$3\r\nget\r\n:2\r\n*2\r\n$8\r\nreadonly\r\n$4\r\nfast\r\n:1\r\n:1\r\n:1\r\n*3\r\n$5\r\n@read\r\n$7\r\n@string\r\n$5\r\n@fast\r\n*0\r\n*0\r\n*0\r\n",
+		execute(db, "COMMAND", "INFO", "get"))
+	assert.Equal(t, "*1\r\n$-1\r\n", execute(db, "COMMAND", "INFO", "nope"))
+	assert.Equal(t, "*2\r\n$4\r\nping\r\n*8\r\n$7\r\nsummary\r\n$41\r\nReturns the server's liveliness response.\r\n$5\r\nsince\r\n$5\r\n1.0.0\r\n$5\r\ngroup\r\n$10\r\nconnection\r\n$10\r\ncomplexity\r\n$4\r\nO(1)\r\n",
+		execute(db, "COMMAND", "DOCS", "ping"))
+
+	count := execute(db, "COMMAND", "COUNT")
+	all, err := core.Decode(core.ExecuteCommand(db, &core.Command{Cmd: "COMMAND"}))
+	assert.NoError(t, err)
+	assert.Equal(t, count, string(core.Encode(len(all.([]any)), false)))
+}
+
+func TestCommandCategories(t *testing.T) {
+	assert.Equal(t, []string{"read", "string", "fast"}, core.LookupCommand("GET").Categories())
+	assert.Equal(t, []string{"write", "list", "slow", "blocking"}, core.LookupCommand("BLPOP").Categories())
+	assert.Equal(t, []string{"admin", "dangerous", "slow"}, core.LookupCommand("CONFIG").Categories())
+	assert.Equal(t, []string{"write", "keyspace", "slow"}, core.LookupCommand("DEL").Categories())
+	assert.True(t, core.LookupCommand("PUBLISH").HasCategory("pubsub"))
+	assert.False(t, core.LookupCommand("PUBLISH").HasCategory("write"))
+}
@@ -2,6 +2,7 @@ package data_structure
 
 import (
 	"math"
+	"unsafe"
 
 	"github.com/spaolacci/murmur3"
 )
@@ -105,3 +106,36 @@ func (b *BloomFilter) ExistHash(hashValue HashValue) bool {
 
 	return true
 }
+
+// MarshalBinary encodes the parameters and the bit array for snapshots
+func (b *BloomFilter) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(b.Hashes))
+	buf = appendUvarint(buf, b.Entries)
+	buf = appendFloat(buf, b.Error)
+	buf = appendFloat(buf, b.bitPerEntry)
+	buf = appendUvarint(buf, b.bits)
+	buf = appendUvarint(buf, b.bytes)
+	buf = append(buf, b.bf...)
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the filter with the one encoded by MarshalBinary
+func (b *BloomFilter) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	b.Hashes = int(d.uvarint())
+	b.Entries = d.uvarint()
+	b.Error = d.float()
+	b.bitPerEntry = d.float()
+	b.bits = d.uvarint()
+	b.bytes = d.uvarint()
+	if d.err == nil && (b.bits != b.bytes*8 || b.bits == 0) {
+		return ErrCorrupted
+	}
+	b.bf = append([]uint8(nil), d.bytes(b.bytes)...)
+	return d.finish()
+}
+
+// MemoryUsage is the size of the bit array, a filter does not grow with the entries added
+func (b *BloomFilter) MemoryUsage() int64 {
+	return int64(unsafe.Sizeof(*b)) + int64(cap(b.bf))
+}
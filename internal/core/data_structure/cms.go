@@ -2,6 +2,7 @@ package data_structure
 
 import (
 	"math"
+	"unsafe"
 
 	"github.com/spaolacci/murmur3"
 )
@@ -102,3 +103,43 @@ func (c *CMS) Count(item string) uint64 {
 
 	return minCount
 }
+
+// MarshalBinary encodes the dimensions and the counters for snapshots
+func (c *CMS) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(c.width))
+	buf = appendUvarint(buf, uint64(c.depth))
+	for i := uint32(0); i < c.depth; i++ {
+		for j := uint32(0); j < c.width; j++ {
+			buf = appendUvarint(buf, c.counter[i][j])
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the sketch with the one encoded by MarshalBinary
+func (c *CMS) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	width := d.uvarint()
+	depth := d.uvarint()
+	// every counter takes at least one byte
+	if d.err != nil || width > math.MaxUint32 || depth > math.MaxUint32 || width*depth > uint64(len(d.data)) {
+		return ErrCorrupted
+	}
+
+	*c = *CreateCMS(uint32(width), uint32(depth))
+	for i := uint32(0); i < c.depth; i++ {
+		for j := uint32(0); j < c.width; j++ {
+			c.counter[i][j] = d.uvarint()
+		}
+	}
+	return d.finish()
+}
+
+// MemoryUsage is the size of the counters, a sketch does not grow with the items counted
+func (c *CMS) MemoryUsage() int64 {
+	size := int64(unsafe.Sizeof(*c)) + int64(len(c.counter))*SliceHeaderSize
+	for _, row := range c.counter {
+		size += int64(cap(row)) * int64(unsafe.Sizeof(uint64(0)))
+	}
+	return size
+}
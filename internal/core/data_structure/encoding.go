@@ -0,0 +1,94 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrCorrupted is returned when a serialized value cannot be decoded
+var ErrCorrupted = errors.New("corrupted serialized value")
+
+// The helpers below build the binary form used by snapshots:
+// integers are uvarints, floats are their IEEE 754 bits and strings are length prefixed.
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendFloat(buf []byte, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// decoder reads values in the order they were appended, the first error sticks
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrCorrupted
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) < 8 {
+		d.err = ErrCorrupted
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.data))
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.data)) < n {
+		d.err = ErrCorrupted
+		return nil
+	}
+	v := d.data[:n]
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
+
+// count reads a number of elements, each element takes at least minSize bytes
+// so a corrupted count cannot make us allocate a huge slice
+func (d *decoder) count(minSize int) int {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.data)/minSize) {
+		d.err = ErrCorrupted
+		return 0
+	}
+	return int(n)
+}
+
+// finish fails if bytes are left over
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.err = ErrCorrupted
+	}
+	return d.err
+}
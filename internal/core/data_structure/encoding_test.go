@@ -0,0 +1,109 @@
+package data_structure
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleSetBinaryRoundTrip(t *testing.T) {
+	s := NewSimpleSet()
+	s.Add("a", "b", "")
+
+	data, err := s.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := NewSimpleSet()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.ElementsMatch(t, []string{"a", "b", ""}, decoded.Members())
+}
+
+func TestZSetBinaryRoundTrip(t *testing.T) {
+	zs := NewZSet()
+	zs.Add(2.5, "b")
+	zs.Add(-1, "a")
+	zs.Add(10, "c")
+
+	data, err := zs.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := NewZSet()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	for _, elm := range []string{"a", "b", "c"} {
+		score, _ := zs.GetScore(elm)
+		decodedScore, ok := decoded.GetScore(elm)
+		assert.True(t, ok)
+		assert.Equal(t, score, decodedScore)
+		rank, _ := zs.GetRank(elm, false)
+		decodedRank, _ := decoded.GetRank(elm, false)
+		assert.Equal(t, rank, decodedRank)
+	}
+}
+
+func TestCMSBinaryRoundTrip(t *testing.T) {
+	cms := CreateCMS(100, 4)
+	cms.IncrBy("a", 3)
+	cms.IncrBy("b", 1)
+
+	data, err := cms.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := &CMS{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.EqualValues(t, 3, decoded.Count("a"))
+	assert.EqualValues(t, 1, decoded.Count("b"))
+	assert.EqualValues(t, 0, decoded.Count("c"))
+}
+
+func TestBloomBinaryRoundTrip(t *testing.T) {
+	b := CreateBloomFilter(100, 0.01)
+	b.Add("a")
+
+	data, err := b.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := &BloomFilter{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.True(t, decoded.Exist("a"))
+	assert.False(t, decoded.Exist("b"))
+	assert.Equal(t, b.Entries, decoded.Entries)
+	assert.Equal(t, b.Error, decoded.Error)
+}
+
+func TestUnmarshalCorrupted(t *testing.T) {
+	zs := NewZSet()
+	zs.Add(1, "a")
+	data, _ := zs.MarshalBinary()
+
+	assert.ErrorIs(t, NewZSet().UnmarshalBinary(data[:len(data)-1]), ErrCorrupted)
+	assert.ErrorIs(t, NewSimpleSet().UnmarshalBinary([]byte{0xff}), ErrCorrupted)
+	assert.ErrorIs(t, (&CMS{}).UnmarshalBinary(append(data, 0)), ErrCorrupted)
+}
+
+func TestHashBinaryRoundTrip(t *testing.T) {
+	h := NewHash()
+	h.Set("a", "1")
+	h.Set("b", "")
+
+	data, err := h.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := NewHash()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.ElementsMatch(t, h.Entries(), decoded.Entries())
+}
+
+func TestQuickListBinaryRoundTrip(t *testing.T) {
+	ql := NewQuickList()
+	for i := 0; i < 300; i++ {
+		ql.PushBack(strconv.Itoa(i))
+	}
+
+	data, err := ql.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := NewQuickList()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, quickListElements(ql), quickListElements(decoded))
+}
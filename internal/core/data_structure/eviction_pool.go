@@ -7,16 +7,18 @@ import (
 )
 
 type EvictionCandidate struct {
-	key            string
-	lastAccessTime uint32
+	key string
+	// idle ranks the candidates, the key with the highest idle is evicted first:
+	// the milliseconds since the last access for LRU, 255 minus the access counter for LFU
+	idle uint64
 }
 
 func (ec *EvictionCandidate) Key() string {
 	return ec.key
 }
 
-func (ec *EvictionCandidate) LastAccessTime() uint32 {
-	return ec.lastAccessTime
+func (ec *EvictionCandidate) Idle() uint64 {
+	return ec.idle
 }
 
 type EvictionPool struct {
@@ -25,7 +27,7 @@ type EvictionPool struct {
 
 func NewEpool(n int) *EvictionPool {
 	return &EvictionPool{
-		pool: make([]*EvictionCandidate, n),
+		pool: make([]*EvictionCandidate, 0, n),
 	}
 }
 
@@ -33,27 +35,27 @@ func (e *EvictionPool) Pool() []*EvictionCandidate {
 	return e.pool
 }
 
-// ByLastAccessTime used for sort the pool.
-type ByLastAccessTime []*EvictionCandidate
+// ByIdle used for sort the pool.
+type ByIdle []*EvictionCandidate
 
-func (b ByLastAccessTime) Len() int {
+func (b ByIdle) Len() int {
 	return len(b)
 }
 
-func (b ByLastAccessTime) Swap(i, j int) {
+func (b ByIdle) Swap(i, j int) {
 	b[i], b[j] = b[j], b[i]
 }
 
-func (b ByLastAccessTime) Less(i, j int) bool {
-	return b[i].lastAccessTime < b[j].lastAccessTime
+func (b ByIdle) Less(i, j int) bool {
+	return b[i].idle > b[j].idle
 }
 
-// Push add a new item to the pool, maintains the lassAccessTime accenting order (old items are on the left).
-// If pool size > EpoolMaxSize, remove the newest item.
-func (p *EvictionPool) Push(key string, lastAccessTime uint32) {
+// Push add a new item to the pool, maintains the idle descending order (best candidates are on the left).
+// If pool size > EpoolMaxSize, remove the worst candidate.
+func (p *EvictionPool) Push(key string, idle uint64) {
 	newItem := &EvictionCandidate{
-		key:            key,
-		lastAccessTime: lastAccessTime,
+		key:  key,
+		idle: idle,
 	}
 
 	// Note: In redis implementation, it does not explicity check if a key is already in the eviction pool
@@ -65,7 +67,7 @@ func (p *EvictionPool) Push(key string, lastAccessTime uint32) {
 	for i := 0; i < len(p.pool); i++ {
 		if p.pool[i].key == key {
 			exist = true
-			p.pool[i].lastAccessTime = lastAccessTime
+			p.pool[i].idle = idle
 		}
 	}
 
@@ -73,29 +75,21 @@ func (p *EvictionPool) Push(key string, lastAccessTime uint32) {
 		p.pool = append(p.pool, newItem)
 	}
 
-	sort.Sort(ByLastAccessTime(p.pool))
+	sort.Sort(ByIdle(p.pool))
 	if len(p.pool) > config.EpoolMaxSize {
 		lastIndex := len(p.pool) - 1
 		p.pool = p.pool[:lastIndex]
 	}
 }
 
-// Remove the oldest item in the pool.
+// Remove the best candidate in the pool.
 func (p *EvictionPool) Pop() *EvictionCandidate {
 	if len(p.pool) == 0 {
 		return nil
 	}
 
-	oldestItem := p.pool[0]
+	bestItem := p.pool[0]
 	p.pool = p.pool[1:]
 
-	return oldestItem
+	return bestItem
 }
-
-func newEpool(n int) *EvictionPool {
-	return &EvictionPool{
-		pool: make([]*EvictionCandidate, n),
-	}
-}
-
-var epool *EvictionPool = newEpool(0)
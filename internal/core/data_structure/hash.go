@@ -0,0 +1,88 @@
+package data_structure
+
+// Hash maps fields to string values
+type Hash struct {
+	table *HashTable[string]
+}
+
+func NewHash() *Hash {
+	return &Hash{
+		table: NewHashTable[string](),
+	}
+}
+
+// Set sets the field, it returns true when the field is new
+func (h *Hash) Set(field string, value string) bool {
+	return h.table.Set(field, value)
+}
+
+func (h *Hash) Get(field string) (string, bool) {
+	return h.table.Get(field)
+}
+
+func (h *Hash) Delete(fields ...string) int {
+	deleted := 0
+	for _, field := range fields {
+		if h.table.Delete(field) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+func (h *Hash) Len() int {
+	return h.table.Len()
+}
+
+// Entries returns field1, value1, field2, value2...
+func (h *Hash) Entries() []string {
+	entries := make([]string, 0, 2*h.table.Len())
+	h.table.Range(func(field string, value string) bool {
+		entries = append(entries, field, value)
+		return true
+	})
+
+	return entries
+}
+
+// Scan calls fn for some fields and returns the next cursor, see HashTable.Scan
+func (h *Hash) Scan(cursor uint64, fn func(field string, value string)) uint64 {
+	return h.table.Scan(cursor, fn)
+}
+
+// Random returns a random field and its value, the hash must not be empty
+func (h *Hash) Random() (string, string) {
+	field, value, _ := h.table.Random()
+	return field, value
+}
+
+// MarshalBinary encodes the fields and values for snapshots
+func (h *Hash) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(h.table.Len()))
+	h.table.Range(func(field string, value string) bool {
+		buf = appendString(buf, field)
+		buf = appendString(buf, value)
+		return true
+	})
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the fields with the ones encoded by MarshalBinary
+func (h *Hash) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	n := d.count(2)
+	h.table = NewHashTable[string]()
+	for i := 0; i < n; i++ {
+		field := d.string()
+		h.table.Set(field, d.string())
+	}
+	return d.finish()
+}
+
+// MemoryUsage estimates the bytes used by the fields and their values
+func (h *Hash) MemoryUsage(samples int) int64 {
+	return PointerSize + h.table.MemoryUsage(samples, func(value string) int64 {
+		return int64(len(value))
+	})
+}
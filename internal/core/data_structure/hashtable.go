@@ -0,0 +1,265 @@
+package data_structure
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+	"unsafe"
+)
+
+const hashTableInitSize = 4
+
+// hashTableRehashEmptyVisits bounds the empty buckets visited by a rehash step, like redis
+const hashTableRehashEmptyVisits = 10
+
+type hashEntry[V any] struct {
+	key   string
+	value V
+	next  *hashEntry[V]
+}
+
+// HashTable is a chained hash table with power of two buckets, like the redis dict.
+// Unlike a Go map it supports a SCAN cursor that stays valid when the table grows or shrinks
+// between calls, and picking a random entry in O(1).
+// It is resized incrementally: a resize allocates a second table and every write moves a bucket
+// of the old table to it, so no single operation rehashes all the entries.
+type HashTable[V any] struct {
+	// tables[1] only exists during a resize, the buckets of tables[0] before rehashIdx are moved to it
+	tables    [2][]*hashEntry[V]
+	rehashIdx int
+	size      int
+	seed      maphash.Seed
+}
+
+func NewHashTable[V any]() *HashTable[V] {
+	return &HashTable[V]{
+		tables:    [2][]*hashEntry[V]{make([]*hashEntry[V], hashTableInitSize)},
+		rehashIdx: -1,
+		seed:      maphash.MakeSeed(),
+	}
+}
+
+func (ht *HashTable[V]) isRehashing() bool {
+	return ht.rehashIdx >= 0
+}
+
+func (ht *HashTable[V]) hash(key string) uint64 {
+	return maphash.String(ht.seed, key)
+}
+
+func mask[V any](table []*hashEntry[V]) uint64 {
+	return uint64(len(table) - 1)
+}
+
+func (ht *HashTable[V]) Len() int {
+	return ht.size
+}
+
+// find returns the entry of key with the table and the bucket holding it, e is nil when the key does not exist
+func (ht *HashTable[V]) find(key string) (e *hashEntry[V], table int, idx uint64) {
+	hash := ht.hash(key)
+	for table = 0; table < 2; table++ {
+		if table == 1 && !ht.isRehashing() {
+			break
+		}
+		idx = hash & mask(ht.tables[table])
+		for e = ht.tables[table][idx]; e != nil; e = e.next {
+			if e.key == key {
+				return e, table, idx
+			}
+		}
+	}
+	return nil, 0, 0
+}
+
+func (ht *HashTable[V]) Get(key string) (V, bool) {
+	if e, _, _ := ht.find(key); e != nil {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set adds or updates the key, it returns true when the key was added
+func (ht *HashTable[V]) Set(key string, value V) bool {
+	ht.rehashStep()
+	if e, _, _ := ht.find(key); e != nil {
+		e.value = value
+		return false
+	}
+
+	// during a resize new entries go to the new table, the old one only empties
+	table := ht.tables[0]
+	if ht.isRehashing() {
+		table = ht.tables[1]
+	}
+	idx := ht.hash(key) & mask(table)
+	table[idx] = &hashEntry[V]{key: key, value: value, next: table[idx]}
+	ht.size++
+	ht.resizeIfNeeded()
+	return true
+}
+
+// Delete removes the key, it returns false when the key does not exist
+func (ht *HashTable[V]) Delete(key string) bool {
+	ht.rehashStep()
+	e, t, idx := ht.find(key)
+	if e == nil {
+		return false
+	}
+	table := ht.tables[t]
+	if table[idx] == e {
+		table[idx] = e.next
+	} else {
+		prev := table[idx]
+		for prev.next != e {
+			prev = prev.next
+		}
+		prev.next = e.next
+	}
+	ht.size--
+	ht.resizeIfNeeded()
+	return true
+}
+
+// resizeIfNeeded starts a resize when there are more entries than buckets, or below 10% usage like redis
+func (ht *HashTable[V]) resizeIfNeeded() {
+	if ht.isRehashing() {
+		return
+	}
+	n := len(ht.tables[0])
+	if ht.size > n || (n > hashTableInitSize && ht.size*10 < n) {
+		ht.startRehash(max(hashTableInitSize, 1<<bits.Len(uint(ht.size))))
+	}
+}
+
+// startRehash allocates the table of n buckets the entries are moved to by the following writes
+func (ht *HashTable[V]) startRehash(n int) {
+	ht.tables[1] = make([]*hashEntry[V], n)
+	ht.rehashIdx = 0
+}
+
+// rehashStep moves the next non empty bucket of the old table to the new one during a resize,
+// and replaces the old table once it is empty
+func (ht *HashTable[V]) rehashStep() {
+	if !ht.isRehashing() {
+		return
+	}
+	old, table := ht.tables[0], ht.tables[1]
+	for visits := 0; ht.rehashIdx < len(old) && old[ht.rehashIdx] == nil; visits++ {
+		if visits == hashTableRehashEmptyVisits {
+			return
+		}
+		ht.rehashIdx++
+	}
+	if ht.rehashIdx < len(old) {
+		for e := old[ht.rehashIdx]; e != nil; {
+			next := e.next
+			idx := ht.hash(e.key) & mask(table)
+			e.next = table[idx]
+			table[idx] = e
+			e = next
+		}
+		old[ht.rehashIdx] = nil
+		ht.rehashIdx++
+	}
+	if ht.rehashIdx == len(old) {
+		ht.tables = [2][]*hashEntry[V]{table}
+		ht.rehashIdx = -1
+		// the entries changed meanwhile may call for another resize
+		ht.resizeIfNeeded()
+	}
+}
+
+// Range calls fn for every entry until it returns false, the table must not be modified meanwhile
+func (ht *HashTable[V]) Range(fn func(key string, value V) bool) {
+	for _, table := range ht.tables {
+		for _, e := range table {
+			for ; e != nil; e = e.next {
+				if !fn(e.key, e.value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Scan calls fn for the entries of the bucket at cursor and returns the next cursor, 0 when done.
+// The cursor is incremented on its reversed bits, so the buckets already visited map to
+// buckets before the cursor after a resize: every entry present during the whole scan
+// is returned at least once, some may be returned twice.
+// During a resize the bucket of the smaller table is visited with all the buckets of the larger one
+// it expands to, like redis.
+func (ht *HashTable[V]) Scan(cursor uint64, fn func(key string, value V)) uint64 {
+	emit := func(e *hashEntry[V]) {
+		for ; e != nil; e = e.next {
+			fn(e.key, e.value)
+		}
+	}
+	if !ht.isRehashing() {
+		m := mask(ht.tables[0])
+		emit(ht.tables[0][cursor&m])
+		return nextCursor(cursor, m)
+	}
+
+	small, large := ht.tables[0], ht.tables[1]
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	m0, m1 := mask(small), mask(large)
+	emit(small[cursor&m0])
+	// the buckets of the larger table whose low bits are the bucket of the smaller one
+	for {
+		emit(large[cursor&m1])
+		cursor = nextCursor(cursor, m1)
+		if cursor&(m0^m1) == 0 {
+			return cursor
+		}
+	}
+}
+
+// nextCursor increments the reversed bits of cursor masked by m
+func nextCursor(cursor, m uint64) uint64 {
+	// set the bits above the mask so incrementing the reversed cursor carries into the masked bits
+	cursor |= ^m
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// Random returns a random entry, ok is false when the table is empty.
+// Entries in long chains are a bit less likely to be picked, like in redis.
+func (ht *HashTable[V]) Random() (key string, value V, ok bool) {
+	if ht.size == 0 {
+		return "", value, false
+	}
+
+	// during a resize the buckets of both tables are candidates, the moved ones are empty
+	n0 := len(ht.tables[0])
+	var e *hashEntry[V]
+	for e == nil {
+		if idx := rand.Intn(n0 + len(ht.tables[1])); idx < n0 {
+			e = ht.tables[0][idx]
+		} else {
+			e = ht.tables[1][idx-n0]
+		}
+	}
+	n := 0
+	for c := e; c != nil; c = c.next {
+		n++
+	}
+	for i := rand.Intn(n); i > 0; i-- {
+		e = e.next
+	}
+	return e.key, e.value, true
+}
+
+// MemoryUsage estimates the bytes used by the table and its entries, valueSize gives the size of a value
+func (ht *HashTable[V]) MemoryUsage(samples int, valueSize func(V) int64) int64 {
+	size := int64(unsafe.Sizeof(*ht)) + int64(len(ht.tables[0])+len(ht.tables[1]))*PointerSize
+	entries := newSampler(ht.size, samples)
+	ht.Range(func(key string, value V) bool {
+		return entries.add(int64(unsafe.Sizeof(hashEntry[V]{})) + int64(len(key)) + valueSize(value))
+	})
+	return size + entries.total()
+}
@@ -0,0 +1,157 @@
+package data_structure
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashTableSetGetDelete(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 1000; i++ {
+		assert.True(t, ht.Set(strconv.Itoa(i), i))
+	}
+	assert.False(t, ht.Set("10", -10))
+	assert.Equal(t, 1000, ht.Len())
+
+	v, ok := ht.Get("10")
+	assert.True(t, ok)
+	assert.Equal(t, -10, v)
+
+	for i := 0; i < 990; i++ {
+		assert.True(t, ht.Delete(strconv.Itoa(i)))
+	}
+	assert.False(t, ht.Delete("0"))
+	assert.Equal(t, 10, ht.Len())
+	// the table shrinks once it is mostly empty, the entries are moved by the following writes
+	for ht.isRehashing() {
+		ht.Delete("missing")
+	}
+	assert.LessOrEqual(t, len(ht.tables[0]), 16)
+	v, ok = ht.Get("999")
+	assert.True(t, ok)
+	assert.Equal(t, 999, v)
+}
+
+func TestHashTableIncrementalRehash(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; !ht.isRehashing(); i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+	// the first writes after growing the table leave most entries in the old table
+	for i := 0; i < 1000; i++ {
+		ht.Set(strconv.Itoa(i), i)
+		if i == 0 {
+			assert.True(t, ht.isRehashing())
+			assert.Less(t, ht.rehashIdx, len(ht.tables[0]))
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		v, ok := ht.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+
+	n := 0
+	ht.Range(func(string, int) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 1000, n)
+
+	// an update during a resize does not add the key twice
+	for i := 0; i < 2000 && !ht.isRehashing(); i++ {
+		ht.Set(strconv.Itoa(1000+i), i)
+	}
+	assert.True(t, ht.isRehashing())
+	assert.False(t, ht.Set("0", -1))
+	assert.True(t, ht.Delete("0"))
+	_, ok := ht.Get("0")
+	assert.False(t, ok)
+}
+
+func TestHashTableScanWhileRehashing(t *testing.T) {
+	for _, grow := range []bool{true, false} {
+		ht := NewHashTable[int]()
+		for i := 0; i < 1000; i++ {
+			ht.Set(strconv.Itoa(i), i)
+		}
+		if grow {
+			for i := 1000; !ht.isRehashing(); i++ {
+				ht.Set(strconv.Itoa(i), i)
+			}
+		} else {
+			for i := 999; !ht.isRehashing(); i-- {
+				ht.Delete(strconv.Itoa(i))
+			}
+		}
+
+		// the resize is still in progress when the scan ends
+		seen := make(map[string]bool)
+		var cursor uint64
+		for {
+			cursor = ht.Scan(cursor, func(key string, _ int) {
+				seen[key] = true
+			})
+			if cursor == 0 {
+				break
+			}
+		}
+		assert.True(t, ht.isRehashing())
+		assert.Len(t, seen, ht.Len())
+	}
+}
+
+func TestHashTableScanAcrossResize(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 100; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+
+	seen := make(map[string]bool)
+	var cursor uint64
+	steps := 0
+	for {
+		cursor = ht.Scan(cursor, func(key string, _ int) {
+			seen[key] = true
+		})
+		steps++
+		// grow then shrink the table while scanning, keys 0-99 stay put
+		switch steps {
+		case 5:
+			for i := 100; i < 1000; i++ {
+				ht.Set(strconv.Itoa(i), i)
+			}
+		case 50:
+			for i := 100; i < 1000; i++ {
+				ht.Delete(strconv.Itoa(i))
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, seen[strconv.Itoa(i)], "key %d not returned by the scan", i)
+	}
+}
+
+func TestHashTableRandom(t *testing.T) {
+	ht := NewHashTable[int]()
+	_, _, ok := ht.Random()
+	assert.False(t, ok)
+
+	for i := 0; i < 10; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key, value, ok := ht.Random()
+		assert.True(t, ok)
+		assert.Equal(t, key, strconv.Itoa(value))
+		seen[key] = true
+	}
+	assert.Len(t, seen, 10)
+}
@@ -0,0 +1,50 @@
+package data_structure
+
+import (
+	"math/rand"
+	"time"
+)
+
+// The LFU access counter is a Morris counter like in redis: it is 8 bits and it is incremented
+// with a probability that gets lower as it grows, so 255 is reached after about a million hits
+// with a log factor of 10. It is decremented as time passes, so keys that were hot only once are evicted eventually.
+// Ref: https://github.com/redis/redis/blob/unstable/src/evict.c
+
+// LFUInitVal is the counter of new keys, so they are not evicted before they have a chance to be accessed
+const LFUInitVal = 5
+
+// LFULogIncr increments the counter with a probability of 1/((counter-LFUInitVal)*logFactor+1)
+func LFULogIncr(counter uint8, logFactor int) uint8 {
+	if counter == 255 {
+		return 255
+	}
+	baseval := max(float64(counter)-LFUInitVal, 0)
+	p := 1.0 / (baseval*float64(logFactor) + 1)
+	if rand.Float64() < p {
+		counter++
+	}
+	return counter
+}
+
+// LFUTimeInMinutes is the current time in minutes on 16 bits, it wraps around every 45 days
+func LFUTimeInMinutes(now time.Time) uint16 {
+	return uint16(now.Unix() / 60)
+}
+
+// LFUTimeElapsed is the number of minutes since ldt, a wrap around is assumed to happen at most once
+func LFUTimeElapsed(ldt uint16, now uint16) uint64 {
+	// the subtraction wraps around like the minutes
+	return uint64(now - ldt)
+}
+
+// LFUDecr decrements the counter by one for every decayTime minutes elapsed, decayTime 0 never decrements
+func LFUDecr(counter uint8, elapsedMinutes uint64, decayTime int) uint8 {
+	if decayTime <= 0 {
+		return counter
+	}
+	periods := elapsedMinutes / uint64(decayTime)
+	if periods >= uint64(counter) {
+		return 0
+	}
+	return counter - uint8(periods)
+}
@@ -0,0 +1,48 @@
+package data_structure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLFULogIncr(t *testing.T) {
+	// below the initial value every hit counts
+	assert.Equal(t, uint8(1), LFULogIncr(0, 10))
+	assert.Equal(t, uint8(LFUInitVal+1), LFULogIncr(LFUInitVal, 10))
+	assert.Equal(t, uint8(255), LFULogIncr(255, 10))
+
+	// the counter grows logarithmically with the hits
+	counter := uint8(LFUInitVal)
+	for i := 0; i < 1000; i++ {
+		counter = LFULogIncr(counter, 10)
+	}
+	assert.Greater(t, counter, uint8(LFUInitVal+5))
+	assert.Less(t, counter, uint8(LFUInitVal+50))
+
+	after := counter
+	for i := 0; i < 100000; i++ {
+		after = LFULogIncr(after, 10)
+	}
+	assert.Greater(t, after, counter)
+	assert.Less(t, after, uint8(255))
+
+	// a log factor of 0 counts every hit
+	assert.Equal(t, uint8(101), LFULogIncr(100, 0))
+}
+
+func TestLFUDecay(t *testing.T) {
+	assert.Equal(t, uint8(10), LFUDecr(10, 0, 1))
+	assert.Equal(t, uint8(7), LFUDecr(10, 3, 1))
+	assert.Equal(t, uint8(9), LFUDecr(10, 3, 2))
+	assert.Equal(t, uint8(0), LFUDecr(10, 30, 1))
+	assert.Equal(t, uint8(10), LFUDecr(10, 30, 0))
+
+	now := time.Unix(1_000_000*60, 0)
+	ldt := LFUTimeInMinutes(now)
+	assert.Equal(t, uint64(0), LFUTimeElapsed(ldt, LFUTimeInMinutes(now)))
+	assert.Equal(t, uint64(5), LFUTimeElapsed(ldt, LFUTimeInMinutes(now.Add(5*time.Minute))))
+	// the minutes wrap around on 16 bits
+	assert.Equal(t, uint64(11), LFUTimeElapsed(65530, 5))
+}
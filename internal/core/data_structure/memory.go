@@ -0,0 +1,51 @@
+package data_structure
+
+// Memory usage is estimated from the sizes of the Go values on a 64-bit platform, it ignores the
+// allocator rounding and the garbage not collected yet, so it is a lower bound like redis used_memory.
+// Large collections are sampled like redis MEMORY USAGE: the size of a few elements
+// is extrapolated to the whole collection, samples 0 means every element.
+const (
+	PointerSize      = 8
+	StringHeaderSize = 16
+	SliceHeaderSize  = 24
+	// a Go map entry also costs its tophash byte and the unused slots of buckets,
+	// a bucket holds 8 entries and is about 80% full before growing
+	mapEntryOverhead = 4
+	// hmap header
+	mapHeaderSize = 48
+)
+
+// StringSize is the size of a string header and its bytes
+func StringSize(s string) int64 {
+	return StringHeaderSize + int64(len(s))
+}
+
+// sampler sums the sizes of the first samples elements of a collection of n elements
+// and extrapolates the total, samples 0 sums every element
+type sampler struct {
+	n       int
+	samples int
+	seen    int
+	sum     int64
+}
+
+func newSampler(n int, samples int) *sampler {
+	if samples <= 0 || samples > n {
+		samples = n
+	}
+	return &sampler{n: n, samples: samples}
+}
+
+// add counts the size of one element, it returns false once enough elements are sampled
+func (s *sampler) add(size int64) bool {
+	s.sum += size
+	s.seen++
+	return s.seen < s.samples
+}
+
+func (s *sampler) total() int64 {
+	if s.seen == 0 {
+		return 0
+	}
+	return s.sum * int64(s.n) / int64(s.seen)
+}
@@ -0,0 +1,97 @@
+package data_structure
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryUser interface {
+	MemoryUsage(samples int) int64
+}
+
+func TestMemoryUsageGrows(t *testing.T) {
+	value := strings.Repeat("v", 100)
+	for name, fill := range map[string]func(n int) memoryUser{
+		"hash": func(n int) memoryUser {
+			h := NewHash()
+			for i := 0; i < n; i++ {
+				h.Set(strconv.Itoa(i), value)
+			}
+			return h
+		},
+		"set": func(n int) memoryUser {
+			s := NewSimpleSet()
+			for i := 0; i < n; i++ {
+				s.Add(value + strconv.Itoa(i))
+			}
+			return s
+		},
+		"zset": func(n int) memoryUser {
+			zs := NewZSet()
+			for i := 0; i < n; i++ {
+				zs.Add(float64(i), value+strconv.Itoa(i))
+			}
+			return zs
+		},
+		"list": func(n int) memoryUser {
+			ql := NewQuickList()
+			for i := 0; i < n; i++ {
+				ql.PushBack(value)
+			}
+			return ql
+		},
+	} {
+		empty := fill(0).MemoryUsage(0)
+		small := fill(10).MemoryUsage(0)
+		large := fill(1000).MemoryUsage(0)
+		assert.Greater(t, empty, int64(0), name)
+		// every element holds at least its 100 bytes
+		assert.GreaterOrEqual(t, small-empty, int64(10*100), name)
+		assert.GreaterOrEqual(t, large-empty, int64(1000*100), name)
+		// and not much more than the bytes of its strings and a few pointers
+		assert.Less(t, large, int64(1000*(100+200)), name)
+
+		// elements have about the same size, sampling a few of them gives about the same estimate
+		sampled := fill(1000).MemoryUsage(5)
+		assert.InEpsilon(t, large, sampled, 0.2, name)
+	}
+}
+
+func TestMemoryUsageFixedSize(t *testing.T) {
+	cms := CreateCMS(1000, 5)
+	size := cms.MemoryUsage()
+	assert.GreaterOrEqual(t, size, int64(1000*5*8))
+	cms.IncrBy("a", 10)
+	assert.Equal(t, size, cms.MemoryUsage())
+
+	small := CreateBloomFilter(100, 0.01).MemoryUsage()
+	large := CreateBloomFilter(10000, 0.01).MemoryUsage()
+	// about 9.6 bits per entry for a 1% error rate
+	assert.GreaterOrEqual(t, large, int64(10000*9/8))
+	assert.Greater(t, large, small)
+}
+
+func TestQuickListNodeCount(t *testing.T) {
+	ql := NewQuickList()
+	for i := 0; i < 10*quickListNodeSize; i++ {
+		ql.PushBack(strconv.Itoa(i))
+	}
+	for i := 0; i < quickListNodeSize; i++ {
+		ql.PushFront(strconv.Itoa(i))
+	}
+	assert.Equal(t, 11, ql.nodes)
+
+	ql.Trim(quickListNodeSize+1, 5*quickListNodeSize)
+	n := 0
+	for node := ql.head; node != nil; node = node.next {
+		n++
+	}
+	assert.Equal(t, n, ql.nodes)
+
+	ql.Trim(1, 0)
+	assert.Equal(t, 0, ql.nodes)
+	assert.Equal(t, int64(0), ql.MemoryUsage(0)-NewQuickList().MemoryUsage(0))
+}
@@ -0,0 +1,391 @@
+package data_structure
+
+import "unsafe"
+
+// quickListNodeSize is the maximum number of elements of a node, redis limits nodes to 8kb by default
+const quickListNodeSize = 128
+
+type quickListNode struct {
+	entries []string
+	prev    *quickListNode
+	next    *quickListNode
+}
+
+// QuickList is a doubly linked list of small arrays, like the redis quicklist.
+// Pushing and popping at both ends is O(1), and compared to a linked list of elements
+// it needs far fewer pointers and keeps neighbouring elements close in memory.
+// Indexes are 0-based from the head, callers convert negative indexes.
+type QuickList struct {
+	head *quickListNode
+	tail *quickListNode
+	size int
+	// nodes is the number of nodes, for MemoryUsage
+	nodes int
+}
+
+func NewQuickList() *QuickList {
+	return &QuickList{}
+}
+
+func (ql *QuickList) Len() int {
+	return ql.size
+}
+
+// PushFront inserts the values at the head one after the other, so the last one ends up first
+func (ql *QuickList) PushFront(values ...string) {
+	for _, value := range values {
+		if ql.head == nil || len(ql.head.entries) == quickListNodeSize {
+			ql.linkBefore(ql.head, &quickListNode{entries: make([]string, 0, 4)})
+		}
+		node := ql.head
+		node.entries = append(node.entries, "")
+		copy(node.entries[1:], node.entries)
+		node.entries[0] = value
+		ql.size++
+	}
+}
+
+// PushBack appends the values at the tail
+func (ql *QuickList) PushBack(values ...string) {
+	for _, value := range values {
+		if ql.tail == nil || len(ql.tail.entries) == quickListNodeSize {
+			ql.linkAfter(ql.tail, &quickListNode{entries: make([]string, 0, 4)})
+		}
+		ql.tail.entries = append(ql.tail.entries, value)
+		ql.size++
+	}
+}
+
+// PopFront removes and returns the head, ok is false when the list is empty
+func (ql *QuickList) PopFront() (string, bool) {
+	if ql.size == 0 {
+		return "", false
+	}
+	node := ql.head
+	value := node.entries[0]
+	node.entries[0] = ""
+	node.entries = node.entries[1:]
+	ql.size--
+	if len(node.entries) == 0 {
+		ql.unlink(node)
+	}
+	return value, true
+}
+
+// PopBack removes and returns the tail, ok is false when the list is empty
+func (ql *QuickList) PopBack() (string, bool) {
+	if ql.size == 0 {
+		return "", false
+	}
+	node := ql.tail
+	last := len(node.entries) - 1
+	value := node.entries[last]
+	node.entries[last] = ""
+	node.entries = node.entries[:last]
+	ql.size--
+	if len(node.entries) == 0 {
+		ql.unlink(node)
+	}
+	return value, true
+}
+
+// locate returns the node holding the element at index and its offset in the node,
+// walking from the closest end
+func (ql *QuickList) locate(index int) (*quickListNode, int) {
+	if index < 0 || index >= ql.size {
+		return nil, 0
+	}
+	if index < ql.size/2 {
+		node := ql.head
+		for index >= len(node.entries) {
+			index -= len(node.entries)
+			node = node.next
+		}
+		return node, index
+	}
+
+	node := ql.tail
+	index = ql.size - 1 - index
+	for index >= len(node.entries) {
+		index -= len(node.entries)
+		node = node.prev
+	}
+	return node, len(node.entries) - 1 - index
+}
+
+// Index returns the element at index, ok is false when index is out of range
+func (ql *QuickList) Index(index int) (string, bool) {
+	node, offset := ql.locate(index)
+	if node == nil {
+		return "", false
+	}
+	return node.entries[offset], true
+}
+
+// Set replaces the element at index, it returns false when index is out of range
+func (ql *QuickList) Set(index int, value string) bool {
+	node, offset := ql.locate(index)
+	if node == nil {
+		return false
+	}
+	node.entries[offset] = value
+	return true
+}
+
+// Range returns the elements from start to stop included, both must be in range
+func (ql *QuickList) Range(start int, stop int) []string {
+	if start > stop {
+		return []string{}
+	}
+	res := make([]string, 0, stop-start+1)
+	node, offset := ql.locate(start)
+	for node != nil && len(res) < cap(res) {
+		end := min(len(node.entries), offset+cap(res)-len(res))
+		res = append(res, node.entries[offset:end]...)
+		node, offset = node.next, 0
+	}
+	return res
+}
+
+// Iterate calls fn with the index and value of each element until it returns false,
+// from the tail to the head when reverse is set
+func (ql *QuickList) Iterate(reverse bool, fn func(index int, value string) bool) {
+	if !reverse {
+		index := 0
+		for node := ql.head; node != nil; node = node.next {
+			for _, value := range node.entries {
+				if !fn(index, value) {
+					return
+				}
+				index++
+			}
+		}
+		return
+	}
+
+	index := ql.size - 1
+	for node := ql.tail; node != nil; node = node.prev {
+		for i := len(node.entries) - 1; i >= 0; i-- {
+			if !fn(index, node.entries[i]) {
+				return
+			}
+			index--
+		}
+	}
+}
+
+// Insert inserts value before or after the first occurrence of pivot,
+// it returns false when pivot is not in the list
+func (ql *QuickList) Insert(pivot string, value string, after bool) bool {
+	for node := ql.head; node != nil; node = node.next {
+		for i, entry := range node.entries {
+			if entry != pivot {
+				continue
+			}
+			if after {
+				i++
+			}
+			ql.insertAt(node, i, value)
+			return true
+		}
+	}
+	return false
+}
+
+// insertAt inserts value at offset in node, a full node is split in two halves
+func (ql *QuickList) insertAt(node *quickListNode, offset int, value string) {
+	if len(node.entries) == quickListNodeSize {
+		half := quickListNodeSize / 2
+		newNode := &quickListNode{entries: make([]string, len(node.entries)-half, quickListNodeSize)}
+		copy(newNode.entries, node.entries[half:])
+		clear(node.entries[half:])
+		node.entries = node.entries[:half]
+		ql.linkAfter(node, newNode)
+		if offset > half {
+			node, offset = newNode, offset-half
+		}
+	}
+
+	node.entries = append(node.entries, "")
+	copy(node.entries[offset+1:], node.entries[offset:])
+	node.entries[offset] = value
+	ql.size++
+}
+
+// Remove removes the occurrences of value: the first count ones from the head when count > 0,
+// the first -count ones from the tail when count < 0, or all of them when count is 0.
+// It returns the number of removed elements.
+func (ql *QuickList) Remove(value string, count int) int {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	removed := 0
+	filter := func(node *quickListNode) {
+		kept := node.entries[:0]
+		if count >= 0 {
+			for _, entry := range node.entries {
+				if entry == value && (limit == 0 || removed < limit) {
+					removed++
+					continue
+				}
+				kept = append(kept, entry)
+			}
+		} else {
+			// filter from the end of the node, then move the kept entries back to the front
+			n := len(node.entries)
+			for i := n - 1; i >= 0; i-- {
+				if node.entries[i] == value && removed < limit {
+					removed++
+					continue
+				}
+				n--
+				node.entries[n] = node.entries[i]
+			}
+			kept = node.entries[:copy(node.entries, node.entries[n:])]
+		}
+		clear(node.entries[len(kept):])
+		node.entries = kept
+	}
+
+	node := ql.head
+	if count < 0 {
+		node = ql.tail
+	}
+	for node != nil && (limit == 0 || removed < limit) {
+		next := node.next
+		if count < 0 {
+			next = node.prev
+		}
+		before := len(node.entries)
+		filter(node)
+		ql.size -= before - len(node.entries)
+		if len(node.entries) == 0 {
+			ql.unlink(node)
+		}
+		node = next
+	}
+	return removed
+}
+
+// Trim keeps the elements from start to stop included, the list is emptied when start > stop.
+// Both must be in range unless the list is emptied.
+func (ql *QuickList) Trim(start int, stop int) {
+	if start > stop || start >= ql.size {
+		ql.head, ql.tail, ql.size, ql.nodes = nil, nil, 0, 0
+		return
+	}
+
+	// drop whole nodes at both ends, then the remaining elements of the boundary nodes
+	dropFront := start
+	for dropFront > 0 && len(ql.head.entries) <= dropFront {
+		dropFront -= len(ql.head.entries)
+		ql.size -= len(ql.head.entries)
+		ql.unlink(ql.head)
+	}
+	if dropFront > 0 {
+		clear(ql.head.entries[:dropFront])
+		ql.head.entries = ql.head.entries[dropFront:]
+		ql.size -= dropFront
+	}
+
+	dropBack := ql.size - 1 - (stop - start)
+	for dropBack > 0 && len(ql.tail.entries) <= dropBack {
+		dropBack -= len(ql.tail.entries)
+		ql.size -= len(ql.tail.entries)
+		ql.unlink(ql.tail)
+	}
+	if dropBack > 0 {
+		keep := len(ql.tail.entries) - dropBack
+		clear(ql.tail.entries[keep:])
+		ql.tail.entries = ql.tail.entries[:keep]
+		ql.size -= dropBack
+	}
+}
+
+// linkBefore inserts node before at, or as the only node when at is nil
+func (ql *QuickList) linkBefore(at *quickListNode, node *quickListNode) {
+	ql.nodes++
+	if at == nil {
+		ql.head, ql.tail = node, node
+		return
+	}
+	node.next = at
+	node.prev = at.prev
+	if at.prev != nil {
+		at.prev.next = node
+	} else {
+		ql.head = node
+	}
+	at.prev = node
+}
+
+// linkAfter inserts node after at, or as the only node when at is nil
+func (ql *QuickList) linkAfter(at *quickListNode, node *quickListNode) {
+	ql.nodes++
+	if at == nil {
+		ql.head, ql.tail = node, node
+		return
+	}
+	node.prev = at
+	node.next = at.next
+	if at.next != nil {
+		at.next.prev = node
+	} else {
+		ql.tail = node
+	}
+	at.next = node
+}
+
+func (ql *QuickList) unlink(node *quickListNode) {
+	ql.nodes--
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		ql.head = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		ql.tail = node.prev
+	}
+	node.prev, node.next = nil, nil
+}
+
+// MemoryUsage estimates the bytes used by the nodes and their elements, samples counts nodes
+// rather than elements like the redis quicklist
+func (ql *QuickList) MemoryUsage(samples int) int64 {
+	nodes := newSampler(ql.nodes, samples)
+	for node := ql.head; node != nil; node = node.next {
+		size := int64(unsafe.Sizeof(*node)) + int64(cap(node.entries))*StringHeaderSize
+		for _, value := range node.entries {
+			size += int64(len(value))
+		}
+		if !nodes.add(size) {
+			break
+		}
+	}
+	return int64(unsafe.Sizeof(*ql)) + nodes.total()
+}
+
+// MarshalBinary encodes the elements from head to tail for snapshots
+func (ql *QuickList) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(ql.size))
+	ql.Iterate(false, func(_ int, value string) bool {
+		buf = appendString(buf, value)
+		return true
+	})
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the elements with the ones encoded by MarshalBinary
+func (ql *QuickList) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	n := d.count(1)
+	*ql = QuickList{}
+	for i := 0; i < n; i++ {
+		ql.PushBack(d.string())
+	}
+	return d.finish()
+}
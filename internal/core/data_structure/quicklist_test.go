@@ -0,0 +1,130 @@
+package data_structure
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func quickListElements(ql *QuickList) []string {
+	res := []string{}
+	ql.Iterate(false, func(_ int, value string) bool {
+		res = append(res, value)
+		return true
+	})
+	return res
+}
+
+func TestQuickListPushPop(t *testing.T) {
+	ql := NewQuickList()
+	ql.PushBack("b", "c")
+	ql.PushFront("a", "z")
+	assert.Equal(t, []string{"z", "a", "b", "c"}, quickListElements(ql))
+
+	v, ok := ql.PopFront()
+	assert.True(t, ok)
+	assert.Equal(t, "z", v)
+	v, ok = ql.PopBack()
+	assert.True(t, ok)
+	assert.Equal(t, "c", v)
+	ql.PopBack()
+	ql.PopBack()
+	_, ok = ql.PopBack()
+	assert.False(t, ok)
+	assert.Nil(t, ql.head)
+	assert.Nil(t, ql.tail)
+}
+
+// TestQuickListModel applies random operations to a quicklist and a slice and compares them,
+// the lists are long enough to span several nodes
+func TestQuickListModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ql := NewQuickList()
+	var model []string
+
+	for i := 0; i < 20000; i++ {
+		value := strconv.Itoa(rng.Intn(50))
+		switch op := rng.Intn(10); {
+		case op < 3:
+			ql.PushBack(value)
+			model = append(model, value)
+		case op < 5:
+			ql.PushFront(value)
+			model = slices.Insert(model, 0, value)
+		case op == 5 && len(model) > 0:
+			v, _ := ql.PopFront()
+			assert.Equal(t, model[0], v)
+			model = model[1:]
+		case op == 6:
+			after := rng.Intn(2) == 0
+			pivot := strconv.Itoa(rng.Intn(50))
+			idx := slices.Index(model, pivot)
+			assert.Equal(t, idx >= 0, ql.Insert(pivot, value, after))
+			if idx >= 0 {
+				if after {
+					idx++
+				}
+				model = slices.Insert(model, idx, value)
+			}
+		case op == 7 && rng.Intn(10) == 0:
+			count := rng.Intn(5) - 2
+			removed := ql.Remove(value, count)
+			expected := 0
+			if count >= 0 {
+				for j := 0; j < len(model); j++ {
+					if model[j] == value && (count == 0 || expected < count) {
+						model = slices.Delete(model, j, j+1)
+						j--
+						expected++
+					}
+				}
+			} else {
+				for j := len(model) - 1; j >= 0; j-- {
+					if model[j] == value && expected < -count {
+						model = slices.Delete(model, j, j+1)
+						expected++
+					}
+				}
+			}
+			assert.Equal(t, expected, removed)
+		case op == 8 && len(model) > 0:
+			idx := rng.Intn(len(model))
+			v, ok := ql.Index(idx)
+			assert.True(t, ok)
+			assert.Equal(t, model[idx], v)
+			assert.True(t, ql.Set(idx, value))
+			model[idx] = value
+		case op == 9 && len(model) > 300 && rng.Intn(20) == 0:
+			start := rng.Intn(len(model) / 4)
+			stop := len(model) - 1 - rng.Intn(len(model)/4)
+			ql.Trim(start, stop)
+			model = model[start : stop+1]
+		}
+		assert.Equal(t, len(model), ql.Len())
+	}
+
+	assert.Equal(t, model, quickListElements(ql))
+	if len(model) > 10 {
+		assert.Equal(t, model[3:10], ql.Range(3, 9))
+	}
+	for node := ql.head; node != nil; node = node.next {
+		assert.NotEmpty(t, node.entries)
+		assert.LessOrEqual(t, len(node.entries), quickListNodeSize)
+	}
+}
+
+func TestQuickListTrimAll(t *testing.T) {
+	ql := NewQuickList()
+	for i := 0; i < 1000; i++ {
+		ql.PushBack(strconv.Itoa(i))
+	}
+	ql.Trim(500, 502)
+	assert.Equal(t, []string{"500", "501", "502"}, quickListElements(ql))
+	ql.Trim(2, 1)
+	assert.Equal(t, 0, ql.Len())
+	_, ok := ql.Index(0)
+	assert.False(t, ok)
+}
@@ -1,12 +1,13 @@
 package data_structure
 
+// SimpleSet is a set of strings. It is a HashTable rather than a map for the SSCAN cursor and SRANDMEMBER.
 type SimpleSet struct {
-	dict map[string]struct{}
+	table *HashTable[struct{}]
 }
 
 func NewSimpleSet() *SimpleSet {
 	return &SimpleSet{
-		dict: make(map[string]struct{}, 0),
+		table: NewHashTable[struct{}](),
 	}
 }
 
@@ -14,8 +15,7 @@ func (s *SimpleSet) Add(members ...string) int {
 	added := 0
 
 	for _, m := range members {
-		if _, exist := s.dict[m]; !exist {
-			s.dict[m] = struct{}{}
+		if s.table.Set(m, struct{}{}) {
 			added++
 		}
 	}
@@ -26,8 +26,7 @@ func (s *SimpleSet) Add(members ...string) int {
 func (s *SimpleSet) Remove(members ...string) int {
 	removed := 0
 	for _, m := range members {
-		if _, exist := s.dict[m]; exist {
-			delete(s.dict, m)
+		if s.table.Delete(m) {
 			removed++
 		}
 	}
@@ -36,18 +35,72 @@ func (s *SimpleSet) Remove(members ...string) int {
 }
 
 func (s *SimpleSet) IsMember(member string) int {
-	if _, exist := s.dict[member]; exist {
+	if _, exist := s.table.Get(member); exist {
 		return 1
 	}
 
 	return 0
 }
 
+func (s *SimpleSet) Len() int {
+	return s.table.Len()
+}
+
+// Members copies every member, Range and Scan go through the set without copying it
 func (s *SimpleSet) Members() []string {
-	m := make([]string, 0)
-	for member := range s.dict {
+	m := make([]string, 0, s.table.Len())
+	s.Range(func(member string) bool {
 		m = append(m, member)
-	}
+		return true
+	})
 
 	return m
 }
+
+// Range calls fn for every member until it returns false, the set must not be modified meanwhile
+func (s *SimpleSet) Range(fn func(member string) bool) {
+	s.table.Range(func(member string, _ struct{}) bool {
+		return fn(member)
+	})
+}
+
+// Scan calls fn for some members and returns the next cursor, see HashTable.Scan
+func (s *SimpleSet) Scan(cursor uint64, fn func(member string)) uint64 {
+	return s.table.Scan(cursor, func(member string, _ struct{}) {
+		fn(member)
+	})
+}
+
+// Random returns a random member, the set must not be empty
+func (s *SimpleSet) Random() string {
+	member, _, _ := s.table.Random()
+	return member
+}
+
+// MarshalBinary encodes the members for snapshots
+func (s *SimpleSet) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(s.table.Len()))
+	s.Range(func(member string) bool {
+		buf = appendString(buf, member)
+		return true
+	})
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the members with the ones encoded by MarshalBinary
+func (s *SimpleSet) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	n := d.count(1)
+	s.table = NewHashTable[struct{}]()
+	for i := 0; i < n; i++ {
+		s.table.Set(d.string(), struct{}{})
+	}
+	return d.finish()
+}
+
+// MemoryUsage estimates the bytes used by the members
+func (s *SimpleSet) MemoryUsage(samples int) int64 {
+	return PointerSize + s.table.MemoryUsage(samples, func(struct{}) int64 {
+		return 0
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"math/rand"
 	"strings"
+	"unsafe"
 )
 
 const (
@@ -283,3 +284,270 @@ func (sl *Skiplist) Get(score float64, elm string) *SkiplistNode {
 
 	return nil
 }
+
+func (x *SkiplistNode) Elm() string {
+	return x.elm
+}
+
+func (x *SkiplistNode) Score() float64 {
+	return x.score
+}
+
+/*
+Find the element by its 1-based rank, like GetRank, using the spans to skip nodes.
+Return nil if the rank is out of range.
+*/
+func (sl *Skiplist) GetByRank(rank uint64) *SkiplistNode {
+	x := sl.head
+	var traversed uint64 = 0
+
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank && x != sl.head {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// ScoreRange is a range of scores like the min and max of ZRANGEBYSCORE, a bound is excluded with "("
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r *ScoreRange) gteMin(score float64) bool {
+	if r.MinEx {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r *ScoreRange) lteMax(score float64) bool {
+	if r.MaxEx {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+// isInRange reports whether a part of the skiplist is in the range
+func (sl *Skiplist) isInRange(r *ScoreRange) bool {
+	if r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx)) {
+		return false
+	}
+	if sl.tail == nil || !r.gteMin(sl.tail.score) {
+		return false
+	}
+	return r.lteMax(sl.head.levels[0].forward.score)
+}
+
+// FirstInRange returns the node with the lowest score in the range, nil when no score is in it
+func (sl *Skiplist) FirstInRange(r *ScoreRange) *SkiplistNode {
+	if !sl.isInRange(r) {
+		return nil
+	}
+
+	x := sl.head
+	// go forward while the next node is below the range
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.score) {
+			x = x.levels[i].forward
+		}
+	}
+
+	// the range is not empty so the next node exists, it may be above the range
+	x = x.levels[0].forward
+	if !r.lteMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+// LastInRange returns the node with the highest score in the range, nil when no score is in it
+func (sl *Skiplist) LastInRange(r *ScoreRange) *SkiplistNode {
+	if !sl.isInRange(r) {
+		return nil
+	}
+
+	x := sl.head
+	// go forward while the next node is in the range or below it
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && r.lteMax(x.levels[i].forward.score) {
+			x = x.levels[i].forward
+		}
+	}
+
+	// the range is not empty so x is not the head, it may be below the range
+	if !r.gteMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+// LexBound is a bound of a lexicographic range like the min and max of ZRANGEBYLEX:
+// "[elm" includes elm, "(elm" excludes it, "-" and "+" are below and above every element
+type LexBound struct {
+	Elm       string
+	Exclusive bool
+	// Inf is -1 for "-", 1 for "+" and 0 for a bound on Elm
+	Inf int
+}
+
+// LexRange is a range of elements, it is only meaningful when the elements have the same score
+type LexRange struct {
+	Min, Max LexBound
+}
+
+// compareLex compares elm to the bound, like strings.Compare
+func compareLex(elm string, b LexBound) int {
+	if b.Inf != 0 {
+		return -b.Inf
+	}
+	return strings.Compare(elm, b.Elm)
+}
+
+func (r *LexRange) gteMin(elm string) bool {
+	c := compareLex(elm, r.Min)
+	return c > 0 || (c == 0 && !r.Min.Exclusive)
+}
+
+func (r *LexRange) lteMax(elm string) bool {
+	c := compareLex(elm, r.Max)
+	return c < 0 || (c == 0 && !r.Max.Exclusive)
+}
+
+// isEmpty reports whether no element can be in the range
+func (r *LexRange) isEmpty() bool {
+	if r.Min.Inf == 1 || r.Max.Inf == -1 {
+		return true
+	}
+	if r.Min.Inf == -1 || r.Max.Inf == 1 {
+		return false
+	}
+	c := strings.Compare(r.Min.Elm, r.Max.Elm)
+	return c > 0 || (c == 0 && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+// isInLexRange reports whether a part of the skiplist is in the range
+func (sl *Skiplist) isInLexRange(r *LexRange) bool {
+	if r.isEmpty() {
+		return false
+	}
+	if sl.tail == nil || !r.gteMin(sl.tail.elm) {
+		return false
+	}
+	return r.lteMax(sl.head.levels[0].forward.elm)
+}
+
+// FirstInLexRange returns the first node in the range, nil when no element is in it
+func (sl *Skiplist) FirstInLexRange(r *LexRange) *SkiplistNode {
+	if !sl.isInLexRange(r) {
+		return nil
+	}
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.elm) {
+			x = x.levels[i].forward
+		}
+	}
+
+	x = x.levels[0].forward
+	if !r.lteMax(x.elm) {
+		return nil
+	}
+	return x
+}
+
+// LastInLexRange returns the last node in the range, nil when no element is in it
+func (sl *Skiplist) LastInLexRange(r *LexRange) *SkiplistNode {
+	if !sl.isInLexRange(r) {
+		return nil
+	}
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && r.lteMax(x.levels[i].forward.elm) {
+			x = x.levels[i].forward
+		}
+	}
+
+	if !r.gteMin(x.elm) {
+		return nil
+	}
+	return x
+}
+
+// nodeSize is the size of the node and its levels, not counting the bytes of its element
+func (sl *Skiplist) nodeSize(x *SkiplistNode) int64 {
+	return int64(unsafe.Sizeof(*x)) + int64(len(x.levels))*int64(unsafe.Sizeof(SkiplistLevel{}))
+}
+
+// DeleteRangeByScore deletes the nodes with a score in r, it returns them from the lowest score
+func (sl *Skiplist) DeleteRangeByScore(r *ScoreRange) []*SkiplistNode {
+	update := [SkiplistMaxLevel]*SkiplistNode{}
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.score) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	return sl.deleteWhile(x.levels[0].forward, update, func(x *SkiplistNode) bool { return r.lteMax(x.score) })
+}
+
+// DeleteRangeByLex deletes the nodes in r, it returns them in order
+func (sl *Skiplist) DeleteRangeByLex(r *LexRange) []*SkiplistNode {
+	update := [SkiplistMaxLevel]*SkiplistNode{}
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.elm) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	return sl.deleteWhile(x.levels[0].forward, update, func(x *SkiplistNode) bool { return r.lteMax(x.elm) })
+}
+
+// DeleteRangeByRank deletes the nodes from the 1-based rank start to end included, it returns them in order
+func (sl *Skiplist) DeleteRangeByRank(start, end uint64) []*SkiplistNode {
+	update := [SkiplistMaxLevel]*SkiplistNode{}
+	x := sl.head
+	var traversed uint64 = 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span < start {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	// x is the node before start, the nodes to delete are the next end-start+1 ones
+	remaining := end - start + 1
+	return sl.deleteWhile(x.levels[0].forward, update, func(*SkiplistNode) bool {
+		if remaining == 0 {
+			return false
+		}
+		remaining--
+		return true
+	})
+}
+
+// deleteWhile deletes the nodes from x while inRange, update holds the nodes before x at each level.
+// Deleting a node leaves update pointing before the next one, so it is reused for every node.
+func (sl *Skiplist) deleteWhile(x *SkiplistNode, update [SkiplistMaxLevel]*SkiplistNode, inRange func(x *SkiplistNode) bool) []*SkiplistNode {
+	var deleted []*SkiplistNode
+	for x != nil && inRange(x) {
+		next := x.levels[0].forward
+		sl.DeleteNode(x, update)
+		deleted = append(deleted, x)
+		x = next
+	}
+	return deleted
+}
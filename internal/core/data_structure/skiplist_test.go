@@ -2,6 +2,7 @@ package data_structure_test
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/nhtuan0700/godis/internal/core/data_structure"
@@ -136,3 +137,74 @@ func TestGet(t *testing.T) {
 	assert.Nil(t, sl.Get(80, "kUnknown"))
 	assert.Nil(t, sl.Get(100, "k8"))
 }
+
+func TestGetByRank(t *testing.T) {
+	sl := initSkipList()
+
+	for rank := uint64(1); rank <= sl.Len(); rank++ {
+		x := sl.GetByRank(rank)
+		assert.Equal(t, fmt.Sprintf("k%d", rank), x.Elm())
+		assert.EqualValues(t, rank, sl.GetRank(x.Score(), x.Elm()))
+	}
+	assert.Nil(t, sl.GetByRank(0))
+	assert.Nil(t, sl.GetByRank(sl.Len()+1))
+}
+
+func TestScoreRange(t *testing.T) {
+	sl := initSkipList()
+
+	testCases := []struct {
+		r           data_structure.ScoreRange
+		first, last string
+	}{
+		{r: data_structure.ScoreRange{Min: 20, Max: 50}, first: "k2", last: "k5"},
+		{r: data_structure.ScoreRange{Min: 20, Max: 50, MinEx: true, MaxEx: true}, first: "k3", last: "k4"},
+		{r: data_structure.ScoreRange{Min: 15, Max: 25}, first: "k2", last: "k2"},
+		{r: data_structure.ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)}, first: "k1", last: "k8"},
+		{r: data_structure.ScoreRange{Min: 21, Max: 29}},
+		{r: data_structure.ScoreRange{Min: 90, Max: 100}},
+		{r: data_structure.ScoreRange{Min: 50, Max: 20}},
+		{r: data_structure.ScoreRange{Min: 20, Max: 20, MinEx: true}},
+	}
+	for _, tc := range testCases {
+		first, last := sl.FirstInRange(&tc.r), sl.LastInRange(&tc.r)
+		if tc.first == "" {
+			assert.Nil(t, first, tc.r)
+			assert.Nil(t, last, tc.r)
+			continue
+		}
+		assert.Equal(t, tc.first, first.Elm(), tc.r)
+		assert.Equal(t, tc.last, last.Elm(), tc.r)
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	sl := data_structure.CreateSkiplist()
+	for _, elm := range []string{"d", "a", "c", "e", "b"} {
+		sl.Insert(0, elm)
+	}
+	minusInf, plusInf := data_structure.LexBound{Inf: -1}, data_structure.LexBound{Inf: 1}
+
+	testCases := []struct {
+		r           data_structure.LexRange
+		first, last string
+	}{
+		{r: data_structure.LexRange{Min: minusInf, Max: plusInf}, first: "a", last: "e"},
+		{r: data_structure.LexRange{Min: data_structure.LexBound{Elm: "b"}, Max: data_structure.LexBound{Elm: "d"}}, first: "b", last: "d"},
+		{r: data_structure.LexRange{Min: data_structure.LexBound{Elm: "b", Exclusive: true}, Max: data_structure.LexBound{Elm: "d", Exclusive: true}}, first: "c", last: "c"},
+		{r: data_structure.LexRange{Min: data_structure.LexBound{Elm: "bb"}, Max: plusInf}, first: "c", last: "e"},
+		{r: data_structure.LexRange{Min: minusInf, Max: data_structure.LexBound{Elm: "a", Exclusive: true}}},
+		{r: data_structure.LexRange{Min: plusInf, Max: minusInf}},
+		{r: data_structure.LexRange{Min: data_structure.LexBound{Elm: "c"}, Max: data_structure.LexBound{Elm: "b"}}},
+	}
+	for _, tc := range testCases {
+		first, last := sl.FirstInLexRange(&tc.r), sl.LastInLexRange(&tc.r)
+		if tc.first == "" {
+			assert.Nil(t, first, tc.r)
+			assert.Nil(t, last, tc.r)
+			continue
+		}
+		assert.Equal(t, tc.first, first.Elm(), tc.r)
+		assert.Equal(t, tc.last, last.Elm(), tc.r)
+	}
+}
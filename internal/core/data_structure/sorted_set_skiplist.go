@@ -1,5 +1,10 @@
 package data_structure
 
+import (
+	"slices"
+	"unsafe"
+)
+
 type ZSet struct {
 	zskiplist *Skiplist
 	dict      map[string]float64
@@ -12,13 +17,10 @@ func NewZSet() *ZSet {
 	}
 }
 
+// Add adds elm with score, or updates the score of elm when it is already a member.
+// It returns true when elm was added.
 func (zs *ZSet) Add(score float64, elm string) bool {
-	if len(elm) == 0 {
-		return false
-	}
-	
-	_, exist := zs.dict[elm]
-	if exist {
+	if zs.UpdateScore(elm, score) {
 		return false
 	}
 
@@ -30,6 +32,20 @@ func (zs *ZSet) Add(score float64, elm string) bool {
 	return true
 }
 
+// UpdateScore changes the score of a member, the node stays in place when the new score keeps its position.
+// It returns false when elm is not a member.
+func (zs *ZSet) UpdateScore(elm string, score float64) bool {
+	curScore, exist := zs.dict[elm]
+	if !exist {
+		return false
+	}
+	if curScore != score {
+		zs.zskiplist.UpdateScore(curScore, elm, score)
+		zs.dict[elm] = score
+	}
+	return true
+}
+
 /*
 Return 0-based rank of the object or -1 if the object does not exist.
 If reverse is false, rank is computed considering as first element the one
@@ -58,6 +74,87 @@ func (zs *ZSet) GetScore(elm string) (float64, bool) {
 	return score, exist
 }
 
+// Len returns the number of members
+func (zs *ZSet) Len() int {
+	return len(zs.dict)
+}
+
+// Scores returns the score of each member, the map belongs to the sorted set and must not be modified
+func (zs *ZSet) Scores() map[string]float64 {
+	return zs.dict
+}
+
+// ZElement is a member of a sorted set with its score, as returned by the ranges
+type ZElement struct {
+	Member string
+	Score  float64
+}
+
+// RangeByRank returns the members from the 0-based rank start to stop included, start <= stop < Len.
+// With reverse the ranks count from the highest score.
+func (zs *ZSet) RangeByRank(start, stop int, reverse bool) []ZElement {
+	rank := uint64(start + 1)
+	if reverse {
+		rank = uint64(zs.Len() - start)
+	}
+	return zs.collect(zs.zskiplist.GetByRank(rank), nil, 0, stop-start+1, reverse)
+}
+
+// RangeByScore returns the members with a score in r, from the lowest score or from the highest with reverse.
+// The first offset members are skipped and at most count are returned, a negative count returns all of them.
+func (zs *ZSet) RangeByScore(r *ScoreRange, offset, count int, reverse bool) []ZElement {
+	if reverse {
+		return zs.collect(zs.zskiplist.LastInRange(r), func(x *SkiplistNode) bool { return r.gteMin(x.score) }, offset, count, true)
+	}
+	return zs.collect(zs.zskiplist.FirstInRange(r), func(x *SkiplistNode) bool { return r.lteMax(x.score) }, offset, count, false)
+}
+
+// RangeByLex is RangeByScore for a lexicographic range
+func (zs *ZSet) RangeByLex(r *LexRange, offset, count int, reverse bool) []ZElement {
+	if reverse {
+		return zs.collect(zs.zskiplist.LastInLexRange(r), func(x *SkiplistNode) bool { return r.gteMin(x.elm) }, offset, count, true)
+	}
+	return zs.collect(zs.zskiplist.FirstInLexRange(r), func(x *SkiplistNode) bool { return r.lteMax(x.elm) }, offset, count, false)
+}
+
+// collect walks from x while inRange, nil for every node, skipping offset nodes and returning at most count of them
+func (zs *ZSet) collect(x *SkiplistNode, inRange func(x *SkiplistNode) bool, offset, count int, reverse bool) []ZElement {
+	next := func(x *SkiplistNode) *SkiplistNode {
+		if reverse {
+			return x.backward
+		}
+		return x.levels[0].forward
+	}
+
+	for ; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	var res []ZElement
+	for ; x != nil && count != 0 && (inRange == nil || inRange(x)); x = next(x) {
+		res = append(res, ZElement{Member: x.elm, Score: x.score})
+		count--
+	}
+	return res
+}
+
+// CountInRange returns the number of members with a score in r
+func (zs *ZSet) CountInRange(r *ScoreRange) int {
+	return zs.countBetween(zs.zskiplist.FirstInRange(r), zs.zskiplist.LastInRange(r))
+}
+
+// CountInLexRange returns the number of members in r
+func (zs *ZSet) CountInLexRange(r *LexRange) int {
+	return zs.countBetween(zs.zskiplist.FirstInLexRange(r), zs.zskiplist.LastInLexRange(r))
+}
+
+// countBetween uses the ranks of the first and last nodes of a range, without walking it
+func (zs *ZSet) countBetween(first, last *SkiplistNode) int {
+	if first == nil {
+		return 0
+	}
+	return int(zs.zskiplist.GetRank(last.score, last.elm) - zs.zskiplist.GetRank(first.score, first.elm) + 1)
+}
+
 func (zs *ZSet) Remove(elm string) bool {
 	score, exist := zs.dict[elm]
 	if !exist {
@@ -67,3 +164,86 @@ func (zs *ZSet) Remove(elm string) bool {
 	delete(zs.dict, elm)
 	return zs.zskiplist.Delete(score, elm)
 }
+
+// MarshalBinary encodes the members with their scores in ascending order for snapshots
+func (zs *ZSet) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, zs.zskiplist.Len())
+	for x := zs.zskiplist.head.levels[0].forward; x != nil; x = x.levels[0].forward {
+		buf = appendString(buf, x.elm)
+		buf = appendFloat(buf, x.score)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the members with the ones encoded by MarshalBinary
+func (zs *ZSet) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	n := d.count(9)
+	zs.zskiplist = CreateSkiplist()
+	zs.dict = make(map[string]float64, n)
+	for i := 0; i < n; i++ {
+		elm := d.string()
+		score := d.float()
+		if d.err != nil {
+			break
+		}
+		zs.Add(score, elm)
+	}
+	return d.finish()
+}
+
+// MemoryUsage estimates the bytes used by the skiplist nodes and the member to score map,
+// the member strings are shared by both
+func (zs *ZSet) MemoryUsage(samples int) int64 {
+	size := int64(unsafe.Sizeof(*zs)) + mapHeaderSize + zs.zskiplist.nodeSize(zs.zskiplist.head)
+	nodes := newSampler(int(zs.zskiplist.Len()), samples)
+	for x := zs.zskiplist.head.levels[0].forward; x != nil; x = x.levels[0].forward {
+		// the map entry holds the member string header and the score
+		mapEntry := int64(StringHeaderSize+unsafe.Sizeof(x.score)) + mapEntryOverhead
+		if !nodes.add(zs.zskiplist.nodeSize(x) + int64(len(x.elm)) + mapEntry) {
+			break
+		}
+	}
+	return size + nodes.total()
+}
+
+// Pop removes the count members with the lowest scores, or the highest ones, and returns them in that order
+func (zs *ZSet) Pop(count int, highest bool) []ZElement {
+	count = min(count, zs.Len())
+	if count <= 0 {
+		return nil
+	}
+	if !highest {
+		return zs.forget(zs.zskiplist.DeleteRangeByRank(1, uint64(count)))
+	}
+	length := uint64(zs.Len())
+	elements := zs.forget(zs.zskiplist.DeleteRangeByRank(length-uint64(count)+1, length))
+	slices.Reverse(elements)
+	return elements
+}
+
+// RemoveRangeByRank removes the members from the 0-based rank start to stop included, start <= stop < Len.
+// It returns the number of members removed.
+func (zs *ZSet) RemoveRangeByRank(start, stop int) int {
+	return len(zs.forget(zs.zskiplist.DeleteRangeByRank(uint64(start+1), uint64(stop+1))))
+}
+
+// RemoveRangeByScore removes the members with a score in r, it returns the number of members removed
+func (zs *ZSet) RemoveRangeByScore(r *ScoreRange) int {
+	return len(zs.forget(zs.zskiplist.DeleteRangeByScore(r)))
+}
+
+// RemoveRangeByLex removes the members in r, it returns the number of members removed
+func (zs *ZSet) RemoveRangeByLex(r *LexRange) int {
+	return len(zs.forget(zs.zskiplist.DeleteRangeByLex(r)))
+}
+
+// forget removes the nodes deleted from the skiplist from the dict, it returns them as elements
+func (zs *ZSet) forget(nodes []*SkiplistNode) []ZElement {
+	elements := make([]ZElement, len(nodes))
+	for i, x := range nodes {
+		delete(zs.dict, x.elm)
+		elements[i] = ZElement{Member: x.elm, Score: x.score}
+	}
+	return elements
+}
@@ -1,6 +1,7 @@
 package data_structure_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/nhtuan0700/godis/internal/core/data_structure"
@@ -73,3 +74,95 @@ func TestSortedSetSkiplist(t *testing.T) {
 		})
 	}
 }
+
+func TestZSetRanges(t *testing.T) {
+	zs := data_structure.NewZSet()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		zs.Add(float64(i+1), member)
+	}
+	members := func(elements []data_structure.ZElement) []string {
+		res := []string{}
+		for _, e := range elements {
+			res = append(res, e.Member)
+		}
+		return res
+	}
+
+	assert.Equal(t, 5, zs.Len())
+	assert.Equal(t, []data_structure.ZElement{{Member: "b", Score: 2}, {Member: "c", Score: 3}}, zs.RangeByRank(1, 2, false))
+	assert.Equal(t, []string{"d", "c", "b"}, members(zs.RangeByRank(1, 3, true)))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, members(zs.RangeByRank(0, 4, false)))
+
+	r := &data_structure.ScoreRange{Min: 2, Max: 4}
+	assert.Equal(t, []string{"b", "c", "d"}, members(zs.RangeByScore(r, 0, -1, false)))
+	assert.Equal(t, []string{"d", "c", "b"}, members(zs.RangeByScore(r, 0, -1, true)))
+	assert.Equal(t, []string{"c"}, members(zs.RangeByScore(r, 1, 1, false)))
+	assert.Equal(t, []string{"c", "b"}, members(zs.RangeByScore(r, 1, 5, true)))
+	assert.Empty(t, zs.RangeByScore(r, 3, -1, false))
+	assert.Equal(t, 3, zs.CountInRange(r))
+	assert.Equal(t, 1, zs.CountInRange(&data_structure.ScoreRange{Min: 2, Max: 4, MinEx: true, MaxEx: true}))
+	assert.Equal(t, 0, zs.CountInRange(&data_structure.ScoreRange{Min: 6, Max: 10}))
+
+	same := data_structure.NewZSet()
+	for _, member := range []string{"a", "b", "c", "d", "e"} {
+		same.Add(0, member)
+	}
+	lex := &data_structure.LexRange{Min: data_structure.LexBound{Elm: "b", Exclusive: true}, Max: data_structure.LexBound{Inf: 1}}
+	assert.Equal(t, []string{"c", "d", "e"}, members(same.RangeByLex(lex, 0, -1, false)))
+	assert.Equal(t, []string{"d", "c"}, members(same.RangeByLex(lex, 1, 2, true)))
+	assert.Equal(t, 3, same.CountInLexRange(lex))
+}
+
+func TestZSetUpdateScore(t *testing.T) {
+	zs := data_structure.NewZSet()
+	zs.Add(1, "a")
+	zs.Add(2, "b")
+	zs.Add(3, "c")
+
+	// a score keeping the position of the node, then one moving it
+	assert.False(t, zs.Add(2.5, "b"))
+	score, _ := zs.GetScore("b")
+	assert.Equal(t, 2.5, score)
+	assert.True(t, zs.UpdateScore("a", 10))
+	rank, _ := zs.GetRank("a", false)
+	assert.EqualValues(t, 2, rank)
+	assert.Equal(t, []data_structure.ZElement{{Member: "b", Score: 2.5}, {Member: "c", Score: 3}, {Member: "a", Score: 10}}, zs.RangeByRank(0, 2, false))
+
+	assert.False(t, zs.UpdateScore("x", 1))
+	assert.Equal(t, 3, zs.Len())
+}
+
+func TestZSetRemove(t *testing.T) {
+	newZSet := func() *data_structure.ZSet {
+		zs := data_structure.NewZSet()
+		for i := range 100 {
+			zs.Add(float64(i), fmt.Sprintf("m%02d", i))
+		}
+		return zs
+	}
+
+	zs := newZSet()
+	assert.Equal(t, []data_structure.ZElement{{Member: "m00", Score: 0}, {Member: "m01", Score: 1}}, zs.Pop(2, false))
+	assert.Equal(t, []data_structure.ZElement{{Member: "m99", Score: 99}, {Member: "m98", Score: 98}}, zs.Pop(2, true))
+	assert.Equal(t, 96, zs.Len())
+	_, exist := zs.GetScore("m00")
+	assert.False(t, exist)
+	// the ranks are still right after deleting nodes
+	rank, _ := zs.GetRank("m50", false)
+	assert.EqualValues(t, 48, rank)
+
+	assert.Equal(t, 10, zs.RemoveRangeByRank(0, 9))
+	assert.Equal(t, 5, zs.RemoveRangeByScore(&data_structure.ScoreRange{Min: 20, Max: 25, MinEx: true}))
+	assert.Equal(t, 0, zs.RemoveRangeByScore(&data_structure.ScoreRange{Min: 200, Max: 300}))
+	assert.Equal(t, 3, zs.RemoveRangeByLex(&data_structure.LexRange{
+		Min: data_structure.LexBound{Elm: "m30"}, Max: data_structure.LexBound{Elm: "m33", Exclusive: true}}))
+	assert.Equal(t, 78, zs.Len())
+	assert.Equal(t, []data_structure.ZElement{{Member: "m12", Score: 12}, {Member: "m13", Score: 13}}, zs.RangeByRank(0, 1, false))
+	rank, _ = zs.GetRank("m97", true)
+	assert.EqualValues(t, 0, rank)
+
+	assert.Len(t, zs.Pop(1000, true), 78)
+	assert.Equal(t, 0, zs.Len())
+	assert.Empty(t, zs.Pop(1, false))
+	assert.True(t, zs.Add(1, "a"))
+}
@@ -1,8 +0,0 @@
-package data_structure
-
-type KeySpaceStat struct {
-	Key    int
-	Expire int
-}
-
-var HashKeySpaceStat = KeySpaceStat{Key: 0, Expire: 0}
@@ -0,0 +1,11 @@
+package core
+
+import "errors"
+
+// Error replies shared by several commands
+var (
+	errSyntax     = errors.New("ERR syntax error")
+	errNotInteger = errors.New("ERR value is not an integer or out of range")
+	errNotFloat   = errors.New("ERR value is not a valid float")
+	errOverflow   = errors.New("ERR increment or decrement would overflow")
+)
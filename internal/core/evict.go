@@ -0,0 +1,137 @@
+package core
+
+import (
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+// evictIfNeeded deletes keys until the memory used is below the share of config.MaxMemory of the db,
+// it is called before write commands like redis does.
+// It returns false when the memory is still above the limit because nothing can be evicted,
+// with noeviction or a volatile policy without keys with a TTL.
+func (db *RedisDB) evictIfNeeded() bool {
+	limit := db.maxMemory()
+	if limit <= 0 {
+		return true
+	}
+	for db.usedMemory.Load() > limit {
+		if !db.evict() {
+			return false
+		}
+	}
+	return true
+}
+
+// evict deletes one key picked by config.EvictPolicy, it returns false when there is none to delete
+func (db *RedisDB) evict() bool {
+	var key string
+	switch config.EvictPolicy.Load() {
+	case "allkeys-random":
+		key = db.evictRandom()
+	case "volatile-random":
+		key = db.evictVolatileRandom()
+	case "allkeys-lru", "allkeys-lfu":
+		key = db.evictFromPool(false)
+	case "volatile-lru", "volatile-lfu", "volatile-ttl":
+		key = db.evictFromPool(true)
+	}
+	// noeviction never picks a key
+	if key == "" {
+		return false
+	}
+	log.Println("Evict key ", key)
+	db.Delete(key)
+	db.stats.evictedKeys++
+	db.touchWatchedKey(key)
+	db.alsoPropagate(constant.CMD_DEL, key)
+	return true
+}
+
+func isLFUPolicy() bool {
+	return strings.HasSuffix(config.EvictPolicy.Load(), "-lfu")
+}
+
+// evictionIdle ranks the key in the eviction pool, the key with the highest idle is evicted first
+func (db *RedisDB) evictionIdle(key string, obj *RedisObj) uint64 {
+	switch {
+	case config.EvictPolicy.Load() == "volatile-ttl":
+		// the sooner the key expires, the better candidate it is
+		exp, _ := db.expireDict.Get(key)
+		return math.MaxUint64 - exp
+	case isLFUPolicy():
+		return 255 - uint64(obj.lfuDecr())
+	}
+	// the subtraction wraps around like lastAccessTime
+	return uint64(uint32(time.Now().UnixMilli()) - obj.lastAccessTime)
+}
+
+// populateEpool push the new items with sampled size to the pool, volatile samples only the keys with a TTL
+func (db *RedisDB) populateEpool(volatile bool) {
+	if volatile {
+		for i := 0; i < config.LruSampledSize.Load(); i++ {
+			k, _, ok := db.expireDict.Random()
+			if !ok {
+				return
+			}
+			if obj, ok := db.dict.Get(k); ok {
+				db.epool.Push(k, db.evictionIdle(k, obj))
+			}
+		}
+		return
+	}
+
+	for i := 0; i < config.LruSampledSize.Load(); i++ {
+		k, v, ok := db.dict.Random()
+		if !ok {
+			return
+		}
+		db.epool.Push(k, db.evictionIdle(k, v))
+	}
+}
+
+// evictFromPool returns the best candidate of the pool, the pool may hold keys deleted
+// or made persistent since they were sampled
+func (db *RedisDB) evictFromPool(volatile bool) string {
+	db.populateEpool(volatile)
+	for len(db.epool.Pool()) > 0 {
+		item := db.epool.Pop()
+		if _, ok := db.dict.Get(item.Key()); !ok {
+			continue
+		}
+		if _, hasTTL := db.expireDict.Get(item.Key()); volatile && !hasTTL {
+			continue
+		}
+		return item.Key()
+	}
+	return ""
+}
+
+func (db *RedisDB) evictRandom() string {
+	k, _, _ := db.dict.Random()
+	return k
+}
+
+// evictVolatileRandom returns a random key with a TTL
+func (db *RedisDB) evictVolatileRandom() string {
+	k, _, _ := db.expireDict.Random()
+	return k
+}
+
+// lfuDecr returns the access counter decremented for the minutes elapsed since the last access,
+// see data_structure.LFUDecr
+func (obj *RedisObj) lfuDecr() uint8 {
+	elapsed := data_structure.LFUTimeElapsed(obj.lfuTime, data_structure.LFUTimeInMinutes(time.Now()))
+	return data_structure.LFUDecr(obj.lfuCounter, elapsed, config.LfuDecayTime.Load())
+}
+
+// updateLFU counts an access to obj
+func (obj *RedisObj) updateLFU() {
+	obj.lfuCounter = data_structure.LFULogIncr(obj.lfuDecr(), config.LfuLogFactor.Load())
+	obj.lfuTime = data_structure.LFUTimeInMinutes(time.Now())
+}
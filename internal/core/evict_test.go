@@ -0,0 +1,151 @@
+package core_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// withEviction sets the eviction config for the test
+func withEviction(t *testing.T, policy string, maxMemory int64) {
+	oldPolicy, oldMaxMemory := config.EvictPolicy.Load(), config.MaxMemory.Load()
+	t.Cleanup(func() {
+		config.EvictPolicy.Store(oldPolicy)
+		config.MaxMemory.Store(oldMaxMemory)
+	})
+	config.EvictPolicy.Store(policy)
+	config.MaxMemory.Store(maxMemory)
+}
+
+func TestObjectFreq(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v")
+	assert.Contains(t, execute(db, "OBJECT", "FREQ", "k"), "-ERR An LFU maxmemory policy is not selected")
+
+	withEviction(t, "allkeys-lfu", 0)
+	assert.Equal(t, "$-1\r\n", execute(db, "OBJECT", "FREQ", "missing"))
+	// new keys start at 5 so they are not evicted right away, looking at the counter is not an access
+	execute(db, "SET", "new", "v")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, ":5\r\n", execute(db, "OBJECT", "freq", "new"))
+	}
+
+	for i := 0; i < 1000; i++ {
+		execute(db, "GET", "k")
+	}
+	reply, err := core.Decode([]byte(execute(db, "OBJECT", "FREQ", "k")))
+	assert.NoError(t, err)
+	freq := reply.(int64)
+	// the counter is logarithmic
+	assert.Greater(t, freq, int64(10))
+	assert.Less(t, freq, int64(100))
+
+	// a new value keeps the frequency of the key
+	execute(db, "SET", "k", "other")
+	reply, _ = core.Decode([]byte(execute(db, "OBJECT", "FREQ", "k")))
+	assert.GreaterOrEqual(t, reply.(int64), freq)
+
+	assert.Equal(t, "-ERR unknown subcommand 'NOPE'. Try OBJECT HELP.\r\n", execute(db, "OBJECT", "NOPE", "k"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'object|freq' command\r\n", execute(db, "OBJECT", "FREQ"))
+}
+
+func TestLFUEviction(t *testing.T) {
+	withEviction(t, "allkeys-lfu", 0)
+	db := core.NewRedisDB()
+	value := strings.Repeat("v", 100)
+	execute(db, "SET", "hot", value)
+	for i := 0; i < 1000; i++ {
+		execute(db, "GET", "hot")
+	}
+
+	config.MaxMemory.Store(5000)
+	// keys read once after being written are scan-like traffic, they would push the hot key out of an LRU
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("cold:%d", i)
+		execute(db, "SET", key, value)
+		execute(db, "GET", key)
+		assert.Equal(t, ":1\r\n", execute(db, "EXISTS", "hot"), i)
+	}
+	assert.Regexp(t, `^:[1-9]\d?\r\n$`, execute(db, "DBSIZE"))
+}
+
+func TestVolatileLFUEviction(t *testing.T) {
+	withEviction(t, "volatile-lfu", 0)
+	db := core.NewRedisDB()
+	value := strings.Repeat("v", 100)
+	for i := 0; i < 10; i++ {
+		execute(db, "SET", fmt.Sprintf("persistent:%d", i), value)
+	}
+
+	config.MaxMemory.Store(db.UsedMemory() + 2000)
+	for i := 0; i < 100; i++ {
+		execute(db, "SET", fmt.Sprintf("volatile:%d", i), value, "EX", "100")
+	}
+	// only keys with a TTL are evicted
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, ":1\r\n", execute(db, "EXISTS", fmt.Sprintf("persistent:%d", i)))
+	}
+	assert.LessOrEqual(t, db.UsedMemory(), config.MaxMemory.Load()+300)
+
+	// once no key has a TTL, nothing can be evicted and writes are rejected
+	config.MaxMemory.Store(1)
+	assert.Equal(t, "-OOM command not allowed when used memory > 'maxmemory'\r\n", execute(db, "SET", "another", value))
+	assert.Equal(t, ":10\r\n", execute(db, "DBSIZE"))
+}
+
+func TestVolatilePolicies(t *testing.T) {
+	value := strings.Repeat("v", 100)
+	for _, policy := range []string{"volatile-lru", "volatile-random", "volatile-ttl"} {
+		withEviction(t, policy, 0)
+		db := core.NewRedisDB()
+		for i := 0; i < 10; i++ {
+			execute(db, "SET", fmt.Sprintf("persistent:%d", i), value)
+		}
+		config.MaxMemory.Store(db.UsedMemory() + 2000)
+		for i := 0; i < 100; i++ {
+			execute(db, "SET", fmt.Sprintf("volatile:%d", i), value, "EX", fmt.Sprint(1000+i))
+		}
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, ":1\r\n", execute(db, "EXISTS", fmt.Sprintf("persistent:%d", i)), policy)
+		}
+		assert.LessOrEqual(t, db.UsedMemory(), config.MaxMemory.Load()+300, policy)
+	}
+
+	// volatile-ttl evicts the keys expiring first
+	withEviction(t, "volatile-ttl", 0)
+	db := core.NewRedisDB()
+	execute(db, "SET", "late", value, "EX", "10000")
+	execute(db, "SET", "soon", value, "EX", "10")
+	config.MaxMemory.Store(db.UsedMemory() - 1)
+	execute(db, "SET", "other", value, "EX", "5000")
+	assert.Equal(t, ":0\r\n", execute(db, "EXISTS", "soon"))
+	assert.Equal(t, ":1\r\n", execute(db, "EXISTS", "late"))
+}
+
+func TestNoEviction(t *testing.T) {
+	withEviction(t, "noeviction", 0)
+	db := core.NewRedisDB()
+	execute(db, "SET", "a", "v")
+	execute(db, "RPUSH", "list", "a", "b")
+	execute(db, "SET", "volatile", "v", "EX", "100")
+	config.MaxMemory.Store(1)
+
+	oom := "-OOM command not allowed when used memory > 'maxmemory'\r\n"
+	assert.Equal(t, oom, execute(db, "SET", "b", "v"))
+	assert.Equal(t, oom, execute(db, "RPUSH", "list", "c"))
+	assert.Equal(t, oom, execute(db, "INCR", "counter"))
+	assert.Equal(t, ":3\r\n", execute(db, "DBSIZE"))
+
+	// reads and commands freeing memory still work
+	assert.Equal(t, "$1\r\nv\r\n", execute(db, "GET", "a"))
+	assert.Equal(t, "$1\r\na\r\n", execute(db, "LPOP", "list"))
+	assert.Equal(t, ":1\r\n", execute(db, "EXPIRE", "a", "100"))
+	assert.Equal(t, ":1\r\n", execute(db, "DEL", "a"))
+
+	config.MaxMemory.Store(0)
+	assert.Equal(t, "+OK\r\n", execute(db, "SET", "b", "v"))
+}
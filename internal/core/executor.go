@@ -2,70 +2,88 @@ package core
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/nhtuan0700/godis/internal/constant"
 )
 
-// ExecuteCommand given a command, executes it and response
+// ExecuteCommand given a command, executes it and response.
+// Blocking commands do not block here, they reply as if their timeout was reached, see ExecuteTask.
 func ExecuteCommand(redisDB *RedisDB, cmd *Command) []byte {
-	var res []byte
+	res := executeCommand(redisDB, cmd)
+	redisDB.takeBlockRequest()
+	return res
+}
+
+// ExecuteTask executes the command of the task and replies. A blocking command that finds no data
+// does not reply: the task is parked in redisDB until a command creates one of its keys or its timeout is reached,
+// so the goroutine owning redisDB keeps executing other tasks meanwhile.
+func ExecuteTask(redisDB *RedisDB, task *Task) {
+	redisDB.operands = task.Operands
+	res := executeCommand(redisDB, task.Command)
+	redisDB.operands = nil
+	if req := redisDB.takeBlockRequest(); req != nil {
+		redisDB.block(task, req)
+	} else {
+		task.Reply(res)
+	}
+	redisDB.serveBlockedTasks()
+}
+
+// CheckCommand returns the spec of cmd, or the error replied for an unknown command or a wrong number of arguments
+func CheckCommand(cmd *Command) (*CommandSpec, error) {
+	spec := LookupCommand(cmd.Cmd)
+	if spec == nil {
+		var argsPreview strings.Builder
+		for _, arg := range cmd.Args {
+			fmt.Fprintf(&argsPreview, "'%s' ", arg)
+		}
+		return nil, fmt.Errorf("ERR unknown command '%s', with args beginning with: %s", cmd.Cmd, argsPreview.String())
+	}
+
+	if !spec.CheckArity(len(cmd.Args)) {
+		return nil, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(spec.Name))
+	}
+	return spec, nil
+}
+
+func executeCommand(redisDB *RedisDB, cmd *Command) []byte {
+	spec, err := CheckCommand(cmd)
+	if err != nil {
+		return Encode(err, false)
+	}
 
-	switch cmd.Cmd {
-	case constant.CMD_PING:
-		res = cmdPING(cmd.Args)
-	case constant.CMD_SET:
-		res = cmdSet(redisDB, cmd.Args)
-	case constant.CMD_GET:
-		res = cmdGet(redisDB, cmd.Args)
-	case constant.CMD_TTL:
-		res = cmdTTL(redisDB, cmd.Args)
-	case constant.CMD_PTTL:
-		res = cmdPTTL(redisDB, cmd.Args)
-	case constant.CMD_DEL:
-		res = cmdDel(redisDB, cmd.Args)
-	case constant.CMD_EXIST:
-		res = cmdExists(redisDB, cmd.Args)
-	case constant.CMD_EXPIRE:
-		res = cmdExpire(redisDB, cmd.Args)
-	case constant.CMD_SADD:
-		res = cmdSADD(redisDB, cmd.Args)
-	case constant.CMD_SREM:
-		res = cmdSREM(redisDB, cmd.Args)
-	case constant.CMD_SISMEMBER:
-		res = cmdSISMEMBER(redisDB, cmd.Args)
-	case constant.CMD_SMEMBERS:
-		res = cmdSMEMEBERS(redisDB, cmd.Args)
-	case constant.CMD_ZADD:
-		res = cmdZADD(redisDB, cmd.Args)
-	case constant.CMD_ZSCORE:
-		res = cmdZSCORE(redisDB, cmd.Args)
-	case constant.CMD_ZRANK:
-		res = cmdZRANK(redisDB, cmd.Args)
-	case constant.CMD_ZREM:
-		res = cmdZREM(redisDB, cmd.Args)
-	case constant.CMD_CMS_INITBYDIM:
-		res = cmdCMSINITBYDIM(redisDB, cmd.Args)
-	case constant.CMD_CMS_INITBYPROB:
-		res = cmdCMSINITBYPROB(redisDB, cmd.Args)
-	case constant.CMD_CMS_INCRBY:
-		res = cmdCMSINCRBY(redisDB, cmd.Args)
-	case constant.CMD_CMS_QUERY:
-		res = cmdCMSQUERY(redisDB, cmd.Args)
-	case constant.CMD_BF_RESERVE:
-		res = cmdBFRESERVE(redisDB, cmd.Args)
-	case constant.CMD_BF_ADD:
-		res = cmdBFADD(redisDB, cmd.Args)
-	case constant.CMD_BF_MADD:
-		res = cmdBFMADD(redisDB, cmd.Args)
-	case constant.CMD_BF_EXISTS:
-		res = cmdBFEXISTS(redisDB, cmd.Args)
-	case constant.CMD_BF_MEXISTS:
-		res = cmdBFMEXISTS(redisDB, cmd.Args)
-	case constant.CMD_INFO:
-		res = cmdINFO(redisDB, cmd.Args)
-	default:
-		res = []byte(fmt.Sprintf("-ERR unknown command %s, with args beginning with:\r\n", cmd.Cmd))
+	if spec.Handler == nil {
+		return Encode(fmt.Errorf("ERR '%s' command is executed by the server", strings.ToLower(spec.Name)), false)
 	}
 
+	// reads and commands freeing memory like DEL keep working when nothing can be evicted
+	if spec.HasFlag(FlagWrite) && !redisDB.evictIfNeeded() && spec.HasFlag(FlagDenyOOM) {
+		redisDB.feedAOF(spec, cmd.Args, false)
+		return constant.ErrorOOM
+	}
+	redisDB.stats.numCommands++
+	if spec.HasFlag(FlagWrite) && redisDB.snapshot != nil {
+		for _, key := range spec.Keys(cmd.Args) {
+			redisDB.beforeWrite(key)
+		}
+	}
+	res := spec.Handler(redisDB, cmd.Args)
+	// values are changed in place by the commands, their size is estimated again
+	if spec.HasFlag(FlagWrite) {
+		for _, key := range spec.Keys(cmd.Args) {
+			redisDB.updateMemory(key)
+		}
+	}
+	// a blocked command did not change anything yet
+	changed := spec.HasFlag(FlagWrite) && (len(res) == 0 || res[0] != '-') && redisDB.blockRequest == nil && !redisDB.unchanged
+	redisDB.unchanged = false
+	if changed {
+		redisDB.dirty.Add(1)
+		for _, key := range spec.Keys(cmd.Args) {
+			redisDB.touchWatchedKey(key)
+		}
+	}
+	redisDB.feedAOF(spec, cmd.Args, changed)
 	return res
 }
@@ -11,16 +11,16 @@ func ActiveDeleteExpiredKeys(redisDB *RedisDB) {
 		var expiredCount = 0
 		var sampleCountRemain = constant.ActiveExpireSampleSized
 
-		for key, expiredTime := range redisDB.GetExpireDict() {
-			sampleCountRemain--
-			// get first ActiveExpireSampleSized elements
-			if sampleCountRemain < 0 {
+		for ; sampleCountRemain > 0; sampleCountRemain-- {
+			// sample ActiveExpireSampleSized random keys with a TTL
+			key, expiredTime, ok := redisDB.GetExpireDict().Random()
+			if !ok {
 				break
 			}
 
 			// if expired then delete and increase expiredcount
 			if time.Now().UnixMilli() > int64(expiredTime) {
-				redisDB.Delete(key)
+				redisDB.deleteExpired(key)
 				expiredCount++
 			}
 		}
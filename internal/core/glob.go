@@ -0,0 +1,78 @@
+package core
+
+// GlobMatch reports whether str matches the glob-style pattern used by KEYS, the SCAN family and PSUBSCRIBE,
+// same rules as redis: * ? [abc] [^abc] [a-z] and \ to escape a special character
+func GlobMatch(pattern string, str string) bool {
+	p, s := 0, 0
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			// consecutive stars are the same as one
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for i := s; i <= len(str); i++ {
+				if GlobMatch(pattern[p+1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == len(str) {
+				return false
+			}
+			s++
+		case '[':
+			if s == len(str) {
+				return false
+			}
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+			match := false
+			for p < len(pattern) && pattern[p] != ']' {
+				switch {
+				case pattern[p] == '\\' && p+1 < len(pattern):
+					p++
+					match = match || pattern[p] == str[s]
+				case p+2 < len(pattern) && pattern[p+1] == '-':
+					start, end := pattern[p], pattern[p+2]
+					if start > end {
+						start, end = end, start
+					}
+					match = match || (str[s] >= start && str[s] <= end)
+					p += 2
+				default:
+					match = match || pattern[p] == str[s]
+				}
+				p++
+			}
+			// an unclosed class ends at the end of the pattern
+			if p == len(pattern) {
+				p--
+			}
+			if match == not {
+				return false
+			}
+			s++
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+		default:
+			if s == len(str) || pattern[p] != str[s] {
+				return false
+			}
+			s++
+		}
+		p++
+	}
+
+	return s == len(str)
+}
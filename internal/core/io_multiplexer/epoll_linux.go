@@ -4,6 +4,7 @@ package io_multiplexer
 
 import (
 	"syscall"
+	"time"
 
 	"github.com/nhtuan0700/godis/internal/config"
 )
@@ -34,9 +35,31 @@ func (ep *Epoll) Monitor(event Event) error {
 	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_ADD, event.Fd, &epollEvent)
 }
 
+// Change the operations monitored for a file descriptor
+func (ep *Epoll) Modify(event Event) error {
+	epollEvent := event.toNative()
+	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_MOD, event.Fd, &epollEvent)
+}
+
+// Remove file descriptor from the monitoring list
+func (ep *Epoll) Unmonitor(fd int) error {
+	// kernels before 2.6.9 require a non-nil event even though it is ignored
+	var epollEvent syscall.EpollEvent
+	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_DEL, fd, &epollEvent)
+}
+
 // Wait for events in the monitoring list
 func (ep *Epoll) Wait() ([]Event, error) {
-	n, err := syscall.EpollWait(ep.fd, ep.epollEvents, -1)
+	return ep.WaitTimeout(-1)
+}
+
+func (ep *Epoll) WaitTimeout(timeout time.Duration) ([]Event, error) {
+	msec := -1
+	if timeout >= 0 {
+		// round up, a timeout below 1ms would otherwise spin
+		msec = int((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+	n, err := syscall.EpollWait(ep.fd, ep.epollEvents, msec)
 	if err != nil {
 		return nil, err
 	}
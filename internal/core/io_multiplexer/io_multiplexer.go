@@ -1,10 +1,16 @@
 package io_multiplexer
 
+import "time"
+
 type Operation uint32
 
 const OpRead = 0
 const OpWrite = 1
 
+// OpReadWrite monitors both readability and writability,
+// used while a connection has replies the socket did not accept yet
+const OpReadWrite = 2
+
 type Event struct {
 	Fd int
 	Op Operation
@@ -12,6 +18,12 @@ type Event struct {
 
 type IOMultiplexer interface {
 	Monitor(event Event) error
+	// Modify changes the operations monitored for an fd that is already monitored
+	Modify(event Event) error
+	// Unmonitor removes the fd from the monitoring list
+	Unmonitor(fd int) error
 	Wait() ([]Event, error)
+	// WaitTimeout is Wait returning no event once timeout elapsed, a negative timeout waits forever
+	WaitTimeout(timeout time.Duration) ([]Event, error)
 	Close() error
 }
@@ -4,6 +4,7 @@ package io_multiplexer
 
 import (
 	"syscall"
+	"time"
 
 	"github.com/nhtuan0700/godis/internal/config"
 )
@@ -29,15 +30,59 @@ func CreateIOMultiplexer() (*KQueue, error) {
 
 // Subscribe file descriptor's event to the monitoring list
 func (kq *KQueue) Monitor(event Event) error {
-	kqEvent := event.toNative(syscall.EV_ADD)
+	kqEvents := event.toNative(syscall.EV_ADD)
 	// Add event.Fd to the monitoring list of kq.fd
-	_, err := syscall.Kevent(kq.fd, []syscall.Kevent_t{kqEvent}, nil, nil)
+	_, err := syscall.Kevent(kq.fd, kqEvents, nil, nil)
+	return err
+}
+
+// Change the operations monitored for a file descriptor
+func (kq *KQueue) Modify(event Event) error {
+	// EV_ADD on an existing filter only updates it
+	if err := kq.Monitor(event); err != nil {
+		return err
+	}
+
+	// Remove the filter that is no longer wanted
+	switch event.Op {
+	case OpRead:
+		return kq.deleteFilter(event.Fd, OpWrite)
+	case OpWrite:
+		return kq.deleteFilter(event.Fd, OpRead)
+	}
+	return nil
+}
+
+// Remove file descriptor from the monitoring list
+func (kq *KQueue) Unmonitor(fd int) error {
+	if err := kq.deleteFilter(fd, OpRead); err != nil {
+		return err
+	}
+	return kq.deleteFilter(fd, OpWrite)
+}
+
+// deleteFilter ignores ENOENT, the filter may not have been added
+func (kq *KQueue) deleteFilter(fd int, op Operation) error {
+	kqEvents := Event{Fd: fd, Op: op}.toNative(syscall.EV_DELETE)
+	_, err := syscall.Kevent(kq.fd, kqEvents, nil, nil)
+	if err == syscall.ENOENT {
+		return nil
+	}
 	return err
 }
 
 // Wait for events in the monitoring list
 func (kq *KQueue) Wait() ([]Event, error) {
-	n, err := syscall.Kevent(kq.fd, nil, kq.kqEvents, nil)
+	return kq.WaitTimeout(-1)
+}
+
+func (kq *KQueue) WaitTimeout(timeout time.Duration) ([]Event, error) {
+	var ts *syscall.Timespec
+	if timeout >= 0 {
+		t := syscall.NsecToTimespec(int64(timeout))
+		ts = &t
+	}
+	n, err := syscall.Kevent(kq.fd, nil, kq.kqEvents, ts)
 	if err != nil {
 		return nil, err
 	}
@@ -6,19 +6,29 @@ import "syscall"
 
 func (e Event) toNative() syscall.EpollEvent {
 	var event uint32 = syscall.EPOLLIN
-	if e.Op == OpWrite {
+	switch e.Op {
+	case OpWrite:
 		event = syscall.EPOLLOUT
+	case OpReadWrite:
+		event = syscall.EPOLLIN | syscall.EPOLLOUT
 	}
 
 	return syscall.EpollEvent{
-		Fd: int32(e.Fd),
+		Fd:     int32(e.Fd),
 		Events: event,
 	}
 }
 
 func createEvent(epollEvent syscall.EpollEvent) Event {
+	// EPOLLHUP and EPOLLERR are reported as read events,
+	// the following read returns the error or EOF and the connection gets closed
+	readable := epollEvent.Events&(syscall.EPOLLIN|syscall.EPOLLHUP|syscall.EPOLLERR) != 0
+	writable := epollEvent.Events&syscall.EPOLLOUT != 0
+
 	var op Operation = OpRead
-	if epollEvent.Events == syscall.EPOLLOUT {
+	if writable && readable {
+		op = OpReadWrite
+	} else if writable {
 		op = OpWrite
 	}
 
@@ -4,18 +4,26 @@ package io_multiplexer
 
 import "syscall"
 
-// get syscall Event from Operation of generic Event
-func (e Event) toNative(flags uint16) syscall.Kevent_t {
-	var filter int16 = syscall.EVFILT_READ // read event
-	if e.Op == OpWrite {
-		filter = syscall.EVFILT_WRITE
+// get syscall Events from Operation of generic Event,
+// kqueue has one filter per operation so OpReadWrite needs two events
+func (e Event) toNative(flags uint16) []syscall.Kevent_t {
+	filters := []int16{syscall.EVFILT_READ} // read event
+	switch e.Op {
+	case OpWrite:
+		filters = []int16{syscall.EVFILT_WRITE}
+	case OpReadWrite:
+		filters = []int16{syscall.EVFILT_READ, syscall.EVFILT_WRITE}
 	}
 
-	return syscall.Kevent_t{
-		Ident:  uint64(e.Fd),
-		Filter: filter,
-		Flags:  flags,
+	kEvents := make([]syscall.Kevent_t, len(filters))
+	for i, filter := range filters {
+		kEvents[i] = syscall.Kevent_t{
+			Ident:  uint64(e.Fd),
+			Filter: filter,
+			Flags:  flags,
+		}
 	}
+	return kEvents
 }
 
 func createEvent(kEvent syscall.Kevent_t) Event {
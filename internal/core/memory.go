@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+const (
+	// an entry of the keyspace table: the key header and the pointers to the object and the next entry
+	dictEntrySize = data_structure.StringHeaderSize + 2*data_structure.PointerSize
+	// an entry of expireDict: the key header and the deadline
+	expireEntrySize = data_structure.StringHeaderSize + 8
+)
+
+// keySize estimates the bytes used by a key: its entry in the keyspace, the key, the object and the value.
+// Collections are sampled, see data_structure.MemoryUsage.
+func (db *RedisDB) keySize(key string, obj *RedisObj, samples int) int64 {
+	size := dictEntrySize + int64(len(key)) + int64(unsafe.Sizeof(*obj)) + valueSize(obj.value, samples)
+	if _, ok := db.expireDict.Get(key); ok {
+		size += expireEntrySize
+	}
+	return size
+}
+
+func valueSize(value any, samples int) int64 {
+	switch v := value.(type) {
+	case string:
+		return data_structure.StringSize(v)
+	case int64:
+		return 8
+	case *data_structure.QuickList:
+		return v.MemoryUsage(samples)
+	case *data_structure.SimpleSet:
+		return v.MemoryUsage(samples)
+	case *data_structure.ZSet:
+		return v.MemoryUsage(samples)
+	case *data_structure.Hash:
+		return v.MemoryUsage(samples)
+	case *data_structure.CMS:
+		return v.MemoryUsage()
+	case *data_structure.BloomFilter:
+		return v.MemoryUsage()
+	}
+	return 0
+}
+
+// UsedMemory returns the bytes used by the keys of the db, it is safe to call from any goroutine
+func (db *RedisDB) UsedMemory() int64 {
+	return db.usedMemory.Load()
+}
+
+// updateMemory estimates the size of key again after a command changed it
+func (db *RedisDB) updateMemory(key string) {
+	obj, ok := db.dict.Get(key)
+	if !ok {
+		return
+	}
+	size := db.keySize(key, obj, config.MemorySamples)
+	db.usedMemory.Add(size - obj.size)
+	obj.size = size
+}
+
+// maxMemory is the share of config.MaxMemory of the db, 0 means no limit
+func (db *RedisDB) maxMemory() int64 {
+	return config.MaxMemory.Load() / int64(db.shards)
+}
+
+// SetShards tells the db it holds one of n shards of the keyspace, so it gets 1/n of config.MaxMemory
+func (db *RedisDB) SetShards(n int) {
+	db.shards = max(n, 1)
+}
+
+// MEMORY USAGE key [SAMPLES count]
+func cmdMEMORY(redisDB *RedisDB, args []string) []byte {
+	switch strings.ToUpper(args[0]) {
+	case "USAGE":
+		if len(args) < 2 {
+			return Encode(errors.New("ERR wrong number of arguments for 'memory|usage' command"), false)
+		}
+		samples := config.MemorySamples
+		if len(args) > 2 {
+			if len(args) != 4 || strings.ToUpper(args[2]) != "SAMPLES" {
+				return Encode(errSyntax, false)
+			}
+			n, err := strconv.Atoi(args[3])
+			if err != nil || n < 0 {
+				return Encode(errNotInteger, false)
+			}
+			// 0 samples every element
+			samples = n
+		}
+		key := args[1]
+		obj := redisDB.Get(key)
+		if obj == nil {
+			return constant.RespNil
+		}
+		return Encode(redisDB.keySize(key, obj, samples), false)
+	default:
+		return Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try MEMORY HELP.", args[0]), false)
+	}
+}
+
+// BytesToHuman formats a number of bytes like redis INFO, e.g. 1.50M
+func BytesToHuman(n int64) string {
+	units := []string{"B", "K", "M", "G", "T"}
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.2f%s", value, units[i])
+}
@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func memoryUsage(t *testing.T, db *core.RedisDB, key string) int64 {
+	decoded, err := core.Decode([]byte(execute(db, "MEMORY", "USAGE", key)))
+	assert.NoError(t, err)
+	usage, _ := decoded.(int64)
+	return usage
+}
+
+func TestMemoryUsage(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, "$-1\r\n", execute(db, "MEMORY", "USAGE", "missing"))
+
+	execute(db, "SET", "small", "v")
+	execute(db, "SET", "large", strings.Repeat("v", 1000))
+	execute(db, "SET", "int", "12345")
+	assert.GreaterOrEqual(t, memoryUsage(t, db, "large")-memoryUsage(t, db, "small"), int64(999))
+	assert.Less(t, memoryUsage(t, db, "int"), memoryUsage(t, db, "small")+int64(len("12345")))
+
+	for i := 0; i < 100; i++ {
+		execute(db, "RPUSH", "list", strings.Repeat("v", 100))
+		execute(db, "SADD", "set", fmt.Sprintf("%0100d", i))
+		execute(db, "ZADD", "zset", fmt.Sprint(i), fmt.Sprintf("%0100d", i))
+		execute(db, "HSET", "hash", fmt.Sprint(i), strings.Repeat("v", 100))
+	}
+	for _, key := range []string{"list", "set", "zset", "hash"} {
+		assert.GreaterOrEqual(t, memoryUsage(t, db, key), int64(100*100), key)
+		assert.Greater(t, memoryUsage(t, db, key), int64(0), key)
+		assert.Regexp(t, `^:\d+\r\n$`, execute(db, "MEMORY", "USAGE", key, "SAMPLES", "0"), key)
+	}
+	execute(db, "CMS.INITBYDIM", "cms", "1000", "5")
+	assert.GreaterOrEqual(t, memoryUsage(t, db, "cms"), int64(1000*5*8))
+	execute(db, "BF.RESERVE", "bf", "0.01", "10000")
+	assert.GreaterOrEqual(t, memoryUsage(t, db, "bf"), int64(10000*9/8))
+
+	assert.Equal(t, "-ERR syntax error\r\n", execute(db, "MEMORY", "USAGE", "list", "SAMPLES"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", execute(db, "MEMORY", "USAGE", "list", "SAMPLES", "-1"))
+	assert.Equal(t, "-ERR unknown subcommand 'NOPE'. Try MEMORY HELP.\r\n", execute(db, "MEMORY", "NOPE"))
+}
+
+func TestUsedMemoryAccounting(t *testing.T) {
+	db := core.NewRedisDB()
+	assert.Equal(t, int64(0), db.UsedMemory())
+
+	// collections stay below config.MemorySamples elements so their size is not extrapolated
+	execute(db, "SET", "str", "value")
+	execute(db, "APPEND", "str", strings.Repeat("v", 100))
+	execute(db, "INCR", "counter")
+	execute(db, "RPUSH", "list", "a", "b", "c")
+	execute(db, "LPOP", "list")
+	execute(db, "HSET", "hash", "f1", "v1", "f2", "v2")
+	execute(db, "HDEL", "hash", "f1")
+	execute(db, "SADD", "set", "a", "b")
+	execute(db, "ZADD", "zset", "1", "a")
+	execute(db, "EXPIRE", "set", "100")
+	execute(db, "RENAME", "zset", "zset2")
+	execute(db, "SET", "gone", "v")
+	execute(db, "DEL", "gone")
+
+	keys := []string{"str", "counter", "list", "hash", "set", "zset2"}
+	var total int64
+	for _, key := range keys {
+		total += memoryUsage(t, db, key)
+	}
+	assert.Equal(t, total, db.UsedMemory())
+	assert.Contains(t, execute(db, "INFO", "memory"), fmt.Sprintf("used_memory:%d\r\n", total))
+
+	for _, key := range keys {
+		execute(db, "DEL", key)
+	}
+	assert.Equal(t, int64(0), db.UsedMemory())
+}
+
+func TestMaxMemoryEviction(t *testing.T) {
+	defer config.MaxMemory.Store(config.MaxMemory.Load())
+	config.MaxMemory.Store(5000)
+
+	db := core.NewRedisDB()
+	value := strings.Repeat("v", 100)
+	for i := 0; i < 200; i++ {
+		execute(db, "SET", fmt.Sprintf("key:%d", i), value)
+		// a write may go above the limit, the next one evicts first
+		assert.LessOrEqual(t, db.UsedMemory(), config.MaxMemory.Load()+memoryUsage(t, db, "key:0")+200)
+	}
+	assert.Regexp(t, `^:[1-9]\d?\r\n$`, execute(db, "DBSIZE"))
+	// the key written last is never evicted by its own command
+	assert.Equal(t, ":1\r\n", execute(db, "EXISTS", "key:199"))
+
+	// reads do not evict
+	config.MaxMemory.Store(1)
+	execute(db, "GET", "key:199")
+	assert.Equal(t, ":1\r\n", execute(db, "EXISTS", "key:199"))
+	execute(db, "SET", "last", "v")
+	assert.Equal(t, ":1\r\n", execute(db, "DBSIZE"))
+}
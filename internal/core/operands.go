@@ -0,0 +1,169 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+// Operands are the source keys of a command combining sets or sorted sets, like ZUNIONSTORE, when they are
+// owned by other workers than the one executing it. The server reads them on their workers with ReadOperands
+// and passes them with the task. A sorted set maps its members to their scores and a set maps them to 1.
+type Operands map[string]map[string]float64
+
+// ReadOperands reads the source keys of cmd on the worker owning them, or returns the WRONGTYPE reply
+// when one holds a type cmd does not accept. The sorted set commands accept sets too.
+func ReadOperands(redisDB *RedisDB, cmd *Command, keys []string) (Operands, []byte) {
+	spec := LookupCommand(cmd.Cmd)
+	zsets := spec != nil && spec.Group == "sorted-set"
+	operands := make(Operands, len(keys))
+	for _, key := range keys {
+		input, errReply := redisDB.readOperand(key, zsets)
+		if errReply != nil {
+			return nil, errReply
+		}
+		// the worker executing the command gets a copy, the key may change meanwhile
+		members := make(map[string]float64, input.Len())
+		input.Range(func(member string, score float64) bool {
+			members[member] = score
+			return true
+		})
+		operands[key] = members
+	}
+	return operands, nil
+}
+
+// operand is a source key of a command combining sets or sorted sets, it is read in place
+type operand interface {
+	Len() int
+	// Score returns the score of member, 1 for the members of a set
+	Score(member string) (float64, bool)
+	// Range calls fn for every member until it returns false
+	Range(fn func(member string, score float64) bool)
+}
+
+// scores is an operand read on another worker or a sorted set, a key that does not exist has no member
+type scores map[string]float64
+
+func (s scores) Len() int {
+	return len(s)
+}
+
+func (s scores) Score(member string) (float64, bool) {
+	score, ok := s[member]
+	return score, ok
+}
+
+func (s scores) Range(fn func(member string, score float64) bool) {
+	for member, score := range s {
+		if !fn(member, score) {
+			return
+		}
+	}
+}
+
+// setOperand is a set, its members score 1
+type setOperand struct {
+	set *data_structure.SimpleSet
+}
+
+func (s setOperand) Len() int {
+	return s.set.Len()
+}
+
+func (s setOperand) Score(member string) (float64, bool) {
+	return 1, s.set.IsMember(member) == 1
+}
+
+func (s setOperand) Range(fn func(member string, score float64) bool) {
+	s.set.Range(func(member string) bool {
+		return fn(member, 1)
+	})
+}
+
+// readOperands reads the source keys of the command being executed, the sorted set commands pass zsets.
+// The operands must not be used once the command wrote the keyspace.
+func (db *RedisDB) readOperands(keys []string, zsets bool) ([]operand, []byte) {
+	res := make([]operand, len(keys))
+	for i, key := range keys {
+		input, errReply := db.readOperand(key, zsets)
+		if errReply != nil {
+			return nil, errReply
+		}
+		res[i] = input
+	}
+	return res, nil
+}
+
+// readOperand reads a source key from the operands of the command being executed,
+// or from the db when the key is owned by this worker
+func (db *RedisDB) readOperand(key string, zsets bool) (operand, []byte) {
+	if members, ok := db.operands[key]; ok {
+		return scores(members), nil
+	}
+	obj := db.Get(key)
+	if obj == nil {
+		return scores(nil), nil
+	}
+	switch value := obj.value.(type) {
+	case *data_structure.SimpleSet:
+		return setOperand{value}, nil
+	case *data_structure.ZSet:
+		if zsets {
+			return scores(value.Scores()), nil
+		}
+	}
+	return nil, constant.ErrorWrongTypeKey
+}
+
+// smallest returns the operand with the fewest members, the only candidates of an intersection
+func smallest(inputs []operand) operand {
+	res := inputs[0]
+	for _, input := range inputs[1:] {
+		if input.Len() < res.Len() {
+			res = input
+		}
+	}
+	return res
+}
+
+// parseInterCard parses numkeys key [key ...] [LIMIT limit] of ZINTERCARD and SINTERCARD
+func parseInterCard(args []string) ([]string, int, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 {
+		return nil, 0, errors.New("ERR numkeys should be greater than 0")
+	}
+	if numKeys > len(args)-1 {
+		return nil, 0, errors.New("ERR Number of keys can't be greater than number of args")
+	}
+	keys, options := args[1:1+numKeys], args[1+numKeys:]
+	limit := 0
+	switch {
+	case len(options) == 0:
+	case len(options) == 2 && strings.ToUpper(options[0]) == "LIMIT":
+		if limit, err = strconv.Atoi(options[1]); err != nil || limit < 0 {
+			return nil, 0, errors.New("ERR LIMIT can't be negative")
+		}
+	default:
+		return nil, 0, errSyntax
+	}
+	return keys, limit, nil
+}
+
+// interCard counts the members found in every input, it stops at limit unless it is 0
+func interCard(inputs []operand, limit int) int {
+	count := 0
+	smallest(inputs).Range(func(member string, _ float64) bool {
+		for _, input := range inputs {
+			if _, ok := input.Score(member); !ok {
+				return true
+			}
+		}
+		count++
+		return count != limit
+	})
+	return count
+}
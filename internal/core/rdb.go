@@ -0,0 +1,419 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core/data_structure"
+)
+
+// Snapshot file layout:
+//
+//	"GODIS" | version (2 bytes) | entry ... | EOF opcode | CRC-64 of everything before it (8 bytes)
+//
+// entry: [expire opcode | unix ms deadline (8 bytes)] | value type | key | value
+// Keys and values are length prefixed, values are encoded by their data structure.
+const (
+	rdbMagic          = "GODIS"
+	RDBVersion uint16 = 1
+)
+
+// value types
+const (
+	rdbTypeString byte = iota
+	rdbTypeSet
+	rdbTypeZSet
+	rdbTypeCMS
+	rdbTypeBloom
+	rdbTypeHash
+	rdbTypeList
+)
+
+// opcodes, they never collide with value types
+const (
+	rdbOpExpireMs byte = 0xFC
+	rdbOpEOF      byte = 0xFF
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+var ErrBadSnapshot = errors.New("bad snapshot file")
+
+// DumpSnapshot appends every key that is not expired to buf, in the snapshot entry format.
+// Workers dump their own shard, the server writes the entries of all shards in one file.
+func (db *RedisDB) DumpSnapshot(buf []byte) ([]byte, error) {
+	now := uint64(time.Now().UnixMilli())
+	var err error
+	db.dict.Range(func(key string, obj *RedisObj) bool {
+		buf, err = db.appendEntry(buf, key, obj, now)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// appendEntry appends key to buf in the snapshot entry format, unless it is expired at now
+func (db *RedisDB) appendEntry(buf []byte, key string, obj *RedisObj, now uint64) ([]byte, error) {
+	exp, hasExpiry := db.expireDict.Get(key)
+	if hasExpiry && exp <= now {
+		return buf, nil
+	}
+
+	valueType, value, err := encodeValue(obj.value)
+	if err != nil {
+		return buf, fmt.Errorf("key %s: %w", key, err)
+	}
+
+	if hasExpiry {
+		buf = append(buf, rdbOpExpireMs)
+		buf = binary.LittleEndian.AppendUint64(buf, exp)
+	}
+	buf = append(buf, valueType)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return buf, nil
+}
+
+// snapshotChunkKeys is about the number of keys a chunk of a Snapshot dumps
+const snapshotChunkKeys = 1024
+
+// Snapshot dumps the keys of a db as they were when BeginSnapshot was called, a chunk at a time,
+// so the db keeps executing commands between the chunks instead of waiting for the whole dump.
+// A write to a key that is not dumped yet dumps its old value first, which is the copy on write
+// redis gets from forking the process that saves.
+type Snapshot struct {
+	db     *RedisDB
+	now    uint64
+	cursor uint64
+	done   bool
+	// buf holds the entries of the chunk being dumped, then it is moved to chunks:
+	// one buffer growing up to the size of the db would be copied whole on every growth
+	buf    []byte
+	chunks [][]byte
+	err    error
+	// dumped are the keys in buf and the keys created since the beginning, the next chunks skip them
+	dumped map[string]struct{}
+}
+
+// BeginSnapshot starts a snapshot of db, DumpChunk must be called until it returns true before a new one begins
+func (db *RedisDB) BeginSnapshot() *Snapshot {
+	s := &Snapshot{
+		db:     db,
+		now:    uint64(time.Now().UnixMilli()),
+		dumped: make(map[string]struct{}),
+	}
+	db.snapshot = s
+	return s
+}
+
+// DumpChunk dumps the next keys of the snapshot, it returns true once every key is dumped.
+// It must be called by the goroutine executing the commands of the db.
+func (s *Snapshot) DumpChunk() bool {
+	if s.done {
+		return true
+	}
+	// empty buckets are bounded too, a table shrinking later can be sparse
+	for dumped, visits := 0, 0; dumped < snapshotChunkKeys && visits < 10*snapshotChunkKeys; visits++ {
+		s.cursor = s.db.dict.Scan(s.cursor, func(key string, obj *RedisObj) {
+			if _, ok := s.dumped[key]; !ok {
+				s.add(key, obj)
+				dumped++
+			}
+		})
+		if s.cursor == 0 || s.err != nil {
+			s.flush()
+			s.done = true
+			s.db.snapshot = nil
+			s.dumped = nil
+			return true
+		}
+	}
+	s.flush()
+	return false
+}
+
+func (s *Snapshot) flush() {
+	if len(s.buf) > 0 {
+		s.chunks = append(s.chunks, s.buf)
+		s.buf = nil
+	}
+}
+
+// Entries returns the keys dumped in the snapshot entry format, a slice per chunk, once DumpChunk returned true
+func (s *Snapshot) Entries() ([][]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.chunks, nil
+}
+
+func (s *Snapshot) add(key string, obj *RedisObj) {
+	s.dumped[key] = struct{}{}
+	if s.err == nil {
+		s.buf, s.err = s.db.appendEntry(s.buf, key, obj, s.now)
+	}
+}
+
+// beforeWrite dumps the value key had when the snapshot in progress began before it changes
+func (db *RedisDB) beforeWrite(key string) {
+	s := db.snapshot
+	if s == nil {
+		return
+	}
+	if _, ok := s.dumped[key]; ok {
+		return
+	}
+	if obj, ok := db.dict.Get(key); ok {
+		s.add(key, obj)
+	} else {
+		s.dumped[key] = struct{}{}
+	}
+}
+
+func encodeValue(value any) (byte, []byte, error) {
+	var valueType byte
+	switch v := value.(type) {
+	case string:
+		return rdbTypeString, []byte(v), nil
+	case int64:
+		return rdbTypeString, strconv.AppendInt(nil, v, 10), nil
+	case *data_structure.SimpleSet:
+		valueType = rdbTypeSet
+	case *data_structure.ZSet:
+		valueType = rdbTypeZSet
+	case *data_structure.CMS:
+		valueType = rdbTypeCMS
+	case *data_structure.BloomFilter:
+		valueType = rdbTypeBloom
+	case *data_structure.Hash:
+		valueType = rdbTypeHash
+	case *data_structure.QuickList:
+		valueType = rdbTypeList
+	default:
+		return 0, nil, fmt.Errorf("unsupported value type %T", value)
+	}
+
+	data, err := value.(encoding.BinaryMarshaler).MarshalBinary()
+	return valueType, data, err
+}
+
+func decodeValue(valueType byte, data []byte) (any, error) {
+	var value encoding.BinaryUnmarshaler
+	switch valueType {
+	case rdbTypeString:
+		return compactString(string(data)), nil
+	case rdbTypeSet:
+		value = data_structure.NewSimpleSet()
+	case rdbTypeZSet:
+		value = data_structure.NewZSet()
+	case rdbTypeCMS:
+		value = &data_structure.CMS{}
+	case rdbTypeBloom:
+		value = &data_structure.BloomFilter{}
+	case rdbTypeHash:
+		value = data_structure.NewHash()
+	case rdbTypeList:
+		value = data_structure.NewQuickList()
+	default:
+		return nil, fmt.Errorf("%w: unknown value type %d", ErrBadSnapshot, valueType)
+	}
+
+	if err := value.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WriteSnapshot writes a complete snapshot made of the entries dumped by every shard
+func WriteSnapshot(w io.Writer, shards ...[]byte) error {
+	crc := crc64.New(crcTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	bw.WriteString(rdbMagic)
+	binary.Write(bw, binary.LittleEndian, RDBVersion)
+	for _, entries := range shards {
+		bw.Write(entries)
+	}
+	bw.WriteByte(rdbOpEOF)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, crc.Sum64())
+}
+
+// SaveSnapshotFile writes the snapshot to a temporary file and renames it,
+// so a crash in the middle of a save never leaves a truncated file behind
+func SaveSnapshotFile(path string, shards ...[]byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf("temp-%d-*.rdb", os.Getpid()))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := WriteSnapshot(tmp, shards...); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadSnapshot validates the snapshot and calls fn for every key that is not expired yet,
+// expireAtMs is 0 for keys without expiry.
+// Nothing is passed to fn unless the whole file is valid.
+func ReadSnapshot(data []byte, fn func(key string, obj *RedisObj, expireAtMs uint64)) error {
+	entries, n, err := readSnapshotPrefix(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("%w: unexpected data after the checksum", ErrBadSnapshot)
+	}
+
+	loadSnapshotEntries(entries, fn)
+	return nil
+}
+
+type snapshotEntry struct {
+	key        string
+	valueType  byte
+	value      []byte
+	expireAtMs uint64
+	obj        *RedisObj
+}
+
+// readSnapshotPrefix reads the snapshot at the head of data and returns its length,
+// an AOF starts with a snapshot followed by commands.
+func readSnapshotPrefix(data []byte) ([]snapshotEntry, int, error) {
+	headerLen := len(rdbMagic) + 2
+	if len(data) < headerLen+1+8 || !bytes.Equal(data[:len(rdbMagic)], []byte(rdbMagic)) {
+		return nil, 0, ErrBadSnapshot
+	}
+	version := binary.LittleEndian.Uint16(data[len(rdbMagic):])
+	if version > RDBVersion {
+		return nil, 0, fmt.Errorf("%w: can't handle version %d", ErrBadSnapshot, version)
+	}
+
+	var entries []snapshotEntry
+	pos := headerLen
+	next := func(n int) ([]byte, error) {
+		if n < 0 || pos+n > len(data) {
+			return nil, ErrBadSnapshot
+		}
+		b := data[pos : pos+n]
+		pos += n
+		return b, nil
+	}
+	nextString := func() ([]byte, error) {
+		n, size := binary.Uvarint(data[pos:])
+		if size <= 0 || n > uint64(len(data)) {
+			return nil, ErrBadSnapshot
+		}
+		pos += size
+		return next(int(n))
+	}
+
+	for {
+		op, err := next(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		if op[0] == rdbOpEOF {
+			break
+		}
+
+		var expireAtMs uint64
+		if op[0] == rdbOpExpireMs {
+			exp, err := next(8)
+			if err != nil {
+				return nil, 0, err
+			}
+			expireAtMs = binary.LittleEndian.Uint64(exp)
+			if op, err = next(1); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		key, err := nextString()
+		if err != nil {
+			return nil, 0, err
+		}
+		value, err := nextString()
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, snapshotEntry{key: string(key), valueType: op[0], value: value, expireAtMs: expireAtMs})
+	}
+
+	body := data[:pos]
+	checksum, err := next(8)
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc64.Checksum(body, crcTable) != binary.LittleEndian.Uint64(checksum) {
+		return nil, 0, fmt.Errorf("%w: wrong checksum", ErrBadSnapshot)
+	}
+
+	// values are decoded once the checksum is verified
+	for i := range entries {
+		value, err := decodeValue(entries[i].valueType, entries[i].value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("key %s: %w", entries[i].key, err)
+		}
+		entries[i].obj = NewRedisObj(value)
+	}
+	return entries, pos, nil
+}
+
+func loadSnapshotEntries(entries []snapshotEntry, fn func(key string, obj *RedisObj, expireAtMs uint64)) {
+	now := uint64(time.Now().UnixMilli())
+	for _, e := range entries {
+		// keys that expired while the server was down are not loaded
+		if e.expireAtMs > 0 && e.expireAtMs <= now {
+			continue
+		}
+		fn(e.key, e.obj, e.expireAtMs)
+	}
+}
+
+// LoadSnapshotFile reads the snapshot at path, see ReadSnapshot
+func LoadSnapshotFile(path string, fn func(key string, obj *RedisObj, expireAtMs uint64)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ReadSnapshot(data, fn)
+}
+
+// Restore puts a key loaded from a snapshot, expireAtMs is an absolute unix ms deadline or 0
+func (db *RedisDB) Restore(key string, obj *RedisObj, expireAtMs uint64) {
+	db.Delete(key)
+	db.dict.Set(key, obj)
+	if expireAtMs > 0 {
+		db.expireDict.Set(key, expireAtMs)
+	}
+	db.updateMemory(key)
+}
@@ -0,0 +1,116 @@
+package core_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "str", "hello")
+	execute(db, "INCRBY", "counter", "-42")
+	execute(db, "SET", "ttl", "v", "EX", "100")
+	execute(db, "SADD", "set", "a", "b")
+	execute(db, "ZADD", "zset", "1", "a", "2", "b")
+	execute(db, "CMS.INITBYDIM", "cms", "10", "2")
+	execute(db, "CMS.INCRBY", "cms", "a", "5")
+	execute(db, "BF.ADD", "bf", "a")
+
+	entries, err := db.DumpSnapshot(nil)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	assert.NoError(t, core.SaveSnapshotFile(path, entries))
+
+	loaded := core.NewRedisDB()
+	assert.NoError(t, core.LoadSnapshotFile(path, loaded.Restore))
+
+	assert.Equal(t, "$5\r\nhello\r\n", execute(loaded, "GET", "str"))
+	assert.Equal(t, ":-41\r\n", execute(loaded, "INCR", "counter"))
+	assert.Regexp(t, `^:99\d{3}\r\n$`, execute(loaded, "PTTL", "ttl"))
+	assert.Equal(t, ":-1\r\n", execute(loaded, "TTL", "str"))
+	assert.Equal(t, ":1\r\n", execute(loaded, "SISMEMBER", "set", "b"))
+	assert.Equal(t, ":1\r\n", execute(loaded, "ZRANK", "zset", "b"))
+	assert.Equal(t, "*1\r\n:5\r\n", execute(loaded, "CMS.QUERY", "cms", "a"))
+	assert.Equal(t, ":1\r\n", execute(loaded, "BF.EXISTS", "bf", "a"))
+}
+
+func TestSnapshotSkipsExpiredKeys(t *testing.T) {
+	db := core.NewRedisDB()
+	db.Restore("expired", core.NewRedisObj("v"), uint64(time.Now().UnixMilli())-1)
+	execute(db, "SET", "long", "v")
+
+	entries, err := db.DumpSnapshot(nil)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, core.WriteSnapshot(&buf, entries))
+
+	var keys []string
+	assert.NoError(t, core.ReadSnapshot(buf.Bytes(), func(key string, _ *core.RedisObj, _ uint64) {
+		keys = append(keys, key)
+	}))
+	assert.Equal(t, []string{"long"}, keys)
+}
+
+func TestSnapshotInChunks(t *testing.T) {
+	db := core.NewRedisDB()
+	for i := range 3000 {
+		execute(db, "SET", "k"+strconv.Itoa(i), "old")
+	}
+	execute(db, "RPUSH", "list", "a")
+
+	snap := db.BeginSnapshot()
+	assert.False(t, snap.DumpChunk())
+	// the keys written between the chunks are saved with their value from the beginning
+	for i := range 3000 {
+		execute(db, "SET", "k"+strconv.Itoa(i), "new")
+	}
+	execute(db, "DEL", "k1")
+	execute(db, "RPUSH", "list", "b")
+	execute(db, "SET", "created", "v")
+	for !snap.DumpChunk() {
+		execute(db, "DEL", "k2")
+	}
+
+	entries, err := snap.Entries()
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, core.WriteSnapshot(&buf, entries...))
+	loaded := core.NewRedisDB()
+	assert.NoError(t, core.ReadSnapshot(buf.Bytes(), loaded.Restore))
+
+	assert.Equal(t, ":3001\r\n", execute(loaded, "DBSIZE"))
+	for i := range 3000 {
+		assert.Equal(t, "$3\r\nold\r\n", execute(loaded, "GET", "k"+strconv.Itoa(i)))
+	}
+	assert.Equal(t, ":1\r\n", execute(loaded, "LLEN", "list"))
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "created"))
+	// the writes after the last chunk are not copied anymore
+	execute(db, "SET", "k3", "newer")
+	after, _ := snap.Entries()
+	assert.Len(t, after, len(entries))
+}
+
+func TestSnapshotCorrupted(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v")
+	entries, _ := db.DumpSnapshot(nil)
+	var buf bytes.Buffer
+	assert.NoError(t, core.WriteSnapshot(&buf, entries))
+	data := buf.Bytes()
+
+	called := false
+	fn := func(string, *core.RedisObj, uint64) { called = true }
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0xff
+	assert.ErrorIs(t, core.ReadSnapshot(flipped, fn), core.ErrBadSnapshot)
+	assert.ErrorIs(t, core.ReadSnapshot(data[:len(data)-3], fn), core.ErrBadSnapshot)
+	assert.ErrorIs(t, core.ReadSnapshot([]byte("REDIS0011"), fn), core.ErrBadSnapshot)
+	assert.False(t, called)
+}
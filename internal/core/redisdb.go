@@ -1,7 +1,7 @@
 package core
 
 import (
-	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/nhtuan0700/godis/internal/config"
@@ -9,142 +9,232 @@ import (
 )
 
 type RedisDB struct {
-	dict       map[string]*RedisObj
-	expireDict map[string]uint64
+	// dict is a HashTable rather than a map for the SCAN cursor and RANDOMKEY
+	dict *data_structure.HashTable[*RedisObj]
+	// expireDict maps the keys with a TTL to their unix ms deadline,
+	// the volatile eviction policies and the active expiration sample it at random
+	expireDict *data_structure.HashTable[uint64]
 	epool      data_structure.EvictionPool
+	// dirty counts the write commands since the last snapshot,
+	// it is atomic so the server can check the save policy without going through the worker
+	dirty atomic.Int64
+	// usedMemory is the sum of the sizes of the keys, see updateMemory,
+	// it is atomic so INFO can be computed without going through the worker
+	usedMemory atomic.Int64
+	// counters reported by INFO stats
+	stats stats
+	// shards is the number of dbs holding the keyspace, each one gets an equal share of config.MaxMemory
+	shards int
+
+	aof *AOF
+	// commands to log to the AOF for the command being executed, see feedAOF
+	propagated [][]string
+	rewritten  [][]string
+	// unchanged is set by a write command that changed nothing, see preventPropagation
+	unchanged bool
+
+	// tasks of blocking commands waiting for a key to be created, see blocking.go
+	blockRequest *blockRequest
+	blocked      map[string][]*blockedTask
+	blockedTasks map[*Task]*blockedTask
+	readyKeys    []string
+	// blockedVersion changes whenever a task is parked or unparked
+	blockedVersion uint64
+
+	// operands are the source keys of the command being executed read on other workers, see operands.go
+	operands Operands
+
+	// watched are the keys watched by clients for their transactions, see transaction.go
+	watched map[string]*watchedKey
+	// transactionAOF holds the commands logged by the transaction being executed, nil outside of EXEC
+	transactionAOF [][]string
+
+	// snapshot is the background snapshot in progress, the keys it did not dump yet are dumped before they change
+	snapshot *Snapshot
 }
 
 func NewRedisDB() *RedisDB {
 	return &RedisDB{
-		dict:       make(map[string]*RedisObj),
-		expireDict: make(map[string]uint64),
+		dict:       data_structure.NewHashTable[*RedisObj](),
+		expireDict: data_structure.NewHashTable[uint64](),
 		epool:      *data_structure.NewEpool(config.EpoolMaxSize),
+		shards:     1,
+
+		blocked:      make(map[string][]*blockedTask),
+		blockedTasks: make(map[*Task]*blockedTask),
+		watched:      make(map[string]*watchedKey),
 	}
 }
 
+// Dirty returns the number of write commands since the last snapshot, it is safe to call from any goroutine
+func (db *RedisDB) Dirty() int64 {
+	return db.dirty.Load()
+}
+
+// ClearDirty forgets the n changes written by a snapshot, changes made after the snapshot are kept
+func (db *RedisDB) ClearDirty(n int64) {
+	db.dirty.Add(-n)
+}
+
+// stats are the counters of INFO stats, CONFIG RESETSTAT clears them
+type stats struct {
+	numCommands int64
+	expiredKeys int64
+	evictedKeys int64
+}
+
+// ResetStats clears the counters of INFO stats
+func (db *RedisDB) ResetStats() {
+	db.stats = stats{}
+}
+
 // In redis, it will define a const for each type, and the RedisObj will have a field to indicate the type of value it holds.
 // For simplicity, we just check the type when casting the value.
 type RedisObj struct {
 	value          any
 	lastAccessTime uint32
+	// lfuCounter is the logarithmic access counter for the LFU eviction policies,
+	// it is decremented for the minutes elapsed since lfuTime, see lfuDecr
+	lfuCounter uint8
+	lfuTime    uint16
+	// size is the estimate of the bytes used by the key accounted in usedMemory
+	size int64
 }
 
 func NewRedisObj(v any) *RedisObj {
+	now := time.Now()
 	obj := &RedisObj{
 		value:          v,
-		lastAccessTime: uint32(time.Now().UnixMilli()),
+		lastAccessTime: uint32(now.UnixMilli()),
+		lfuCounter:     data_structure.LFUInitVal,
+		lfuTime:        data_structure.LFUTimeInMinutes(now),
 	}
 
 	return obj
 }
 
 func (db *RedisDB) Get(key string) *RedisObj {
-	if obj, ok := db.dict[key]; ok {
+	obj := db.getNoTouch(key)
+	if obj != nil {
+		obj.lastAccessTime = uint32(time.Now().UnixMilli())
+		obj.updateLFU()
+	}
+	return obj
+}
+
+// getNoTouch is Get without counting an access to the key, for introspection like OBJECT FREQ
+func (db *RedisDB) getNoTouch(key string) *RedisObj {
+	if obj, ok := db.dict.Get(key); ok {
 		// delete epxired key in passive mode
 		if db.HasExpired(key) {
-			db.Delete(key)
+			db.deleteExpired(key)
 			return nil
 		}
-		obj.lastAccessTime = uint32(time.Now().UnixMilli())
 		return obj
 	}
 
 	return nil
 }
 
+// Set stores obj at key, replacing the previous value and its TTL, ttlMs 0 means no TTL
 func (db *RedisDB) Set(key string, obj *RedisObj, ttlMs uint64) {
-	if len(db.dict) == config.MaxKeyNumber {
-		db.evict()
+	old, exist := db.dict.Get(key)
+	if exist && old != obj {
+		db.usedMemory.Add(-old.size)
+		// the new value keeps the access frequency of the key like redis
+		obj.lfuCounter, obj.lfuTime = old.lfuCounter, old.lfuTime
 	}
 
-	db.dict[key] = obj
+	db.dict.Set(key, obj)
+	if !exist {
+		db.signalKeyAsReady(key)
+	}
 
 	if ttlMs > 0 {
 		db.SetExpiry(key, ttlMs)
+	} else {
+		db.RemoveExpiry(key)
 	}
+	db.updateMemory(key)
 }
 
 func (db *RedisDB) Delete(key string) bool {
-	delete(db.expireDict, key)
+	db.beforeWrite(key)
+	db.expireDict.Delete(key)
+	if obj, ok := db.dict.Get(key); ok {
+		db.usedMemory.Add(-obj.size)
+		obj.size = 0
+	}
+	return db.dict.Delete(key)
+}
 
-	_, ok := db.dict[key]
-	if !ok {
+// Rename moves the value and its expiry to newKey, overwriting newKey.
+// It returns false if key does not exist.
+func (db *RedisDB) Rename(key string, newKey string) bool {
+	obj := db.Get(key)
+	if obj == nil {
 		return false
 	}
+	if key == newKey {
+		return true
+	}
 
-	delete(db.dict, key)
+	exp, hasExpiry := db.expireDict.Get(key)
+	db.Delete(key)
+	db.Delete(newKey)
+	db.dict.Set(newKey, obj)
+	if hasExpiry {
+		db.expireDict.Set(newKey, exp)
+	}
+	db.updateMemory(newKey)
+	db.signalKeyAsReady(newKey)
 	return true
 }
 
-func (db *RedisDB) GetExpireDict() map[string]uint64 {
+func (db *RedisDB) GetExpireDict() *data_structure.HashTable[uint64] {
 	return db.expireDict
 }
 
 func (db *RedisDB) SetExpiry(key string, ttl uint64) {
-	db.expireDict[key] = uint64(time.Now().UnixMilli()) + ttl
+	db.expireDict.Set(key, uint64(time.Now().UnixMilli())+ttl)
 }
 
-func (db *RedisDB) GetExpiry(key string) (uint64, bool) {
-	ttl, exist := db.expireDict[key]
-	return ttl, exist
+// SetExpireAt sets an absolute unix ms deadline
+func (db *RedisDB) SetExpireAt(key string, expireAtMs uint64) {
+	db.expireDict.Set(key, expireAtMs)
 }
 
-func (db *RedisDB) HasExpired(key string) bool {
-	if ttl, exist := db.expireDict[key]; exist {
-		return ttl <= uint64(time.Now().UnixMilli())
-	}
-
-	return false
+// RemoveExpiry makes the key persistent, it returns false when the key had no TTL
+func (db *RedisDB) RemoveExpiry(key string) bool {
+	return db.expireDict.Delete(key)
 }
 
-func (db *RedisDB) evict() {
-	switch config.EvictPolicy {
-	case "allkeys-random":
-		db.evictRandom()
-	case "allkeys-lru":
-		db.evictLru()
-	}
+// GetExpiry returns the unix ms deadline of key, ok is false when the key has no TTL
+func (db *RedisDB) GetExpiry(key string) (uint64, bool) {
+	return db.expireDict.Get(key)
 }
 
-// populateEpool push the new items with sampled size to the pool
-func (db *RedisDB) populateEpool() {
-	remain := config.LruSampledSize
-	for k, v := range db.dict {
-		db.epool.Push(k, v.lastAccessTime)
-		remain--
-		if remain == 0 {
-			break
-		}
-	}
-
-	log.Println("Epool: ")
-	for _, item := range db.epool.Pool() {
-		log.Println(item.Key(), item.LastAccessTime())
+// TTL returns the milliseconds left before key expires, ok is false when the key has no TTL.
+// The subtraction is signed, it is 0 or negative when the deadline is reached but the key is not deleted yet.
+func (db *RedisDB) TTL(key string) (ttlMs int64, ok bool) {
+	exp, ok := db.expireDict.Get(key)
+	if !ok {
+		return 0, false
 	}
+	return int64(exp) - time.Now().UnixMilli(), true
 }
 
-func (db *RedisDB) evictLru() {
-	db.populateEpool()
-
-	evictCount := int64(config.EvictionRatio * float64(config.MaxKeyNumber))
-	log.Println("Trigger LRU eviction")
-	for i := 0; i < int(evictCount) && len(db.epool.Pool()) > 0; i++ {
-		item := db.epool.Pop()
-		log.Println("Delete key ", item.Key())
-		db.Delete(item.Key())
+func (db *RedisDB) HasExpired(key string) bool {
+	if ttl, exist := db.expireDict.Get(key); exist {
+		return ttl <= uint64(time.Now().UnixMilli())
 	}
-}
 
-func (db *RedisDB) evictRandom() {
-	evictCount := int64(config.EvictionRatio * float64(config.MaxKeyNumber))
+	return false
+}
 
-	for k := range db.dict {
-		if evictCount == 0 {
-			break
-		}
-		log.Println("Trigger evict random")
-		log.Println("delete key: ", k)
-		evictCount--
-		db.Delete(k)
-	}
+// deleteExpired deletes a key whose TTL is reached
+func (db *RedisDB) deleteExpired(key string) {
+	db.Delete(key)
+	db.stats.expiredKeys++
+	db.touchWatchedKey(key)
 }
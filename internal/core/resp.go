@@ -9,69 +9,124 @@ import (
 	"github.com/nhtuan0700/godis/internal/constant"
 )
 
+// ErrIncompleteRESP is returned when data ends in the middle of a RESP value.
+// A stream reader should keep the bytes and wait for the rest to arrive.
+var ErrIncompleteRESP = errors.New("incorrect RESP standard format")
+
+// ErrProtocol is returned when data can never become a valid RESP value,
+// the connection that sent it should be closed.
+var ErrProtocol = errors.New("ERR Protocol error")
+
+// Same limit as redis proto-max-bulk-len
+const maxBulkLen = 512 * 1024 * 1024
+
+// Same limit as redis for the number of arguments of a command sent by a client
+const maxMultibulkLen = 1024 * 1024
+
+// readLine returns the position of the '\r' that ends the line starting at data[0]
+func readLine(data []byte) (int, error) {
+	pos := bytes.IndexByte(data, '\r')
+	if pos < 0 || pos+1 >= len(data) {
+		return 0, ErrIncompleteRESP
+	}
+	if data[pos+1] != '\n' {
+		return 0, ErrProtocol
+	}
+	return pos, nil
+}
+
 // +OK\r\n => OK, 5, nil
 // return params:
 // 1: original string
 // 2: next pos for next string
 // 3: error
 func readSimpleString(data []byte) (string, int, error) {
-	pos := 1
-	for pos < len(data) && data[pos] != '\r' {
-		pos++
-	}
-	if pos == len(data) {
-		return "", 0, errors.New("incorrect RESP standard format")
+	pos, err := readLine(data)
+	if err != nil {
+		return "", 0, err
 	}
 	return string(data[1:pos]), pos + 2, nil
 }
 
 // :-100\r\n => -100, 7, nil
 func readInt64(data []byte) (int64, int, error) {
+	end, err := readLine(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
 	pos := 1
 	var signed int64 = 1
-	if data[pos] == '-' {
+	if pos < end && data[pos] == '-' {
 		signed = -1
 		pos++
-	}
-	if data[pos] == '+' {
+	} else if pos < end && data[pos] == '+' {
 		pos++
 	}
+	if pos == end {
+		return 0, 0, ErrProtocol
+	}
 
 	var value int64
-	for pos < len(data) && data[pos] != '\r' {
+	for ; pos < end; pos++ {
+		if data[pos] < '0' || data[pos] > '9' {
+			return 0, 0, ErrProtocol
+		}
 		value = value*10 + int64(data[pos]-'0')
-		pos++
 	}
 
-	if pos == len(data) {
-		return 0, 0, errors.New("incorrect RESP standard format")
-	}
-	return signed * value, pos + 2, nil
+	return signed * value, end + 2, nil
 }
 
 // $5\r\nhello\r\n => 5, 4
-func readLen(data []byte) (int, int) {
-	res, pos, _ := readInt64(data)
-	return int(res), pos
+func readLen(data []byte) (int, int, error) {
+	res, pos, err := readInt64(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	if res < -1 || res > maxBulkLen {
+		return 0, 0, ErrProtocol
+	}
+	return int(res), pos, nil
 }
 
 // $5\r\nhello\r\n => hello, 11
-func readBulkString(data []byte) (string, int, error) {
-	length, pos := readLen(data)
+// $-1\r\n => nil, 5
+func readBulkString(data []byte) (any, int, error) {
+	length, pos, err := readLen(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length == -1 {
+		return nil, pos, nil
+	}
+	if pos+length+2 > len(data) {
+		return nil, 0, ErrIncompleteRESP
+	}
+	if data[pos+length] != '\r' || data[pos+length+1] != '\n' {
+		return nil, 0, ErrProtocol
+	}
 	return string(data[pos:(pos + length)]), pos + length + 2, nil
 }
 
 // *2\r\n$5\r\nhello\r\n$5\r\nworld\r\n => {"hello", "world"}
 func readArray(data []byte) (any, int, error) {
-	length, pos := readLen(data)
+	length, pos, err := readLen(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length == -1 {
+		return nil, pos, nil
+	}
 
-	res := make([]any, length)
+	// the length comes from the peer, the elements are only allocated once they arrived
+	res := make([]any, 0)
 	for i := 0; i < length; i++ {
 		elm, delta, err := DecodeOne(data[pos:])
 		if err != nil {
 			return nil, 0, err
 		}
-		res[i] = elm
+		res = append(res, elm)
 		pos += delta
 	}
 
@@ -82,9 +137,11 @@ func readError(data []byte) (string, int, error) {
 	return readSimpleString(data)
 }
 
+// DecodeOne decodes the first RESP value in data and returns how many bytes it took.
+// ErrIncompleteRESP means data holds only a prefix of the value.
 func DecodeOne(data []byte) (any, int, error) {
 	if len(data) == 0 {
-		return nil, 0, errors.New("no data")
+		return nil, 0, ErrIncompleteRESP
 	}
 
 	switch data[0] {
@@ -100,7 +157,7 @@ func DecodeOne(data []byte) (any, int, error) {
 		return readError(data)
 	}
 
-	return nil, 0, nil
+	return nil, 0, ErrProtocol
 }
 
 // RESP data => raw data
@@ -157,17 +214,101 @@ func Encode(value any, isSimpleString bool) []byte {
 	}
 }
 
+// ParseCommand parses a single command, data must hold the whole command
 func ParseCommand(data []byte) (*Command, error) {
-	value, err := Decode(data)
+	cmd, _, err := parseCommand(data)
 	if err != nil {
 		return nil, err
 	}
+	if cmd == nil {
+		return nil, ErrProtocol
+	}
+	return cmd, nil
+}
+
+// ParseCommands parses every complete command at the head of data (pipelining).
+// It returns the commands and the number of bytes they took, the remaining
+// bytes are the prefix of a command that has not fully arrived yet.
+func ParseCommands(data []byte) ([]*Command, int, error) {
+	var cmds []*Command
+	pos := 0
+	for pos < len(data) {
+		err := checkMultibulkLen(data[pos:])
+		var cmd *Command
+		var n int
+		if err == nil {
+			cmd, n, err = parseCommand(data[pos:])
+		}
+		if err == ErrIncompleteRESP {
+			break
+		}
+		if err != nil {
+			return cmds, pos, err
+		}
+		pos += n
+		// empty array or blank inline line, nothing to execute
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	return cmds, pos, nil
+}
+
+// checkMultibulkLen rejects a command with too many arguments as soon as its header arrived
+func checkMultibulkLen(data []byte) error {
+	if data[0] != '*' {
+		return nil
+	}
+	length, _, err := readLen(data)
+	if err != nil {
+		return err
+	}
+	if length > maxMultibulkLen {
+		return fmt.Errorf("%w: invalid multibulk length", ErrProtocol)
+	}
+	return nil
+}
+
+// parseCommand reads one command, either a RESP array of bulk strings
+// or an inline command like "PING\r\n" sent by telnet.
+func parseCommand(data []byte) (*Command, int, error) {
+	if data[0] != '*' {
+		return parseInlineCommand(data)
+	}
 
-	array := value.([]any)
+	value, n, err := DecodeOne(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	array, _ := value.([]any)
+	if len(array) == 0 {
+		return nil, n, nil
+	}
 	tokens := make([]string, len(array))
 	for i := range tokens {
-		tokens[i] = array[i].(string)
+		token, ok := array[i].(string)
+		if !ok {
+			return nil, 0, ErrProtocol
+		}
+		tokens[i] = token
+	}
+
+	return &Command{Cmd: strings.ToUpper(tokens[0]), Args: tokens[1:]}, n, nil
+}
+
+// PING hello\r\n => {PING, [hello]}, 12
+func parseInlineCommand(data []byte) (*Command, int, error) {
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return nil, 0, ErrIncompleteRESP
+	}
+
+	tokens := strings.Fields(string(data[:end]))
+	if len(tokens) == 0 {
+		return nil, end + 1, nil
 	}
 
-	return &Command{Cmd: strings.ToUpper(tokens[0]), Args: tokens[1:]}, nil
+	return &Command{Cmd: strings.ToUpper(tokens[0]), Args: tokens[1:]}, end + 1, nil
 }
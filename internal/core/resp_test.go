@@ -180,3 +180,99 @@ func TestEncodeString2DArray(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []*core.Command
+		consumed int
+	}{
+		{
+			name:     "single command",
+			data:     "*1\r\n$4\r\nPING\r\n",
+			expected: []*core.Command{{Cmd: "PING", Args: []string{}}},
+			consumed: 14,
+		},
+		{
+			name: "pipelined commands",
+			data: "*3\r\n$3\r\nset\r\n$1\r\nk\r\n$1\r\nv\r\n*2\r\n$3\r\nget\r\n$1\r\nk\r\n",
+			expected: []*core.Command{
+				{Cmd: "SET", Args: []string{"k", "v"}},
+				{Cmd: "GET", Args: []string{"k"}},
+			},
+			consumed: 47,
+		},
+		{
+			name:     "partial command is kept for the next read",
+			data:     "*2\r\n$3\r\nget\r\n$1\r\nk\r\n*2\r\n$3\r\nget\r\n$5\r\nhel",
+			expected: []*core.Command{{Cmd: "GET", Args: []string{"k"}}},
+			consumed: 20,
+		},
+		{
+			name:     "header split in the middle",
+			data:     "*2\r",
+			expected: nil,
+			consumed: 0,
+		},
+		{
+			name:     "inline command",
+			data:     "ping hello\r\nPING\r\n",
+			expected: []*core.Command{{Cmd: "PING", Args: []string{"hello"}}, {Cmd: "PING", Args: []string{}}},
+			consumed: 18,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, consumed, err := core.ParseCommands([]byte(tt.data))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.consumed, consumed)
+			assert.Equal(t, tt.expected, cmds)
+		})
+	}
+}
+
+func TestParseCommandsByteByByte(t *testing.T) {
+	data := []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$11\r\nhello world\r\n")
+
+	var buf []byte
+	var cmds []*core.Command
+	for _, b := range data {
+		buf = append(buf, b)
+		parsed, consumed, err := core.ParseCommands(buf)
+		assert.NoError(t, err)
+		buf = buf[consumed:]
+		cmds = append(cmds, parsed...)
+	}
+
+	assert.Len(t, buf, 0)
+	assert.Equal(t, []*core.Command{{Cmd: "SET", Args: []string{"key", "hello world"}}}, cmds)
+}
+
+func TestParseCommandsProtocolError(t *testing.T) {
+	_, _, err := core.ParseCommands([]byte("*1\r\n$x\r\n"))
+	assert.Equal(t, core.ErrProtocol, err)
+
+	_, _, err = core.ParseCommands([]byte("*1\r\n:1\r\n"))
+	assert.Equal(t, core.ErrProtocol, err)
+}
+
+func TestParseCommandsMultibulkLimit(t *testing.T) {
+	// the header alone is rejected, nothing is allocated for the announced arguments
+	_, _, err := core.ParseCommands([]byte("*536870912\r\n"))
+	assert.ErrorIs(t, err, core.ErrProtocol)
+	assert.EqualError(t, err, "ERR Protocol error: invalid multibulk length")
+
+	// a large array still waits for its elements
+	cmds, consumed, err := core.ParseCommands([]byte("*1048576\r\n$3\r\nSET\r\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, cmds)
+	assert.Equal(t, 0, consumed)
+}
+
+func TestNullBulkStringDecode(t *testing.T) {
+	value, err := core.Decode([]byte("$-1\r\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+}
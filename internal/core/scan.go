@@ -0,0 +1,98 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("ERR invalid cursor")
+
+const defaultScanCount = 10
+
+// scanOptions are the options shared by SCAN, HSCAN, SSCAN and ZSCAN
+type scanOptions struct {
+	match    string
+	count    int
+	noValues bool
+	// typ is the TYPE option of SCAN
+	typ string
+}
+
+// parseScanArgs parses "cursor [MATCH pattern] [COUNT count]",
+// the options specific to a command, NOVALUES for HSCAN or TYPE for SCAN, are only accepted when listed in extra
+func parseScanArgs(args []string, extra ...string) (uint64, scanOptions, error) {
+	opts := scanOptions{count: defaultScanCount}
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, opts, errInvalidCursor
+	}
+
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			if i+1 == len(args) {
+				return 0, opts, errSyntax
+			}
+			i++
+			opts.match = args[i]
+		case "COUNT":
+			if i+1 == len(args) {
+				return 0, opts, errSyntax
+			}
+			i++
+			count, err := strconv.Atoi(args[i])
+			if err != nil {
+				return 0, opts, errNotInteger
+			}
+			if count < 1 {
+				return 0, opts, errSyntax
+			}
+			opts.count = count
+		case "NOVALUES":
+			if !slices.Contains(extra, "NOVALUES") {
+				return 0, opts, errSyntax
+			}
+			opts.noValues = true
+		case "TYPE":
+			if !slices.Contains(extra, "TYPE") || i+1 == len(args) {
+				return 0, opts, errSyntax
+			}
+			i++
+			opts.typ = strings.ToLower(args[i])
+		default:
+			return 0, opts, errSyntax
+		}
+	}
+
+	return cursor, opts, nil
+}
+
+// matches reports whether a key or a field is selected by the MATCH option
+func (opts scanOptions) matches(s string) bool {
+	return opts.match == "" || GlobMatch(opts.match, s)
+}
+
+// scanLoop calls scan until count elements were collected or the scan is done,
+// visiting at most 10*count buckets so a sparse table does not block the worker
+func scanLoop(cursor uint64, count int, collected func() int, scan func(cursor uint64) uint64) uint64 {
+	// a huge COUNT must not overflow into a scan visiting nothing
+	maxIterations := math.MaxInt
+	if count < math.MaxInt/10 {
+		maxIterations = count * 10
+	}
+	for ; maxIterations > 0; maxIterations-- {
+		cursor = scan(cursor)
+		if cursor == 0 || collected() >= count {
+			break
+		}
+	}
+	return cursor
+}
+
+// encodeScanReply builds the [cursor, elements] reply of the SCAN family
+func encodeScanReply(cursor uint64, elements []string) []byte {
+	return Encode([]any{strconv.FormatUint(cursor, 10), elements}, false)
+}
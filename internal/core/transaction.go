@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+)
+
+// watchedKey is the version of a key watched by at least one client,
+// it is incremented whenever the key is changed, expired or evicted
+type watchedKey struct {
+	version  uint64
+	watchers int
+}
+
+// WatchedKeys are the keys watched by a client with their version when WATCH was executed.
+// It is only used by the goroutine owning the RedisDB of the keys: WATCH, EXEC and UNWATCH of a client
+// are all executed there, in order.
+type WatchedKeys struct {
+	versions map[string]uint64
+}
+
+func NewWatchedKeys() *WatchedKeys {
+	return &WatchedKeys{versions: make(map[string]uint64)}
+}
+
+// touchWatchedKey records a change of key for the transactions watching it
+func (db *RedisDB) touchWatchedKey(key string) {
+	if wk, ok := db.watched[key]; ok {
+		wk.version++
+	}
+}
+
+// Watch adds keys to the keys watched by w
+func (db *RedisDB) Watch(w *WatchedKeys, keys []string) {
+	for _, key := range keys {
+		if _, ok := w.versions[key]; ok {
+			continue
+		}
+		// a key already expired is deleted now, its deletion is not a change made after WATCH
+		db.getNoTouch(key)
+		wk, ok := db.watched[key]
+		if !ok {
+			wk = &watchedKey{}
+			db.watched[key] = wk
+		}
+		wk.watchers++
+		w.versions[key] = wk.version
+	}
+}
+
+// Unwatch forgets the keys watched by w
+func (db *RedisDB) Unwatch(w *WatchedKeys) {
+	for key := range w.versions {
+		wk := db.watched[key]
+		wk.watchers--
+		if wk.watchers == 0 {
+			delete(db.watched, key)
+		}
+	}
+	clear(w.versions)
+}
+
+// changedSinceWatch reports whether a key watched by w was changed since WATCH.
+// A watched key whose TTL is reached counts as changed even if it is not deleted yet, like redis.
+func (db *RedisDB) changedSinceWatch(w *WatchedKeys) bool {
+	changed := false
+	for key, version := range w.versions {
+		db.getNoTouch(key)
+		if db.watched[key].version != version {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ExecTransaction executes the commands queued between MULTI and EXEC, nothing else runs on the db meanwhile.
+// It replies a nil array without executing anything when a key watched by w changed, w may be nil.
+// Blocking commands do not block in a transaction, they reply as if their timeout was reached.
+func (db *RedisDB) ExecTransaction(cmds []*Command, w *WatchedKeys) []byte {
+	if w != nil {
+		changed := db.changedSinceWatch(w)
+		db.Unwatch(w)
+		if changed {
+			return constant.RespNilArray
+		}
+	}
+
+	// the changes are logged to the AOF at once, between MULTI and EXEC, so a replay applies all of them or none
+	db.transactionAOF = [][]string{}
+	var replies bytes.Buffer
+	for _, cmd := range cmds {
+		replies.Write(executeCommand(db, cmd))
+		db.takeBlockRequest()
+	}
+	if len(db.transactionAOF) > 0 {
+		argvs := append([][]string{{constant.CMD_MULTI}}, db.transactionAOF...)
+		db.aof.Append(append(argvs, []string{constant.CMD_EXEC}))
+	}
+	db.transactionAOF = nil
+	// the transaction may have created keys clients are blocked on
+	db.serveBlockedTasks()
+
+	return append(fmt.Appendf(nil, "*%d\r\n", len(cmds)), replies.Bytes()...)
+}
+
+// UNWATCH, queued in a transaction. Outside of one it is executed by the connection,
+// in one EXEC has already forgotten the watched keys.
+func cmdUNWATCH(redisDB *RedisDB, args []string) []byte {
+	return constant.RespOk
+}
@@ -0,0 +1,123 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// commands builds the commands of a transaction from lines like "SET k v"
+func commands(lines ...string) []*core.Command {
+	cmds := make([]*core.Command, len(lines))
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		cmds[i] = &core.Command{Cmd: fields[0], Args: fields[1:]}
+	}
+	return cmds
+}
+
+func TestExecTransaction(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "1")
+
+	res := db.ExecTransaction(commands("INCR k", "GET k", "LPUSH k x", "BLPOP empty 0", "UNWATCH"), nil)
+	// an error does not stop the commands after it, a blocking command does not block
+	assert.Equal(t, "*5\r\n:2\r\n$1\r\n2\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n*-1\r\n+OK\r\n", string(res))
+}
+
+func TestWatchAbortsOnChange(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "1")
+
+	w := core.NewWatchedKeys()
+	db.Watch(w, []string{"k", "missing"})
+	// reads and writes changing nothing are not changes
+	execute(db, "GET", "k")
+	execute(db, "EXPIRE", "k", "100", "GT")
+	assert.Equal(t, "*1\r\n+OK\r\n", string(db.ExecTransaction(commands("SET k 2"), w)))
+
+	db.Watch(w, []string{"k"})
+	execute(db, "APPEND", "k", "x")
+	assert.Equal(t, "*-1\r\n", string(db.ExecTransaction(commands("SET k 3"), w)))
+	assert.Equal(t, "$2\r\n2x\r\n", execute(db, "GET", "k"))
+
+	// a key created after WATCH
+	db.Watch(w, []string{"missing"})
+	execute(db, "SET", "missing", "v")
+	assert.Equal(t, "*-1\r\n", string(db.ExecTransaction(commands("GET missing"), w)))
+
+	// EXEC forgot the keys, another client watching them still sees the changes
+	other := core.NewWatchedKeys()
+	db.Watch(w, []string{"k"})
+	db.Watch(other, []string{"k"})
+	db.Unwatch(w)
+	execute(db, "DEL", "k")
+	assert.Equal(t, "*0\r\n", string(db.ExecTransaction(nil, w)))
+	assert.Equal(t, "*-1\r\n", string(db.ExecTransaction(nil, other)))
+}
+
+func TestWatchExpiredKey(t *testing.T) {
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v", "PX", "20")
+	execute(db, "SET", "gone", "v", "PX", "1")
+	time.Sleep(30 * time.Millisecond)
+
+	// gone had already expired when it was watched
+	w := core.NewWatchedKeys()
+	db.Watch(w, []string{"gone"})
+	assert.Equal(t, "*1\r\n$-1\r\n", string(db.ExecTransaction(commands("GET gone"), w)))
+
+	execute(db, "SET", "k", "v", "PX", "20")
+	db.Watch(w, []string{"k"})
+	time.Sleep(30 * time.Millisecond)
+	// the TTL is reached but nothing deleted the key yet
+	assert.Equal(t, "*-1\r\n", string(db.ExecTransaction(commands("GET k"), w)))
+}
+
+func TestWatchEvictedKey(t *testing.T) {
+	withEviction(t, "allkeys-random", 0)
+	db := core.NewRedisDB()
+	execute(db, "SET", "k", "v")
+
+	w := core.NewWatchedKeys()
+	db.Watch(w, []string{"k"})
+	config.MaxMemory.Store(1)
+	execute(db, "SET", "other", "v")
+	assert.Equal(t, "*-1\r\n", string(db.ExecTransaction(nil, w)))
+}
+
+func TestAOFTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := core.OpenAOF(path, core.FsyncAlways)
+	assert.NoError(t, err)
+
+	db := core.NewRedisDB()
+	db.SetAOF(aof)
+	db.ExecTransaction(commands("SET a 1", "GET a", "INCR a"), nil)
+	db.ExecTransaction(commands("GET a"), nil)
+	assert.NoError(t, aof.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n*2\r\n$4\r\nINCR\r\n$1\r\na\r\n*1\r\n$4\r\nEXEC\r\n", string(data))
+
+	// a transaction cut by a crash is dropped as a whole
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString("*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$1\r\n1\r\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	loaded := replay(t, path)
+	assert.Equal(t, "$1\r\n2\r\n", execute(loaded, "GET", "a"))
+	assert.Equal(t, ":0\r\n", execute(loaded, "EXISTS", "b"))
+	truncated, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, truncated)
+}
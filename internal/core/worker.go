@@ -5,12 +5,30 @@ import (
 	"sync"
 	"time"
 
-	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/config"
 )
 
 type Task struct {
 	Command   *Command
 	ReplyChan chan []byte
+	// Notify is called after the reply is sent, so an event loop waiting in epoll/kqueue
+	// knows there is a reply to collect without blocking on ReplyChan
+	Notify func()
+	// Fn, when set, is run on the worker's RedisDB instead of Command,
+	// it lets the server reach every shard for SAVE and loading snapshots
+	Fn func(redisDB *RedisDB) []byte
+	// Key routes a task with Fn sent by a client to the worker owning the key, like the transactions
+	Key string
+	// Operands are the source keys of Command owned by other workers, read there by the server, see ReadOperands
+	Operands Operands
+}
+
+// Reply sends the result to the task owner, ReplyChan must be buffered so it never blocks the worker
+func (t *Task) Reply(res []byte) {
+	t.ReplyChan <- res
+	if t.Notify != nil {
+		t.Notify()
+	}
 }
 
 type Worker struct {
@@ -19,14 +37,25 @@ type Worker struct {
 	TaskChan chan *Task
 	once     sync.Once
 	wg       sync.WaitGroup
+
+	// blockTimer fires at blockDeadline, the earliest timeout of the tasks parked by blocking commands
+	blockTimer    *time.Timer
+	blockDeadline time.Time
+	// blockedVersion is the RedisDB.blockedVersion blockDeadline was computed for
+	blockedVersion uint64
 }
 
-func NewWorker(id int, bufferSize int) *Worker {
+// NewWorker starts a worker owning one of numShards shards of the keyspace
+func NewWorker(id int, numShards int, bufferSize int) *Worker {
 	worker := &Worker{
 		id:       id,
 		redisDB:  NewRedisDB(),
 		TaskChan: make(chan *Task, bufferSize),
+		// armed once a task is parked with a timeout
+		blockTimer: time.NewTimer(time.Hour),
 	}
+	worker.blockTimer.Stop()
+	worker.redisDB.SetShards(numShards)
 	worker.wg.Add(1)
 	go worker.run()
 	return worker
@@ -37,8 +66,10 @@ func (w *Worker) run() {
 	log.Printf("Worker %d started\n", w.id)
 	// Not like single-threaded, active expire is triggered before executing the command, so we need to check expire before executing the command
 	// We can also use a ticker to trigger active expire periodically
-	ticker := time.NewTicker(constant.ActiveExpireFrequency)
+	period := config.ActiveExpireFrequency()
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
+	defer w.blockTimer.Stop()
 
 	for {
 		select {
@@ -51,10 +82,43 @@ func (w *Worker) run() {
 			w.ExecuteAndRespond(task)
 		case <-ticker.C:
 			ActiveDeleteExpiredKeys(w.redisDB)
+			// CONFIG SET hz takes effect at the next tick
+			if p := config.ActiveExpireFrequency(); p != period {
+				period = p
+				ticker.Reset(period)
+			}
+		case now := <-w.blockTimer.C:
+			w.redisDB.TimeoutBlockedTasks(now)
+			w.blockDeadline = time.Time{}
+			w.armBlockTimer()
 		}
 	}
 }
 
+// resetBlockTimer rearms the timer when tasks were parked or unparked since the last call
+func (w *Worker) resetBlockTimer() {
+	if w.blockedVersion != w.redisDB.blockedVersion {
+		w.armBlockTimer()
+	}
+}
+
+// armBlockTimer arms the timer for the earliest timeout of the parked tasks
+func (w *Worker) armBlockTimer() {
+	w.blockedVersion = w.redisDB.blockedVersion
+	deadline, ok := w.redisDB.NextBlockDeadline()
+	if !ok {
+		if !w.blockDeadline.IsZero() {
+			w.blockTimer.Stop()
+			w.blockDeadline = time.Time{}
+		}
+		return
+	}
+	if !deadline.Equal(w.blockDeadline) {
+		w.blockTimer.Reset(time.Until(deadline))
+		w.blockDeadline = deadline
+	}
+}
+
 func (w *Worker) Stop() {
 	w.once.Do(func() {
 		close(w.TaskChan)
@@ -62,8 +126,16 @@ func (w *Worker) Stop() {
 	w.wg.Wait()
 }
 
-func (w *Worker) ExecuteAndRespond(task *Task) {
-	res := ExecuteCommand(w.redisDB, task.Command)
+// Dirty returns the number of write commands since the last snapshot
+func (w *Worker) Dirty() int64 {
+	return w.redisDB.Dirty()
+}
 
-	task.ReplyChan <- res
+func (w *Worker) ExecuteAndRespond(task *Task) {
+	if task.Fn != nil {
+		task.Reply(task.Fn(w.redisDB))
+	} else {
+		ExecuteTask(w.redisDB, task)
+	}
+	w.resetBlockTimer()
 }
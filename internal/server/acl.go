@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"syscall"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var (
+	errNoAuth              = errors.New("NOAUTH Authentication required.")
+	errWrongPass           = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+	errAuthWithoutPassword = errors.New("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	errSyntax              = errors.New("ERR syntax error")
+)
+
+// respProtectedMode is written to the connections refused by protected mode before closing them
+var respProtectedMode = []byte("-DENIED Running in protected mode because protected mode is enabled and no password is set " +
+	"for the default user. In this mode connections are only accepted from the loopback interface. " +
+	"Set a password with CONFIG SET requirepass, bind to an address with the bind option, " +
+	"or disable protected mode with CONFIG SET protected-mode no.\r\n")
+
+// aclCommands are executed by the connection, they read or change its user
+var aclCommands = map[string]bool{constant.CMD_AUTH: true, constant.CMD_ACL: true}
+
+// newACL returns the users of a server, with the ones of the ACL file when there is one.
+// A missing ACL file is not an error, ACL SAVE creates it.
+func newACL() (*acl.ACL, error) {
+	users := acl.New()
+	if config.ACLFile == "" {
+		return users, nil
+	}
+	if err := users.LoadFile(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("loading the ACL file: %w", err)
+	}
+	return users, nil
+}
+
+// refusedByProtectedMode reports whether a connection from addr is refused: the server binds to every interface,
+// the default user has no password and addr is not a loopback address
+func refusedByProtectedMode(users *acl.ACL, addr *net.TCPAddr) bool {
+	return config.ProtectedMode.Load() && config.Bind == "" && !users.AuthRequired() &&
+		addr != nil && !addr.IP.IsLoopback()
+}
+
+// sockaddrToTCPAddr converts the address returned by accept, it is nil for other kinds of sockets
+func sockaddrToTCPAddr(sa syscall.Sockaddr) *net.TCPAddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	case *syscall.SockaddrInet6:
+		return &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	}
+	return nil
+}
+
+// authorize returns the error replied instead of executing cmd, nil when the user of the client may run it.
+// AUTH is always allowed, a command with a wrong arity is left to the execution for its error.
+// A command rejected while queuing a transaction aborts it, like any other error.
+func (c *client) authorize(cmd *core.Command) []byte {
+	spec := core.LookupCommand(cmd.Cmd)
+	if spec != nil && spec.Name == constant.CMD_AUTH {
+		return nil
+	}
+	queuing := c.tx.multi && (spec == nil || !transactionCommands[spec.Name])
+	if !c.authenticated && c.users.AuthRequired() {
+		c.tx.aborted = c.tx.aborted || queuing
+		return core.Encode(errNoAuth, false)
+	}
+	if spec == nil || !spec.CheckArity(len(cmd.Args)) {
+		return nil
+	}
+
+	denial := c.users.Check(c.user, spec, cmd.Args)
+	if denial == nil {
+		return nil
+	}
+	context := "toplevel"
+	if c.tx.multi {
+		context = "multi"
+	}
+	c.tx.aborted = c.tx.aborted || queuing
+	c.users.LogDenial(denial, c.user, context, c.info())
+	return core.Encode(denial, false)
+}
+
+// executeACL executes AUTH and ACL on the event loop, it returns false for the other commands
+func (c *client) executeACL(cmd *core.Command) ([]byte, bool) {
+	spec := core.LookupCommand(cmd.Cmd)
+	if spec == nil || !spec.CheckArity(len(cmd.Args)) || !aclCommands[spec.Name] {
+		return nil, false
+	}
+	if spec.Name == constant.CMD_ACL {
+		return c.users.Execute(c.user, cmd.Args), true
+	}
+	return c.auth(cmd.Args), true
+}
+
+// AUTH [username] password
+//
+// A failed AUTH leaves the connection authenticated as before.
+func (c *client) auth(args []string) []byte {
+	if len(args) > 2 {
+		return core.Encode(errSyntax, false)
+	}
+	username, password := acl.DefaultUser, args[0]
+	if len(args) == 2 {
+		username, password = args[0], args[1]
+	} else if !c.users.AuthRequired() {
+		return core.Encode(errAuthWithoutPassword, false)
+	}
+
+	if !c.users.Authenticate(username, password) {
+		c.users.LogAuthFailure(username, c.info())
+		return core.Encode(errWrongPass, false)
+	}
+	c.user, c.authenticated = username, true
+	return constant.RespOk
+}
+
+// info describes the client in ACL LOG
+func (c *client) info() string {
+	return fmt.Sprintf("fd=%d addr=%s user=%s", c.fd, c.addr, c.user)
+}
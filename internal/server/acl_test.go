@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	t.Cleanup(func() { config.RequirePass.Store("") })
+	s := newTestServer(t, 2)
+	admin := newTestClient(s)
+
+	assert.Equal(t, "-ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?\r\n",
+		admin("AUTH", "secret"))
+	assert.Equal(t, "+OK\r\n", admin("CONFIG", "SET", "requirepass", "secret"))
+	// the connections already authenticated as default stay so
+	assert.Equal(t, "+PONG\r\n", admin("PING"))
+
+	send := newTestClient(s)
+	assert.Equal(t, "-NOAUTH Authentication required.\r\n", send("GET", "k"))
+	assert.Equal(t, "-NOAUTH Authentication required.\r\n", send("NOPE"))
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", send("AUTH", "nope"))
+	assert.Equal(t, "-ERR syntax error\r\n", send("AUTH", "a", "b", "c"))
+	assert.Equal(t, "+OK\r\n", send("AUTH", "secret"))
+	assert.Equal(t, "$-1\r\n", send("GET", "k"))
+	assert.Equal(t, "$7\r\ndefault\r\n", send("ACL", "WHOAMI"))
+
+	assert.Equal(t, "+OK\r\n", admin("CONFIG", "SET", "requirepass", ""))
+	assert.Equal(t, "+PONG\r\n", newTestClient(s)("PING"))
+}
+
+func TestACLPermissions(t *testing.T) {
+	s := newTestServer(t, 2)
+	admin := newTestClient(s)
+	assert.Equal(t, "+OK\r\n", admin("ACL", "SETUSER", "alice", "on", ">pw", "~app:*", "+@read", "+set", "+multi", "+exec", "+acl|whoami"))
+
+	send := newTestClient(s)
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", send("AUTH", "alice", "nope"))
+	assert.Equal(t, "+OK\r\n", send("AUTH", "alice", "pw"))
+	assert.Equal(t, "$5\r\nalice\r\n", send("ACL", "WHOAMI"))
+	assert.Equal(t, "+OK\r\n", send("SET", "app:1", "v"))
+	assert.Equal(t, "$1\r\nv\r\n", send("GET", "app:1"))
+	assert.Equal(t, "-NOPERM No permissions to access a key\r\n", send("GET", "other"))
+	assert.Equal(t, "-NOPERM User alice has no permissions to run the 'del' command\r\n", send("DEL", "app:1"))
+	assert.Equal(t, "-NOPERM User alice has no permissions to run the 'acl|list' command\r\n", send("ACL", "LIST"))
+	// the arity is checked before the permissions
+	assert.Equal(t, "-ERR wrong number of arguments for 'del' command\r\n", send("DEL"))
+
+	// a denied command aborts the transaction
+	assert.Equal(t, "+OK\r\n", send("MULTI"))
+	assert.Equal(t, "-NOPERM User alice has no permissions to run the 'del' command\r\n", send("DEL", "app:1"))
+	assert.Equal(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", send("EXEC"))
+
+	log := admin("ACL", "LOG")
+	assert.True(t, strings.HasPrefix(log, "*5\r\n"), log)
+	assert.Contains(t, log, "$7\r\ncontext\r\n$5\r\nmulti\r\n$6\r\nobject\r\n$3\r\ndel\r\n")
+	assert.Contains(t, log, "$6\r\nreason\r\n$4\r\nauth\r\n")
+
+	// the connections of a deleted user can only AUTH again
+	assert.Equal(t, ":1\r\n", admin("ACL", "DELUSER", "alice"))
+	assert.Equal(t, "-NOPERM User alice has no permissions to run the 'get' command\r\n", send("GET", "app:1"))
+	assert.Equal(t, "+OK\r\n", send("AUTH", "default", "any"))
+	assert.Equal(t, "$1\r\nv\r\n", send("GET", "app:1"))
+}
+
+func TestProtectedMode(t *testing.T) {
+	t.Cleanup(func() { config.RequirePass.Store("") })
+	users := newTestServer(t, 1).users
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}
+
+	assert.True(t, refusedByProtectedMode(users, remote))
+	assert.False(t, refusedByProtectedMode(users, &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}))
+	assert.False(t, refusedByProtectedMode(users, &net.TCPAddr{IP: net.ParseIP("::1")}))
+
+	users.SetRequirePass("secret")
+	assert.False(t, refusedByProtectedMode(users, remote))
+	users.SetRequirePass("")
+
+	config.ProtectedMode.Store(false)
+	defer config.ProtectedMode.Store(true)
+	assert.False(t, refusedByProtectedMode(users, remote))
+}
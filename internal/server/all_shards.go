@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// dispatchAllShards sends a command about the whole keyspace, like KEYS or DBSIZE,
+// to every worker and merges their replies
+func (s *Server) dispatchAllShards(task *core.Task, spec *core.CommandSpec) {
+	workerIDs := make([]int, s.numWorker)
+	subTasks := make([]*core.Task, s.numWorker)
+	for i := range workerIDs {
+		workerIDs[i] = i
+		subTasks[i] = &core.Task{Command: task.Command}
+	}
+	policy := responsePolicy(spec)
+	s.fanOut(workerIDs, subTasks, func(replies [][]byte) {
+		task.Reply(mergeReplies(policy, replies, nil))
+	})
+}
+
+// dispatchScan sends SCAN to the worker the cursor is in. The cursor of the client is
+// the cursor in the shard times the number of workers plus the shard, so one walk covers every shard:
+// when a shard is done, the next cursor is the start of the next shard.
+func (s *Server) dispatchScan(task *core.Task) {
+	args := task.Command.Args
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		// the worker replies with the error
+		s.sendToWorker(0, task)
+		return
+	}
+
+	numShards := uint64(s.numWorker)
+	shard := cursor % numShards
+	subArgs := append([]string{strconv.FormatUint(cursor/numShards, 10)}, args[1:]...)
+	cmd := &core.Command{Cmd: task.Command.Cmd, Args: subArgs}
+	s.fanOut([]int{int(shard)}, []*core.Task{{Command: cmd}}, func(replies [][]byte) {
+		task.Reply(scanReply(replies[0], shard, numShards))
+	})
+}
+
+// scanReply replaces the cursor in the SCAN reply of a shard with the cursor of the client
+func scanReply(reply []byte, shard uint64, numShards uint64) []byte {
+	if len(reply) > 0 && reply[0] == '-' {
+		return reply
+	}
+	elements, ok := splitArray(reply)
+	if !ok || len(elements) != 2 {
+		return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+	}
+	value, err := core.Decode(elements[0])
+	str, isString := value.(string)
+	if err != nil || !isString {
+		return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+	}
+	cursor, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+	}
+
+	switch {
+	case cursor != 0:
+		cursor = cursor*numShards + shard
+	case shard+1 < numShards:
+		cursor = shard + 1
+	}
+	res := append([]byte("*2\r\n"), core.Encode(strconv.FormatUint(cursor, 10), false)...)
+	return append(res, elements[1]...)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllShards(t *testing.T) {
+	s := newTestServer(t, 4)
+	assert.Equal(t, "$-1\r\n", execute(s, "RANDOMKEY"))
+	assert.Equal(t, "*0\r\n", execute(s, "KEYS", "*"))
+
+	keys := keysOnDifferentWorkers(s, 4)
+	for _, key := range keys {
+		execute(s, "SET", key, "v")
+	}
+	assert.Equal(t, ":4\r\n", execute(s, "DBSIZE"))
+
+	reply, err := core.Decode([]byte(execute(s, "KEYS", "key:*")))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []any{keys[0], keys[1], keys[2], keys[3]}, reply)
+	assert.Contains(t, []string{
+		"$" + fmt.Sprint(len(keys[0])) + "\r\n" + keys[0] + "\r\n",
+		"$" + fmt.Sprint(len(keys[1])) + "\r\n" + keys[1] + "\r\n",
+		"$" + fmt.Sprint(len(keys[2])) + "\r\n" + keys[2] + "\r\n",
+		"$" + fmt.Sprint(len(keys[3])) + "\r\n" + keys[3] + "\r\n",
+	}, execute(s, "RANDOMKEY"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'keys' command\r\n", execute(s, "KEYS"))
+}
+
+func TestScanEveryShard(t *testing.T) {
+	s := newTestServer(t, 4)
+	// 8 keys per worker
+	var expected []string
+	perWorker := make(map[int]int)
+	for i := 0; len(expected) < 32; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if id := s.getWorkerID(key); perWorker[id] < 8 {
+			perWorker[id]++
+			expected = append(expected, key)
+			execute(s, "SET", key, "v")
+		}
+	}
+	execute(s, "LPUSH", "list", "a")
+
+	var seen []string
+	cursor := "0"
+	for calls := 0; ; calls++ {
+		assert.Less(t, calls, 1000)
+		reply, err := core.Decode([]byte(execute(s, "SCAN", cursor, "COUNT", "3", "TYPE", "string")))
+		assert.NoError(t, err)
+		res := reply.([]any)
+		for _, key := range res[1].([]any) {
+			seen = append(seen, key.(string))
+		}
+		cursor = res[0].(string)
+		if cursor == "0" {
+			break
+		}
+	}
+
+	// no resize happened during the walk, every key is returned exactly once
+	sort.Strings(expected)
+	sort.Strings(seen)
+	assert.Equal(t, expected, seen)
+	assert.Equal(t, "-ERR invalid cursor\r\n", execute(s, "SCAN", "-1"))
+}
+
+func TestScanReply(t *testing.T) {
+	shardReply := "*2\r\n$1\r\n3\r\n*1\r\n$1\r\na\r\n"
+	assert.Equal(t, "*2\r\n$2\r\n13\r\n*1\r\n$1\r\na\r\n", string(scanReply([]byte(shardReply), 1, 4)))
+
+	// the next cursor of a finished shard is the start of the next one, 0 after the last one
+	done := "*2\r\n$1\r\n0\r\n*0\r\n"
+	assert.Equal(t, "*2\r\n$1\r\n2\r\n*0\r\n", string(scanReply([]byte(done), 1, 4)))
+	assert.Equal(t, done, string(scanReply([]byte(done), 3, 4)))
+}
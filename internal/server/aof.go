@@ -0,0 +1,176 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var (
+	errRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+	errAppendOnlyOff     = errors.New("ERR Background append only file rewriting is not possible while appendonly is off")
+)
+
+// aofPersistence replays the append only file at startup and rewrites it with BGREWRITEAOF
+type aofPersistence struct {
+	ks   keyspace
+	path string
+	// aof is nil while appendonly is off
+	aof *core.AOF
+
+	mu                sync.Mutex
+	rewriteInProgress bool
+	rewrites          sync.WaitGroup
+}
+
+func newAOFPersistence(ks keyspace) *aofPersistence {
+	return &aofPersistence{
+		ks:   ks,
+		path: filepath.Join(config.RDBDir, config.AppendFilename),
+	}
+}
+
+// load replays the AOF, it returns false when there is no AOF yet
+func (p *aofPersistence) load() (bool, error) {
+	start := time.Now()
+	shards := make([][]func(db *core.RedisDB), p.ks.numShards())
+	restored := false
+	// the keys of the snapshot at the head of the file are restored before the first command runs
+	restore := func() {
+		restored = true
+		p.ks.atomically(func(shard int, db *core.RedisDB) {
+			for _, restore := range shards[shard] {
+				restore(db)
+			}
+		})
+		shards = nil
+	}
+
+	numCmds, numErrs := 0, 0
+	err := core.LoadAOF(p.path,
+		func(key string, obj *core.RedisObj, expireAtMs uint64) {
+			shard := p.ks.shardOf(key)
+			shards[shard] = append(shards[shard], func(db *core.RedisDB) {
+				db.Restore(key, obj, expireAtMs)
+			})
+		},
+		func(cmd *core.Command) {
+			if !restored {
+				restore()
+			}
+			numCmds++
+			if res := p.ks.execute(cmd); len(res) > 0 && res[0] == '-' {
+				// a command logged by one worker may not fit the shards anymore when the number of workers changed
+				numErrs++
+				log.Printf("Error replaying %s from the AOF: %s", cmd.Cmd, res[1:len(res)-2])
+			}
+		})
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("loading %s: %w", p.path, err)
+	}
+	if !restored {
+		restore()
+	}
+
+	// the replayed commands are already on disk
+	p.ks.atomically(func(_ int, db *core.RedisDB) {
+		db.ClearDirty(db.Dirty())
+	})
+	log.Printf("DB loaded from append only file: %d commands (%d errors) in %v", numCmds, numErrs, time.Since(start))
+	return true, nil
+}
+
+// open starts appending the write commands of every shard to the AOF
+func (p *aofPersistence) open() error {
+	aof, err := core.OpenAOF(p.path, config.AppendFsync)
+	if err != nil {
+		return err
+	}
+
+	p.aof = aof
+	p.ks.atomically(func(_ int, db *core.RedisDB) {
+		db.SetAOF(aof)
+	})
+	return nil
+}
+
+// bgrewrite replaces the AOF with a snapshot of the keyspace, written in the background.
+// Commands executed meanwhile are appended to both files.
+func (p *aofPersistence) bgrewrite() error {
+	if p.aof == nil {
+		return errAppendOnlyOff
+	}
+
+	p.mu.Lock()
+	if p.rewriteInProgress {
+		p.mu.Unlock()
+		return errRewriteInProgress
+	}
+	p.rewriteInProgress = true
+	p.mu.Unlock()
+
+	n := p.ks.numShards()
+	shards := make([][]byte, n)
+	errs := make([]error, n)
+	var once sync.Once
+	p.ks.atomically(func(shard int, db *core.RedisDB) {
+		// the first shard starts the rewrite before any worker goes on with the next command
+		once.Do(p.aof.StartRewrite)
+		shards[shard], errs[shard] = db.DumpSnapshot(nil)
+	})
+	if err := errors.Join(errs...); err != nil {
+		p.aof.AbortRewrite()
+		p.finishRewrite()
+		return err
+	}
+
+	p.rewrites.Add(1)
+	go func() {
+		defer p.rewrites.Done()
+		defer p.finishRewrite()
+
+		start := time.Now()
+		if err := p.aof.FinishRewrite(shards...); err != nil {
+			log.Printf("Background AOF rewrite failed: %v", err)
+			return
+		}
+		log.Printf("Background AOF rewrite finished successfully in %v", time.Since(start))
+	}()
+	return nil
+}
+
+func (p *aofPersistence) finishRewrite() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rewriteInProgress = false
+}
+
+// close waits for a running rewrite and flushes the AOF, workers must be stopped
+func (p *aofPersistence) close() {
+	if p.aof == nil {
+		return
+	}
+
+	p.rewrites.Wait()
+	if err := p.aof.Close(); err != nil {
+		log.Printf("Error closing the AOF: %v", err)
+	}
+}
+
+// execute runs BGREWRITEAOF, the arity is already checked
+func (p *aofPersistence) execute(cmd *core.Command) []byte {
+	if err := p.bgrewrite(); err != nil {
+		return core.Encode(toRespError(err), false)
+	}
+	return core.Encode("Background append only file rewriting started", true)
+}
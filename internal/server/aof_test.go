@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAOFReplay(t *testing.T) {
+	s := newTestServer(t, 4)
+	assert.Equal(t, "-"+errAppendOnlyOff.Error()+"\r\n", execute(s, "BGREWRITEAOF"))
+	assert.NoError(t, s.persistence.aof.open())
+
+	for i := 0; i < 10; i++ {
+		execute(s, "SET", fmt.Sprintf("key:%d", i), "before")
+	}
+	execute(s, "SET", "ttl", "v", "EX", "100")
+	assert.Equal(t, "+Background append only file rewriting started\r\n", execute(s, "BGREWRITEAOF"))
+	for i := 0; i < 10; i += 2 {
+		execute(s, "SET", fmt.Sprintf("key:%d", i), "after")
+	}
+	execute(s, "DEL", "key:1", "key:3")
+	s.persistence.aof.close()
+
+	loaded := newTestServer(t, 3)
+	loaded.persistence.aof.path = s.persistence.aof.path
+	ok, err := loaded.persistence.aof.load()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "$5\r\nafter\r\n", execute(loaded, "GET", "key:0"))
+	assert.Equal(t, "$-1\r\n", execute(loaded, "GET", "key:1"))
+	assert.Equal(t, "$6\r\nbefore\r\n", execute(loaded, "GET", "key:5"))
+	assert.Regexp(t, `^:99\d{3}\r\n$`, execute(loaded, "PTTL", "ttl"))
+	assert.EqualValues(t, 0, loaded.dirty())
+}
+
+func TestAOFRewriteInProgress(t *testing.T) {
+	s := newTestServer(t, 2)
+	assert.NoError(t, s.persistence.aof.open())
+	t.Cleanup(s.persistence.aof.close)
+
+	s.persistence.aof.mu.Lock()
+	s.persistence.aof.rewriteInProgress = true
+	s.persistence.aof.mu.Unlock()
+	assert.Equal(t, "-"+errRewriteInProgress.Error()+"\r\n", execute(s, "BGREWRITEAOF"))
+
+	s.persistence.aof.finishRewrite()
+	assert.Equal(t, "+Background append only file rewriting started\r\n", execute(s, "BGREWRITEAOF"))
+	assert.Eventually(t, func() bool {
+		s.persistence.aof.mu.Lock()
+		defer s.persistence.aof.mu.Unlock()
+		return !s.persistence.aof.rewriteInProgress
+	}, time.Second, 10*time.Millisecond)
+}
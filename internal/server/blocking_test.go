@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// dispatchAsync dispatches the command without waiting for the reply
+func dispatchAsync(s *Server, cmd string, args ...string) *core.Task {
+	task := &core.Task{
+		Command:   &core.Command{Cmd: cmd, Args: args},
+		ReplyChan: make(chan []byte, 1),
+	}
+	s.dispatch(task)
+	return task
+}
+
+func waitReply(t *testing.T, task *core.Task, timeout time.Duration) string {
+	select {
+	case res := <-task.ReplyChan:
+		return string(res)
+	case <-time.After(timeout):
+		t.Fatalf("no reply to %s after %v", task.Command.Cmd, timeout)
+		return ""
+	}
+}
+
+func TestBlockedTaskDoesNotBlockWorker(t *testing.T) {
+	s := newTestServer(t, 1)
+
+	blocked := dispatchAsync(s, "BLPOP", "q", "0")
+	// the worker keeps executing other commands meanwhile
+	assert.Equal(t, "+OK\r\n", execute(s, "SET", "k", "v"))
+	select {
+	case res := <-blocked.ReplyChan:
+		t.Fatalf("BLPOP replied %q before a push", res)
+	default:
+	}
+
+	assert.Equal(t, ":1\r\n", execute(s, "RPUSH", "q", "x"))
+	assert.Equal(t, "*2\r\n$1\r\nq\r\n$1\r\nx\r\n", waitReply(t, blocked, time.Second))
+}
+
+func TestBlockedTaskTimeout(t *testing.T) {
+	s := newTestServer(t, 2)
+
+	start := time.Now()
+	short := dispatchAsync(s, "BLMOVE", "src", "src", "LEFT", "LEFT", "0.1")
+	long := dispatchAsync(s, "BRPOP", "src", "0.3")
+	assert.Equal(t, "$-1\r\n", waitReply(t, short, time.Second))
+	assert.Equal(t, "*-1\r\n", waitReply(t, long, time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestUnblockDisconnectedClient(t *testing.T) {
+	s := newTestServer(t, 2)
+
+	gone := dispatchAsync(s, "BLPOP", "q", "0")
+	s.unblock(gone)
+	assert.Equal(t, ":1\r\n", execute(s, "RPUSH", "q", "x"))
+	assert.Equal(t, ":1\r\n", execute(s, "LLEN", "q"))
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/nhtuan0700/godis/internal/core/io_multiplexer"
+)
+
+const readChunkSize = 16 * 1024
+
+// client is the per-connection state kept by the event loop that monitors its fd
+type client struct {
+	fd int
+	// conn is kept so the GC does not close the socket while we use its fd,
+	// it is nil for fds accepted directly by the single-threaded server
+	conn net.Conn
+	// readBuf accumulates bytes read from the socket until they form complete commands,
+	// a command split across several reads stays here until the rest arrives
+	readBuf []byte
+	// pending holds the reply channels of dispatched commands in request order,
+	// a reply is only written once every reply before it has been written
+	pending []chan []byte
+	// writeBuf holds replies that the socket has not accepted yet
+	writeBuf []byte
+	// watchingWrite is true while the fd is monitored for writability
+	watchingWrite bool
+	// blocking is the task of a blocking command (BLPOP) whose reply was not collected yet,
+	// the commands read meanwhile are held until it is, like redis does for a blocked client.
+	// A command gathering keys from several workers holds them too, see isGatherCommand.
+	blocking *core.Task
+	held     []*core.Command
+
+	// addr is the remote address, reported by ACL LOG
+	addr string
+	// users checks every command against the permissions of user, see authorize.
+	// A connection is the default user until AUTH. It is authenticated when it connects while the default user
+	// has no password, like in redis setting requirepass later does not log it out.
+	users         *acl.ACL
+	user          string
+	authenticated bool
+
+	// ks tells which shard the keys of a transaction belong to
+	ks keyspace
+	tx transaction
+
+	// ps executes the Pub/Sub commands, subs are the channels, patterns and shard channels of the client.
+	// A client with subscriptions is in push mode: it receives messages without sending commands.
+	ps   *pubsub
+	subs [numKinds]map[string]struct{}
+	// notify wakes the event loop of the client up when a message is pushed, it is set before the first subscription
+	notify func()
+	// inbox holds the messages pushed by publishers running on other goroutines,
+	// the event loop moves them to pending, see takeMessages
+	inboxMu sync.Mutex
+	inbox   [][]byte
+	// inboxSize is the size of inbox, inboxFull is set when a message is dropped because it reached the hard limit
+	inboxSize int64
+	inboxFull bool
+	// softLimitSince is when the output buffer went above the soft limit, zero while it is below
+	softLimitSince time.Time
+}
+
+var errOutputBufferLimit = errors.New("output buffer limit exceeded")
+
+func newClient(fd int, conn net.Conn, ks keyspace, ps *pubsub, users *acl.ACL) *client {
+	c := &client{
+		fd:    fd,
+		conn:  conn,
+		users: users,
+		user:  acl.DefaultUser,
+		ks:    ks,
+		ps:    ps,
+	}
+	c.authenticated = !users.AuthRequired()
+	if conn != nil {
+		c.addr = conn.RemoteAddr().String()
+	}
+	for kind := range c.subs {
+		c.subs[kind] = make(map[string]struct{})
+	}
+	return c
+}
+
+// protocolErrorReply is the error written to a client breaking the protocol, ErrProtocol alone gives no reason
+func protocolErrorReply(err error) []byte {
+	if err == core.ErrProtocol {
+		err = fmt.Errorf("%w: invalid request", err)
+	}
+	return core.Encode(err, false)
+}
+
+// readCommands reads what is available on the socket and returns every complete command
+// in the read buffer, in the order the client sent them.
+// It returns no command and no error when the read would block.
+func (c *client) readCommands() ([]*core.Command, error) {
+	if cap(c.readBuf)-len(c.readBuf) < readChunkSize {
+		buf := make([]byte, len(c.readBuf), 2*cap(c.readBuf)+readChunkSize)
+		copy(buf, c.readBuf)
+		c.readBuf = buf
+	}
+
+	n, err := syscall.Read(c.fd, c.readBuf[len(c.readBuf):cap(c.readBuf)])
+	if err != nil {
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if n == 0 {
+		return nil, io.EOF
+	}
+	c.readBuf = c.readBuf[:len(c.readBuf)+n]
+
+	cmds, consumed, err := core.ParseCommands(c.readBuf)
+	if err != nil {
+		// like redis, the client is told why it is disconnected
+		c.writeBuf = append(c.writeBuf, protocolErrorReply(err)...)
+		_ = c.flush()
+		return nil, err
+	}
+
+	// keep the incomplete tail for the next read
+	c.readBuf = c.readBuf[:copy(c.readBuf, c.readBuf[consumed:])]
+	if int64(len(c.readBuf)) > config.MaxQueryBufferSize.Load() {
+		return nil, errors.New("query buffer limit exceeded")
+	}
+
+	return cmds, nil
+}
+
+// enqueue registers the reply channel of a dispatched command
+func (c *client) enqueue(replyChan chan []byte) {
+	c.pending = append(c.pending, replyChan)
+}
+
+// enqueueReply registers a reply computed by the event loop itself
+func (c *client) enqueueReply(res []byte) {
+	replyChan := make(chan []byte, 1)
+	replyChan <- res
+	c.enqueue(replyChan)
+}
+
+// subscribed reports whether the client is in push mode
+func (c *client) subscribed() bool {
+	for _, subs := range c.subs {
+		if len(subs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionCount is the number reported by the confirmations of kind:
+// channels and patterns are counted together, shard channels apart
+func (c *client) subscriptionCount(kind subscriptionKind) int {
+	if kind == shardKind {
+		return len(c.subs[shardKind])
+	}
+	return len(c.subs[channelKind]) + len(c.subs[patternKind])
+}
+
+// push is called by a publisher, from any goroutine.
+// The messages of a subscriber that does not keep up are dropped once they reach the hard limit,
+// the event loop closes it, see checkOutputBuffer.
+func (c *client) push(msg []byte) {
+	limit := config.OutputBufferLimits.Load()[config.ClientPubSub].Hard
+	c.inboxMu.Lock()
+	if limit > 0 && c.inboxSize+int64(len(msg)) > limit {
+		c.inboxFull = true
+	} else {
+		c.inbox = append(c.inbox, msg)
+		c.inboxSize += int64(len(msg))
+	}
+	c.inboxMu.Unlock()
+	c.notify()
+}
+
+// takeMessages queues the pushed messages after the replies dispatched so far
+func (c *client) takeMessages() {
+	c.inboxMu.Lock()
+	msgs := c.inbox
+	c.inbox = nil
+	c.inboxSize = 0
+	c.inboxMu.Unlock()
+
+	for _, msg := range msgs {
+		c.enqueueReply(msg)
+	}
+}
+
+// executePubSub executes the Pub/Sub commands on the event loop, they need the connection.
+// While the client is subscribed the other commands are rejected, like redis with RESP2.
+// It returns false for the commands to dispatch to the workers, including the ones with a wrong arity.
+func (c *client) executePubSub(cmd *core.Command, notify func()) ([]byte, bool) {
+	spec := core.LookupCommand(cmd.Cmd)
+	if spec == nil || !spec.CheckArity(len(cmd.Args)) {
+		return nil, false
+	}
+	if c.subscribed() {
+		if !subscribedCommands[spec.Name] {
+			return core.Encode(fmt.Errorf("ERR Can't execute '%s': %w", strings.ToLower(spec.Name), errSubscribedContext), false), true
+		}
+		if spec.Name == constant.CMD_PING {
+			message := ""
+			if len(cmd.Args) > 0 {
+				message = cmd.Args[0]
+			}
+			return core.Encode([]string{"pong", message}, false), true
+		}
+	}
+	if !spec.HasFlag(core.FlagPubSub) {
+		return nil, false
+	}
+	// set once, before the client is in the subscriber lists read by publishers
+	if c.notify == nil {
+		c.notify = notify
+	}
+	return c.ps.execute(c, spec.Name, cmd.Args), true
+}
+
+// dispatch creates a task for each command, queues its reply and hands it to exec in order.
+// The commands following a blocking command are held until its reply is collected.
+func (c *client) dispatch(cmds []*core.Command, notify func(), exec func(task *core.Task)) {
+	for i, cmd := range cmds {
+		if c.blocking != nil {
+			c.held = append(c.held, cmds[i:]...)
+			return
+		}
+
+		if res := c.authorize(cmd); res != nil {
+			c.enqueueReply(res)
+			continue
+		}
+		if c.executeTransaction(cmd, notify, exec) {
+			continue
+		}
+		if res, ok := c.executePubSub(cmd, notify); ok {
+			c.enqueueReply(res)
+			continue
+		}
+		if res, ok := c.executeACL(cmd); ok {
+			c.enqueueReply(res)
+			continue
+		}
+
+		task := &core.Task{
+			Command:   cmd,
+			ReplyChan: make(chan []byte, 1),
+			Notify:    notify,
+		}
+		c.enqueue(task.ReplyChan)
+		if isBlockingCommand(cmd) || isGatherCommand(cmd) {
+			c.blocking = task
+		}
+		exec(task)
+	}
+}
+
+// resume dispatches the held commands once the reply of the blocking command was collected,
+// it returns false when there is nothing to resume
+func (c *client) resume(notify func(), exec func(task *core.Task)) bool {
+	if c.blocking != nil || len(c.held) == 0 {
+		return false
+	}
+	cmds := c.held
+	c.held = nil
+	c.dispatch(cmds, notify, exec)
+	return true
+}
+
+// isBlockingCommand reports whether cmd may not reply until a key is written, see core.ExecuteTask
+func isBlockingCommand(cmd *core.Command) bool {
+	spec := core.LookupCommand(cmd.Cmd)
+	return spec != nil && spec.HasFlag(core.FlagBlocking) && spec.CheckArity(len(cmd.Args))
+}
+
+// collectReplies moves the replies that are ready to the write buffer.
+// It stops at the first reply that is still being computed so the request order is kept.
+// It returns false when a reply channel was closed because the server is shutting down.
+func (c *client) collectReplies() bool {
+	c.takeMessages()
+	collected := 0
+	defer func() {
+		c.pending = c.pending[:copy(c.pending, c.pending[collected:])]
+	}()
+
+	for _, replyChan := range c.pending {
+		select {
+		case res, ok := <-replyChan:
+			if !ok {
+				return false
+			}
+			c.writeBuf = append(c.writeBuf, res...)
+			collected++
+			if c.blocking != nil && replyChan == c.blocking.ReplyChan {
+				c.blocking = nil
+			}
+		default:
+			return true
+		}
+	}
+
+	return true
+}
+
+// flush writes as much of the write buffer as the socket accepts without blocking
+func (c *client) flush() error {
+	written := 0
+	defer func() {
+		c.writeBuf = c.writeBuf[:copy(c.writeBuf, c.writeBuf[written:])]
+	}()
+
+	for written < len(c.writeBuf) {
+		n, err := syscall.Write(c.fd, c.writeBuf[written:])
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if errors.Is(err, syscall.EAGAIN) {
+				return nil
+			}
+			return err
+		}
+		written += n
+	}
+
+	return nil
+}
+
+// class is the class of the output buffer limits of the client
+func (c *client) class() int {
+	if c.subscribed() {
+		return config.ClientPubSub
+	}
+	return config.ClientNormal
+}
+
+// checkOutputBuffer returns an error when the replies not written yet exceed the output buffer limits
+// of the client class, the client is then closed like in redis. It is called after flush.
+func (c *client) checkOutputBuffer(now time.Time) error {
+	c.inboxMu.Lock()
+	size, full := int64(len(c.writeBuf))+c.inboxSize, c.inboxFull
+	c.inboxMu.Unlock()
+
+	limit := config.OutputBufferLimits.Load()[c.class()]
+	if full || (limit.Hard > 0 && size >= limit.Hard) {
+		return errOutputBufferLimit
+	}
+	if limit.Soft == 0 || size < limit.Soft {
+		c.softLimitSince = time.Time{}
+		return nil
+	}
+	if c.softLimitSince.IsZero() {
+		c.softLimitSince = now
+	}
+	if now.Sub(c.softLimitSince) >= time.Duration(limit.SoftSeconds)*time.Second {
+		return errOutputBufferLimit
+	}
+	return nil
+}
+
+// updateWriteInterest monitors the fd for writability only while there are unsent replies,
+// otherwise a socket that is always writable would wake the event loop for nothing
+func (c *client) updateWriteInterest(ioMultiplexer io_multiplexer.IOMultiplexer) error {
+	wantWrite := len(c.writeBuf) > 0
+	if wantWrite == c.watchingWrite {
+		return nil
+	}
+
+	var op io_multiplexer.Operation = io_multiplexer.OpRead
+	if wantWrite {
+		op = io_multiplexer.OpReadWrite
+	}
+	if err := ioMultiplexer.Modify(io_multiplexer.Event{Fd: c.fd, Op: op}); err != nil {
+		return err
+	}
+	c.watchingWrite = wantWrite
+	return nil
+}
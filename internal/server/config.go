@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// CONFIG GET parameter [parameter ...]
+// CONFIG SET parameter value [parameter value ...]
+// CONFIG RESETSTAT
+// CONFIG REWRITE
+//
+// The settings changed by CONFIG SET are atomic, see config.Setting, so workers and I/O handlers
+// read the new value at their next use without going through the server.
+// requirepass is the exception, it is applied to the default user of users.
+func executeConfig(ks keyspace, users *acl.ACL, cmd *core.Command) []byte {
+	args := cmd.Args
+	sub := strings.ToUpper(args[0])
+	wrongArgs := core.Encode(fmt.Errorf("ERR wrong number of arguments for 'config|%s' command", strings.ToLower(sub)), false)
+	switch sub {
+	case "GET":
+		if len(args) < 2 {
+			return wrongArgs
+		}
+		return core.Encode(config.Get(args[1:]...), false)
+	case "SET":
+		if len(args) < 3 || len(args)%2 != 1 {
+			return wrongArgs
+		}
+		if err := config.Set(args[1:]...); err != nil {
+			return core.Encode(err, false)
+		}
+		for i := 1; i < len(args); i += 2 {
+			if strings.EqualFold(args[i], "requirepass") {
+				users.SetRequirePass(config.RequirePass.Load())
+			}
+		}
+		return constant.RespOk
+	case "RESETSTAT":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		ks.atomically(func(_ int, db *core.RedisDB) {
+			db.ResetStats()
+		})
+		return constant.RespOk
+	case "REWRITE":
+		if len(args) != 1 {
+			return wrongArgs
+		}
+		if err := config.Rewrite(); err != nil {
+			if errors.Is(err, config.ErrNoConfigFile) {
+				return core.Encode(err, false)
+			}
+			return core.Encode(errors.New("ERR Rewriting config file: "+err.Error()), false)
+		}
+		return constant.RespOk
+	default:
+		return core.Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try CONFIG HELP.", args[0]), false)
+	}
+}
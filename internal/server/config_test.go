@@ -0,0 +1,80 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigSetReachesWorkers(t *testing.T) {
+	defer config.EvictPolicy.Store(config.EvictPolicy.Load())
+	defer config.MaxMemory.Store(config.MaxMemory.Load())
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 4)
+
+	assert.Equal(t, "+OK\r\n", execute(s, "CONFIG", "SET", "maxmemory-policy", "noeviction", "maxmemory", "4"))
+	assert.Equal(t, "*4\r\n$9\r\nmaxmemory\r\n$1\r\n4\r\n$16\r\nmaxmemory-policy\r\n$10\r\nnoeviction\r\n",
+		execute(s, "CONFIG", "GET", "maxmemory", "maxmemory-policy"))
+	// every worker gets 1 byte, its first write goes above it and the next one is rejected
+	for _, key := range keys {
+		assert.Equal(t, "+OK\r\n", execute(s, "SET", key, "value"))
+		assert.Equal(t, string(constant.ErrorOOM), execute(s, "SET", key, "value"))
+	}
+
+	assert.Equal(t, "+OK\r\n", execute(s, "CONFIG", "SET", "maxmemory", "0"))
+	for _, key := range keys {
+		assert.Equal(t, "+OK\r\n", execute(s, "SET", key, "value"))
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	s := newTestServer(t, 2)
+	assert.Equal(t, "-ERR wrong number of arguments for 'config|set' command\r\n", execute(s, "CONFIG", "SET", "hz"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'config|get' command\r\n", execute(s, "CONFIG", "GET"))
+	assert.Equal(t, "-ERR unknown subcommand 'NOPE'. Try CONFIG HELP.\r\n", execute(s, "CONFIG", "NOPE"))
+	assert.Equal(t, "-ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config\r\n",
+		execute(s, "CONFIG", "SET", "port", "6380"))
+}
+
+func TestConfigResetStat(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 4)
+	for _, key := range keys {
+		execute(s, "SET", key, "value", "PX", "1")
+	}
+	// the lookups delete the expired keys
+	time.Sleep(5 * time.Millisecond)
+	for _, key := range keys {
+		assert.Equal(t, "$-1\r\n", execute(s, "GET", key))
+	}
+
+	info := execute(s, "INFO", "stats")
+	assert.Equal(t, "4", infoField(t, info, "expired_keys"))
+	assert.Equal(t, "0", infoField(t, info, "evicted_keys"))
+	assert.NotEqual(t, "0", infoField(t, info, "total_commands_processed"))
+
+	assert.Equal(t, "+OK\r\n", execute(s, "CONFIG", "RESETSTAT"))
+	info = execute(s, "INFO", "stats")
+	assert.Equal(t, "0", infoField(t, info, "expired_keys"))
+	// INFO itself is counted once by every worker
+	assert.Equal(t, "4", infoField(t, info, "total_commands_processed"))
+}
+
+func TestConfigRewrite(t *testing.T) {
+	defer config.Hz.Store(config.Hz.Load())
+	path := filepath.Join(t.TempDir(), "godis.conf")
+	assert.NoError(t, os.WriteFile(path, []byte("# comment\nhz 5\n"), 0600))
+	assert.NoError(t, config.Load([]string{path}))
+
+	s := newTestServer(t, 2)
+	assert.Equal(t, "+OK\r\n", execute(s, "CONFIG", "SET", "hz", "15"))
+	assert.Equal(t, "+OK\r\n", execute(s, "CONFIG", "REWRITE"))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "# comment\nhz 15\n", string(data))
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"maps"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// gatherCommands combine sets or sorted sets, they read their source keys on the workers owning them
+// when those are not the worker executing the command, see dispatchGather
+var gatherCommands = map[string]bool{
+	constant.CMD_ZUNION:      true,
+	constant.CMD_ZUNIONSTORE: true,
+	constant.CMD_ZINTER:      true,
+	constant.CMD_ZINTERSTORE: true,
+	constant.CMD_ZINTERCARD:  true,
+	constant.CMD_ZDIFF:       true,
+	constant.CMD_ZDIFFSTORE:  true,
+	constant.CMD_SUNION:      true,
+	constant.CMD_SUNIONSTORE: true,
+	constant.CMD_SINTER:      true,
+	constant.CMD_SINTERSTORE: true,
+	constant.CMD_SINTERCARD:  true,
+	constant.CMD_SDIFF:       true,
+	constant.CMD_SDIFFSTORE:  true,
+}
+
+// isGatherCommand reports whether cmd may read its keys on several workers before it is executed,
+// the commands of the client sent after it are held until it replies so they cannot run before it
+func isGatherCommand(cmd *core.Command) bool {
+	spec := core.LookupCommand(cmd.Cmd)
+	return spec != nil && gatherCommands[spec.Name] && spec.CheckArity(len(cmd.Args))
+}
+
+// dispatchGather executes a command combining sets or sorted sets whose keys are owned by several workers.
+// It runs on the worker owning its first key, the destination of the STORE variants: the source keys owned
+// by the other workers are read there first and passed with the task, see core.Operands.
+// Unlike redis it is not atomic, a source key can be written between the time it is read and the command runs.
+// It returns false when all keys belong to a single worker and the task can be dispatched as is.
+func (s *Server) dispatchGather(task *core.Task, keyIdx []int) bool {
+	args := task.Command.Args
+	home := s.getWorkerID(args[keyIdx[0]])
+
+	// group the source keys owned by other workers, keeping the order in which workers are first seen
+	var workerIDs []int
+	groups := make(map[int][]string)
+	for _, i := range keyIdx[1:] {
+		workerID := s.getWorkerID(args[i])
+		if workerID == home {
+			continue
+		}
+		if _, ok := groups[workerID]; !ok {
+			workerIDs = append(workerIDs, workerID)
+		}
+		groups[workerID] = append(groups[workerID], args[i])
+	}
+	if len(workerIDs) == 0 {
+		return false
+	}
+
+	operands := make([]core.Operands, len(workerIDs))
+	subTasks := make([]*core.Task, len(workerIDs))
+	for n, workerID := range workerIDs {
+		keys := groups[workerID]
+		subTasks[n] = &core.Task{
+			Fn: func(redisDB *core.RedisDB) []byte {
+				var errReply []byte
+				operands[n], errReply = core.ReadOperands(redisDB, task.Command, keys)
+				return errReply
+			},
+		}
+	}
+	s.fanOut(workerIDs, subTasks, func(replies [][]byte) {
+		// a key of the wrong type fails the command
+		for _, reply := range replies {
+			if reply != nil {
+				task.Reply(reply)
+				return
+			}
+		}
+		task.Operands = make(core.Operands)
+		for _, o := range operands {
+			maps.Copy(task.Operands, o)
+		}
+		// this runs on a worker, which must not wait for the queue of another one
+		go s.sendToWorker(home, task)
+	})
+	return true
+}
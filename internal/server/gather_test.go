@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherAcrossWorkers(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 4)
+	z1, z2, set, dst := keys[0], keys[1], keys[2], keys[3]
+	execute(s, "ZADD", z1, "1", "a", "2", "b")
+	execute(s, "ZADD", z2, "10", "b", "20", "c")
+	execute(s, "SADD", set, "b", "c")
+
+	assert.Equal(t, "*6\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$2\r\n13\r\n$1\r\nc\r\n$2\r\n21\r\n",
+		execute(s, "ZUNION", "3", z1, z2, set, "WITHSCORES"))
+	assert.Equal(t, ":1\r\n", execute(s, "ZINTERCARD", "3", z1, z2, set))
+	assert.Equal(t, ":1\r\n", execute(s, "ZDIFFSTORE", dst, "2", z1, set))
+	assert.Equal(t, "*1\r\n$1\r\na\r\n", execute(s, "ZRANGE", dst, "0", "-1"))
+	assert.Equal(t, ":1\r\n", execute(s, "ZINTERSTORE", dst, "2", z1, z2, "AGGREGATE", "MAX"))
+	assert.Equal(t, "*2\r\n$1\r\nb\r\n$2\r\n10\r\n", execute(s, "ZRANGE", dst, "0", "-1", "WITHSCORES"))
+
+	execute(s, "SET", set, "v")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(s, "ZUNION", "2", z1, set))
+}
+
+func TestGatherSetsAcrossWorkers(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 3)
+	execute(s, "SADD", keys[0], "a", "b")
+	execute(s, "SADD", keys[1], "b", "c")
+
+	assert.Equal(t, "*1\r\n$1\r\nb\r\n", execute(s, "SINTER", keys[0], keys[1]))
+	assert.Equal(t, ":1\r\n", execute(s, "SINTERCARD", "2", keys[0], keys[1]))
+	assert.Equal(t, ":3\r\n", execute(s, "SUNIONSTORE", keys[2], keys[0], keys[1]))
+	assert.Equal(t, "*1\r\n$1\r\nc\r\n", execute(s, "SDIFF", keys[2], keys[0]))
+	// SMOVE must see both keys at once
+	assert.Equal(t, "-"+errCrossShard.Error()+"\r\n", execute(s, "SMOVE", keys[0], keys[1], "a"))
+
+	execute(s, "DEL", keys[1])
+	execute(s, "ZADD", keys[1], "1", "z")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", execute(s, "SUNION", keys[0], keys[1]))
+}
+
+func TestGatherStoreReplay(t *testing.T) {
+	s := newTestServer(t, 4)
+	assert.NoError(t, s.persistence.aof.open())
+	keys := keysOnDifferentWorkers(s, 3)
+	execute(s, "ZADD", keys[0], "1", "a", "2", "b")
+	execute(s, "ZADD", keys[1], "3", "b")
+	execute(s, "ZUNIONSTORE", keys[2], "2", keys[0], keys[1])
+	// the result of the store is logged, the replay does not depend on the state of the sources
+	execute(s, "DEL", keys[0], keys[1])
+	s.persistence.aof.close()
+
+	loaded := newTestServer(t, 3)
+	loaded.persistence.aof.path = s.persistence.aof.path
+	ok, err := loaded.persistence.aof.load()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n5\r\n", execute(loaded, "ZRANGE", keys[2], "0", "-1", "WITHSCORES"))
+}
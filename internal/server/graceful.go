@@ -1,26 +1,67 @@
 package server
 
 import (
+	"context"
 	"log"
 	"os"
-	"sync"
-	"sync/atomic"
+	"time"
 
-	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/config"
 )
 
-func WaitForSignal(wg *sync.WaitGroup, signals chan os.Signal) {
-	defer wg.Done()
-	// Wait for signal in channel, it not available then wait
-	<-signals
-	// log.Println("Shutting down gracefully...")
-	// os.Exit(0)
+// shutdownTimeout is how long the connections have to get their replies once a signal is received
+const shutdownTimeout = 10 * time.Second
 
-	// Busy loop
-	log.Println("Shutting down gracefully...")
-	for {
-		if atomic.CompareAndSwapInt32(&serverStatus, constant.ServerStatusIdle, constant.ServerStatusShuttingDown) {
-			os.Exit(0) // shutdown
+// shutdowner is a server architecture that stops gracefully: it stops accepting connections,
+// lets the commands being executed finish, then saves the keyspace like redis SHUTDOWN
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Run starts the server architecture selected by config.Mode and shuts it down once a signal is received
+func Run(signals <-chan os.Signal) error {
+	var srv shutdowner
+	// stopped gets the error ending the event loop of the single-threaded server
+	var stopped chan error
+	switch config.Mode {
+	case "single-thread":
+		st := newSingleThreadServer()
+		stopped = make(chan error, 1)
+		go func() {
+			stopped <- st.run()
+		}()
+		srv = st
+	default:
+		s, err := NewServer()
+		if err != nil {
+			return err
 		}
+		start := s.StartMultiListeners
+		if config.Mode == "multi-worker" {
+			start = s.StartSingleListener
+		}
+		if err := start(); err != nil {
+			shutdown(s)
+			return err
+		}
+		srv = s
+	}
+
+	select {
+	case err := <-stopped:
+		return err
+	case <-signals:
+	}
+	log.Println("Shutting down gracefully...")
+	return shutdown(srv)
+}
+
+func shutdown(srv shutdowner) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		log.Printf("Shutdown finished with error: %v", err)
 	}
+	return err
 }
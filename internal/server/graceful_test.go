@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// dialServer connects to the server once it accepts connections
+func dialServer(t *testing.T) net.Conn {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(config.Port))
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			return conn
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunModes(t *testing.T) {
+	defer func(mode string, port, workers, ioHandlers int, dir string) {
+		config.Mode, config.Port, config.Workers, config.IOHandlers, config.RDBDir = mode, port, workers, ioHandlers, dir
+	}(config.Mode, config.Port, config.Workers, config.IOHandlers, config.RDBDir)
+	config.Workers, config.IOHandlers = 2, 2
+
+	for _, mode := range config.Modes {
+		config.Mode, config.Port, config.RDBDir = mode, freePort(t), t.TempDir()
+		signals := make(chan os.Signal, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- Run(signals)
+		}()
+
+		conn := dialServer(t)
+		reader := bufio.NewReader(conn)
+		_, err := conn.Write([]byte("*3\r\n$3\r\nSET\r\n$4\r\nmode\r\n$" + strconv.Itoa(len(mode)) + "\r\n" + mode + "\r\n"))
+		assert.NoError(t, err)
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "+OK\r\n", line, mode)
+		conn.Close()
+
+		// a client breaking the protocol is told why before it is disconnected,
+		// an argument count over the limit is rejected before the arguments arrive
+		conn = dialServer(t)
+		reader = bufio.NewReader(conn)
+		_, err = conn.Write([]byte("*536870912\r\n"))
+		assert.NoError(t, err)
+		line, err = reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "-ERR Protocol error: invalid multibulk length\r\n", line, mode)
+		_, err = reader.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF, mode)
+		conn.Close()
+
+		// every mode saves the keyspace on shutdown
+		signals <- os.Interrupt
+		select {
+		case err := <-done:
+			assert.NoError(t, err, mode)
+		case <-time.After(shutdownTimeout):
+			t.Fatalf("%s: server did not stop", mode)
+		}
+		_, err = os.Stat(filepath.Join(config.RDBDir, config.RDBFileName))
+		assert.NoError(t, err, mode)
+	}
+}
+
+func TestSavePolicyOnIdleServer(t *testing.T) {
+	defer func(mode string, port int, dir string, params []config.SaveParam) {
+		config.Mode, config.Port, config.RDBDir = mode, port, dir
+		config.SaveParams.Store(params)
+	}(config.Mode, config.Port, config.RDBDir, config.SaveParams.Load())
+	config.SaveParams.Store([]config.SaveParam{{Seconds: 1, Changes: 1}})
+
+	for _, mode := range config.Modes {
+		config.Mode, config.Port, config.RDBDir = mode, freePort(t), t.TempDir()
+		signals := make(chan os.Signal, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- Run(signals)
+		}()
+
+		conn := dialServer(t)
+		_, err := conn.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+		assert.NoError(t, err)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "+OK\r\n", line, mode)
+
+		// nothing is sent anymore, the snapshot is taken by the periodic jobs alone
+		path := filepath.Join(config.RDBDir, config.RDBFileName)
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond, mode)
+		conn.Close()
+
+		signals <- os.Interrupt
+		select {
+		case err := <-done:
+			assert.NoError(t, err, mode)
+		case <-time.After(shutdownTimeout):
+			t.Fatalf("%s: server did not stop", mode)
+		}
+	}
+}
+
+func TestRunPortInUse(t *testing.T) {
+	defer func(mode string, port int, dir string) {
+		config.Mode, config.Port, config.RDBDir = mode, port, dir
+	}(config.Mode, config.Port, config.RDBDir)
+	listener, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	config.Port, config.RDBDir = listener.Addr().(*net.TCPAddr).Port, t.TempDir()
+	for _, mode := range []string{"multi-worker", "reuseport"} {
+		config.Mode = mode
+		assert.ErrorContains(t, Run(make(chan os.Signal)), "address already in use", mode)
+	}
+}
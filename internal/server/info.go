@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// summedInfoFields are the INFO fields counting something in the shard, the other fields
+// are the same for every worker, like maxmemory
+var summedInfoFields = map[string]bool{
+	"used_memory":              true,
+	"total_commands_processed": true,
+	"expired_keys":             true,
+	"evicted_keys":             true,
+}
+
+// mergeInfo builds the INFO of the server from the INFO of each worker: the lines of the first reply
+// are kept with the counters summed over all the replies, like used_memory or the keys of db0.
+// A human readable field like used_memory_human follows its summed field.
+func mergeInfo(replies [][]byte) []byte {
+	infos := make([][]string, len(replies))
+	for n, reply := range replies {
+		value, err := core.Decode(reply)
+		info, ok := value.(string)
+		if err != nil || !ok {
+			return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+		}
+		infos[n] = strings.Split(info, "\r\n")
+	}
+
+	lines := infos[0]
+	sums := make(map[string]int64)
+	for i, line := range lines {
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch {
+		case summedInfoFields[field]:
+			var sum int64
+			for _, info := range infos {
+				if i >= len(info) {
+					continue
+				}
+				_, v, _ := strings.Cut(info[i], ":")
+				n, _ := strconv.ParseInt(v, 10, 64)
+				sum += n
+			}
+			sums[field] = sum
+			lines[i] = fmt.Sprintf("%s:%d", field, sum)
+		case strings.HasSuffix(field, "_human"):
+			if sum, ok := sums[strings.TrimSuffix(field, "_human")]; ok {
+				lines[i] = field + ":" + core.BytesToHuman(sum)
+			}
+		case strings.HasPrefix(field, "db"):
+			lines[i] = field + ":" + mergeKeyspace(value, infos, i)
+		}
+	}
+	return core.Encode(strings.Join(lines, "\r\n"), false)
+}
+
+// mergeKeyspace sums the keys and expires of a keyspace line like keys=1,expires=0,avg_ttl=0
+func mergeKeyspace(value string, infos [][]string, i int) string {
+	sums := make(map[string]int64)
+	for _, info := range infos {
+		if i >= len(info) {
+			continue
+		}
+		_, v, _ := strings.Cut(info[i], ":")
+		for _, pair := range strings.Split(v, ",") {
+			name, count, _ := strings.Cut(pair, "=")
+			n, _ := strconv.ParseInt(count, 10, 64)
+			sums[name] += n
+		}
+	}
+
+	pairs := strings.Split(value, ",")
+	for j, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if name == "keys" || name == "expires" {
+			pairs[j] = fmt.Sprintf("%s=%d", name, sums[name])
+		}
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func infoField(t *testing.T, info string, field string) string {
+	decoded, err := core.Decode([]byte(info))
+	assert.NoError(t, err)
+	match := regexp.MustCompile(`(?m)^` + field + `:(.*)\r$`).FindStringSubmatch(decoded.(string))
+	if !assert.NotNil(t, match, field) {
+		return ""
+	}
+	return match[1]
+}
+
+func TestInfoSummedAcrossWorkers(t *testing.T) {
+	s := newTestServer(t, 4)
+	assert.Equal(t, "0", infoField(t, execute(s, "INFO", "memory"), "used_memory"))
+
+	keys := keysOnDifferentWorkers(s, 4)
+	var total int64
+	for _, key := range keys {
+		execute(s, "SET", key, "value")
+		execute(s, "EXPIRE", key, "100")
+		usage, err := core.Decode([]byte(execute(s, "MEMORY", "USAGE", key)))
+		assert.NoError(t, err)
+		total += usage.(int64)
+	}
+
+	info := execute(s, "INFO")
+	assert.Equal(t, strconv.FormatInt(total, 10), infoField(t, info, "used_memory"))
+	assert.Equal(t, core.BytesToHuman(total), infoField(t, info, "used_memory_human"))
+	assert.Equal(t, "0", infoField(t, info, "maxmemory"))
+	assert.Equal(t, "keys=4,expires=4,avg_ttl=0", infoField(t, info, "db0"))
+
+	// sections not asked for are left out
+	assert.NotContains(t, execute(s, "INFO", "keyspace"), "used_memory")
+	assert.NotContains(t, execute(s, "INFO", "memory"), "db0")
+}
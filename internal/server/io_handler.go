@@ -6,9 +6,10 @@ import (
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
-	"github.com/nhtuan0700/godis/internal/core"
 	"github.com/nhtuan0700/godis/internal/core/io_multiplexer"
 )
 
@@ -21,7 +22,17 @@ type IOHandler struct {
 	// We use a map to store active connections, the key is the file descriptor of the connection
 	// when running benchmark, the number of connections can be very large, the gc run quickly and close the connection before the I/O handler can read from it
 	// which causes "bad file descriptor" error -> benchmark fails
-	conns map[int]net.Conn
+	clients map[int]*client
+	// waiting holds the clients with dispatched commands whose replies are not collected yet,
+	// and the subscribed clients that may get messages. It is only used by the event loop goroutine.
+	waiting map[int]*client
+
+	// Workers write to the wake pipe after sending a reply, its read end is monitored
+	// together with the connections so a finished task wakes the event loop up.
+	wakeReadFd  int
+	wakeWriteFd int
+	// wakePending avoids writing to the pipe for every reply while the loop has not woken up yet
+	wakePending atomic.Bool
 }
 
 func NewIOHandler(id int, server *Server) (*IOHandler, error) {
@@ -30,11 +41,31 @@ func NewIOHandler(id int, server *Server) (*IOHandler, error) {
 		return nil, err
 	}
 
+	wakeFds := make([]int, 2)
+	if err := syscall.Pipe(wakeFds); err != nil {
+		return nil, err
+	}
+	for _, fd := range wakeFds {
+		syscall.CloseOnExec(fd)
+		if err := syscall.SetNonblock(fd, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := ioMultiplexer.Monitor(io_multiplexer.Event{
+		Fd: wakeFds[0],
+		Op: io_multiplexer.OpRead,
+	}); err != nil {
+		return nil, err
+	}
+
 	ioHandler := &IOHandler{
 		id:            id,
 		ioMultiplexer: ioMultiplexer,
 		server:        server,
-		conns:         make(map[int]net.Conn),
+		clients:       make(map[int]*client),
+		waiting:       make(map[int]*client),
+		wakeReadFd:    wakeFds[0],
+		wakeWriteFd:   wakeFds[1],
 	}
 
 	return ioHandler, nil
@@ -61,7 +92,7 @@ func (h *IOHandler) AddConn(conn net.Conn) error {
 	err = rawConn.Control(func(fd uintptr) {
 		connFd := int(fd)
 		log.Printf("I/O Handler %d is monitoring fd %d", h.id, connFd)
-		h.conns[connFd] = conn
+		h.clients[connFd] = newClient(connFd, conn, h.server, h.server.pubsub, h.server.users)
 		h.ioMultiplexer.Monitor(io_multiplexer.Event{
 			Fd: connFd,
 			Op: io_multiplexer.OpRead,
@@ -96,67 +127,172 @@ func (h *IOHandler) Run() {
 				return
 			}
 
+			if event.Fd == h.wakeReadFd {
+				if !h.sendWaitingReplies() {
+					return
+				}
+				continue
+			}
+
 			connFd := event.Fd
 			// log.Printf("I/O Handler %d received event on fd %d\n", h.id, connFd)
 
-			cmd, err := readCommand(connFd)
-			if err != nil {
-				if err == io.EOF || err == syscall.ECONNRESET {
-					log.Printf("I/O Handler %d: connection closed on fd %d\n", h.id, connFd)
-				} else {
-					log.Printf("Read error on fd %d: %v\n", connFd, err)
-				}
-				h.closeConn(connFd)
+			h.mu.Lock()
+			c, ok := h.clients[connFd]
+			h.mu.Unlock()
+			if !ok {
 				continue
 			}
 
-			replyChan := make(chan []byte, 1)
-			task := &core.Task{
-				Command:   cmd,
-				ReplyChan: replyChan,
+			if event.Op == io_multiplexer.OpWrite || event.Op == io_multiplexer.OpReadWrite {
+				if !h.sendReplies(c) {
+					return
+				}
 			}
 
-			// dispatch the command to the corresponding worker
-			h.server.dispatch(task)
-
-			res, ok := <-replyChan
-			if !ok {
-				return
-			}
-			if err := respond(res, connFd); err != nil {
-				log.Printf("Write error on fd %d: %v\n", connFd, err)
+			if event.Op == io_multiplexer.OpRead || event.Op == io_multiplexer.OpReadWrite {
+				if !h.handleRead(c) {
+					return
+				}
 			}
 		}
 	}
 }
 
+// handleRead dispatches every complete command sent by the client.
+// It returns false when the server is shutting down.
+func (h *IOHandler) handleRead(c *client) bool {
+	cmds, err := c.readCommands()
+	if err != nil {
+		if err == io.EOF || err == syscall.ECONNRESET {
+			log.Printf("I/O Handler %d: connection closed on fd %d\n", h.id, c.fd)
+		} else {
+			log.Printf("Read error on fd %d: %v\n", c.fd, err)
+		}
+		h.closeConn(c.fd)
+		return true
+	}
+
+	// Pipelined commands are dispatched without waiting for each other,
+	// the client queue keeps their replies in request order
+	c.dispatch(cmds, h.wake, h.server.dispatch)
+
+	return h.sendReplies(c)
+}
+
+// sendReplies writes the replies that are ready without blocking,
+// the rest is written when the socket becomes writable or the worker wakes us up.
+// It returns false when the server is shutting down.
+func (h *IOHandler) sendReplies(c *client) bool {
+	if !c.collectReplies() {
+		return false
+	}
+	if c.resume(h.wake, h.server.dispatch) && !c.collectReplies() {
+		return false
+	}
+	if len(c.pending) == 0 && !c.subscribed() {
+		delete(h.waiting, c.fd)
+	} else {
+		h.waiting[c.fd] = c
+	}
+
+	if err := c.flush(); err != nil {
+		log.Printf("Write error on fd %d: %v\n", c.fd, err)
+		h.closeConn(c.fd)
+		return true
+	}
+	if err := c.checkOutputBuffer(time.Now()); err != nil {
+		log.Printf("Closing fd %d: %v\n", c.fd, err)
+		h.closeConn(c.fd)
+		return true
+	}
+	if err := c.updateWriteInterest(h.ioMultiplexer); err != nil {
+		log.Printf("I/O Handler %d failed to update events of fd %d: %v\n", h.id, c.fd, err)
+		h.closeConn(c.fd)
+	}
+
+	return true
+}
+
+// sendWaitingReplies is called when a worker has woken the event loop up
+func (h *IOHandler) sendWaitingReplies() bool {
+	// Reset the flag before collecting, a reply sent after this point writes to the pipe again
+	h.wakePending.Store(false)
+	buf := make([]byte, 64)
+	for {
+		if _, err := syscall.Read(h.wakeReadFd, buf); err != nil {
+			break
+		}
+	}
+
+	for _, c := range h.waiting {
+		if !h.sendReplies(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// wake is called by workers after a reply has been sent
+func (h *IOHandler) wake() {
+	if h.server.isDraining() {
+		return
+	}
+	if h.wakePending.CompareAndSwap(false, true) {
+		// The pipe is non-blocking, it can only be full if the loop already has a wake up to handle
+		_, _ = syscall.Write(h.wakeWriteFd, []byte{1})
+	}
+}
+
 func (h *IOHandler) closeConn(fd int) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	delete(h.waiting, fd)
+	if c, ok := h.clients[fd]; ok {
+		// a task parked by a blocking command would be served for nobody
+		if c.blocking != nil {
+			h.server.unblock(c.blocking)
+		}
+		h.server.pubsub.removeClient(c)
+		if task := c.unwatchTask(); task != nil {
+			h.server.dispatch(task)
+		}
+	}
 	h.closeConnLocked(fd)
 }
 
 func (h *IOHandler) closeConnLocked(fd int) {
-	if conn, ok := h.conns[fd]; ok {
-		if err := conn.Close(); err != nil {
+	if c, ok := h.clients[fd]; ok {
+		// stop monitoring before closing, the fd number is reused by the next accepted connection
+		_ = h.ioMultiplexer.Unmonitor(fd)
+		if err := c.conn.Close(); err != nil {
 			log.Printf("I/O Handler %d failed to close fd %d: %v", h.id, fd, err)
 		}
-		delete(h.conns, fd)
+		delete(h.clients, fd)
 	}
 }
 
 func (h *IOHandler) CloseMultiplexer() {
+	// closing an epoll fd does not return from a pending epoll_wait, the wake pipe does
+	_, _ = syscall.Write(h.wakeWriteFd, []byte{1})
 	if err := h.ioMultiplexer.Close(); err != nil {
 		log.Printf("I/O Handler %d failed to close multiplexer: %v", h.id, err)
 	}
 }
 
+// CloseConnections is called once workers are stopped, so the wake pipe can be closed as well
 func (h *IOHandler) CloseConnections() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	for fd := range h.conns {
+	for fd := range h.clients {
 		h.closeConnLocked(fd)
 	}
+	if h.wakeReadFd > 0 {
+		syscall.Close(h.wakeReadFd)
+		syscall.Close(h.wakeWriteFd)
+		h.wakeReadFd, h.wakeWriteFd = -1, -1
+	}
 }
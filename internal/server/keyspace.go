@@ -0,0 +1,173 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// keyspace gives commands involving every key, like SAVE, access to all the RedisDB shards.
+// The multi-threaded server has one shard per worker, the single-threaded server has a single one.
+type keyspace interface {
+	// atomically runs fn on every shard, from the goroutine owning the shard.
+	// All the calls see the keyspace at the same point in time.
+	atomically(fn func(shard int, db *core.RedisDB))
+	numShards() int
+	// shardOf returns the shard owning key
+	shardOf(key string) int
+	// dirty returns the number of write commands since the last snapshot
+	dirty() int64
+	// execute runs cmd like a client would and returns the reply
+	execute(cmd *core.Command) []byte
+	// background calls step from the goroutine owning the shard between the commands until it returns true,
+	// then calls done from any goroutine. It returns at once.
+	background(shard int, step func(db *core.RedisDB) bool, done func())
+}
+
+// Every worker first executes the tasks queued before the barrier, then waits for the others,
+// so fn runs once all workers reached the same point.
+func (s *Server) atomically(fn func(shard int, db *core.RedisDB)) {
+	var arrived, done sync.WaitGroup
+	arrived.Add(s.numWorker)
+	done.Add(s.numWorker)
+	release := make(chan struct{})
+
+	// holding the lock keeps the sub-tasks of a multi-key command on the same side of the barrier
+	s.barrierMu.Lock()
+	for i := 0; i < s.numWorker; i++ {
+		shard := i
+		s.sendToWorker(shard, &core.Task{
+			ReplyChan: make(chan []byte, 1),
+			Fn: func(redisDB *core.RedisDB) []byte {
+				arrived.Done()
+				<-release
+				fn(shard, redisDB)
+				done.Done()
+				return nil
+			},
+		})
+	}
+	s.barrierMu.Unlock()
+
+	arrived.Wait()
+	close(release)
+	done.Wait()
+}
+
+func (s *Server) numShards() int {
+	return s.numWorker
+}
+
+func (s *Server) shardOf(key string) int {
+	return s.getWorkerID(key)
+}
+
+func (s *Server) dirty() int64 {
+	var dirty int64
+	for _, worker := range s.worker {
+		dirty += worker.Dirty()
+	}
+	return dirty
+}
+
+func (s *Server) execute(cmd *core.Command) []byte {
+	task := &core.Task{
+		Command:   cmd,
+		ReplyChan: make(chan []byte, 1),
+	}
+	s.dispatch(task)
+	return <-task.ReplyChan
+}
+
+// Every step is a task of its own, so the commands queued meanwhile are executed between two steps
+func (s *Server) background(shard int, step func(db *core.RedisDB) bool, done func()) {
+	go func() {
+		for finished := false; !finished; {
+			task := &core.Task{
+				ReplyChan: make(chan []byte, 1),
+				Fn: func(redisDB *core.RedisDB) []byte {
+					finished = step(redisDB)
+					return nil
+				},
+			}
+			s.sendToWorker(shard, task)
+			<-task.ReplyChan
+		}
+		done()
+	}()
+}
+
+// singleKeyspace is the keyspace of the single-threaded server, everything runs in its event loop
+type singleKeyspace struct {
+	db *core.RedisDB
+	// jobs are the background steps run by the event loop, a keyspace without one runs them at once
+	jobs *backgroundJobs
+}
+
+func (k singleKeyspace) atomically(fn func(shard int, db *core.RedisDB)) {
+	fn(0, k.db)
+}
+
+func (k singleKeyspace) numShards() int {
+	return 1
+}
+
+func (k singleKeyspace) shardOf(key string) int {
+	return 0
+}
+
+func (k singleKeyspace) dirty() int64 {
+	return k.db.Dirty()
+}
+
+func (k singleKeyspace) execute(cmd *core.Command) []byte {
+	return core.ExecuteCommand(k.db, cmd)
+}
+
+func (k singleKeyspace) background(shard int, step func(db *core.RedisDB) bool, done func()) {
+	if k.jobs == nil {
+		for !step(k.db) {
+		}
+		done()
+		return
+	}
+	k.jobs.add(step, done)
+}
+
+// backgroundJobs are the steps the event loop of the single-threaded server runs after serving its clients
+type backgroundJobs struct {
+	jobs []backgroundJob
+}
+
+type backgroundJob struct {
+	step func(db *core.RedisDB) bool
+	done func()
+}
+
+func (b *backgroundJobs) add(step func(db *core.RedisDB) bool, done func()) {
+	b.jobs = append(b.jobs, backgroundJob{step: step, done: done})
+}
+
+func (b *backgroundJobs) pending() bool {
+	return len(b.jobs) > 0
+}
+
+// run runs a step of every job and forgets the jobs that are done
+func (b *backgroundJobs) run(db *core.RedisDB) {
+	jobs := b.jobs
+	b.jobs = nil
+	for _, job := range jobs {
+		if job.step(db) {
+			job.done()
+		} else {
+			b.jobs = append(b.jobs, job)
+		}
+	}
+}
+
+// finish runs the jobs until they are all done, once the event loop is stopped
+func (b *backgroundJobs) finish(db *core.RedisDB) {
+	for b.pending() {
+		b.run(db)
+	}
+}
@@ -11,49 +11,18 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// StartSingleListener accepts the connections with one listener and hands them to the I/O handlers,
+// it returns once the listener is bound
 func (s *Server) StartSingleListener() error {
-	// Start all I/O handlers event loops
-	for _, handler := range s.ioHandlers {
-		s.wg.Add(1)
-		go func(handler *IOHandler) {
-			defer s.wg.Done()
-			handler.Run()
-		}(handler)
-	}
-
-	// Setup listener socket
+	s.startIOHandlers()
 
-	listener, err := net.Listen(config.Protocol, config.Address)
+	listener, err := net.Listen(config.Protocol, config.Address())
 	if err != nil {
 		return err
 	}
-	s.addListener(listener)
-	defer listener.Close()
-
-	log.Printf("Server listening on %s", config.Address)
-
-	for {
-		if s.isDraining() {
-			return nil
-		}
-
-		conn, err := listener.Accept()
-		if err != nil {
-			if s.isDraining() || errors.Is(err, net.ErrClosed) {
-				return nil
-			}
-			log.Printf("Failed to accept connection: %v", err)
-			continue
-		}
-
-		handler := s.nextHandler()
-
-		if err := handler.AddConn(conn); err != nil {
-			log.Printf("Failed to add connection to I/O handler %d: %v", handler.id, err)
-			// If adding fails, close the connection to avoid resource leak
-			conn.Close()
-		}
-	}
+	log.Printf("Server listening on %s", config.Address())
+	s.serve(listener)
+	return nil
 }
 
 func createReusablePortListener(network, addr string) (net.Listener, error) {
@@ -71,8 +40,24 @@ func createReusablePortListener(network, addr string) (net.Listener, error) {
 	return lc.Listen(context.Background(), network, addr)
 }
 
+// StartMultiListeners accepts the connections with config.ListenerNumber listeners bound to the same port
+// with SO_REUSEPORT, the kernel spreads the connections across them. It returns once every listener is bound.
 func (s *Server) StartMultiListeners() error {
-	// Start all I/O handlers event loops
+	s.startIOHandlers()
+
+	for i := 0; i < config.ListenerNumber; i++ {
+		listener, err := createReusablePortListener(config.Protocol, config.Address())
+		if err != nil {
+			return err
+		}
+		log.Printf("Listener %d started on %s", i, config.Address())
+		s.serve(listener)
+	}
+	return nil
+}
+
+// startIOHandlers starts the event loops of all I/O handlers
+func (s *Server) startIOHandlers() {
 	for _, handler := range s.ioHandlers {
 		s.wg.Add(1)
 		go func(handler *IOHandler) {
@@ -80,45 +65,43 @@ func (s *Server) StartMultiListeners() error {
 			handler.Run()
 		}(handler)
 	}
+}
 
-	// Start a listener for each I/O handler
-	for i := 0; i < config.ListenerNumber; i++ {
-		s.wg.Add(1)
-		go func(listenerID int) {
-			defer s.wg.Done()
-
-			listener, err := createReusablePortListener(config.Protocol, config.Address)
-			if err != nil {
-				log.Fatal(err)
+// serve accepts the connections of listener until the server shuts down
+func (s *Server) serve(listener net.Listener) {
+	s.addListener(listener)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer listener.Close()
+
+		for {
+			if s.isDraining() {
+				return
 			}
-			s.addListener(listener)
-			defer listener.Close()
-			log.Printf("Listener %d started on %s", listenerID, config.Address)
 
-			for {
-				if s.isDraining() {
+			conn, err := listener.Accept()
+			if err != nil {
+				if s.isDraining() || errors.Is(err, net.ErrClosed) {
 					return
 				}
+				log.Printf("Failed to accept connection: %v", err)
+				continue
+			}
 
-				conn, err := listener.Accept()
-				if err != nil {
-					if s.isDraining() || errors.Is(err, net.ErrClosed) {
-						return
-					}
-					log.Printf("Failed to accept connection: %v", err)
-					continue
-				}
+			if addr, _ := conn.RemoteAddr().(*net.TCPAddr); refusedByProtectedMode(s.users, addr) {
+				conn.Write(respProtectedMode)
+				conn.Close()
+				continue
+			}
 
-				handler := s.nextHandler()
+			handler := s.nextHandler()
 
-				if err := handler.AddConn(conn); err != nil {
-					log.Printf("Failed to add connection to I/O handler %d: %v", handler.id, err)
-					// If adding fails, close the connection to avoid resource leak
-					conn.Close()
-				}
+			if err := handler.AddConn(conn); err != nil {
+				log.Printf("Failed to add connection to I/O handler %d: %v", handler.id, err)
+				// If adding fails, close the connection to avoid resource leak
+				conn.Close()
 			}
-		}(i)
-	}
-
-	return nil
+		}
+	}()
 }
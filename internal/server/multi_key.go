@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var errCrossShard = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+
+// mergePolicy tells how the replies of the workers owning the keys are combined,
+// it comes from the response_policy tip of the command
+type mergePolicy int
+
+const (
+	// Each worker replies with an integer, the reply is the sum: DEL, EXISTS
+	mergeSum mergePolicy = iota
+	// Each worker replies with an array holding one element per key, the reply has them in the order of the keys: MGET.
+	// It is the default of multi-shard commands with keys in redis.
+	mergeKeyOrder
+	// Each worker replies with an array, the reply is the concatenation: KEYS.
+	// It is the default of commands without keys sent to all shards in redis.
+	mergeConcat
+	// Each worker replies with a value or nil, the reply is one of the values picked at random: RANDOMKEY
+	mergeRandom
+	// Each worker replies with its INFO, the counters of the shards are summed, see mergeInfo
+	mergeInfoFields
+)
+
+// specialPolicies are the merge policies of the commands with the response_policy:special tip
+var specialPolicies = map[string]mergePolicy{
+	constant.CMD_RANDOMKEY: mergeRandom,
+	constant.CMD_INFO:      mergeInfoFields,
+}
+
+func responsePolicy(spec *core.CommandSpec) mergePolicy {
+	switch {
+	case spec.HasTip("response_policy:agg_sum"):
+		return mergeSum
+	// SCAN is special too, but it is sent to one shard at a time, see dispatchScan
+	case spec.HasTip("response_policy:special"):
+		return specialPolicies[spec.Name]
+	case spec.FirstKey == 0:
+		return mergeConcat
+	}
+	return mergeKeyOrder
+}
+
+// dispatchMultiKey sends a command with several keys to every worker owning one of them.
+// Commands without the request_policy:multi_shard tip must see all their keys at once
+// (RENAME moves a value between two keys, MSET sets all its keys or none), they are rejected
+// when the keys are owned by different workers.
+// It returns false when all keys belong to a single worker and the task can be dispatched as is.
+func (s *Server) dispatchMultiKey(task *core.Task, spec *core.CommandSpec, keyIdx []int) bool {
+	args := task.Command.Args
+
+	// group key positions by owning worker, keeping the order in which workers are first seen,
+	// keyOrder tells which keys of the command each worker has
+	var workerIDs []int
+	groups := make(map[int][]int)
+	keyOrder := make(map[int][]int)
+	for n, i := range keyIdx {
+		workerID := s.getWorkerID(args[i])
+		if _, ok := groups[workerID]; !ok {
+			workerIDs = append(workerIDs, workerID)
+		}
+		groups[workerID] = append(groups[workerID], i)
+		keyOrder[workerID] = append(keyOrder[workerID], n)
+	}
+	if len(workerIDs) <= 1 {
+		return false
+	}
+
+	if !spec.HasTip("request_policy:multi_shard") {
+		task.Reply(core.Encode(errCrossShard, false))
+		return true
+	}
+	// a key without its values would be dropped by subArgs, the whole command is rejected like a worker would
+	if (len(args)-spec.FirstKey+1)%spec.Step != 0 {
+		task.Reply(core.Encode(fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(spec.Name)), false))
+		return true
+	}
+	policy := responsePolicy(spec)
+	positions := make([][]int, len(workerIDs))
+	for n, workerID := range workerIDs {
+		positions[n] = keyOrder[workerID]
+	}
+
+	subTasks := make([]*core.Task, len(workerIDs))
+	for n, workerID := range workerIDs {
+		subTasks[n] = &core.Task{Command: &core.Command{Cmd: task.Command.Cmd, Args: subArgs(args, groups[workerID], spec.Step)}}
+	}
+	s.fanOut(workerIDs, subTasks, func(replies [][]byte) {
+		task.Reply(mergeReplies(policy, replies, positions))
+	})
+	return true
+}
+
+// fanOut sends subTasks[n] to the worker workerIDs[n], done is called with their replies once they all replied
+func (s *Server) fanOut(workerIDs []int, subTasks []*core.Task, done func(replies [][]byte)) {
+	replies := make([][]byte, len(workerIDs))
+	var remaining atomic.Int32
+	remaining.Store(int32(len(workerIDs)))
+
+	for n, subTask := range subTasks {
+		subTask.ReplyChan = make(chan []byte, 1)
+		// The last worker to finish merges the partial replies, so nobody blocks waiting for them
+		subTask.Notify = func() {
+			replies[n] = <-subTask.ReplyChan
+			if remaining.Add(-1) == 0 {
+				done(replies)
+			}
+		}
+	}
+
+	// a snapshot sees all the sub-tasks or none of them
+	s.barrierMu.RLock()
+	defer s.barrierMu.RUnlock()
+	for n, workerID := range workerIDs {
+		s.sendToWorker(workerID, subTasks[n])
+	}
+}
+
+// subArgs keeps the arguments of the keys at keyIdx, each key followed by its step-1 values
+func subArgs(args []string, keyIdx []int, step int) []string {
+	res := make([]string, 0, len(keyIdx)*step)
+	for _, i := range keyIdx {
+		res = append(res, args[i:i+step]...)
+	}
+	return res
+}
+
+// mergeReplies builds the reply of the original command from the replies of each worker,
+// positions[n] are the indexes among the keys of the command of the keys sent to the n-th worker
+func mergeReplies(policy mergePolicy, replies [][]byte, positions [][]int) []byte {
+	// any worker failing makes the whole command fail
+	for _, reply := range replies {
+		if len(reply) > 0 && reply[0] == '-' {
+			return reply
+		}
+	}
+
+	switch policy {
+	case mergeSum:
+		var sum int64
+		for _, reply := range replies {
+			n, err := strconv.ParseInt(string(bytes.TrimSpace(reply[1:])), 10, 64)
+			if err != nil {
+				return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+			}
+			sum += n
+		}
+		return core.Encode(sum, false)
+	case mergeKeyOrder:
+		numKeys := 0
+		for _, pos := range positions {
+			numKeys += len(pos)
+		}
+		elements := make([][]byte, numKeys)
+		for n, reply := range replies {
+			values, ok := splitArray(reply)
+			if !ok || len(values) != len(positions[n]) {
+				return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+			}
+			for i, value := range values {
+				elements[positions[n][i]] = value
+			}
+		}
+		return append(fmt.Appendf(nil, "*%d\r\n", numKeys), bytes.Join(elements, nil)...)
+	case mergeConcat:
+		var elements [][]byte
+		for _, reply := range replies {
+			values, ok := splitArray(reply)
+			if !ok {
+				return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+			}
+			elements = append(elements, values...)
+		}
+		return append(fmt.Appendf(nil, "*%d\r\n", len(elements)), bytes.Join(elements, nil)...)
+	case mergeRandom:
+		var values [][]byte
+		for _, reply := range replies {
+			if !bytes.Equal(reply, constant.RespNil) {
+				values = append(values, reply)
+			}
+		}
+		if len(values) == 0 {
+			return constant.RespNil
+		}
+		return values[rand.Intn(len(values))]
+	case mergeInfoFields:
+		return mergeInfo(replies)
+	}
+
+	return core.Encode(errors.New("ERR unexpected reply from worker"), false)
+}
+
+// splitArray returns the encoded elements of an array reply without decoding them
+func splitArray(reply []byte) ([][]byte, bool) {
+	value, end, err := core.DecodeOne(reply)
+	array, ok := value.([]any)
+	if err != nil || !ok || end != len(reply) {
+		return nil, false
+	}
+
+	elements := make([][]byte, len(array))
+	pos := bytes.Index(reply, []byte("\r\n")) + 2
+	for i := range elements {
+		_, n, err := core.DecodeOne(reply[pos:])
+		if err != nil {
+			return nil, false
+		}
+		elements[i] = reply[pos : pos+n]
+		pos += n
+	}
+	return elements, true
+}
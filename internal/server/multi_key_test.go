@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, numWorker int) *Server {
+	s := &Server{
+		worker:    make([]*core.Worker, numWorker),
+		numWorker: numWorker,
+		users:     acl.New(),
+	}
+	for i := 0; i < numWorker; i++ {
+		s.worker[i] = core.NewWorker(i, numWorker, 16)
+	}
+	dir := t.TempDir()
+	s.persistence = newPersistence(s)
+	s.persistence.rdb.path = filepath.Join(dir, "dump.rdb")
+	s.persistence.aof.path = filepath.Join(dir, "appendonly.aof")
+	t.Cleanup(func() {
+		for _, w := range s.worker {
+			w.Stop()
+		}
+	})
+	return s
+}
+
+func execute(s *Server, args ...string) string {
+	return string(s.execute(&core.Command{Cmd: strings.ToUpper(args[0]), Args: args[1:]}))
+}
+
+// keysOnDifferentWorkers returns n keys each owned by a different worker
+func keysOnDifferentWorkers(s *Server, n int) []string {
+	var keys []string
+	seen := make(map[int]bool)
+	for i := 0; len(keys) < n; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if id := s.getWorkerID(key); !seen[id] {
+			seen[id] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestMultiKeySum(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 4)
+	for _, key := range keys {
+		assert.Equal(t, "+OK\r\n", execute(s, "SET", key, "v"))
+	}
+
+	assert.Equal(t, ":4\r\n", execute(s, "EXISTS", keys[0], keys[1], keys[2], keys[3]))
+	assert.Equal(t, ":5\r\n", execute(s, "EXISTS", keys[0], keys[1], keys[2], keys[3], keys[0]))
+	assert.Equal(t, ":3\r\n", execute(s, "DEL", keys[0], keys[2], keys[3], "missing"))
+	assert.Equal(t, ":1\r\n", execute(s, "EXISTS", keys[0], keys[1], keys[2], keys[3]))
+}
+
+func TestMultiKeySameShard(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 2)
+	execute(s, "SET", keys[0], "v")
+
+	assert.Equal(t, "-"+errCrossShard.Error()+"\r\n", execute(s, "RENAME", keys[0], keys[1]))
+	assert.Equal(t, "$1\r\nv\r\n", execute(s, "GET", keys[0]))
+}
+
+func TestMultiKeyOrder(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 3)
+
+	for i, value := range []string{"a", "b", "c"} {
+		execute(s, "SET", keys[i], value)
+	}
+	assert.Equal(t, "*5\r\n$1\r\nc\r\n$1\r\na\r\n$-1\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		execute(s, "MGET", keys[2], keys[0], "missing", keys[1], keys[2]))
+
+	// MSETNX must check all its keys at once
+	assert.Equal(t, "-"+errCrossShard.Error()+"\r\n", execute(s, "MSETNX", keys[0], "x", keys[1], "y"))
+	assert.Equal(t, ":1\r\n", execute(s, "MSETNX", "new", "x", "new", "y"))
+}
+
+func TestMSetAcrossWorkers(t *testing.T) {
+	s := newTestServer(t, 4)
+	keys := keysOnDifferentWorkers(s, 2)
+	execute(s, "SET", keys[0], "a")
+
+	// MSET sets all its keys or none, it is rejected before any worker sets a value
+	assert.Equal(t, "-"+errCrossShard.Error()+"\r\n", execute(s, "MSET", keys[0], "x", keys[1], "y"))
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$-1\r\n", execute(s, "MGET", keys[0], keys[1]))
+
+	assert.Equal(t, "+OK\r\n", execute(s, "MSET", keys[0], "x", keys[0], "y"))
+	assert.Equal(t, "$1\r\ny\r\n", execute(s, "GET", keys[0]))
+}
+
+func TestSplitArray(t *testing.T) {
+	elements, ok := splitArray([]byte("*3\r\n$1\r\na\r\n$-1\r\n*1\r\n:1\r\n"))
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("$1\r\na\r\n"), []byte("$-1\r\n"), []byte("*1\r\n:1\r\n")}, elements)
+
+	_, ok = splitArray([]byte("+OK\r\n"))
+	assert.False(t, ok)
+}
+
+func TestSubArgs(t *testing.T) {
+	args := []string{"k1", "v1", "k2", "v2", "k3", "v3"}
+	assert.Equal(t, []string{"k1", "v1", "k3", "v3"}, subArgs(args, []int{0, 4}, 2))
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// persistence groups the snapshots and the append only file of a keyspace
+type persistence struct {
+	rdb *rdbPersistence
+	aof *aofPersistence
+}
+
+func newPersistence(ks keyspace) *persistence {
+	return &persistence{
+		rdb: newRDBPersistence(ks),
+		aof: newAOFPersistence(ks),
+	}
+}
+
+// load fills the keyspace at startup, from the AOF when it is enabled and from the snapshot otherwise
+func (p *persistence) load() error {
+	if !config.AppendOnly {
+		return p.rdb.load()
+	}
+
+	loaded, err := p.aof.load()
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		if err := p.rdb.load(); err != nil {
+			return err
+		}
+	}
+	if err := p.aof.open(); err != nil {
+		return err
+	}
+	// a new AOF must contain the keys loaded from the snapshot
+	if !loaded {
+		return p.aof.bgrewrite()
+	}
+	return nil
+}
+
+func (p *persistence) cron() {
+	p.rdb.cron()
+}
+
+// saveOnShutdown is called while workers still run, close once they are stopped
+func (p *persistence) saveOnShutdown() {
+	p.rdb.saveOnShutdown()
+}
+
+func (p *persistence) close() {
+	p.aof.close()
+}
+
+// isServerCommand reports whether the server executes cmd itself instead of a RedisDB,
+// see core.CommandSpec.Handler. Commands with a wrong arity are left to ExecuteCommand for the error,
+// the Pub/Sub, transaction, AUTH and ACL commands are executed by the connection.
+func isServerCommand(cmd *core.Command) bool {
+	spec := core.LookupCommand(cmd.Cmd)
+	return spec != nil && spec.Handler == nil && !spec.HasFlag(core.FlagPubSub) && !transactionCommands[spec.Name] &&
+		!aclCommands[spec.Name] && spec.CheckArity(len(cmd.Args))
+}
+
+// executeServerCommand runs a command for which isServerCommand is true
+func executeServerCommand(ks keyspace, p *persistence, users *acl.ACL, cmd *core.Command) []byte {
+	if strings.ToUpper(cmd.Cmd) == constant.CMD_CONFIG {
+		return executeConfig(ks, users, cmd)
+	}
+	return p.execute(cmd)
+}
+
+// execute runs the persistence commands like SAVE
+func (p *persistence) execute(cmd *core.Command) []byte {
+	if strings.ToUpper(cmd.Cmd) == constant.CMD_BGREWRITEAOF {
+		return p.aof.execute(cmd)
+	}
+	return p.rdb.execute(cmd)
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+// subscriptionKind is one of the namespaces of Pub/Sub: SPUBLISH only reaches SSUBSCRIBE,
+// PUBLISH reaches SUBSCRIBE and the PSUBSCRIBE patterns matching the channel
+type subscriptionKind int
+
+const (
+	channelKind subscriptionKind = iota
+	patternKind
+	shardKind
+	numKinds
+)
+
+// kindReplies are the names of the confirmations sent for each kind
+var kindReplies = [numKinds]struct {
+	subscribe   string
+	unsubscribe string
+}{
+	{"subscribe", "unsubscribe"},
+	{"psubscribe", "punsubscribe"},
+	{"ssubscribe", "sunsubscribe"},
+}
+
+// subscribers maps a channel or a pattern to the clients subscribed to it
+type subscribers map[string]map[*client]struct{}
+
+func (s subscribers) add(name string, c *client) {
+	clients, ok := s[name]
+	if !ok {
+		clients = make(map[*client]struct{})
+		s[name] = clients
+	}
+	clients[c] = struct{}{}
+}
+
+func (s subscribers) remove(name string, c *client) {
+	delete(s[name], c)
+	if len(s[name]) == 0 {
+		delete(s, name)
+	}
+}
+
+// pubsub holds the subscriptions of the clients of every I/O handler.
+// The commands are executed by the event loop of the client that sent them, a message is pushed
+// to the inbox of each subscriber and its event loop is woken up to write it, see client.push.
+type pubsub struct {
+	// ks tells which shard a shard channel belongs to
+	ks keyspace
+
+	mu   sync.RWMutex
+	subs [numKinds]subscribers
+}
+
+func newPubSub(ks keyspace) *pubsub {
+	ps := &pubsub{ks: ks}
+	for kind := range ps.subs {
+		ps.subs[kind] = make(subscribers)
+	}
+	return ps
+}
+
+// execute runs a command flagged pubsub for c, from the event loop of c
+func (ps *pubsub) execute(c *client, name string, args []string) []byte {
+	switch name {
+	case constant.CMD_SUBSCRIBE:
+		return ps.subscribe(c, channelKind, args)
+	case constant.CMD_PSUBSCRIBE:
+		return ps.subscribe(c, patternKind, args)
+	case constant.CMD_SSUBSCRIBE:
+		if !ps.sameShard(args) {
+			return core.Encode(errCrossShard, false)
+		}
+		return ps.subscribe(c, shardKind, args)
+	case constant.CMD_UNSUBSCRIBE:
+		return ps.unsubscribe(c, channelKind, args)
+	case constant.CMD_PUNSUBSCRIBE:
+		return ps.unsubscribe(c, patternKind, args)
+	case constant.CMD_SUNSUBSCRIBE:
+		return ps.unsubscribe(c, shardKind, args)
+	case constant.CMD_PUBLISH:
+		return core.Encode(ps.publish(channelKind, args[0], args[1]), false)
+	case constant.CMD_SPUBLISH:
+		return core.Encode(ps.publish(shardKind, args[0], args[1]), false)
+	case constant.CMD_PUBSUB:
+		return ps.introspect(args)
+	}
+	return core.Encode(fmt.Errorf("ERR unknown command '%s'", name), false)
+}
+
+// sameShard reports whether the shard channels belong to the same shard, like the keys of a command
+func (ps *pubsub) sameShard(channels []string) bool {
+	for _, channel := range channels[1:] {
+		if ps.ks.shardOf(channel) != ps.ks.shardOf(channels[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// subscribe replies a confirmation for each name with the number of subscriptions of c.
+// The messages published before are taken from the inbox while holding the lock,
+// so they are written before the confirmation and the ones published after it.
+func (ps *pubsub) subscribe(c *client, kind subscriptionKind, names []string) []byte {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	c.takeMessages()
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		if _, ok := c.subs[kind][name]; !ok {
+			c.subs[kind][name] = struct{}{}
+			ps.subs[kind].add(name, c)
+		}
+		buf.Write(core.Encode([]any{kindReplies[kind].subscribe, name, c.subscriptionCount(kind)}, false))
+	}
+	return buf.Bytes()
+}
+
+// unsubscribe removes the subscriptions of c to names, or all of them when names is empty.
+// Like subscribe, no message of a removed subscription is written after its confirmation.
+func (ps *pubsub) unsubscribe(c *client, kind subscriptionKind, names []string) []byte {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	c.takeMessages()
+
+	if len(names) == 0 {
+		for name := range c.subs[kind] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return core.Encode([]any{kindReplies[kind].unsubscribe, nil, c.subscriptionCount(kind)}, false)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		if _, ok := c.subs[kind][name]; ok {
+			delete(c.subs[kind], name)
+			ps.subs[kind].remove(name, c)
+		}
+		buf.Write(core.Encode([]any{kindReplies[kind].unsubscribe, name, c.subscriptionCount(kind)}, false))
+	}
+	return buf.Bytes()
+}
+
+// removeClient drops every subscription of a closed connection
+func (ps *pubsub) removeClient(c *client) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for kind := range ps.subs {
+		for name := range c.subs[kind] {
+			ps.subs[kind].remove(name, c)
+		}
+		clear(c.subs[kind])
+	}
+}
+
+// publish pushes the message to the subscribers and returns the number of clients that received it,
+// a client receives it once per matching pattern like redis
+func (ps *pubsub) publish(kind subscriptionKind, channel string, message string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	received := 0
+	if clients := ps.subs[kind][channel]; len(clients) > 0 {
+		name := "message"
+		if kind == shardKind {
+			name = "smessage"
+		}
+		msg := core.Encode([]any{name, channel, message}, false)
+		for c := range clients {
+			c.push(msg)
+			received++
+		}
+	}
+	if kind == shardKind {
+		return received
+	}
+	for pattern, clients := range ps.subs[patternKind] {
+		if !core.GlobMatch(pattern, channel) {
+			continue
+		}
+		msg := core.Encode([]any{"pmessage", pattern, channel, message}, false)
+		for c := range clients {
+			c.push(msg)
+			received++
+		}
+	}
+	return received
+}
+
+// PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT | SHARDCHANNELS [pattern] | SHARDNUMSUB [channel ...]
+func (ps *pubsub) introspect(args []string) []byte {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	sub := strings.ToUpper(args[0])
+	switch sub {
+	case "CHANNELS", "SHARDCHANNELS":
+		if len(args) > 2 {
+			break
+		}
+		kind := channelKind
+		if sub == "SHARDCHANNELS" {
+			kind = shardKind
+		}
+		channels := make([]string, 0)
+		for channel := range ps.subs[kind] {
+			if len(args) == 1 || core.GlobMatch(args[1], channel) {
+				channels = append(channels, channel)
+			}
+		}
+		slices.Sort(channels)
+		return core.Encode(channels, false)
+	case "NUMSUB", "SHARDNUMSUB":
+		kind := channelKind
+		if sub == "SHARDNUMSUB" {
+			kind = shardKind
+		}
+		res := make([]any, 0, 2*(len(args)-1))
+		for _, channel := range args[1:] {
+			res = append(res, channel, len(ps.subs[kind][channel]))
+		}
+		return core.Encode(res, false)
+	case "NUMPAT":
+		if len(args) > 1 {
+			break
+		}
+		return core.Encode(len(ps.subs[patternKind]), false)
+	default:
+		return core.Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try PUBSUB HELP.", args[0]), false)
+	}
+	return core.Encode(fmt.Errorf("ERR wrong number of arguments for 'pubsub|%s' command", strings.ToLower(sub)), false)
+}
+
+// subscribedCommands are the commands a client may send while it has subscriptions, like redis with RESP2
+var subscribedCommands = map[string]bool{
+	constant.CMD_SUBSCRIBE: true, constant.CMD_PSUBSCRIBE: true, constant.CMD_SSUBSCRIBE: true,
+	constant.CMD_UNSUBSCRIBE: true, constant.CMD_PUNSUBSCRIBE: true, constant.CMD_SUNSUBSCRIBE: true,
+	constant.CMD_PING: true, "QUIT": true, "RESET": true,
+}
+
+var errSubscribedContext = errors.New("only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context")
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSubscriber returns a client of ps and a function returning what it would write to its socket
+func newTestSubscriber(ps *pubsub) (*client, func() string) {
+	c := newClient(-1, nil, ps.ks, ps, acl.New())
+	c.notify = func() {}
+	return c, func() string {
+		c.collectReplies()
+		out := string(c.writeBuf)
+		c.writeBuf = nil
+		return out
+	}
+}
+
+// run dispatches the commands of c, the ones that are not Pub/Sub reply "+worker"
+func run(c *client, args ...[]string) {
+	cmds := make([]*core.Command, len(args))
+	for i, arg := range args {
+		cmds[i] = &core.Command{Cmd: arg[0], Args: arg[1:]}
+	}
+	c.dispatch(cmds, func() {}, func(task *core.Task) {
+		task.Reply([]byte("+worker\r\n"))
+	})
+}
+
+func TestPubSubPublish(t *testing.T) {
+	ps := newPubSub(singleKeyspace{db: core.NewRedisDB()})
+	c1, out1 := newTestSubscriber(ps)
+	c2, out2 := newTestSubscriber(ps)
+	publisher, out := newTestSubscriber(ps)
+
+	run(c1, []string{"SUBSCRIBE", "news", "sport"})
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n*3\r\n$9\r\nsubscribe\r\n$5\r\nsport\r\n:2\r\n", out1())
+	run(c2, []string{"PSUBSCRIBE", "n*", "*s"}, []string{"SSUBSCRIBE", "news"})
+	assert.Equal(t, "*3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:1\r\n*3\r\n$10\r\npsubscribe\r\n$2\r\n*s\r\n:2\r\n"+
+		"*3\r\n$10\r\nssubscribe\r\n$4\r\nnews\r\n:1\r\n", out2())
+
+	// c2 gets the message once per matching pattern
+	run(publisher, []string{"PUBLISH", "news", "hello"})
+	assert.Equal(t, ":3\r\n", out())
+	assert.Equal(t, "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n", out1())
+	msgs := out2()
+	assert.Contains(t, msgs, "*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+	assert.Contains(t, msgs, "*4\r\n$8\r\npmessage\r\n$2\r\n*s\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+
+	// shard channels are another namespace
+	run(publisher, []string{"SPUBLISH", "news", "hi"}, []string{"SPUBLISH", "sport", "hi"})
+	assert.Equal(t, ":1\r\n:0\r\n", out())
+	assert.Equal(t, "", out1())
+	assert.Equal(t, "*3\r\n$8\r\nsmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n", out2())
+
+	run(publisher, []string{"PUBSUB", "CHANNELS"}, []string{"PUBSUB", "CHANNELS", "s*"}, []string{"PUBSUB", "NUMSUB", "news", "none"},
+		[]string{"PUBSUB", "NUMPAT"}, []string{"PUBSUB", "SHARDCHANNELS"}, []string{"PUBSUB", "SHARDNUMSUB", "news"})
+	assert.Equal(t, "*2\r\n$4\r\nnews\r\n$5\r\nsport\r\n*1\r\n$5\r\nsport\r\n*4\r\n$4\r\nnews\r\n:1\r\n$4\r\nnone\r\n:0\r\n"+
+		":2\r\n*1\r\n$4\r\nnews\r\n*2\r\n$4\r\nnews\r\n:1\r\n", out())
+
+	// a closed connection stops receiving
+	ps.removeClient(c2)
+	run(publisher, []string{"PUBLISH", "news", "bye"}, []string{"PUBSUB", "NUMPAT"})
+	assert.Equal(t, ":1\r\n:0\r\n", out())
+}
+
+func TestPubSubUnsubscribe(t *testing.T) {
+	ps := newPubSub(singleKeyspace{db: core.NewRedisDB()})
+	c, out := newTestSubscriber(ps)
+
+	run(c, []string{"SUBSCRIBE", "a", "b"}, []string{"UNSUBSCRIBE", "a", "nope"})
+	out()
+	run(c, []string{"UNSUBSCRIBE"})
+	assert.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:0\r\n", out())
+	run(c, []string{"PUNSUBSCRIBE"})
+	assert.Equal(t, "*3\r\n$12\r\npunsubscribe\r\n$-1\r\n:0\r\n", out())
+	assert.Equal(t, 0, ps.publish(channelKind, "b", "x"))
+	// back to normal mode
+	run(c, []string{"GET", "a"})
+	assert.Equal(t, "+worker\r\n", out())
+}
+
+func TestPubSubSubscribedContext(t *testing.T) {
+	ps := newPubSub(singleKeyspace{db: core.NewRedisDB()})
+	c, out := newTestSubscriber(ps)
+
+	run(c, []string{"GET", "a"}, []string{"SUBSCRIBE", "a"}, []string{"GET", "a"}, []string{"PING"}, []string{"PING", "hi"},
+		[]string{"NOPE"}, []string{"SSUBSCRIBE", "b"})
+	assert.Equal(t, "+worker\r\n*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n"+
+		"-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n"+
+		"*2\r\n$4\r\npong\r\n$0\r\n\r\n*2\r\n$4\r\npong\r\n$2\r\nhi\r\n+worker\r\n"+
+		"*3\r\n$10\r\nssubscribe\r\n$1\r\nb\r\n:1\r\n", out())
+}
+
+// a message is never written before the confirmation of its subscription or after the one of its unsubscription
+func TestPubSubConfirmationOrder(t *testing.T) {
+	ps := newPubSub(singleKeyspace{db: core.NewRedisDB()})
+	c, out := newTestSubscriber(ps)
+
+	res, ok := c.executePubSub(&core.Command{Cmd: "SUBSCRIBE", Args: []string{"a"}}, func() {})
+	assert.True(t, ok)
+	// published by another I/O handler before the event loop queued the confirmation
+	ps.publish(channelKind, "a", "first")
+	c.enqueueReply(res)
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n*3\r\n$7\r\nmessage\r\n$1\r\na\r\n$5\r\nfirst\r\n", out())
+
+	ps.publish(channelKind, "a", "last")
+	run(c, []string{"UNSUBSCRIBE", "a"})
+	assert.Equal(t, "*3\r\n$7\r\nmessage\r\n$1\r\na\r\n$4\r\nlast\r\n*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:0\r\n", out())
+}
+
+func TestPubSubShardChannelsCrossShard(t *testing.T) {
+	s := newTestServer(t, 4)
+	ps := newPubSub(s)
+	c, out := newTestSubscriber(ps)
+	keys := keysOnDifferentWorkers(s, 2)
+
+	run(c, []string{"SSUBSCRIBE", keys[0], keys[1]})
+	assert.Equal(t, "-"+errCrossShard.Error()+"\r\n", out())
+	run(c, []string{"SSUBSCRIBE", keys[0], keys[0]})
+	assert.Equal(t, 1, ps.publish(shardKind, keys[0], "x"))
+}
+
+// messages published on a connection of an I/O handler reach the subscribers of the others
+func TestPubSubAcrossIOHandlers(t *testing.T) {
+	defer func(mode string, port, workers, ioHandlers int, dir string) {
+		config.Mode, config.Port, config.Workers, config.IOHandlers, config.RDBDir = mode, port, workers, ioHandlers, dir
+	}(config.Mode, config.Port, config.Workers, config.IOHandlers, config.RDBDir)
+	config.Mode, config.Port, config.Workers, config.IOHandlers, config.RDBDir = "multi-worker", freePort(t), 2, 2, t.TempDir()
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(signals)
+	}()
+	defer func() {
+		signals <- os.Interrupt
+		<-done
+	}()
+
+	// connections are handed to the I/O handlers in turn
+	subscriber := dialServer(t)
+	defer subscriber.Close()
+	publisher := dialServer(t)
+	defer publisher.Close()
+	reader := bufio.NewReader(subscriber)
+	expectReply := func(expected string) {
+		assert.NoError(t, subscriber.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, len(expected))
+		_, err := io.ReadFull(reader, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(buf))
+	}
+
+	_, err := subscriber.Write([]byte("*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n"))
+	assert.NoError(t, err)
+	expectReply("*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n")
+
+	_, err = publisher.Write([]byte("*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$5\r\nhello\r\n"))
+	assert.NoError(t, err)
+	expectReply("*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+}
+
+func TestOutputBufferLimits(t *testing.T) {
+	defer config.OutputBufferLimits.Store(config.OutputBufferLimits.Load())
+	config.OutputBufferLimits.Store([2]config.OutputBufferLimit{
+		config.ClientNormal: {Hard: 100},
+		config.ClientPubSub: {Hard: 200, Soft: 50, SoftSeconds: 10},
+	})
+	ps := newPubSub(singleKeyspace{db: core.NewRedisDB()})
+	now := time.Now()
+
+	// a client pipelining without reading its replies
+	c, _ := newTestSubscriber(ps)
+	c.writeBuf = make([]byte, 99)
+	assert.NoError(t, c.checkOutputBuffer(now))
+	c.writeBuf = make([]byte, 100)
+	assert.ErrorIs(t, c.checkOutputBuffer(now), errOutputBufferLimit)
+
+	// a subscriber above the soft limit is closed once it stayed above for the soft seconds
+	subscriber, _ := newTestSubscriber(ps)
+	run(subscriber, []string{"SUBSCRIBE", "news"})
+	subscriber.collectReplies()
+	subscriber.writeBuf = make([]byte, 60)
+	assert.NoError(t, subscriber.checkOutputBuffer(now))
+	assert.NoError(t, subscriber.checkOutputBuffer(now.Add(9*time.Second)))
+	assert.ErrorIs(t, subscriber.checkOutputBuffer(now.Add(10*time.Second)), errOutputBufferLimit)
+	// going below resets it
+	subscriber.writeBuf = nil
+	assert.NoError(t, subscriber.checkOutputBuffer(now.Add(11*time.Second)))
+	subscriber.writeBuf = make([]byte, 60)
+	assert.NoError(t, subscriber.checkOutputBuffer(now.Add(12*time.Second)))
+
+	// the messages of a subscriber that does not keep up are dropped at the hard limit
+	subscriber.writeBuf = nil
+	for i := 0; i < 10; i++ {
+		ps.publish(channelKind, "news", strings.Repeat("m", 30))
+	}
+	assert.LessOrEqual(t, subscriber.inboxSize, int64(200))
+	assert.ErrorIs(t, subscriber.checkOutputBuffer(now), errOutputBufferLimit)
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/config"
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var errBgsaveInProgress = errors.New("ERR Background save already in progress")
+
+// After a failed automatic save, wait before trying again like redis does
+const saveRetryDelay = 5 * time.Second
+
+// rdbPersistence takes the snapshots of a keyspace: SAVE, BGSAVE, LASTSAVE and the save policy
+type rdbPersistence struct {
+	ks   keyspace
+	path string
+
+	// saveMu is held for a whole save so two saves never race on the dirty counters
+	saveMu sync.Mutex
+
+	mu               sync.Mutex
+	lastSave         time.Time
+	lastSaveAttempt  time.Time
+	lastSaveErr      error
+	bgsaveInProgress bool
+	// bgsaveScheduled is set by BGSAVE SCHEDULE during a background save, cron starts it afterwards
+	bgsaveScheduled bool
+}
+
+func newRDBPersistence(ks keyspace) *rdbPersistence {
+	now := time.Now()
+	return &rdbPersistence{
+		ks:              ks,
+		path:            filepath.Join(config.RDBDir, config.RDBFileName),
+		lastSave:        now,
+		lastSaveAttempt: now,
+	}
+}
+
+// snapshot is a point in time copy of the keyspace, ready to be written
+type snapshot struct {
+	shards [][]byte
+	dbs    []*core.RedisDB
+	dirty  []int64
+}
+
+// snapshot dumps every shard while the workers are paused, for SAVE and the save on shutdown
+func (p *rdbPersistence) snapshot() (*snapshot, error) {
+	snap := newSnapshot(p.ks.numShards())
+	errs := make([]error, len(snap.shards))
+	p.ks.atomically(func(shard int, db *core.RedisDB) {
+		snap.shards[shard], errs[shard] = db.DumpSnapshot(nil)
+		snap.dbs[shard] = db
+		snap.dirty[shard] = db.Dirty()
+	})
+
+	return snap, errors.Join(errs...)
+}
+
+// bgsnapshot takes the snapshot of BGSAVE. The workers are only paused to begin it on every shard
+// at the same point in time, then the shards are dumped a chunk at a time between the commands
+// and done is called with the snapshot once they all are.
+func (p *rdbPersistence) bgsnapshot(done func(snap *snapshot, err error)) {
+	snap := newSnapshot(p.ks.numShards())
+	dumps := make([]*core.Snapshot, len(snap.shards))
+	start := time.Now()
+	p.ks.atomically(func(shard int, db *core.RedisDB) {
+		dumps[shard] = db.BeginSnapshot()
+		snap.dbs[shard] = db
+		snap.dirty[shard] = db.Dirty()
+	})
+	log.Printf("Background saving started, the keyspace was paused for %v", time.Since(start))
+
+	var mu sync.Mutex
+	remaining := len(dumps)
+	chunks := make([][][]byte, len(dumps))
+	errs := make([]error, len(dumps))
+	for shard, dump := range dumps {
+		p.ks.background(shard, func(*core.RedisDB) bool {
+			return dump.DumpChunk()
+		}, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			chunks[shard], errs[shard] = dump.Entries()
+			if remaining--; remaining == 0 {
+				// the file holds the entries of every shard in any order
+				snap.shards = slices.Concat(chunks...)
+				done(snap, errors.Join(errs...))
+			}
+		})
+	}
+}
+
+func newSnapshot(shards int) *snapshot {
+	return &snapshot{
+		shards: make([][]byte, shards),
+		dbs:    make([]*core.RedisDB, shards),
+		dirty:  make([]int64, shards),
+	}
+}
+
+func (p *rdbPersistence) write(snap *snapshot) error {
+	start := time.Now()
+	err := core.SaveSnapshotFile(p.path, snap.shards...)
+
+	p.mu.Lock()
+	p.lastSaveAttempt = time.Now()
+	p.lastSaveErr = err
+	if err == nil {
+		p.lastSave = p.lastSaveAttempt
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Failed to save the snapshot: %v", err)
+		return err
+	}
+	// the dirty counters are atomic, so they are cleared without going through the workers
+	for i, db := range snap.dbs {
+		db.ClearDirty(snap.dirty[i])
+	}
+	log.Printf("DB saved on disk in %v", time.Since(start))
+	return nil
+}
+
+// save takes a snapshot and writes it before returning
+func (p *rdbPersistence) save() error {
+	p.mu.Lock()
+	inProgress := p.bgsaveInProgress
+	p.mu.Unlock()
+	if inProgress {
+		return errBgsaveInProgress
+	}
+
+	p.saveMu.Lock()
+	defer p.saveMu.Unlock()
+	snap, err := p.snapshot()
+	if err != nil {
+		return err
+	}
+	return p.write(snap)
+}
+
+// bgsave takes a snapshot and writes it in the background,
+// commands keep being served while the keys are dumped and the file is written
+func (p *rdbPersistence) bgsave() error {
+	p.mu.Lock()
+	if p.bgsaveInProgress {
+		p.mu.Unlock()
+		return errBgsaveInProgress
+	}
+	p.bgsaveInProgress = true
+	p.mu.Unlock()
+
+	p.saveMu.Lock()
+	p.bgsnapshot(func(snap *snapshot, err error) {
+		// the single-threaded server calls it from its event loop, the file is written elsewhere
+		go func() {
+			defer p.saveMu.Unlock()
+			if err != nil {
+				p.mu.Lock()
+				p.lastSaveAttempt = time.Now()
+				p.lastSaveErr = err
+				p.mu.Unlock()
+				log.Printf("Failed to save the snapshot: %v", err)
+			} else {
+				p.write(snap)
+			}
+			p.mu.Lock()
+			p.bgsaveInProgress = false
+			p.mu.Unlock()
+		}()
+	})
+	return nil
+}
+
+// cron starts a background save when a save param is reached, it is called about every second
+func (p *rdbPersistence) cron() {
+	p.mu.Lock()
+	skip := p.bgsaveInProgress ||
+		(p.lastSaveErr != nil && time.Since(p.lastSaveAttempt) < saveRetryDelay)
+	scheduled := p.bgsaveScheduled && !p.bgsaveInProgress
+	if scheduled {
+		p.bgsaveScheduled = false
+	}
+	sinceLastSave := time.Since(p.lastSave)
+	p.mu.Unlock()
+
+	if scheduled {
+		log.Println("Starting the scheduled background save")
+		if err := p.bgsave(); err != nil {
+			log.Printf("Background saving error: %v", err)
+		}
+		return
+	}
+	if skip {
+		return
+	}
+
+	dirty := p.ks.dirty()
+	for _, param := range config.SaveParams.Load() {
+		if dirty >= param.Changes && sinceLastSave >= time.Duration(param.Seconds)*time.Second {
+			log.Printf("%d changes in %d seconds. Saving...", param.Changes, param.Seconds)
+			if err := p.bgsave(); err != nil && !errors.Is(err, errBgsaveInProgress) {
+				log.Printf("Background saving error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// saveOnShutdown writes a last snapshot when a save policy is configured,
+// waiting for a running background save instead of failing
+func (p *rdbPersistence) saveOnShutdown() {
+	if len(config.SaveParams.Load()) == 0 || p.ks.dirty() == 0 {
+		return
+	}
+
+	p.saveMu.Lock()
+	defer p.saveMu.Unlock()
+	snap, err := p.snapshot()
+	if err == nil {
+		err = p.write(snap)
+	}
+	if err != nil {
+		log.Printf("Error trying to save the DB on shutdown: %v", err)
+	}
+}
+
+// load puts the keys of the snapshot file in their shards, a missing file means an empty keyspace
+func (p *rdbPersistence) load() error {
+	start := time.Now()
+	shards := make([][]func(db *core.RedisDB), p.ks.numShards())
+	numKeys := 0
+	err := core.LoadSnapshotFile(p.path, func(key string, obj *core.RedisObj, expireAtMs uint64) {
+		shard := p.ks.shardOf(key)
+		shards[shard] = append(shards[shard], func(db *core.RedisDB) {
+			db.Restore(key, obj, expireAtMs)
+		})
+		numKeys++
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", p.path, err)
+	}
+
+	p.ks.atomically(func(shard int, db *core.RedisDB) {
+		for _, restore := range shards[shard] {
+			restore(db)
+		}
+	})
+	log.Printf("DB loaded from disk: %d keys in %v", numKeys, time.Since(start))
+	return nil
+}
+
+// execute runs SAVE, BGSAVE and LASTSAVE, the arity is already checked
+func (p *rdbPersistence) execute(cmd *core.Command) []byte {
+	switch strings.ToUpper(cmd.Cmd) {
+	case constant.CMD_SAVE:
+		if err := p.save(); err != nil {
+			return core.Encode(toRespError(err), false)
+		}
+		return constant.RespOk
+	case constant.CMD_BGSAVE:
+		if len(cmd.Args) > 1 || (len(cmd.Args) == 1 && strings.ToUpper(cmd.Args[0]) != "SCHEDULE") {
+			return core.Encode(errors.New("ERR syntax error"), false)
+		}
+		err := p.bgsave()
+		if errors.Is(err, errBgsaveInProgress) && len(cmd.Args) == 1 {
+			p.mu.Lock()
+			p.bgsaveScheduled = true
+			p.mu.Unlock()
+			return core.Encode("Background saving scheduled", true)
+		}
+		if err != nil {
+			return core.Encode(toRespError(err), false)
+		}
+		return core.Encode("Background saving started", true)
+	default: // LASTSAVE
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return core.Encode(p.lastSave.Unix(), false)
+	}
+}
+
+// toRespError keeps errors already in the redis format and prefixes the others with ERR
+func toRespError(err error) error {
+	if errors.Is(err, errBgsaveInProgress) || errors.Is(err, errRewriteInProgress) || errors.Is(err, errAppendOnlyOff) {
+		return err
+	}
+	return fmt.Errorf("ERR %w", err)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	s := newTestServer(t, 4)
+	for i := 0; i < 20; i++ {
+		execute(s, "SET", fmt.Sprintf("key:%d", i), fmt.Sprint(i))
+	}
+	execute(s, "SADD", "set", "a", "b")
+	assert.EqualValues(t, 21, s.dirty())
+
+	assert.Equal(t, "+OK\r\n", execute(s, "SAVE"))
+	assert.EqualValues(t, 0, s.dirty())
+	assert.Equal(t, fmt.Sprintf(":%d\r\n", time.Now().Unix()), execute(s, "LASTSAVE"))
+
+	// the keys are spread again when the number of workers changes
+	loaded := newTestServer(t, 3)
+	loaded.persistence.rdb.path = s.persistence.rdb.path
+	assert.NoError(t, loaded.persistence.rdb.load())
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, fmt.Sprintf("$%d\r\n%d\r\n", len(fmt.Sprint(i)), i), execute(loaded, "GET", fmt.Sprintf("key:%d", i)))
+	}
+	assert.Equal(t, ":1\r\n", execute(loaded, "SISMEMBER", "set", "b"))
+	assert.EqualValues(t, 0, loaded.dirty())
+}
+
+func TestBgsave(t *testing.T) {
+	s := newTestServer(t, 2)
+	execute(s, "SET", "k", "v")
+
+	assert.Equal(t, "+Background saving started\r\n", execute(s, "BGSAVE"))
+	assert.Eventually(t, func() bool {
+		return s.dirty() == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "-ERR syntax error\r\n", execute(s, "BGSAVE", "NOW"))
+
+	loaded := newTestServer(t, 2)
+	loaded.persistence.rdb.path = s.persistence.rdb.path
+	assert.NoError(t, loaded.persistence.rdb.load())
+	assert.Equal(t, "$1\r\nv\r\n", execute(loaded, "GET", "k"))
+}
+
+func TestLoadMissingSnapshot(t *testing.T) {
+	s := newTestServer(t, 2)
+	assert.NoError(t, s.persistence.rdb.load())
+	assert.Equal(t, "-ERR wrong number of arguments for 'save' command\r\n", execute(s, "SAVE", "extra"))
+}
@@ -7,12 +7,12 @@ import (
 	"log"
 	"math/rand"
 	"net"
-	"os"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/nhtuan0700/godis/internal/acl"
+	"github.com/nhtuan0700/godis/internal/config"
 	"github.com/nhtuan0700/godis/internal/constant"
 	"github.com/nhtuan0700/godis/internal/core"
 )
@@ -35,24 +35,47 @@ type Server struct {
 	draining atomic.Bool
 
 	listenerMu sync.Mutex
+
+	// barrierMu orders the barrier of atomically with the dispatch of multi-key commands
+	barrierMu   sync.RWMutex
+	persistence *persistence
+	pubsub      *pubsub
+	users       *acl.ACL
+	stopCron    chan struct{}
 }
 
 func NewServer() (*Server, error) {
-	numCore := runtime.NumCPU()
-	numIOHandler := numCore / 2
-	numWorker := numCore / 2
+	numIOHandler := config.IOHandlers
+	numWorker := config.Workers
+
+	users, err := newACL()
+	if err != nil {
+		return nil, err
+	}
 
 	log.Printf("Initialize server with %d IO Handlers and %d Workers \n", numIOHandler, numWorker)
 	server := &Server{
+		users:        users,
 		worker:       make([]*core.Worker, numWorker),
 		ioHandlers:   make([]*IOHandler, numIOHandler),
 		numWorker:    numWorker,
 		numIOHandler: numIOHandler,
+		stopCron:     make(chan struct{}),
 	}
 
 	for i := 0; i < numWorker; i++ {
-		server.worker[i] = core.NewWorker(i, 1024)
+		server.worker[i] = core.NewWorker(i, numWorker, 1024)
+	}
+
+	server.persistence = newPersistence(server)
+	server.pubsub = newPubSub(server)
+	if err := server.persistence.load(); err != nil {
+		for _, worker := range server.worker {
+			worker.Stop()
+		}
+		return nil, err
 	}
+	go server.cron()
 
 	for i := 0; i < numIOHandler; i++ {
 		ioHandler, err := NewIOHandler(i, server)
@@ -66,6 +89,21 @@ func NewServer() (*Server, error) {
 	return server, nil
 }
 
+// cron runs the periodic server jobs, like the save policy
+func (s *Server) cron() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCron:
+			return
+		case <-ticker.C:
+			s.persistence.cron()
+		}
+	}
+}
+
 func (s *Server) isDraining() bool {
 	return s.draining.Load()
 }
@@ -112,18 +150,72 @@ func (s *Server) dispatch(task *core.Task) {
 		close(task.ReplyChan)
 		return
 	}
+	// the transactions of a client run on the worker owning their keys, see client.executeTransaction
+	if task.Fn != nil {
+		s.sendToWorker(s.getWorkerID(task.Key), task)
+		return
+	}
 
-	// For commands like PING etc., dont have a key
-	// We can send them to any worker
-	// TODO for commands with keys, we will
-	var workerID int
-	if len(task.Command.Args) > 0 {
-		key := task.Command.Args[0]
-		workerID = s.getWorkerID(key)
-	} else {
-		workerID = rand.Intn(s.numWorker)
+	// Commands without keys like PING can be executed by any worker,
+	// unknown commands or wrong arities too since the worker only replies with an error
+	spec := core.LookupCommand(task.Command.Cmd)
+	var keyIdx []int
+	if spec != nil && spec.CheckArity(len(task.Command.Args)) {
+		keyIdx = spec.KeyIndexes(task.Command.Args)
+	}
+	// SAVE and alike wait for every worker, they must not block the IO handler
+	if isServerCommand(task.Command) {
+		go func() {
+			task.Reply(executeServerCommand(s, s.persistence, s.users, task.Command))
+		}()
+		return
+	}
+	// KEYS and alike need every shard
+	if spec != nil && spec.CheckArity(len(task.Command.Args)) {
+		switch {
+		case spec.HasTip("request_policy:all_shards"):
+			s.dispatchAllShards(task, spec)
+			return
+		case spec.Name == constant.CMD_SCAN:
+			s.dispatchScan(task)
+			return
+		}
+	}
+	if len(keyIdx) == 0 {
+		s.sendToWorker(rand.Intn(s.numWorker), task)
+		return
+	}
+
+	// Keys of a multi-key command may be owned by several workers
+	if len(keyIdx) > 1 && gatherCommands[spec.Name] && s.dispatchGather(task, keyIdx) {
+		return
+	}
+	if len(keyIdx) > 1 && s.dispatchMultiKey(task, spec, keyIdx) {
+		return
 	}
 
+	workerID := s.getWorkerID(task.Command.Args[keyIdx[0]])
+	s.sendToWorker(workerID, task)
+}
+
+// unblock drops the task of a blocking command whose client is gone, if it is still parked.
+// The worker owning the keys receives it after the task, so it is parked by then or already served.
+func (s *Server) unblock(task *core.Task) {
+	spec := core.LookupCommand(task.Command.Cmd)
+	keys := spec.Keys(task.Command.Args)
+	if s.isDraining() || len(keys) == 0 {
+		return
+	}
+	s.sendToWorker(s.getWorkerID(keys[0]), &core.Task{
+		ReplyChan: make(chan []byte, 1),
+		Fn: func(redisDB *core.RedisDB) []byte {
+			redisDB.UnblockTask(task)
+			return nil
+		},
+	})
+}
+
+func (s *Server) sendToWorker(workerID int, task *core.Task) {
 	s.worker[workerID].TaskChan <- task
 }
 
@@ -132,6 +224,7 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		log.Println("Shutting down server")
 		s.draining.Store(true)
 		s.closeListeners()
+		close(s.stopCron)
 
 		for _, handler := range s.ioHandlers {
 			handler.CloseMultiplexer()
@@ -140,9 +233,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		done := make(chan struct{})
 		go func() {
 			s.wg.Wait()
+			s.persistence.saveOnShutdown()
 			for _, worker := range s.worker {
 				worker.Stop()
 			}
+			s.persistence.close()
 			for _, handler := range s.ioHandlers {
 				handler.CloseConnections()
 			}
@@ -161,16 +256,3 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 	return ctx.Err()
 }
-
-func (s *Server) WaitForSignal(signals chan os.Signal) {
-	// Wait for signal in channel, it not available then wait
-	<-signals
-
-	log.Println("Shutting down gracefully...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := s.Shutdown(ctx); err != nil {
-		log.Printf("Shutdown finished with error: %v", err)
-	}
-}
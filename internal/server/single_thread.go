@@ -1,54 +1,57 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log"
 	"net"
-	"sync"
+	"runtime"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/nhtuan0700/godis/internal/acl"
 	"github.com/nhtuan0700/godis/internal/config"
 	"github.com/nhtuan0700/godis/internal/constant"
 	"github.com/nhtuan0700/godis/internal/core"
 	"github.com/nhtuan0700/godis/internal/core/io_multiplexer"
 )
 
-var redisDB = core.NewRedisDB()
+// singleThreadServer runs every command in the event loop accepting the connections, like redis
+type singleThreadServer struct {
+	db          *core.RedisDB
+	ks          singleKeyspace
+	persistence *persistence
+	pubsub      *pubsub
+	users       *acl.ACL
+}
 
-func readCommand(fd int) (*core.Command, error) {
-	var buf = make([]byte, 512)
-	n, err := syscall.Read(fd, buf)
-	if err != nil {
-		return nil, err
-	}
-	if n == 0 {
-		return nil, io.EOF
-	}
-	// log.Println("command: ", string(buf[:n]))
-	return core.ParseCommand(buf)
+func newSingleThreadServer() *singleThreadServer {
+	db := core.NewRedisDB()
+	ks := singleKeyspace{db: db, jobs: &backgroundJobs{}}
+	return &singleThreadServer{db: db, ks: ks, persistence: newPersistence(ks), pubsub: newPubSub(ks)}
 }
 
-func respond(res []byte, fd int) error {
-	_, err := syscall.Write(fd, res)
+// run loads the keyspace and runs the event loop until Shutdown
+func (st *singleThreadServer) run() error {
+	users, err := newACL()
 	if err != nil {
 		return err
 	}
-
-	return nil
-}
-
-func RunIOMultiplexingServer(wg *sync.WaitGroup) error {
-	defer wg.Done()
+	st.users = users
+	// the status is left to shutting down by a previous run in the same process
+	atomic.StoreInt32(&serverStatus, constant.ServerStatusIdle)
+	if err := st.persistence.load(); err != nil {
+		return err
+	}
 	// 1. Create listener FD
-	listener, err := net.Listen(config.Protocol, config.Address)
+	listener, err := net.Listen(config.Protocol, config.Address())
 	if err != nil {
 		return err
 	}
 	defer listener.Close()
-	log.Println("Starting an I/O Multiplexing TCP server on ", config.Address)
+	log.Println("Starting an I/O Multiplexing TCP server on ", config.Address())
 
 	// Get file descriptor of listener
 	tcpListener, ok := listener.(*net.TCPListener)
@@ -78,26 +81,86 @@ func RunIOMultiplexingServer(wg *sync.WaitGroup) error {
 		return err
 	}
 
+	clients := make(map[int]*client)
+	// ready holds the clients with replies to write, a task parked by a blocking command
+	// replies while the command of another client is executed
+	ready := make(map[int]*client)
+	exec := func(task *core.Task) {
+		if task.Fn != nil {
+			task.Reply(task.Fn(st.db))
+			return
+		}
+		if isServerCommand(task.Command) {
+			task.Reply(executeServerCommand(st.ks, st.persistence, st.users, task.Command))
+			return
+		}
+		core.ExecuteTask(st.db, task)
+	}
+	closeClient := func(c *client, err error) {
+		if err == io.EOF || err == syscall.ECONNRESET {
+			log.Println("client disconnected")
+		} else {
+			log.Println("client err: ", err)
+		}
+		if c.blocking != nil {
+			st.db.UnblockTask(c.blocking)
+		}
+		st.pubsub.removeClient(c)
+		if task := c.unwatchTask(); task != nil {
+			exec(task)
+		}
+		delete(clients, c.fd)
+		delete(ready, c.fd)
+		_ = ioMultiplexer.Unmonitor(c.fd)
+		_ = syscall.Close(c.fd)
+	}
+	notifier := func(c *client) func() {
+		return func() { ready[c.fd] = c }
+	}
+	// serve writes the collected replies and runs the commands held by a blocking command that replied
+	serve := func(c *client) error {
+		c.collectReplies()
+		if c.resume(notifier(c), exec) {
+			c.collectReplies()
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+		if err := c.checkOutputBuffer(time.Now()); err != nil {
+			return err
+		}
+		return c.updateWriteInterest(ioMultiplexer)
+	}
+
 	var lastActiveExpireExecTime = time.Now()
 	// 3. Monitor all the FDs in the monitoring list
 	// events := make([]io_multiplexer.Event, config.MaxConnections)
 	for atomic.LoadInt32(&serverStatus) != constant.ServerStatusShuttingDown {
 		// Check last execution time and call it if it is more than 100ms ago
-		if time.Now().After(lastActiveExpireExecTime.Add(constant.ActiveExpireFrequency)) {
+		if time.Now().After(lastActiveExpireExecTime.Add(config.ActiveExpireFrequency())) {
 			// Idle
 			if !atomic.CompareAndSwapInt32(&serverStatus, constant.ServerStatusIdle, constant.ServerStatusBusy) {
 				if serverStatus == constant.ServerStatusShuttingDown {
 					return nil
 				}
 			}
-			core.ActiveDeleteExpiredKeys(redisDB) // Busy
+			core.ActiveDeleteExpiredKeys(st.db) // Busy
+			st.persistence.cron()
 			atomic.SwapInt32(&serverStatus, constant.ServerStatusIdle)
 			lastActiveExpireExecTime = time.Now()
 		}
 		// wait for file descriptor in the monitoring list to be ready for I/O
-		// it is a blocking call
+		// it is a blocking call, until the next periodic jobs or the next timeout of a blocked client at most,
+		// an idle server still runs the save policy
 		// Idle
-		events, err := ioMultiplexer.Wait()
+		timeout := max(time.Until(lastActiveExpireExecTime.Add(config.ActiveExpireFrequency())), 0)
+		if st.ks.jobs.pending() {
+			timeout = 0
+		}
+		if deadline, ok := st.db.NextBlockDeadline(); ok {
+			timeout = min(timeout, max(time.Until(deadline), 0))
+		}
+		events, err := ioMultiplexer.WaitTimeout(timeout)
 		if err != nil {
 			continue
 		}
@@ -109,16 +172,29 @@ func RunIOMultiplexingServer(wg *sync.WaitGroup) error {
 			}
 		}
 		// Busy
+		st.db.TimeoutBlockedTasks(time.Now())
 		for i := 0; i < len(events); i++ {
 			if events[i].Fd == listenerFD {
 				log.Println("new client is trying to connect")
 				// setup new connection
-				connFd, _, err := syscall.Accept(events[i].Fd)
+				connFd, sa, err := syscall.Accept(events[i].Fd)
 				if err != nil {
 					log.Println("err", err)
 					continue
 				}
+				addr := sockaddrToTCPAddr(sa)
+				if refusedByProtectedMode(st.users, addr) {
+					_, _ = syscall.Write(connFd, respProtectedMode)
+					_ = syscall.Close(connFd)
+					continue
+				}
 				log.Println("setup a new connection")
+				// replies are flushed without blocking the loop, the rest waits for a write event
+				if err := syscall.SetNonblock(connFd, true); err != nil {
+					log.Println("err", err)
+					_ = syscall.Close(connFd)
+					continue
+				}
 				// ask epoll to monitor this connection
 				if err := ioMultiplexer.Monitor(io_multiplexer.Event{
 					Fd: connFd,
@@ -126,27 +202,57 @@ func RunIOMultiplexingServer(wg *sync.WaitGroup) error {
 				}); err != nil {
 					return err
 				}
+				clients[connFd] = newClient(connFd, nil, st.ks, st.pubsub, st.users)
+				if addr != nil {
+					clients[connFd].addr = addr.String()
+				}
 			} else {
-				cmd, err := readCommand(events[i].Fd)
-				if err != nil {
-					if err == io.EOF || err == syscall.ECONNRESET {
-						log.Println("client disconnected")
-						_ = syscall.Close(events[i].Fd)
-						continue
-					}
-					log.Println("read err: ", err)
+				c, ok := clients[events[i].Fd]
+				if !ok {
 					continue
 				}
 
-				res := core.ExecuteCommand(redisDB, cmd)
-				if err := respond(res, events[i].Fd); err != nil {
-					log.Println("write err: ", err)
+				// replies are written once every event is handled, with the ones of the clients served meanwhile
+				ready[c.fd] = c
+				if events[i].Op == io_multiplexer.OpRead || events[i].Op == io_multiplexer.OpReadWrite {
+					cmds, err := c.readCommands()
+					if err != nil {
+						closeClient(c, err)
+						continue
+					}
+					c.dispatch(cmds, notifier(c), exec)
+				}
+			}
+		}
+		// serving a client may serve others blocked on the keys it writes
+		for len(ready) > 0 {
+			for fd, c := range ready {
+				delete(ready, fd)
+				if err := serve(c); err != nil {
+					closeClient(c, err)
 				}
 			}
 		}
+		// a step of the background jobs, like a chunk of BGSAVE, between the commands
+		st.ks.jobs.run(st.db)
 		// Idle
 		atomic.SwapInt32(&serverStatus, constant.ServerStatusIdle)
 	}
 
 	return nil
 }
+
+// Shutdown waits for the event loop to be idle and stops it, then saves the keyspace
+func (st *singleThreadServer) Shutdown(ctx context.Context) error {
+	for !atomic.CompareAndSwapInt32(&serverStatus, constant.ServerStatusIdle, constant.ServerStatusShuttingDown) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		runtime.Gosched()
+	}
+	// the event loop is stopped, the keyspace can be read from here
+	st.ks.jobs.finish(st.db)
+	st.persistence.saveOnShutdown()
+	st.persistence.close()
+	return nil
+}
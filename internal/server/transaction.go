@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/nhtuan0700/godis/internal/constant"
+	"github.com/nhtuan0700/godis/internal/core"
+)
+
+var (
+	errNestedMulti         = errors.New("ERR MULTI calls can not be nested")
+	errExecWithoutMulti    = errors.New("ERR EXEC without MULTI")
+	errDiscardWithoutMulti = errors.New("ERR DISCARD without MULTI")
+	errWatchInsideMulti    = errors.New("ERR WATCH inside MULTI is not allowed")
+	errNotAllowedInMulti   = errors.New("ERR Command not allowed inside a transaction")
+	errExecAbort           = errors.New("EXECABORT Transaction discarded because of previous errors.")
+)
+
+var respQueued = []byte("+QUEUED\r\n")
+
+// transactionCommands are executed by the connection, outside of a transaction UNWATCH too
+var transactionCommands = map[string]bool{
+	constant.CMD_MULTI: true, constant.CMD_EXEC: true, constant.CMD_DISCARD: true,
+	constant.CMD_WATCH: true, constant.CMD_UNWATCH: true,
+}
+
+// transaction is the MULTI/EXEC state of a client. The commands are queued by the connection,
+// EXEC sends them as a single task to the worker owning their keys, so nothing runs in between.
+// The keys of the queued commands and the watched keys must belong to a single worker,
+// a transaction spanning several workers is rejected with CROSSSLOT like RENAME.
+type transaction struct {
+	// multi is set between MULTI and EXEC or DISCARD
+	multi  bool
+	queued []*core.Command
+	// aborted is set when a command was rejected while queuing, EXEC then discards the transaction
+	aborted bool
+	// watched is nil until the first WATCH, its versions are only read and written by the worker, see core.WatchedKeys
+	watched *core.WatchedKeys
+	// key is the first key of the transaction, the tasks of the transaction are sent to its worker
+	key    string
+	hasKey bool
+}
+
+// executeTransaction executes MULTI, EXEC, DISCARD, WATCH and UNWATCH, and queues the commands sent after MULTI.
+// It returns false for the commands to execute now, including the ones with a wrong arity outside of a transaction.
+func (c *client) executeTransaction(cmd *core.Command, notify func(), exec func(task *core.Task)) bool {
+	// a subscribed client is never in a transaction, see executePubSub
+	if c.subscribed() {
+		return false
+	}
+	name := ""
+	if spec := core.LookupCommand(cmd.Cmd); spec != nil && spec.CheckArity(len(cmd.Args)) {
+		name = spec.Name
+	}
+	if !c.tx.multi && !transactionCommands[name] {
+		return false
+	}
+
+	switch {
+	case name == constant.CMD_MULTI:
+		if c.tx.multi {
+			c.enqueueReply(core.Encode(errNestedMulti, false))
+			break
+		}
+		c.tx.multi = true
+		c.enqueueReply(constant.RespOk)
+	case name == constant.CMD_EXEC:
+		if !c.tx.multi {
+			c.enqueueReply(core.Encode(errExecWithoutMulti, false))
+			break
+		}
+		tx := c.tx
+		c.tx = transaction{}
+		if tx.aborted {
+			c.endTransaction(tx, core.Encode(errExecAbort, false), notify, exec)
+			break
+		}
+		c.runOnShard(tx.key, func(redisDB *core.RedisDB) []byte {
+			return redisDB.ExecTransaction(tx.queued, tx.watched)
+		}, notify, exec)
+	case name == constant.CMD_DISCARD:
+		if !c.tx.multi {
+			c.enqueueReply(core.Encode(errDiscardWithoutMulti, false))
+			break
+		}
+		tx := c.tx
+		c.tx = transaction{}
+		c.endTransaction(tx, constant.RespOk, notify, exec)
+	case name == constant.CMD_WATCH:
+		if c.tx.multi {
+			c.enqueueReply(core.Encode(errWatchInsideMulti, false))
+			break
+		}
+		c.watch(cmd.Args, notify, exec)
+	case name == constant.CMD_UNWATCH && !c.tx.multi:
+		tx := c.tx
+		c.tx = transaction{}
+		c.endTransaction(tx, constant.RespOk, notify, exec)
+	default:
+		c.enqueueReply(c.queue(cmd))
+	}
+	return true
+}
+
+// queue adds a command to the transaction, it replies QUEUED or the error that aborts the transaction
+func (c *client) queue(cmd *core.Command) []byte {
+	spec, err := core.CheckCommand(cmd)
+	switch {
+	case err != nil:
+	// SAVE and the Pub/Sub commands are not executed by a RedisDB
+	case spec.Handler == nil:
+		err = errNotAllowedInMulti
+	// KEYS and alike would only see the keys of one worker
+	case c.ks.numShards() > 1 && (spec.HasTip("request_policy:all_shards") || spec.Name == constant.CMD_SCAN):
+		err = errCrossShard
+	case !c.sameShard(spec.Keys(cmd.Args)):
+		err = errCrossShard
+	}
+	if err != nil {
+		c.tx.aborted = true
+		return core.Encode(err, false)
+	}
+
+	c.tx.queued = append(c.tx.queued, cmd)
+	return respQueued
+}
+
+// watch registers the keys on the worker owning them, with their current version
+func (c *client) watch(keys []string, notify func(), exec func(task *core.Task)) {
+	if !c.sameShard(keys) {
+		c.enqueueReply(core.Encode(errCrossShard, false))
+		return
+	}
+	if c.tx.watched == nil {
+		c.tx.watched = core.NewWatchedKeys()
+	}
+	watched := c.tx.watched
+	c.runOnShard(c.tx.key, func(redisDB *core.RedisDB) []byte {
+		redisDB.Watch(watched, keys)
+		return constant.RespOk
+	}, notify, exec)
+}
+
+// sameShard reports whether keys belong to the shard of the transaction, the first key of a transaction sets it
+func (c *client) sameShard(keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	key := c.tx.key
+	if !c.tx.hasKey {
+		key = keys[0]
+	}
+	for _, k := range keys {
+		if c.ks.shardOf(k) != c.ks.shardOf(key) {
+			return false
+		}
+	}
+	c.tx.key, c.tx.hasKey = key, true
+	return true
+}
+
+// endTransaction replies to the command ending tx once its watched keys are forgotten
+func (c *client) endTransaction(tx transaction, res []byte, notify func(), exec func(task *core.Task)) {
+	if tx.watched == nil {
+		c.enqueueReply(res)
+		return
+	}
+	c.runOnShard(tx.key, func(redisDB *core.RedisDB) []byte {
+		redisDB.Unwatch(tx.watched)
+		return res
+	}, notify, exec)
+}
+
+// runOnShard queues the reply of fn, executed on the RedisDB owning key
+func (c *client) runOnShard(key string, fn func(redisDB *core.RedisDB) []byte, notify func(), exec func(task *core.Task)) {
+	task := &core.Task{
+		Fn:        fn,
+		Key:       key,
+		ReplyChan: make(chan []byte, 1),
+		Notify:    notify,
+	}
+	c.enqueue(task.ReplyChan)
+	exec(task)
+}
+
+// unwatchTask returns the task forgetting the keys watched by a closed connection, nil when it watches none
+func (c *client) unwatchTask() *core.Task {
+	if c.tx.watched == nil {
+		return nil
+	}
+	watched, key := c.tx.watched, c.tx.key
+	c.tx = transaction{}
+	return &core.Task{
+		Fn: func(redisDB *core.RedisDB) []byte {
+			redisDB.Unwatch(watched)
+			return nil
+		},
+		Key:       key,
+		ReplyChan: make(chan []byte, 1),
+	}
+}
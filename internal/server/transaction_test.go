@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhtuan0700/godis/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestClient returns a function sending a command as a client of s and returning what is written back
+func newTestClient(s *Server) func(args ...string) string {
+	c := newClient(-1, nil, s, nil, s.users)
+	return func(args ...string) string {
+		c.dispatch([]*core.Command{{Cmd: strings.ToUpper(args[0]), Args: args[1:]}}, func() {}, s.dispatch)
+		for len(c.pending) > 0 {
+			c.collectReplies()
+			time.Sleep(time.Millisecond)
+		}
+		out := string(c.writeBuf)
+		c.writeBuf = nil
+		return out
+	}
+}
+
+func TestTransactionOnWorker(t *testing.T) {
+	s := newTestServer(t, 4)
+	send := newTestClient(s)
+	other := newTestClient(s)
+	// keys of the same worker can be used together
+	var keys []string
+	for i := 0; len(keys) < 2; i++ {
+		if key := fmt.Sprintf("key:%d", i); s.getWorkerID(key) == s.getWorkerID("key:0") {
+			keys = append(keys, key)
+		}
+	}
+
+	assert.Equal(t, "+OK\r\n", send("MULTI"))
+	assert.Equal(t, "+QUEUED\r\n", send("SET", keys[0], "1"))
+	assert.Equal(t, "+QUEUED\r\n", send("RENAME", keys[0], keys[1]))
+	assert.Equal(t, "+QUEUED\r\n", send("INCR", keys[1]))
+	assert.Equal(t, "+QUEUED\r\n", send("PING"))
+	assert.Equal(t, "*4\r\n+OK\r\n+OK\r\n:2\r\n+PONG\r\n", send("EXEC"))
+
+	// a change made by another client aborts the transaction
+	assert.Equal(t, "+OK\r\n", send("WATCH", keys[0], keys[1]))
+	assert.Equal(t, "+OK\r\n", other("SET", keys[1], "changed"))
+	send("MULTI")
+	send("SET", keys[1], "mine")
+	assert.Equal(t, "*-1\r\n", send("EXEC"))
+	assert.Equal(t, "$7\r\nchanged\r\n", other("GET", keys[1]))
+
+	// EXEC unwatched the keys
+	send("MULTI")
+	send("SET", keys[1], "mine")
+	assert.Equal(t, "*1\r\n+OK\r\n", send("EXEC"))
+	assert.Equal(t, "+OK\r\n", send("WATCH", keys[1]))
+	assert.Equal(t, "+OK\r\n", send("UNWATCH"))
+	other("DEL", keys[1])
+	send("MULTI")
+	send("EXISTS", keys[1])
+	assert.Equal(t, "*1\r\n:0\r\n", send("EXEC"))
+}
+
+func TestTransactionErrors(t *testing.T) {
+	s := newTestServer(t, 4)
+	send := newTestClient(s)
+	keys := keysOnDifferentWorkers(s, 2)
+
+	assert.Equal(t, "-ERR EXEC without MULTI\r\n", send("EXEC"))
+	assert.Equal(t, "-ERR DISCARD without MULTI\r\n", send("DISCARD"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'multi' command\r\n", send("MULTI", "x"))
+
+	// errors that do not abort the transaction
+	send("MULTI")
+	assert.Equal(t, "-ERR MULTI calls can not be nested\r\n", send("MULTI"))
+	assert.Equal(t, "-ERR WATCH inside MULTI is not allowed\r\n", send("WATCH", keys[0]))
+	send("SET", keys[0], "v")
+	assert.Equal(t, "*1\r\n+OK\r\n", send("EXEC"))
+
+	// errors while queuing abort it
+	for _, cmd := range [][]string{
+		{"NOPE"},
+		{"GET"},
+		{"SAVE"},
+		{"SUBSCRIBE", "channel"},
+		{"SET", keys[1], "v"},
+		{"MSET", keys[0], "v", keys[1], "v"},
+		{"KEYS", "*"},
+	} {
+		send("MULTI")
+		send("DEL", keys[0])
+		assert.Equal(t, "-", send(cmd...)[:1], cmd)
+		assert.Equal(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", send("EXEC"), cmd)
+		assert.Equal(t, ":1\r\n", execute(s, "EXISTS", keys[0]), cmd)
+	}
+
+	// the watched keys and the keys of the commands belong to the same worker
+	assert.Equal(t, "-CROSSSLOT Keys in request don't hash to the same slot\r\n", send("WATCH", keys[0], keys[1]))
+	assert.Equal(t, "+OK\r\n", send("WATCH", keys[0]))
+	assert.Equal(t, "-CROSSSLOT Keys in request don't hash to the same slot\r\n", send("WATCH", keys[1]))
+	send("MULTI")
+	assert.Equal(t, "-CROSSSLOT Keys in request don't hash to the same slot\r\n", send("GET", keys[1]))
+	assert.Equal(t, "+OK\r\n", send("DISCARD"))
+	// DISCARD unwatched keys[0]
+	assert.Equal(t, "+OK\r\n", send("WATCH", keys[1]))
+}